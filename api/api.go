@@ -18,6 +18,9 @@ var (
     ErrTimeNull = errors.New("times list empty")
     ErrNoOffer = errors.New("table is not offered on given date")
     ErrNoPayInfo = errors.New("no payment info on account")
+    ErrNoPolicy = errors.New("venue does not publish a booking policy")
+    ErrNoTimezone = errors.New("venue does not publish a timezone")
+    ErrNoAvailability = errors.New("service does not publish availability")
 )
 
 
@@ -81,7 +84,7 @@ Type: API Func Output Struct
 Purpose: Output information from 'Search' api function 
 */
 type SearchResponse struct {
-    Results []SearchResult
+    Results []SearchResult  `json:"results"`
 }
 
 /*
@@ -90,11 +93,11 @@ Type: API Output Struct
 Purpose: Output specific results from 'Search' api function 
 */
 type SearchResult struct {
-    VenueID         int64 
-    Name            string
-    Region          string
-    Locality        string
-    Neighborhood    string
+    VenueID         int64   `json:"venue_id"`
+    Name            string  `json:"name"`
+    Region          string  `json:"region"`
+    Locality        string  `json:"locality"`
+    Neighborhood    string  `json:"neighborhood"`
 }
 
 /*
@@ -136,6 +139,81 @@ type ReserveResponse struct {
     ReservationTime time.Time
 }
 
+/*
+Name: BookingPolicyParam
+Type: API Func Input Struct
+Purpose: Input information to the 'BookingPolicy' api function 
+*/
+type BookingPolicyParam struct {
+    VenueID         int64
+}
+
+/*
+Name: BookingPolicyResponse
+Type: API Func Output Struct
+Purpose: Output information from the 'BookingPolicy' api function.
+Note: Services publish different parts of a policy, so ReleaseKnown
+marks whether ReleaseHour and ReleaseMinute are set, and Location is
+nil when the venue timezone is not published
+*/
+type BookingPolicyResponse struct {
+    DaysInAdvance   int
+    ReleaseHour     int
+    ReleaseMinute   int
+    ReleaseKnown    bool
+    Location        *time.Location
+}
+
+/*
+Name: TimezoneParam
+Type: API Func Input Struct
+Purpose: Input information to the 'Timezone' api function 
+*/
+type TimezoneParam struct {
+    VenueID         int64
+}
+
+/*
+Name: TimezoneResponse
+Type: API Func Output Struct
+Purpose: Output information from the 'Timezone' api function 
+*/
+type TimezoneResponse struct {
+    Location        *time.Location
+}
+
+/*
+Name: AvailabilityParam
+Type: API Func Input Struct
+Purpose: Input information to the 'Availability' api function 
+*/
+type AvailabilityParam struct {
+    VenueID         int64
+    Day             time.Time
+    PartySize       int
+    LoginResp       LoginResponse
+}
+
+/*
+Name: Slot
+Type: API Func Output Struct
+Purpose: A table offered at a venue. Time is on the
+day asked for, in the location of that day
+*/
+type Slot struct {
+    Time            time.Time
+    TableType       string
+}
+
+/*
+Name: AvailabilityResponse
+Type: API Func Output Struct
+Purpose: Output information from the 'Availability' api function 
+*/
+type AvailabilityResponse struct {
+    Slots           []Slot
+}
+
 /*
 Name: API 
 Type: Interface 
@@ -148,6 +226,9 @@ type API interface {
     Search(params SearchParam) (*SearchResponse, error)
     Reserve(params ReserveParam) (*ReserveResponse, error)
     AuthMinExpire() (time.Duration)
+    BookingPolicy(params BookingPolicyParam) (*BookingPolicyResponse, error)
+    Timezone(params TimezoneParam) (*TimezoneResponse, error)
+    Availability(params AvailabilityParam) (*AvailabilityResponse, error)
 }
 
 /*
@@ -15,11 +15,15 @@ General Purpose:
 
 API:
 
-    The API interface specifies 3 methods:
+    The API interface specifies 7 methods:
     
         Login(params LoginParam) (*LoginResponse, error)
         Reserve(params ReserveParam) (*ReserveResponse, error)
         Search(params SearchParam) (*SearchResponse, error)
+        AuthMinExpire() (time.Duration)
+        BookingPolicy(params BookingPolicyParam) (*BookingPolicyResponse, error)
+        Timezone(params TimezoneParam) (*TimezoneResponse, error)
+        Availability(params AvailabilityParam) (*AvailabilityResponse, error)
     
 **********************************************************************
 
@@ -66,5 +70,38 @@ AuthMinExpire:
 
 **********************************************************************   
 
+BookingPolicy:
+
+    The BookingPolicy function takes in a venue id and returns the 
+    booking window policy of the venue, i.e. how many days in 
+    advance tables are released, the time of day they are released
+    at and the timezone of the venue. Services only publish parts of
+    this, so the response marks which fields are known, and if a
+    service publishes nothing for a venue, ErrNoPolicy is returned.
+
+**********************************************************************   
+
+Timezone:
+
+    The Timezone function takes in a venue id and returns the 
+    timezone the venue operates in. Reservation times passed to
+    Reserve are compared against the venue's slots by their hour
+    and minute, so they should be expressed in this timezone. If
+    a service does not publish a timezone, ErrNoTimezone is 
+    returned.
+
+**********************************************************************   
+
+Availability:
+
+    The Availability function takes in a venue id, day and party size
+    and returns every slot the venue currently offers that day, with
+    its time and table type, without booking anything. Slot times are
+    built on the day passed in, so they share its location. If a 
+    service does not publish availability, ErrNoAvailability is 
+    returned.
+
+**********************************************************************   
+
 */
 package api
@@ -300,3 +300,18 @@ func (a *API) Search(params api.SearchParam) (*api.SearchResponse, error) {
 
     return &searchResponse, nil
 }
+
+// Opentable does not publish booking windows through any endpoint we use
+func (a *API) BookingPolicy(params api.BookingPolicyParam) (*api.BookingPolicyResponse, error) {
+    return nil, api.ErrNoPolicy
+}
+
+// Opentable venue timezones are not exposed through any endpoint we use
+func (a *API) Timezone(params api.TimezoneParam) (*api.TimezoneResponse, error) {
+    return nil, api.ErrNoTimezone
+}
+
+// Opentable availability is not exposed through any endpoint we use
+func (a *API) Availability(params api.AvailabilityParam) (*api.AvailabilityResponse, error) {
+    return nil, api.ErrNoAvailability
+}
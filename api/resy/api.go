@@ -257,51 +257,13 @@ func (a *API) Reserve(params api.ReserveParam) (*api.ReserveResponse, error) {
     fields := []string{dayField, authField, latField, longField, venueIDField, partySizeField}
 
     findUrl := `https://api.resy.com/4/find?` + strings.Join(fields, "&")
-    
-    request, err := http.NewRequest("GET", findUrl, bytes.NewBuffer([]byte{}))
-    if err != nil {
-        return nil, err
-    }
-    
-    request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-    request.Header.Set("Authorization", `ResyAPI api_key="` + a.APIKey + `"`)
-    request.Header.Set("X-Resy-Auth-Token", params.LoginResp.AuthToken)
-    request.Header.Set("X-Resy-Universal-Auth-Token", params.LoginResp.AuthToken)
-    request.Header.Set("Referer", "https://resy.com/")
 
-
-    client := &http.Client{}
-    response, err := client.Do(request)
+    jsonSlotsList, err := a.findSlots(findUrl, params.LoginResp.AuthToken)
     if err != nil {
         return nil, err
     }
 
-    if isCodeFail(response.StatusCode) {
-        return nil, api.ErrNetwork
-    }
-
-    defer response.Body.Close()
-
-    responseBody, err := io.ReadAll(response.Body)
-    if err != nil {
-        return nil, err
-    }
-
-    var jsonTopLevelMap map[string]interface{}
-    err = json.Unmarshal(responseBody, &jsonTopLevelMap)
-    if err != nil {
-        return nil, err
-    }
-
-
-    // JSON structure is complicated here, see api/resy/doc.go for full explanation
-    jsonResultsMap := jsonTopLevelMap["results"].(map[string]interface{}) 
-    jsonVenuesList := jsonResultsMap["venues"].([]interface{})
-    if len(jsonVenuesList) == 0 {
-        return nil, api.ErrNoOffer
-    }
-    jsonVenueMap := jsonVenuesList[0].(map[string]interface{})
-    jsonSlotsList := jsonVenueMap["slots"].([]interface{}) 
+    client := &http.Client{}
     for k := 0; k < len(params.TableTypes) || (len(params.TableTypes) == 0 && k == 0) ; k++ {
         // table type to search for, we decide this early on since its the least important thing
         
@@ -431,6 +393,110 @@ func (a *API) Reserve(params api.ReserveParam) (*api.ReserveResponse, error) {
     return nil, api.ErrNoTable 
 }
 
+/*
+Name: findSlots 
+Type: Internal Func 
+Purpose: Run a /4/find query and return the JSON list
+of slots the venue offers, see api/resy/doc.go
+*/
+func (a *API) findSlots(findUrl string, authToken string) ([]interface{}, error) {
+    request, err := http.NewRequest("GET", findUrl, bytes.NewBuffer([]byte{}))
+    if err != nil {
+        return nil, err
+    }
+    
+    request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    request.Header.Set("Authorization", `ResyAPI api_key="` + a.APIKey + `"`)
+    request.Header.Set("X-Resy-Auth-Token", authToken)
+    request.Header.Set("X-Resy-Universal-Auth-Token", authToken)
+    request.Header.Set("Referer", "https://resy.com/")
+
+
+    client := &http.Client{}
+    response, err := client.Do(request)
+    if err != nil {
+        return nil, err
+    }
+
+    if isCodeFail(response.StatusCode) {
+        return nil, api.ErrNetwork
+    }
+
+    defer response.Body.Close()
+
+    responseBody, err := io.ReadAll(response.Body)
+    if err != nil {
+        return nil, err
+    }
+
+    var jsonTopLevelMap map[string]interface{}
+    err = json.Unmarshal(responseBody, &jsonTopLevelMap)
+    if err != nil {
+        return nil, err
+    }
+
+
+    // JSON structure is complicated here, see api/resy/doc.go for full explanation
+    jsonResultsMap := jsonTopLevelMap["results"].(map[string]interface{}) 
+    jsonVenuesList := jsonResultsMap["venues"].([]interface{})
+    if len(jsonVenuesList) == 0 {
+        return nil, api.ErrNoOffer
+    }
+    jsonVenueMap := jsonVenuesList[0].(map[string]interface{})
+    jsonSlotsList := jsonVenueMap["slots"].([]interface{}) 
+    return jsonSlotsList, nil
+}
+
+/*
+Name: Availability 
+Type: API Func 
+Purpose: Resy implementation of the Availability api func
+*/
+func (a *API) Availability(params api.AvailabilityParam) (*api.AvailabilityResponse, error) {
+    // same query Reserve starts with, see api/resy/doc.go
+    year := strconv.Itoa(params.Day.Year())
+    month := strconv.Itoa(int(params.Day.Month()))
+    day := strconv.Itoa(params.Day.Day())
+    dayField := `day=` + year + "-" + month + "-" + day
+    authField := `x-resy-auth-token=` + params.LoginResp.AuthToken
+    venueIDField := `venue_id=` + strconv.FormatInt(params.VenueID, 10)
+    partySizeField := `party_size=` + strconv.Itoa(params.PartySize)
+    fields := []string{dayField, authField, `lat=0`, `long=0`, venueIDField, partySizeField}
+
+    findUrl := `https://api.resy.com/4/find?` + strings.Join(fields, "&")
+
+    jsonSlotsList, err := a.findSlots(findUrl, params.LoginResp.AuthToken)
+    if err != nil {
+        if err == api.ErrNoOffer {
+            return &api.AvailabilityResponse{}, nil
+        }
+        return nil, err
+    }
+
+    slots := make([]api.Slot, 0, len(jsonSlotsList))
+    for _, jsonSlot := range jsonSlotsList {
+        jsonSlotMap := jsonSlot.(map[string]interface{})
+        jsonDateMap := jsonSlotMap["date"].(map[string]interface{})
+        // start is "YrYrYrYr-MoMo-DyDy HrHr:MnMn:SeSe", like in Reserve
+        startFields := strings.Split(jsonDateMap["start"].(string), " ")
+        timeFields := strings.Split(startFields[1], ":")
+        hour, err := strconv.Atoi(timeFields[0])
+        if err != nil {
+            return nil, err
+        }
+        minute, err := strconv.Atoi(timeFields[1])
+        if err != nil {
+            return nil, err
+        }
+        jsonConfigMap := jsonSlotMap["config"].(map[string]interface{})
+        slots = append(slots, api.Slot{
+            Time: time.Date(params.Day.Year(), params.Day.Month(), params.Day.Day(), hour, minute, 0, 0, params.Day.Location()),
+            TableType: strings.ToLower(jsonConfigMap["type"].(string)),
+        })
+    }
+    return &api.AvailabilityResponse{Slots: slots}, nil
+}
+
 /*
 Name: AuthMinExpire 
 Type: API Func 
@@ -443,6 +509,112 @@ func (a *API) AuthMinExpire() (time.Duration) {
     return d
 }
 
+/*
+Name: getVenue 
+Type: Internal Func 
+Purpose: Fetch the venue details JSON of a venue, which
+holds both the booking policy and timezone of the venue
+*/
+func (a *API) getVenue(venueID int64) (map[string]interface{}, error) {
+    venueUrl := "https://api.resy.com/3/venue?id=" + strconv.FormatInt(venueID, 10)
+
+    request, err := http.NewRequest("GET", venueUrl, bytes.NewBuffer([]byte{}))
+    if err != nil {
+        return nil, err
+    }
+
+    request.Header.Set("Authorization", `ResyAPI api_key="` + a.APIKey + `"`)
+    request.Header.Set("Origin", `https://resy.com`)
+    request.Header.Set("Referer", `https://resy.com/`)
+
+    client := &http.Client{}
+    response, err := client.Do(request)
+    if err != nil {
+        return nil, err
+    }
+
+    if isCodeFail(response.StatusCode) {
+        return nil, api.ErrNetwork
+    }
+
+    defer response.Body.Close()
+
+    responseBody, err := io.ReadAll(response.Body)
+    if err != nil {
+        return nil, err
+    }
+
+    var jsonTopLevelMap map[string]interface{}
+    err = json.Unmarshal(responseBody, &jsonTopLevelMap)
+    if err != nil {
+        return nil, err
+    }
+    return jsonTopLevelMap, nil
+}
+
+/*
+Name: venueLocation 
+Type: Internal Func 
+Purpose: Read the venue timezone out of the venue 
+details JSON, nil if it is missing or unknown
+*/
+func venueLocation(jsonVenueMap map[string]interface{}) (*time.Location) {
+    // fields are optional here, so we check every assertion
+    // instead of trusting the structure like in Reserve
+    jsonLocationMap, ok := jsonVenueMap["location"].(map[string]interface{})
+    if !ok {
+        return nil
+    }
+    timeZone, ok := jsonLocationMap["time_zone"].(string)
+    if !ok {
+        return nil
+    }
+    loc, err := time.LoadLocation(timeZone)
+    if err != nil {
+        return nil
+    }
+    return loc
+}
+
+/*
+Name: BookingPolicy 
+Type: API Func 
+Purpose: Resy implementation of the BookingPolicy api func.
+Resy publishes how many days ahead a venue opens its calendar
+and the venue timezone, but not the release time of day
+*/
+func (a *API) BookingPolicy(params api.BookingPolicyParam) (*api.BookingPolicyResponse, error) {
+    jsonVenueMap, err := a.getVenue(params.VenueID)
+    if err != nil {
+        return nil, err
+    }
+    leadDays, ok := jsonVenueMap["lead_time_in_days"].(float64)
+    if !ok {
+        return nil, api.ErrNoPolicy
+    }
+    return &api.BookingPolicyResponse{
+        DaysInAdvance: int(leadDays),
+        Location: venueLocation(jsonVenueMap),
+    }, nil
+}
+
+/*
+Name: Timezone 
+Type: API Func 
+Purpose: Resy implementation of the Timezone api func
+*/
+func (a *API) Timezone(params api.TimezoneParam) (*api.TimezoneResponse, error) {
+    jsonVenueMap, err := a.getVenue(params.VenueID)
+    if err != nil {
+        return nil, err
+    }
+    loc := venueLocation(jsonVenueMap)
+    if loc == nil {
+        return nil, api.ErrNoTimezone
+    }
+    return &api.TimezoneResponse{Location: loc}, nil
+}
+
 //func (a *API) Cancel(params api.CancelParam) (*api.CancelResponse, error) {
 //    cancelUrl := `https://api.resy.com/3/cancel` 
 //    resyToken := url.QueryEscape(params.ResyToken)
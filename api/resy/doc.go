@@ -309,6 +309,49 @@ Reserve:
 
     If the server response is any 200 code, the reservation has been made.    
 
+**********************************************************************
+
+BookingPolicy:
+
+    The booking window of a venue is read off the venue endpoint,
+    which is a GET with the venue id, denoted here by ###VID###,
+    as a query field:
+
+        https://api.resy.com/3/venue?id=###VID###
+
+    The same Origin and Referer headers as a search are sent. Of 
+    the large response, we only use the number of days ahead the 
+    venue opens its calendar, denoted ###LEAD###, and the tz 
+    database name of the venue timezone, denoted ###TZ###:
+
+        Response Body:
+
+            {
+                ...
+                "lead_time_in_days": ###LEAD###,
+                "location": {
+                    ...
+                    "time_zone": "###TZ###",
+                    ...
+                },
+                ...
+            }
+
+    Resy does not publish the time of day tables are released at,
+    so the response never has ReleaseKnown set. A venue without a
+    lead time yields api.ErrNoPolicy. The Timezone function reads
+    ###TZ### off the same request.
+
+**********************************************************************
+
+Availability:
+
+    Availability runs the same /4/find query as the first step of a
+    reserve, explained above, but stops there. Every slot in the 
+    response is returned with the hour and minute of its start field
+    and the lowercased type of its config, and nothing is booked. A
+    day the venue offers nothing on is an empty list, not an error.
+
 **********************************************************************
 */
 package resy
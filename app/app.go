@@ -6,10 +6,12 @@ package app
 
 import (
     "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/vault"
+    "github.com/21Bruce/resolved-server/clock"
+    "github.com/21Bruce/resolved-server/notify"
     "errors"
     "time"
-    "strconv"
-    "fmt"
+    "sync"
 )
 
 var (
@@ -21,6 +23,9 @@ var (
     ErrCurrOp = errors.New("operation is in progress")
     ErrIdOp = errors.New("no operation has specified id")
     ErrTimeFut = errors.New("provided time has passed")
+    ErrNoStore = errors.New("no credential store configured")
+    ErrNoAccounts = errors.New("no accounts provided")
+    ErrBadStatus = errors.New("unknown operation status")
 )
 
 // OperationStatus type is an enum, only use with next const def types
@@ -33,6 +38,9 @@ const (
     CancelStatusType 
 )
 
+// Value of Operation.Group for operations not in a group
+const NoGroup int64 = -1
+
 // Hide as much api layer details as permissible 
 type LoginParam api.LoginParam
 
@@ -49,18 +57,59 @@ functions run
 */
 type AppCtx struct {
 
+    // Guards the operations list, login defaults and id
+    // generator, since group threads update operations too
+    mu          sync.Mutex
+
     // The API to run the app on
     API         api.API
 
+    // Optional store for saved accounts, when set login
+    // defaults are kept here instead of in memory
+    Credentials vault.Store
+
+    // Optional clock operations are scheduled on, the 
+    // real clock when nil. Tests set a fake one
+    Clock       clock.Clock
+
     // List of internal concurrent operations, both completed
     // and running
     operations  []Operation    
 
-    // Login Default storage 
-    loginInfo   LoginParam
+    // Logins given to Login, kept in memory under their
+    // email so operations only ever hold the name
+    session     vault.MemStore
+
+    // Name of the default account, saved or from Login
+    account     string
 
     // Simple ID generator
     idGen       int64
+
+    // Finished operations still sending their notification
+    notifying   int
+
+    // Venue timezones already looked up through the api
+    locations   map[int64]*time.Location
+
+    // Release times of day of venues the api doesn't publish,
+    // under AnyVenue for the rest
+    releases    map[int64]time.Duration
+
+    // Last measured skew against the provider's clock, nil
+    // if never synced, and how early to fire requests
+    clockSkew   *ClockSkew
+    leadTime    time.Duration
+
+    // Told how operations ended, unless they have their own
+    notifier    notify.Notifier
+
+    // Subscribers to operation lifecycle events
+    events      eventHub
+
+    // Recurring templates and their ID generator
+    templates       []Template
+    templateIDGen   int64
 }
 
 /*
@@ -71,11 +120,13 @@ reserve at interval operation by a consumer
 */
 type ReserveAtIntervalParam struct {
     Login            LoginParam
+    Account          string
     VenueID          int64
     ReservationTimes []time.Time
     PartySize        int
     RepeatInterval   time.Duration
     TableTypes 	     []api.TableType
+    Notifier         notify.Notifier `json:"-"`
 }
 
 /*
@@ -86,11 +137,29 @@ reserve at time operation by a consumer
 */
 type ReserveAtTimeParam struct {
     Login            LoginParam
+    Account          string
+    VenueID          int64
+    ReservationTimes []time.Time
+    PartySize        int
+    RequestTime      time.Time
+    TableTypes 	     []api.TableType
+    Notifier         notify.Notifier `json:"-"`
+}
+
+/*
+Name: ReserveFanOutParam
+Type: App api func input parameters
+Purpose: Provide a means to make a reserve at time
+operation run across several saved accounts by a consumer
+*/
+type ReserveFanOutParam struct {
+    Accounts         []string
     VenueID          int64
     ReservationTimes []time.Time
     PartySize        int
     RequestTime      time.Time
     TableTypes 	     []api.TableType
+    Notifier         notify.Notifier `json:"-"`
 }
 
 /*
@@ -141,6 +210,29 @@ func (r ReserveAtTimeResponse) Time() (time.Time) {
     return r.ReservationTime
 }
 
+/*
+Name: ReserveFanOutResponse 
+Type: struct
+Purpose: Define the data that should be returned on a
+successful reserve fan out response. AlsoBooked lists any
+sibling accounts that finished booking before they 
+could be stopped
+*/
+type ReserveFanOutResponse struct {
+    ReservationTime time.Time
+    Account         string
+    AlsoBooked      []string
+}
+
+/*
+Name: Time 
+Type: interface method
+Purpose: Satisfy the Timetable interface
+*/
+func (r ReserveFanOutResponse) Time() (time.Time) {
+    return r.ReservationTime
+}
+
 /*
 Name: OperationResult 
 Type: struct 
@@ -159,11 +251,45 @@ Purpose: Maintain the state associated with a running
 go thread operation
 */
 type Operation struct{
-    ID      int64
-    Cancel  chan<- bool
-    Output  <-chan OperationResult
-    Result  *OperationResult
-    Status  OperationStatus
+    ID          int64
+    Group       int64
+    Cancel      chan<- bool
+    Output      <-chan OperationResult
+    Result      *OperationResult
+    Status      OperationStatus
+
+    // Venue timezone and, for operations firing at a set
+    // time, the request time, kept for display
+    Location    *time.Location
+    RequestTime time.Time
+
+    // Told how the operation ended instead of the global 
+    // notifier when set, and the error it gave if any
+    Notifier    notify.Notifier
+    NotifyErr   error
+
+    // What the operation was scheduled with. Params is the
+    // param struct it was scheduled with, the rest are filled
+    // in from it by startOperation
+    Params      interface{}
+    Type        OperationType
+    VenueID     int64
+    Accounts    []string
+
+    // Number of requests made to the service so far, 
+    // when the operation was created, made its first call
+    // and finished, and a record of each call it made.
+    // Dropped counts the records pushed out of a full log
+    Attempts    int
+    Created     time.Time
+    Started     time.Time
+    Finished    time.Time
+    Log         []AttemptRecord
+    Dropped     int
+
+    // Closed and replaced on every edit, to wake the
+    // threads of the operation
+    edited      chan bool
 }
 
 
@@ -185,6 +311,58 @@ func findLastTime(times []time.Time) (*time.Time, error) {
     return &lastTime, nil
 }
 
+/*
+Name: startOperation 
+Type: Internal Func
+Purpose: Generate an id, register a new in progress 
+operation and start its go thread. The descriptive fields
+of op(Group, Location, ...) are kept, the rest are filled
+in here. Must hold a.mu
+*/
+func (a *AppCtx) startOperation(op Operation, run func(id int64, cancel <-chan bool, output chan<- OperationResult)) (int64) {
+    // generate a new id
+    id := a.idGen
+    a.idGen += 1 
+
+    // make cancel and output channels to manage go thread,
+    // output is buffered so a thread can always finish even
+    // if no one reads its result. The thread writes to inner,
+    // so the outcome can be announced as it is passed on
+    cancel := make(chan bool)
+    inner := make(chan OperationResult, 1)
+    output := make(chan OperationResult, 1)
+
+    // add op to internal buffer list 
+    op.ID = id
+    op.Cancel = cancel
+    op.Output = output
+    op.Result = nil
+    op.Status = InProgressStatusType
+    op.edited = make(chan bool)
+    op.Type, op.VenueID, op.Accounts, op.Params = describeParams(op.Params)
+    // not a.now, which needs a.mu
+    op.Created = a.clock().Now()
+    a.operations = append(a.operations, op)
+    a.publish(Event{Type: ScheduledEvent, OperationID: id})
+    // run op
+    go run(id, cancel, inner)
+    go a.finishOperation(id, inner, output)
+    return id
+}
+
+/*
+Name: timesLocation 
+Type: Internal Func
+Purpose: Report the timezone reservation times are
+expressed in, which is the venue timezone
+*/
+func timesLocation(times []time.Time) (*time.Location) {
+    if len(times) == 0 {
+        return nil
+    }
+    return times[0].Location()
+}
+
 /*
 Name: updateOperationResult 
 Type: Internal Func
@@ -224,6 +402,8 @@ Purpose: Used to cancel the operation
 with the specified ID
 */
 func (a *AppCtx) CancelOperation(id int64) (error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
     // update before handling
     err := a.updateOperationResult(id)
     if err != nil {
@@ -239,9 +419,10 @@ func (a *AppCtx) CancelOperation(id int64) (error) {
             // we perform all stateful changes in place,
             // i.e., on a.operations[i] instead of the for loop
             // value 'operation' 
-            a.operations[i].Cancel <- true 
-            a.operations[i].Status = CancelStatusType
-            close(a.operations[i].Cancel)
+            a.stopOperation(i)
+
+            // cancelling a group cancels its members with it
+            a.stopMembers(id, NoGroup)
             return nil
         }
     }
@@ -249,41 +430,49 @@ func (a *AppCtx) CancelOperation(id int64) (error) {
     return ErrIdOp
 }
 
+/*
+Name: stopOperation 
+Type: Internal Func
+Purpose: Mark the in progress operation at index i as 
+cancelled and wake its thread. Closing the cancel channel
+wakes a select just like a send, but never blocks, and
+the status check keeps us from closing twice. Must hold a.mu
+*/
+func (a *AppCtx) stopOperation(i int) {
+    if a.operations[i].Status != InProgressStatusType {
+        return
+    }
+    a.operations[i].Status = CancelStatusType
+    close(a.operations[i].Cancel)
+}
+
 /*
 Name: ScheduleReserveAtIntervalOperation
 Type: External App Func
 Purpose: Used to Schedule a reserve at interval operation, returns ID 
 */
 func (a *AppCtx) ScheduleReserveAtIntervalOperation(params ReserveAtIntervalParam) (int64, error) {
-    // generate a new id
-    id := a.idGen
-    a.idGen += 1 
+    a.mu.Lock()
+    defer a.mu.Unlock()
 
-    // check if user provided any login overrides 
-    if (params.Login.Email == "" || params.Login.Password == "") {
-        // check if user did not, and they haven't logged in through
-        // login func, return err
-        if(a.loginInfo.Email == "" && a.loginInfo.Password == "") {
-            return 0, ErrNoLogin
-        }
-        // override params login info with stored login ijfo 
-        params.Login.Email = a.loginInfo.Email
-        params.Login.Password = a.loginInfo.Password
+    // check if user provided any login overrides, 
+    // otherwise fall back on the defaults
+    login, account, err := a.loginDefaults(params.Login, params.Account)
+    if err != nil {
+        return 0, err
     }
+    params.Login = login
+    params.Account = account
 
-    // make cancel and output channels to manage go thread 
-    cancel := make(chan bool)
-    output := make(chan OperationResult)
-
-    // add op to internal buffer list 
-    a.operations = append(a.operations, Operation{
-        ID: id,
-        Cancel: cancel,
-        Output: output,
-        Status: InProgressStatusType,
+    op := Operation{
+        Group: NoGroup,
+        Params: params,
+        Location: timesLocation(params.ReservationTimes),
+        Notifier: params.Notifier,
+    }
+    id := a.startOperation(op, func(id int64, cancel <-chan bool, output chan<- OperationResult) {
+        a.reserveAtInterval(id, params, cancel, output)
     })
-    // run op
-    go a.reserveAtInterval(params, cancel, output)
     return id, nil
 }
 
@@ -293,7 +482,7 @@ Type: Internal App Func
 Purpose: This function is intended to run on a separate thread, and tries making
 a reservation at a given interval of time
 */
-func (a *AppCtx) reserveAtInterval(params ReserveAtIntervalParam, cancel <-chan bool, output chan<- OperationResult){
+func (a *AppCtx) reserveAtInterval(id int64, params ReserveAtIntervalParam, cancel <-chan bool, output chan<- OperationResult){
 
     // find and store last time from time priority list
     lastTime, err := findLastTime(params.ReservationTimes)
@@ -304,10 +493,19 @@ func (a *AppCtx) reserveAtInterval(params ReserveAtIntervalParam, cancel <-chan
         return
     }
 
-    for {
+    for attempt := 1; ; attempt++ {
+        // pick up edits made since the last attempt
+        edited, _ := a.editedParams(id, params)
+        params = edited.(ReserveAtIntervalParam)
+        lastTime, err = findLastTime(params.ReservationTimes)
+        if err != nil {
+            output<-OperationResult{Response: nil, Err: err}     
+            close(output)
+            return
+        }
         
         // first run pre reservation auth 
-        loginResp, err := a.API.Login(api.LoginParam(params.Login))
+        loginResp, err := a.opLogin(id, params.Login, params.Account)
         
         if err != nil {
             output<-OperationResult{Response: nil, Err: err}     
@@ -316,7 +514,8 @@ func (a *AppCtx) reserveAtInterval(params ReserveAtIntervalParam, cancel <-chan
         }
 
         // next try reservation 
-        reserveResp, err := a.API.Reserve(
+        a.publishAttempt(id, attempt)
+        reserveResp, err := a.opReserve(id, 
             api.ReserveParam{
                 LoginResp: *loginResp,
                 ReservationTimes: params.ReservationTimes,
@@ -335,9 +534,10 @@ func (a *AppCtx) reserveAtInterval(params ReserveAtIntervalParam, cancel <-chan
         if err == api.ErrNoTable {
             // see if last time on list is still in the future,
             // since if it isn't there's no point in trying to reserve it
-            if lastTime.After(time.Now()) {
+            if lastTime.After(a.now()) {
+                a.publishWaiting(id, a.now().Add(params.RepeatInterval))
                 select {
-                case <-time.After(params.RepeatInterval):
+                case <-a.clock().After(params.RepeatInterval):
                     continue
                 case <-cancel:
                     output<-OperationResult{Response: nil, Err: ErrCancel}     
@@ -363,28 +563,31 @@ func (a *AppCtx) reserveAtInterval(params ReserveAtIntervalParam, cancel <-chan
 Name: ScheduleReserveAtTimeOperation
 Type: External App Func
 Purpose: Used to Schedule a reserve at time operation, returns ID 
-Note: Most of this logic should probably be merged
-with the ScheduleReserveAtIntervalOperation func since it's similar logic
 */
 func (a *AppCtx) ScheduleReserveAtTimeOperation(params ReserveAtTimeParam) (int64, error) {
-    id := a.idGen
-    a.idGen += 1 
-    if (params.Login.Email == "" || params.Login.Password == "") {
-        if(a.loginInfo.Email == "" && a.loginInfo.Password == "") {
-            return 0, ErrNoLogin
-        }
-        params.Login.Email = a.loginInfo.Email
-        params.Login.Password = a.loginInfo.Password
+    // with no request time, go by the venue booking policy
+    err := a.fillRequestTime(&params)
+    if err != nil {
+        return 0, err
     }
-    cancel := make(chan bool)
-    output := make(chan OperationResult)
-    a.operations = append(a.operations, Operation{
-        ID: id,
-        Cancel: cancel,
-        Output: output,
-        Status: InProgressStatusType,
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    login, account, err := a.loginDefaults(params.Login, params.Account)
+    if err != nil {
+        return 0, err
+    }
+    params.Login = login
+    params.Account = account
+    op := Operation{
+        Group: NoGroup,
+        Params: params,
+        Location: timesLocation(params.ReservationTimes),
+        RequestTime: params.RequestTime,
+        Notifier: params.Notifier,
+    }
+    id := a.startOperation(op, func(id int64, cancel <-chan bool, output chan<- OperationResult) {
+        a.reserveAtTime(id, params, cancel, output)
     })
-    go a.reserveAtTime(params, cancel, output)
     return id, nil
 }
 
@@ -394,47 +597,68 @@ Type: Internal App Func
 Purpose: This function is intended to run on a separate thread, and tries making
 a reservation at a given time
 */
-func (a *AppCtx) reserveAtTime(params ReserveAtTimeParam, cancel <-chan bool, output chan<- OperationResult) {
+func (a *AppCtx) reserveAtTime(id int64, params ReserveAtTimeParam, cancel <-chan bool, output chan<- OperationResult) {
  
     // if this date is not in the future, err 
-    if params.RequestTime.Before(time.Now().UTC()) {
+    if params.RequestTime.Before(a.now()) {
         output <- OperationResult{Response: nil, Err: ErrTimeFut}     
         close(output)
         return
     }
 
     minAuthTime := a.API.AuthMinExpire()
-    authDate := params.RequestTime.Add(-1 * minAuthTime)
-    if (!authDate.Before(time.Now().UTC())) {
-        select {
-        case <-time.After(time.Until(authDate)):
-            break
-        case <-cancel:
+    var loginResp *api.LoginResponse
+    var loginTime time.Time
+    for {
+        // an edit wakes us up to start over on the new params
+        edited, editCh := a.editedParams(id, params)
+        params = edited.(ReserveAtTimeParam)
+
+        authDate := params.RequestTime.Add(-1 * minAuthTime)
+        if (!authDate.Before(a.now())) {
+            a.publishWaiting(id, authDate)
+            switch a.waitOrEdit(authDate, false, cancel, editCh) {
+            case waitCancelled:
+                output<- OperationResult{Response: nil, Err:ErrCancel}
+                close(output)
+                return
+            case waitEdited:
+                continue
+            }
+        }
+
+        // log in again if the request moved past the token's life
+        if loginResp == nil || loginTime.Before(authDate) {
+            var err error
+            loginResp, err = a.opLogin(id, params.Login, params.Account)
+            if err != nil {
+               output<- OperationResult{Response: nil, Err:err}
+               close(output)
+               return
+            }
+            loginTime = a.now()
+        }
+ 
+        // sleep with ability to cancel, on the provider's clock
+        a.publishWaiting(id, params.RequestTime)
+        switch a.waitOrEdit(params.RequestTime, true, cancel, editCh) {
+        case waitCancelled:
             output<- OperationResult{Response: nil, Err:ErrCancel}
             close(output)
             return
+        case waitEdited:
+            continue
         }
+        break
     }
 
-    loginResp, err := a.API.Login(api.LoginParam(params.Login))
-
-    if err != nil {
-       output<- OperationResult{Response: nil, Err:err}
-       close(output)
-       return
-    }
- 
-    // sleep with ability to cancel 
-    select {
-    case <-time.After(time.Until(params.RequestTime)):
-    case <-cancel:
-        output<- OperationResult{Response: nil, Err:ErrCancel}
-        close(output)
-        return
-    }
+    // pick up any edit made while waking up
+    edited, _ := a.editedParams(id, params)
+    params = edited.(ReserveAtTimeParam)
 
     // reserve 
-    reserveResp, err := a.API.Reserve(
+    a.publishAttempt(id, 1)
+    reserveResp, err := a.opReserve(id, 
         api.ReserveParam{
             LoginResp: *loginResp,
             ReservationTimes: params.ReservationTimes,
@@ -457,11 +681,146 @@ func (a *AppCtx) reserveAtTime(params ReserveAtTimeParam, cancel <-chan bool, ou
     return
 }
 
+/*
+Name: ScheduleReserveFanOutOperation
+Type: External App Func
+Purpose: Used to Schedule a reserve at time operation
+that runs for every given saved account at once, returns ID 
+*/
+func (a *AppCtx) ScheduleReserveFanOutOperation(params ReserveFanOutParam) (int64, error) {
+    if a.Credentials == nil {
+        return 0, ErrNoStore
+    }
+    if len(params.Accounts) == 0 {
+        return 0, ErrNoAccounts
+    }
+    if params.RequestTime.IsZero() {
+        atTime := ReserveAtTimeParam{VenueID: params.VenueID, ReservationTimes: params.ReservationTimes}
+        err := a.fillRequestTime(&atTime)
+        if err != nil {
+            return 0, err
+        }
+        params.RequestTime = atTime.RequestTime
+    }
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    op := Operation{
+        Group: NoGroup,
+        Params: params,
+        Location: timesLocation(params.ReservationTimes),
+        RequestTime: params.RequestTime,
+        Notifier: params.Notifier,
+    }
+    id := a.startOperation(op, func(id int64, cancel <-chan bool, output chan<- OperationResult) {
+        a.reserveFanOut(id, params, cancel, output)
+    })
+    return id, nil
+}
+
+/*
+Name: fanOutResult 
+Type: Internal Struct
+Purpose: Tag the result of a fan out member thread
+with the account it ran for
+*/
+type fanOutResult struct {
+    account     string
+    result      OperationResult
+}
+
+/*
+Name: reserveFanOut 
+Type: Internal App Func
+Purpose: This function is intended to run on a separate thread. It runs
+a reserveAtTime thread per account and stops the rest as soon as one
+of them books. A member that books before a cancel could stop it 
+still wins over the cancel
+*/
+func (a *AppCtx) reserveFanOut(id int64, params ReserveFanOutParam, cancel <-chan bool, output chan<- OperationResult) {
+    // every member gets its own cancel channel, which we close
+    // to stop it, and a buffered output so it never blocks
+    memberCancels := make([]chan bool, len(params.Accounts))
+    results := make(chan fanOutResult, len(params.Accounts))
+    for i, account := range params.Accounts {
+        memberCancels[i] = make(chan bool)
+        memberOutput := make(chan OperationResult, 1)
+        go a.reserveAtTime(id, ReserveAtTimeParam{
+            Account: account,
+            VenueID: params.VenueID,
+            ReservationTimes: params.ReservationTimes,
+            PartySize: params.PartySize,
+            RequestTime: params.RequestTime,
+            TableTypes: params.TableTypes,
+        }, memberCancels[i], memberOutput)
+        go func(account string, memberOutput <-chan OperationResult) {
+            results <- fanOutResult{account: account, result: <-memberOutput}
+        }(account, memberOutput)
+    }
+    stopped := false
+    stopMembers := func() {
+        if stopped {
+            return
+        }
+        stopped = true
+        for _, memberCancel := range memberCancels {
+            close(memberCancel)
+        }
+    }
+
+    var firstErr error
+    cancelled := false
+    for remaining := len(params.Accounts); remaining > 0; {
+        select {
+        case res := <-results:
+            remaining -= 1
+            if res.result.Err != nil {
+                if firstErr == nil {
+                    firstErr = res.result.Err
+                }
+                continue
+            }
+            // first booking wins, stop the siblings and wait for 
+            // them so we can report any that booked anyway
+            stopMembers()
+            response := ReserveFanOutResponse{
+                ReservationTime: res.result.Response.Time(),
+                Account: res.account,
+            }
+            for ; remaining > 0; remaining-- {
+                sibling := <-results
+                if sibling.result.Err == nil {
+                    response.AlsoBooked = append(response.AlsoBooked, sibling.account)
+                }
+            }
+            output<- OperationResult{Response: response, Err: nil}
+            close(output)
+            return
+        case <-cancel:
+            // stop the members but keep collecting them, one may
+            // have booked already. A nil channel keeps us from 
+            // reading cancel again
+            stopMembers()
+            cancel = nil
+            cancelled = true
+        }
+    }
+
+    // nobody booked, report the cancel or else the first failure
+    if cancelled {
+        firstErr = ErrCancel
+    }
+    output<- OperationResult{Response: nil, Err: firstErr}
+    close(output)
+}
+
 /*
 Name: Login 
 Type: External App Func
 Purpose: This function stores loginParams in the
-app Ctx if they pass the Login method
+app Ctx if they pass the Login method and makes them
+the login default. They are only kept in memory, under
+their email, and operations refer to them by that name.
+SaveLogin keeps them in the credential store instead
 */
 func (a *AppCtx) Login(params LoginParam) (error) {
     reqParams := api.LoginParam(params)
@@ -469,10 +828,189 @@ func (a *AppCtx) Login(params LoginParam) (error) {
     if err != nil {
         return err
     }
-    a.loginInfo = params
+    err = a.session.Put(vault.Credential{Name: params.Email, Login: reqParams})
+    if err != nil {
+        return err
+    }
+    a.mu.Lock()
+    // the login wins over a default account
+    a.account = params.Email
+    a.mu.Unlock()
+    return nil
+}
+
+/*
+Name: SaveLogin 
+Type: External App Func
+Purpose: This function saves loginParams in the
+credential store under their email if they pass
+the Login method, and makes that account the
+login default
+*/
+func (a *AppCtx) SaveLogin(params LoginParam) (error) {
+    err := a.SaveAccount(params.Email, params)
+    if err != nil {
+        return err
+    }
+    a.mu.Lock()
+    a.account = params.Email
+    a.mu.Unlock()
+    return nil
+}
+
+/*
+Name: SaveAccount 
+Type: External App Func
+Purpose: This function stores loginParams in the
+credential store under name if they pass the Login
+method
+*/
+func (a *AppCtx) SaveAccount(name string, params LoginParam) (error) {
+    if a.Credentials == nil {
+        return ErrNoStore
+    }
+    if name == "" {
+        return vault.ErrNoName
+    }
+    reqParams := api.LoginParam(params)
+    _, err :=  a.API.Login(reqParams)
+    if err != nil {
+        return err
+    }
+    err = a.Credentials.Put(vault.Credential{Name: name, Login: reqParams})
+    if err != nil {
+        return err
+    }
+    // what was just saved wins over an older login of the name
+    a.session.Delete(name)
+    return nil
+}
+
+/*
+Name: UseAccount 
+Type: External App Func
+Purpose: This function makes a saved account the
+login default if its credentials pass the Login method
+*/
+func (a *AppCtx) UseAccount(name string) (error) {
+    if a.Credentials == nil {
+        return ErrNoStore
+    }
+    _, err := a.login(LoginParam{}, name)
+    if err != nil {
+        return err
+    }
+    a.mu.Lock()
+    a.account = name
+    a.mu.Unlock()
     return nil
 }
 
+/*
+Name: SetDefaultAccount
+Type: External App Func
+Purpose: This function makes a saved account the
+login default without logging in, like one read
+from a config file at startup. Its credentials are
+checked when an operation first logs in with them
+*/
+func (a *AppCtx) SetDefaultAccount(name string) (error) {
+    if a.Credentials == nil {
+        return ErrNoStore
+    }
+    a.mu.Lock()
+    a.account = name
+    a.mu.Unlock()
+    return nil
+}
+
+/*
+Name: DeleteAccount 
+Type: External App Func
+Purpose: This function removes a saved account from
+the credential store, clearing the login default if
+it pointed at that account
+*/
+func (a *AppCtx) DeleteAccount(name string) (error) {
+    if a.Credentials == nil {
+        return ErrNoStore
+    }
+    err := a.Credentials.Delete(name)
+    if err != nil {
+        return err
+    }
+    a.mu.Lock()
+    if a.account == name {
+        a.account = ""
+    }
+    a.mu.Unlock()
+    return nil
+}
+
+/*
+Name: Accounts 
+Type: External App Func
+Purpose: This function lists the names of saved accounts
+*/
+func (a *AppCtx) Accounts() ([]string, error) {
+    if a.Credentials == nil {
+        return nil, ErrNoStore
+    }
+    return a.Credentials.List()
+}
+
+/*
+Name: loginDefaults 
+Type: Internal Func
+Purpose: Decide which credentials an operation logs in with.
+Explicit credentials win, then an explicit account, then the
+default account. The returned login only carries a password
+when the caller provided one, the default is returned by name
+even when it came from Login. Must hold a.mu
+*/
+func (a *AppCtx) loginDefaults(login LoginParam, account string) (LoginParam, string, error) {
+    if login.Email != "" && login.Password != "" {
+        return login, "", nil
+    }
+    if account != "" {
+        _, err := a.session.Get(account)
+        if err != nil && a.Credentials == nil {
+            return LoginParam{}, "", ErrNoStore
+        }
+        return LoginParam{}, account, nil
+    }
+    if a.account == "" {
+        return LoginParam{}, "", ErrNoLogin
+    }
+    return LoginParam{}, a.account, nil
+}
+
+/*
+Name: login 
+Type: Internal Func
+Purpose: Log in to the underlying api, fetching the
+credentials of an account from the logins given to Login
+or the store if needed. Intended to be called from 
+operation threads right before the credentials are used
+*/
+func (a *AppCtx) login(login LoginParam, account string) (*api.LoginResponse, error) {
+    reqParams := api.LoginParam(login)
+    if account != "" {
+        cred, err := a.session.Get(account)
+        if err == vault.ErrNoCred {
+            if a.Credentials == nil {
+                return nil, ErrNoStore
+            }
+            cred, err = a.Credentials.Get(account)
+        }
+        if err != nil {
+            return nil, err
+        }
+        reqParams = cred.Login
+    }
+    return a.API.Login(reqParams)
+}
+
 /*
 Name: Search 
 Type: External App Func
@@ -497,6 +1035,8 @@ from the internal slice of ops, but only does
 this if the op is not in progress
 */
 func (a *AppCtx) CleanOperation(id int64) (error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
     for i, operation := range a.operations {
         if operation.ID == id {
             // update before handling
@@ -508,8 +1048,31 @@ func (a *AppCtx) CleanOperation(id int64) (error) {
             if a.operations[i].Status == InProgressStatusType {
                 return ErrCurrOp
             }
-            // remove op from op list
-            a.operations = append(a.operations[:i], a.operations[i+1:]...)
+            // a group is cleaned along with its members, so 
+            // they all have to be finished
+            for _, member := range a.operations {
+                if member.Group != id {
+                    continue
+                }
+                err := a.updateOperationResult(member.ID)
+                if err != nil {
+                    return err
+                }
+                status, _ := a.status(member.ID)
+                if status == InProgressStatusType {
+                    return ErrCurrOp
+                }
+            }
+            // remove op and its members from op list
+            kept := a.operations[:0]
+            for _, op := range a.operations {
+                if op.ID != id && op.Group != id {
+                    kept = append(kept, op)
+                } else {
+                    a.publish(Event{Type: CleanedEvent, OperationID: op.ID})
+                }
+            }
+            a.operations = kept
             return nil
         }
     }
@@ -519,15 +1082,17 @@ func (a *AppCtx) CleanOperation(id int64) (error) {
 /*
 Name: Logout 
 Type: External App Func
-Purpose: This function removes login info
-from the AppCtx if saved from a Login call
+Purpose: This function clears the login default. 
+A login given to Login stays in memory for the
+operations already scheduled with it
 */
 func (a *AppCtx) Logout() (error) {
-    if (a.loginInfo.Email == "") && (a.loginInfo.Password == "") {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.account == "" {
         return ErrNoLogout
     }
-    params := LoginParam{}
-    a.loginInfo = params
+    a.account = ""
     return nil
 }
 
@@ -538,39 +1103,30 @@ Purpose: This function stringifies operations
 in a use independent manner
 */
 func (a *AppCtx) OperationsToString() (string, error) {
-    if len(a.operations) == 0 {
+    snaps := a.ListOperations(OperationFilter{})
+    if len(snaps) == 0 {
         return "", ErrNoOp
     }
-    opLstStr := "Operations: \n\n"
-    for i, operation := range a.operations {
-        // update before handling
-        err := a.updateOperationResult(operation.ID)
-        if err != nil {
-            return "", err
-        }
-        // stringify based on op type
-        opLstStr += "\tID: " + strconv.FormatInt(operation.ID, 10) + "\n" 
-        opLstStr += "\tStatus: " 
-        switch operation.Status {
-            case InProgressStatusType:
-                opLstStr += "In Progress"
-            case SuccessStatusType:
-                time := operation.Result.Response.Time()
-                opLstStr += "Succeeded\n"
-                opLstStr += fmt.Sprintf("\tResult: %02d:%02d", time.Hour(), time.Minute())
-            case FailStatusType:
-                err := operation.Result.Err.Error()
-                opLstStr += "Failed\n"
-                opLstStr += "\tResult: " + err 
-            case CancelStatusType:
-                opLstStr += "Cancelled"
-        }
-        opLstStr += "\n"
-        if i != (len(opLstStr) - 1) {
-            opLstStr += "\n"
-        }
+    return SnapshotsToString(snaps), nil
+}
+
+/*
+Name: venueAndLocal 
+Type: Internal Func
+Purpose: Stringify a time in the venue timezone, followed
+by the local time when the two differ
+*/
+func venueAndLocal(t time.Time, loc *time.Location) (string) {
+    layout := "2006-01-02 15:04 MST"
+    if loc == nil {
+        loc = t.Location()
+    }
+    venueStr := t.In(loc).Format(layout)
+    localStr := t.In(time.Local).Format(layout)
+    if venueStr == localStr {
+        return venueStr
     }
-    return opLstStr, nil
+    return venueStr + " (" + localStr + " local)"
 }
 
 /*
@@ -579,14 +1135,26 @@ Type: External App Func
 Purpose: This function returns the status of an op 
 */
 func (a *AppCtx) OperationStatus(id int64) (OperationStatus, error) {
-    for i, operation := range a.operations {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    // update before handling
+    err := a.updateOperationResult(id)
+    if err != nil {
+        return InProgressStatusType, err
+    }
+    return a.status(id)
+}
+
+/*
+Name: status
+Type: Internal Func
+Purpose: Look up the status of an op without
+updating it first. Must hold a.mu
+*/
+func (a *AppCtx) status(id int64) (OperationStatus, error) {
+    for _, operation := range a.operations {
         if operation.ID == id {
-            // update before handling
-            err := a.updateOperationResult(operation.ID)
-            if err != nil {
-                return InProgressStatusType, err
-            }
-            return a.operations[i].Status, nil
+            return operation.Status, nil
         }
     }
     return InProgressStatusType, ErrIdOp
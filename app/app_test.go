@@ -0,0 +1,1365 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/clock"
+    "github.com/21Bruce/resolved-server/notify"
+    "github.com/21Bruce/resolved-server/vault"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// How long a test waits in real time for operation threads
+const testTimeout = 5 * time.Second
+
+var testStart = time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+
+/*
+Name: fakeAPI 
+Type: Test Struct
+Purpose: An api.API that records when, on the fake 
+clock, it was asked to login and reserve
+*/
+type fakeAPI struct {
+    mu          sync.Mutex
+    clock       *clock.Fake
+    authExpire  time.Duration
+    // errors returned by successive Reserve calls, 
+    // once used up Reserve succeeds
+    reserveErrs []error
+    // slots offered by successive Availability calls, 
+    // once used up nothing is offered
+    offers      [][]api.Slot
+    // the booking policy offered, none when nil
+    policy      *api.BookingPolicyResponse
+    logins      []time.Time
+    reserves    []time.Time
+    // calls for the emails in these wait for the test to
+    // send the error they return, nil going on as usual
+    loginGates      map[string]chan error
+    reserveGates    map[string]chan error
+}
+
+func (f *fakeAPI) Login(params api.LoginParam) (*api.LoginResponse, error) {
+    if gate, ok := f.loginGates[params.Email]; ok {
+        if err := <-gate; err != nil {
+            return nil, err
+        }
+    }
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.logins = append(f.logins, f.clock.Now())
+    return &api.LoginResponse{Email: params.Email, AuthToken: "token"}, nil
+}
+
+func (f *fakeAPI) Search(params api.SearchParam) (*api.SearchResponse, error) {
+    return &api.SearchResponse{}, nil
+}
+
+func (f *fakeAPI) Reserve(params api.ReserveParam) (*api.ReserveResponse, error) {
+    if gate, ok := f.reserveGates[params.LoginResp.Email]; ok {
+        if err := <-gate; err != nil {
+            return nil, err
+        }
+    }
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.reserves = append(f.reserves, f.clock.Now())
+    if len(f.reserves) <= len(f.reserveErrs) {
+        return nil, f.reserveErrs[len(f.reserves)-1]
+    }
+    return &api.ReserveResponse{ReservationTime: params.ReservationTimes[0]}, nil
+}
+
+func (f *fakeAPI) AuthMinExpire() (time.Duration) {
+    return f.authExpire
+}
+
+func (f *fakeAPI) BookingPolicy(params api.BookingPolicyParam) (*api.BookingPolicyResponse, error) {
+    if f.policy == nil {
+        return nil, api.ErrNoPolicy
+    }
+    policy := *f.policy
+    return &policy, nil
+}
+
+func (f *fakeAPI) Timezone(params api.TimezoneParam) (*api.TimezoneResponse, error) {
+    return nil, api.ErrNoTimezone
+}
+
+func (f *fakeAPI) Availability(params api.AvailabilityParam) (*api.AvailabilityResponse, error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if len(f.offers) == 0 {
+        return &api.AvailabilityResponse{}, nil
+    }
+    slots := f.offers[0]
+    f.offers = f.offers[1:]
+    return &api.AvailabilityResponse{Slots: slots}, nil
+}
+
+func (f *fakeAPI) calls() ([]time.Time, []time.Time) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return append([]time.Time(nil), f.logins...), append([]time.Time(nil), f.reserves...)
+}
+
+/*
+Name: newTestApp 
+Type: Test Func
+Purpose: An AppCtx on a fake clock and fake api, 
+logged in with default credentials
+*/
+func newTestApp(authExpire time.Duration, reserveErrs ...error) (*AppCtx, *fakeAPI, *clock.Fake) {
+    fc := clock.NewFake(testStart)
+    fa := &fakeAPI{clock: fc, authExpire: authExpire, reserveErrs: reserveErrs}
+    a := &AppCtx{API: fa, Clock: fc}
+    a.session.Put(vault.Credential{Name: "test@example.com", Login: api.LoginParam{Email: "test@example.com", Password: "password"}})
+    a.account = "test@example.com"
+    return a, fa, fc
+}
+
+/*
+Name: eventually 
+Type: Test Func
+Purpose: Wait in real time for cond to hold
+*/
+func eventually(t *testing.T, what string, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(testTimeout)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatalf("timed out waiting for %s", what)
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+/*
+Name: finished 
+Type: Test Func
+Purpose: Whether the operation is no longer in progress
+*/
+func finished(t *testing.T, a *AppCtx, id int64) (bool) {
+    status, err := a.OperationStatus(id)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return status != InProgressStatusType
+}
+
+/*
+Name: drive 
+Type: Test Func
+Purpose: Advance the fake clock from one pending wait to
+the next until the operation finishes, then return its 
+result
+*/
+func drive(t *testing.T, a *AppCtx, fc *clock.Fake, id int64) (*OperationResult) {
+    t.Helper()
+    for !finished(t, a, id) {
+        if next, ok := fc.Next(); ok {
+            fc.Advance(next.Sub(fc.Now()))
+            continue
+        }
+        time.Sleep(time.Millisecond)
+    }
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    for _, op := range a.operations {
+        if op.ID == id {
+            return op.Result
+        }
+    }
+    t.Fatalf("operation %d not found", id)
+    return nil
+}
+
+/*
+Name: cancelled 
+Type: Test Func
+Purpose: Cancel the operation and return what its 
+thread reported
+*/
+func cancelled(t *testing.T, a *AppCtx, id int64) (OperationResult) {
+    t.Helper()
+    a.mu.Lock()
+    var output <-chan OperationResult
+    for _, op := range a.operations {
+        if op.ID == id {
+            output = op.Output
+        }
+    }
+    a.mu.Unlock()
+    if err := a.CancelOperation(id); err != nil {
+        t.Fatal(err)
+    }
+    select {
+    case res := <-output:
+        return res
+    case <-time.After(testTimeout):
+        t.Fatal("timed out waiting for cancelled operation")
+    }
+    return OperationResult{}
+}
+
+func atTimeParams(request time.Time) (ReserveAtTimeParam) {
+    return ReserveAtTimeParam{
+        VenueID: 1,
+        ReservationTimes: []time.Time{request.Add(6 * 24 * time.Hour)},
+        PartySize: 2,
+        RequestTime: request,
+    }
+}
+
+func TestReserveAtTimeLogsInBeforeRequest(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(request))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := drive(t, a, fc, id)
+    if res == nil || res.Err != nil {
+        t.Fatalf("expected success, got %+v", res)
+    }
+    logins, reserves := fa.calls()
+    if len(logins) != 1 || !logins[0].Equal(request.Add(-time.Hour)) {
+        t.Errorf("expected one login at %v, got %v", request.Add(-time.Hour), logins)
+    }
+    if len(reserves) != 1 || !reserves[0].Equal(request) {
+        t.Errorf("expected one reserve at %v, got %v", request, reserves)
+    }
+}
+
+func TestReserveAtTimeInsideAuthWindow(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    request := testStart.Add(10 * time.Minute)
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(request))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    drive(t, a, fc, id)
+    logins, reserves := fa.calls()
+    if len(logins) != 1 || !logins[0].Equal(testStart) {
+        t.Errorf("expected an immediate login, got %v", logins)
+    }
+    if len(reserves) != 1 || !reserves[0].Equal(request) {
+        t.Errorf("expected one reserve at %v, got %v", request, reserves)
+    }
+}
+
+func TestReserveAtTimeClockSkewAndLead(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    // the provider's clock is 2s ahead, and we fire 150ms early
+    a.clockSkew = &ClockSkew{Offset: 2 * time.Second}
+    a.SetLeadTime(150 * time.Millisecond)
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(request))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    drive(t, a, fc, id)
+    _, reserves := fa.calls()
+    want := request.Add(-2 * time.Second).Add(-150 * time.Millisecond)
+    if len(reserves) != 1 || !reserves[0].Equal(want) {
+        t.Errorf("expected one reserve at local %v, got %v", want, reserves)
+    }
+}
+
+func TestReserveAtTimeCancelBeforeLogin(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(3 * time.Hour)))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    fc.BlockUntil(1)
+    res := cancelled(t, a, id)
+    if res.Err != ErrCancel {
+        t.Errorf("expected %v, got %v", ErrCancel, res.Err)
+    }
+    logins, reserves := fa.calls()
+    if len(logins) != 0 || len(reserves) != 0 {
+        t.Errorf("expected no api calls, got logins %v reserves %v", logins, reserves)
+    }
+}
+
+func TestReserveAtTimeCancelAfterLogin(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(request))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // run up to the login, then cancel while waiting for the request
+    for {
+        if logins, _ := fa.calls(); len(logins) > 0 {
+            break
+        }
+        fc.BlockUntil(1)
+        next, _ := fc.Next()
+        fc.Advance(next.Sub(fc.Now()))
+    }
+    fc.BlockUntil(1)
+    res := cancelled(t, a, id)
+    if res.Err != ErrCancel {
+        t.Errorf("expected %v, got %v", ErrCancel, res.Err)
+    }
+    logins, reserves := fa.calls()
+    if len(logins) != 1 || len(reserves) != 0 {
+        t.Errorf("expected one login and no reserve, got logins %v reserves %v", logins, reserves)
+    }
+}
+
+func TestReserveAtTimePastDate(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(-time.Minute)))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := drive(t, a, fc, id)
+    if res == nil || res.Err != ErrTimeFut {
+        t.Errorf("expected %v, got %+v", ErrTimeFut, res)
+    }
+    if logins, reserves := fa.calls(); len(logins) != 0 || len(reserves) != 0 {
+        t.Errorf("expected no api calls, got logins %v reserves %v", logins, reserves)
+    }
+}
+
+func intervalParams(last time.Time, interval time.Duration) (ReserveAtIntervalParam) {
+    return ReserveAtIntervalParam{
+        VenueID: 1,
+        ReservationTimes: []time.Time{last},
+        PartySize: 2,
+        RepeatInterval: interval,
+    }
+}
+
+func TestReserveAtIntervalRepeats(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour, api.ErrNoTable, api.ErrNoTable)
+    id, err := a.ScheduleReserveAtIntervalOperation(intervalParams(testStart.Add(time.Hour), time.Minute))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := drive(t, a, fc, id)
+    if res == nil || res.Err != nil {
+        t.Fatalf("expected success, got %+v", res)
+    }
+    _, reserves := fa.calls()
+    want := []time.Time{testStart, testStart.Add(time.Minute), testStart.Add(2 * time.Minute)}
+    if len(reserves) != len(want) {
+        t.Fatalf("expected reserves at %v, got %v", want, reserves)
+    }
+    for i := range want {
+        if !reserves[i].Equal(want[i]) {
+            t.Errorf("expected reserve %d at %v, got %v", i, want[i], reserves[i])
+        }
+    }
+}
+
+func TestReserveAtIntervalPastDate(t *testing.T) {
+    errs := make([]error, 100)
+    for i := range errs {
+        errs[i] = api.ErrNoTable
+    }
+    a, fa, fc := newTestApp(time.Hour, errs...)
+    id, err := a.ScheduleReserveAtIntervalOperation(intervalParams(testStart.Add(5 * time.Minute), 2 * time.Minute))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := drive(t, a, fc, id)
+    if res == nil || res.Err != api.ErrPastDate {
+        t.Fatalf("expected %v, got %+v", api.ErrPastDate, res)
+    }
+    // tries at 0, 2 and 4 minutes, and at 6 the last time has passed
+    if _, reserves := fa.calls(); len(reserves) != 4 {
+        t.Errorf("expected 4 reserves, got %v", reserves)
+    }
+}
+
+func TestReserveAtIntervalCancel(t *testing.T) {
+    errs := make([]error, 100)
+    for i := range errs {
+        errs[i] = api.ErrNoTable
+    }
+    a, fa, fc := newTestApp(time.Hour, errs...)
+    id, err := a.ScheduleReserveAtIntervalOperation(intervalParams(testStart.Add(time.Hour), time.Minute))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    fc.BlockUntil(1)
+    res := cancelled(t, a, id)
+    if res.Err != ErrCancel {
+        t.Errorf("expected %v, got %v", ErrCancel, res.Err)
+    }
+    if _, reserves := fa.calls(); len(reserves) != 1 {
+        t.Errorf("expected 1 reserve, got %v", reserves)
+    }
+}
+
+func TestWatchReportsNewSlots(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    day := testStart.Add(6 * 24 * time.Hour)
+    early := api.Slot{Time: day.Add(19 * time.Hour), TableType: "dining room"}
+    late := api.Slot{Time: day.Add(21 * time.Hour), TableType: "bar"}
+    other := api.Slot{Time: day.Add(20 * time.Hour), TableType: "dining room"}
+    fa.offers = [][]api.Slot{{other}, {early, other}, {early, late}, {late}, {early}}
+
+    stream, unsubscribe := a.Subscribe(0)
+    defer unsubscribe()
+
+    id, err := a.ScheduleWatchOperation(WatchParam{
+        VenueID: 1,
+        ReservationTimes: []time.Time{early.Time, late.Time},
+        PartySize: 2,
+        RepeatInterval: time.Minute,
+        Keep: true,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // polls until the last time passes, so cut it short
+    // once the watch waits again, the events of its last poll are in
+    found := make([]Event, 0)
+    for {
+        fc.BlockUntil(1)
+        for len(stream) != 0 {
+            if event := <-stream; event.Type == SlotFoundEvent {
+                found = append(found, event)
+            }
+        }
+        if len(found) >= 3 {
+            break
+        }
+        fc.Advance(time.Minute)
+    }
+    cancelled(t, a, id)
+
+    // other never matches, and a slot that comes back is reported again
+    want := [][]api.Slot{{early}, {late}, {early}}
+    for i := range want {
+        if found[i].OperationID != id || len(found[i].Slots) != 1 || found[i].Slots[0] != want[i][0] {
+            t.Errorf("expected event %d with %v, got %+v", i, want[i], found[i])
+        }
+    }
+}
+
+func TestOutcomePublishedAfterNotify(t *testing.T) {
+    a, _, fc := newTestApp(time.Hour)
+    // unbuffered, so Notify blocks until the test reads it
+    notifier := make(recordNotifier)
+    a.SetNotifier(notifier)
+    stream, unsubscribe := a.Subscribe(0)
+    defer unsubscribe()
+
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(time.Minute)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    drive(t, a, fc, id)
+    if !a.Notifying() {
+        t.Fatal("expected the notification pending once the operation finished")
+    }
+    for len(stream) != 0 {
+        if event := <-stream; event.Type == BookedEvent {
+            t.Fatal("expected the booking published after the notification")
+        }
+    }
+    if n := <-notifier; n.OperationID != id || n.Outcome != notify.Succeeded {
+        t.Errorf("expected success of %d, got %+v", id, n)
+    }
+    for event := range stream {
+        if event.Type == BookedEvent {
+            break
+        }
+    }
+    if a.Notifying() {
+        t.Error("expected no notification pending once the booking was published")
+    }
+}
+
+func TestEventLifecycle(t *testing.T) {
+    a, _, fc := newTestApp(time.Hour)
+    stream, unsubscribe := a.Subscribe(0)
+    defer unsubscribe()
+
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(3 * time.Hour)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    drive(t, a, fc, id)
+    if err := a.CleanOperation(id); err != nil {
+        t.Fatal(err)
+    }
+
+    want := []EventType{
+        ScheduledEvent, WaitingEvent, LoginStartedEvent, WaitingEvent, 
+        AttemptEvent, BookedEvent, CleanedEvent,
+    }
+    for i, eventType := range want {
+        event := <-stream
+        if event.Type != eventType || event.OperationID != id || event.Seq != int64(i + 1) {
+            t.Fatalf("expected event %d to be %v, got %+v", i, eventType, event)
+        }
+    }
+}
+
+func TestWatchStopsOnFirstMatch(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    day := testStart.Add(6 * 24 * time.Hour)
+    slot := api.Slot{Time: day.Add(19 * time.Hour), TableType: "patio"}
+    fa.offers = [][]api.Slot{{}, {slot}}
+
+    id, err := a.ScheduleWatchOperation(WatchParam{
+        VenueID: 1,
+        ReservationTimes: []time.Time{slot.Time},
+        PartySize: 2,
+        RepeatInterval: time.Minute,
+        TableTypes: []api.TableType{api.Patio},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := drive(t, a, fc, id)
+    if res == nil || res.Err != nil {
+        t.Fatalf("expected success, got %+v", res)
+    }
+    watch := res.Response.(WatchResponse)
+    if len(watch.Slots) != 1 || watch.Slots[0] != slot {
+        t.Errorf("expected %v, got %v", slot, watch.Slots)
+    }
+    if _, reserves := fa.calls(); len(reserves) != 0 {
+        t.Errorf("expected no reserves, got %v", reserves)
+    }
+}
+
+func TestSnipeBooksCancellation(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    day := testStart.Add(6 * 24 * time.Hour)
+    wanted := api.Slot{Time: day.Add(19 * time.Hour), TableType: "dining room"}
+    taken := api.Slot{Time: day.Add(19 * time.Hour), TableType: "bar"}
+    other := api.Slot{Time: day.Add(22 * time.Hour), TableType: "dining room"}
+    // the wanted table shows up on the third poll
+    fa.offers = [][]api.Slot{{taken}, {taken, other}, {taken, other, wanted}}
+
+    id, err := a.ScheduleSnipeOperation(SnipeParam{
+        VenueID: 1,
+        ReservationTimes: []time.Time{wanted.Time},
+        PartySize: 2,
+        TableTypes: []api.TableType{api.DiningRoom},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := drive(t, a, fc, id)
+    if res == nil || res.Err != nil {
+        t.Fatalf("expected success, got %+v", res)
+    }
+    snipe := res.Response.(SnipeResponse)
+    if !snipe.ReservationTime.Equal(wanted.Time) || snipe.Cancellations != 2 {
+        t.Errorf("expected %v with 2 cancellations, got %+v", wanted.Time, snipe)
+    }
+    if _, reserves := fa.calls(); len(reserves) != 1 {
+        t.Errorf("expected 1 reserve, got %v", reserves)
+    }
+}
+
+func TestSnipeInterval(t *testing.T) {
+    min, max := 30 * time.Second, 10 * time.Minute
+    tests := []struct {
+        until   time.Duration
+        want    time.Duration
+    }{
+        {36 * time.Hour, min},
+        {24 * time.Hour, min},
+        {48 * time.Hour, min},
+        {48 * time.Hour + snipeRamp, max},
+        {30 * 24 * time.Hour, max},
+        {48 * time.Hour + snipeRamp / 2, min + (max - min) / 2},
+    }
+    for _, test := range tests {
+        if got := snipeInterval(test.until, min, max); got != test.want {
+            t.Errorf("snipeInterval(%v) = %v, want %v", test.until, got, test.want)
+        }
+    }
+}
+
+/*
+Name: recordNotifier 
+Type: Test Struct
+Purpose: A notifier that sends what it is told on
+a channel
+*/
+type recordNotifier chan notify.Notification
+
+func (r recordNotifier) Notify(n notify.Notification) (error) {
+    r <- n
+    return nil
+}
+
+func TestNotifyOutcomes(t *testing.T) {
+    a, _, fc := newTestApp(time.Hour)
+    global := make(recordNotifier, 10)
+    own := make(recordNotifier, 10)
+    a.SetNotifier(global)
+
+    booked, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(time.Minute)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    drive(t, a, fc, booked)
+    if n := <-global; n.OperationID != booked || n.Outcome != notify.Succeeded || n.Result == "" {
+        t.Errorf("expected success of %d, got %+v", booked, n)
+    }
+
+    params := atTimeParams(testStart.Add(time.Hour))
+    params.Notifier = own
+    cancelledID, err := a.ScheduleReserveAtTimeOperation(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    fc.BlockUntil(1)
+    cancelled(t, a, cancelledID)
+    if n := <-own; n.OperationID != cancelledID || n.Outcome != notify.Cancelled {
+        t.Errorf("expected cancel of %d, got %+v", cancelledID, n)
+    }
+
+    failed, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(-time.Hour)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    drive(t, a, fc, failed)
+    if n := <-global; n.OperationID != failed || n.Outcome != notify.Failed || n.Error != ErrTimeFut.Error() {
+        t.Errorf("expected failure of %d, got %+v", failed, n)
+    }
+    if len(global) != 0 || len(own) != 0 {
+        t.Errorf("unexpected extra notifications")
+    }
+}
+
+func TestListOperationsFilters(t *testing.T) {
+    a, _, fc := newTestApp(time.Hour)
+    booked := atTimeParams(testStart.Add(3 * time.Hour))
+    booked.Login = LoginParam{Email: "a@example.com", Password: "secret"}
+    bookedID, err := a.ScheduleReserveAtTimeOperation(booked)
+    if err != nil {
+        t.Fatal(err)
+    }
+    drive(t, a, fc, bookedID)
+
+    pending := atTimeParams(testStart.Add(48 * time.Hour))
+    pending.VenueID = 2
+    pendingID, err := a.ScheduleReserveAtTimeOperation(pending)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer cancelled(t, a, pendingID)
+
+    snap, err := a.GetOperation(bookedID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if snap.Type != ReserveAtTimeType || snap.Status != SuccessStatusType || snap.Attempts != 1 || snap.Finished == nil {
+        t.Errorf("unexpected snapshot %+v", snap)
+    }
+    if snap.Params.(ReserveAtTimeParam).Login.Password != "" {
+        t.Errorf("expected password to be scrubbed")
+    }
+
+    cases := []struct{
+        filter  OperationFilter
+        want    []int64
+    }{
+        {OperationFilter{}, []int64{bookedID, pendingID}},
+        {OperationFilter{Statuses: []OperationStatus{InProgressStatusType}}, []int64{pendingID}},
+        {OperationFilter{VenueID: 1}, []int64{bookedID}},
+        {OperationFilter{Account: "a@example.com"}, []int64{bookedID}},
+        {OperationFilter{VenueID: 2, Statuses: []OperationStatus{SuccessStatusType}}, nil},
+    }
+    for _, c := range cases {
+        snaps := a.ListOperations(c.filter)
+        ids := make([]int64, 0)
+        for _, snap := range snaps {
+            ids = append(ids, snap.ID)
+        }
+        if len(ids) + len(c.want) != 0 && fmt.Sprint(ids) != fmt.Sprint(c.want) {
+            t.Errorf("filter %+v: expected %v, got %v", c.filter, c.want, ids)
+        }
+    }
+    if _, err := a.GetOperation(99); err != ErrIdOp {
+        t.Errorf("expected ErrIdOp, got %v", err)
+    }
+}
+
+func TestAttemptLog(t *testing.T) {
+    a, _, fc := newTestApp(time.Hour, api.ErrNoTable)
+    id, err := a.ScheduleReserveAtIntervalOperation(intervalParams(testStart.Add(time.Hour), time.Minute))
+    if err != nil {
+        t.Fatal(err)
+    }
+    drive(t, a, fc, id)
+
+    snap, err := a.GetOperation(id)
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []CallType{LoginCall, ReserveCall, LoginCall, ReserveCall}
+    if len(snap.Log) != len(want) {
+        t.Fatalf("expected %d records, got %+v", len(want), snap.Log)
+    }
+    for i, call := range want {
+        if snap.Log[i].Call != call {
+            t.Errorf("expected record %d to be %v, got %+v", i, call, snap.Log[i])
+        }
+    }
+    if snap.Log[1].Error != api.ErrNoTable.Error() || snap.Log[3].ReservationTime == nil {
+        t.Errorf("unexpected reserve outcomes %+v", snap.Log)
+    }
+    if snap.Started == nil || !snap.Started.Equal(snap.Log[0].Time) || snap.Finished == nil {
+        t.Errorf("unexpected timestamps %+v", snap)
+    }
+}
+
+func TestAttemptLogCap(t *testing.T) {
+    a, _, fc := newTestApp(time.Hour)
+    a.operations = append(a.operations, Operation{ID: 7, Group: NoGroup})
+    slots := []api.Slot{{Time: testStart, TableType: "Bar"}}
+    check := func(seen []api.Slot) {
+        start := fc.Now()
+        fc.Advance(time.Second)
+        a.record(7, AttemptRecord{Call: AvailabilityCall, Time: start, Slots: seen}, start, nil)
+    }
+
+    // Identical checks fold into one record
+    check(nil)
+    check(nil)
+    check(nil)
+    check(slots)
+    a.mu.Lock()
+    snap := a.snapshot(7)
+    a.mu.Unlock()
+    if len(snap.Log) != 2 || snap.Log[0].Repeats != 2 || snap.Log[1].Repeats != 0 {
+        t.Fatalf("expected the empty checks folded, got %+v", snap.Log)
+    }
+    if snap.Log[0].Last == nil || !snap.Log[0].Last.Equal(testStart.Add(2 * time.Second)) {
+        t.Errorf("expected the last fold time, got %v", snap.Log[0].Last)
+    }
+    if out := HistoryToString(snap); !strings.Contains(out, "repeated 2 more times") {
+        t.Errorf("expected the repeats shown, got %q", out)
+    }
+
+    // Alternating outcomes never fold, so the log is capped
+    for i := 0; i < maxLogRecords; i++ {
+        if i % 2 == 0 {
+            check(nil)
+        } else {
+            check(slots)
+        }
+    }
+    a.mu.Lock()
+    snap = a.snapshot(7)
+    a.mu.Unlock()
+    if len(snap.Log) != maxLogRecords || snap.Dropped != 2 {
+        t.Fatalf("expected %d records and 2 dropped, got %d and %d", maxLogRecords, len(snap.Log), snap.Dropped)
+    }
+    if len(snap.Log[len(snap.Log) - 1].Slots) != 1 {
+        t.Errorf("expected the newest record kept last, got %+v", snap.Log[len(snap.Log) - 1])
+    }
+    if out := HistoryToString(snap); !strings.Contains(out, "2 older records dropped") {
+        t.Errorf("expected the dropped count shown, got %q", out)
+    }
+}
+
+func TestTemplateLifecycle(t *testing.T) {
+    a, _, fc := newTestApp(time.Hour)
+    // testStart is a Friday, so the first occurrence ahead of it
+    // is the Friday after, released three hours from now
+    id, err := a.ScheduleTemplate(RecurringParam{
+        VenueID: 1,
+        Weekdays: []time.Weekday{time.Friday},
+        ReservationTimes: []time.Duration{19 * time.Hour},
+        PartySize: 2,
+        DaysAhead: 7,
+        ReleaseTime: 3 * time.Hour,
+        Location: time.UTC,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    firstRequest := testStart.Add(3 * time.Hour)
+    spawned := func(n int) (Template) {
+        t.Helper()
+        var template Template
+        eventually(t, "template to spawn operations", func() (bool) {
+            template = a.Templates()[0]
+            return len(template.Operations) == n
+        })
+        return template
+    }
+
+    template := spawned(1)
+    if !template.Next.Equal(firstRequest) {
+        t.Errorf("expected next request %v, got %v", firstRequest, template.Next)
+    }
+    snap, err := a.GetOperation(template.Operations[0])
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := time.Date(2023, 9, 8, 19, 0, 0, 0, time.UTC)
+    if !snap.RequestTime.Equal(firstRequest) || !snap.Params.(ReserveAtTimeParam).ReservationTimes[0].Equal(want) {
+        t.Errorf("unexpected operation %+v", snap)
+    }
+
+    if err := a.PauseTemplate(id); err != nil {
+        t.Fatal(err)
+    }
+    if err := a.PauseTemplate(id); err != ErrPaused {
+        t.Errorf("expected ErrPaused, got %v", err)
+    }
+    if status, _ := a.OperationStatus(template.Operations[0]); status != CancelStatusType {
+        t.Errorf("expected pending occurrence to be cancelled, got %v", status)
+    }
+
+    if err := a.ResumeTemplate(id); err != nil {
+        t.Fatal(err)
+    }
+    template = spawned(2)
+    snap, err = a.GetOperation(template.Operations[1])
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !snap.RequestTime.Equal(firstRequest) {
+        t.Errorf("expected resumed occurrence at %v, got %v", firstRequest, snap.RequestTime)
+    }
+
+    // once the request goes out, the next week is spawned
+    drive(t, a, fc, template.Operations[1])
+    template = spawned(3)
+    if next := firstRequest.AddDate(0, 0, 7); !template.Next.Equal(next) {
+        t.Errorf("expected next request %v, got %v", next, template.Next)
+    }
+
+    if err := a.DeleteTemplate(id); err != nil {
+        t.Fatal(err)
+    }
+    if len(a.Templates()) != 0 {
+        t.Errorf("expected template to be deleted")
+    }
+    if status, _ := a.OperationStatus(template.Operations[2]); status != CancelStatusType {
+        t.Errorf("expected pending occurrence to be cancelled, got %v", status)
+    }
+}
+
+func TestUpdateOperation(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(3 * time.Hour)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    // wait for the thread to sleep until the login
+    fc.BlockUntil(1)
+
+    request := testStart.Add(5 * time.Hour)
+    fallback := testStart.Add(7 * 24 * time.Hour)
+    if err := a.UpdateOperation(id, OperationUpdate{RepeatInterval: time.Minute}); err != ErrBadUpdate {
+        t.Errorf("expected ErrBadUpdate, got %v", err)
+    }
+    err = a.UpdateOperation(id, OperationUpdate{
+        RequestTime: request,
+        ReservationTimes: []time.Time{fallback},
+        PartySize: 4,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    res := drive(t, a, fc, id)
+    if res == nil || res.Err != nil || !res.Response.Time().Equal(fallback) {
+        t.Fatalf("expected booking at %v, got %+v", fallback, res)
+    }
+    logins, reserves := fa.calls()
+    if len(logins) != 1 || !logins[0].Equal(request.Add(-time.Hour)) {
+        t.Errorf("expected one login at %v, got %v", request.Add(-time.Hour), logins)
+    }
+    if len(reserves) != 1 || !reserves[0].Equal(request) {
+        t.Errorf("expected one reserve at %v, got %v", request, reserves)
+    }
+    snap, err := a.GetOperation(id)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if snap.ID != id || snap.Params.(ReserveAtTimeParam).PartySize != 4 || !snap.RequestTime.Equal(request) {
+        t.Errorf("unexpected snapshot %+v", snap)
+    }
+    if err := a.UpdateOperation(id, OperationUpdate{PartySize: 2}); err != ErrFinOp {
+        t.Errorf("expected ErrFinOp, got %v", err)
+    }
+}
+
+/*
+Name: memStore 
+Type: Test Struct
+Purpose: A vault.Store in memory, counting writes
+*/
+type memStore struct {
+    creds   map[string]vault.Credential
+    puts    int
+}
+
+func (m *memStore) Get(name string) (*vault.Credential, error) {
+    cred, ok := m.creds[name]
+    if !ok {
+        return nil, vault.ErrNoCred
+    }
+    return &cred, nil
+}
+
+func (m *memStore) Put(cred vault.Credential) (error) {
+    m.puts++
+    m.creds[cred.Name] = cred
+    return nil
+}
+
+func (m *memStore) Delete(name string) (error) {
+    delete(m.creds, name)
+    return nil
+}
+
+func (m *memStore) List() ([]string, error) {
+    names := make([]string, 0, len(m.creds))
+    for name := range m.creds {
+        names = append(names, name)
+    }
+    return names, nil
+}
+
+func TestLoginSavesOnlyWhenAsked(t *testing.T) {
+    a, _, _ := newTestApp(time.Hour)
+    store := &memStore{creds: make(map[string]vault.Credential)}
+    a.Credentials = store
+    if err := a.SetDefaultAccount("saved"); err != nil {
+        t.Fatal(err)
+    }
+    params := LoginParam{Email: "me@example.com", Password: "secret"}
+    if err := a.Login(params); err != nil {
+        t.Fatal(err)
+    }
+    if store.puts != 0 {
+        t.Errorf("Login wrote to the store %d times, want none", store.puts)
+    }
+    // the login replaces the default account, and is
+    // handed out by name so no password is copied
+    login, account, err := a.loginDefaults(LoginParam{}, "")
+    if err != nil || login != (LoginParam{}) || account != params.Email {
+        t.Errorf("defaults after Login = %v, %q, %v, want the login's email", login, account, err)
+    }
+    id, err := a.ScheduleReserveAtTimeOperation(atTimeParams(testStart.Add(2 * time.Hour)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    a.mu.Lock()
+    scheduled := a.operations[0].Params.(ReserveAtTimeParam)
+    a.mu.Unlock()
+    if scheduled.Login.Password != "" || scheduled.Account != params.Email {
+        t.Errorf("operation params = %+v, want the account without a password", scheduled)
+    }
+    if resp, err := a.login(scheduled.Login, scheduled.Account); err != nil || resp.Email != params.Email {
+        t.Errorf("login by the account = %v, %v, want the login's", resp, err)
+    }
+    cancelled(t, a, id)
+
+    if err := a.SaveLogin(params); err != nil {
+        t.Fatal(err)
+    }
+    if cred, err := store.Get(params.Email); store.puts != 1 || err != nil || cred.Login != api.LoginParam(params) {
+        t.Errorf("SaveLogin stored %v, %v after %d writes, want the login once", cred, err, store.puts)
+    }
+    login, account, err = a.loginDefaults(LoginParam{}, "")
+    if err != nil || login != (LoginParam{}) || account != params.Email {
+        t.Errorf("defaults after SaveLogin = %v, %q, %v, want the saved account", login, account, err)
+    }
+    if _, err := a.session.Get(params.Email); err != vault.ErrNoCred {
+        t.Errorf("login still held in memory after SaveLogin: err = %v", err)
+    }
+}
+
+/*
+Name: newFanOutApp 
+Type: Test Func
+Purpose: A test app with an account saved for each of
+the given emails, their calls gated
+*/
+func newFanOutApp(emails ...string) (*AppCtx, *fakeAPI, *clock.Fake) {
+    a, fa, fc := newTestApp(time.Hour)
+    store := &memStore{creds: make(map[string]vault.Credential)}
+    fa.loginGates = make(map[string]chan error)
+    fa.reserveGates = make(map[string]chan error)
+    for _, email := range emails {
+        store.creds[email] = vault.Credential{Name: email, Login: api.LoginParam{Email: email, Password: "password"}}
+        fa.loginGates[email] = make(chan error)
+        fa.reserveGates[email] = make(chan error)
+    }
+    a.Credentials = store
+    return a, fa, fc
+}
+
+func fanOutParams(request time.Time, accounts ...string) (ReserveFanOutParam) {
+    return ReserveFanOutParam{
+        Accounts: accounts,
+        VenueID: 1,
+        ReservationTimes: []time.Time{request.Add(6 * 24 * time.Hour)},
+        PartySize: 2,
+        RequestTime: request,
+    }
+}
+
+/*
+Name: advanceUntil 
+Type: Test Func
+Purpose: Advance the fake clock from one pending wait 
+to the next until cond holds
+*/
+func advanceUntil(t *testing.T, fc *clock.Fake, what string, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(testTimeout)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatalf("timed out waiting for %s", what)
+        }
+        if next, ok := fc.Next(); ok {
+            fc.Advance(next.Sub(fc.Now()))
+            continue
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+/*
+Name: opState 
+Type: Test Func
+Purpose: The status and result of an operation
+*/
+func opState(a *AppCtx, id int64) (OperationStatus, *OperationResult) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    a.updateOperationResult(id)
+    for _, op := range a.operations {
+        if op.ID == id {
+            return op.Status, op.Result
+        }
+    }
+    return -1, nil
+}
+
+/*
+Name: attempts 
+Type: Test Func
+Purpose: The number of requests an operation made, or
+is about to make
+*/
+func attempts(a *AppCtx, id int64) (int) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    for _, op := range a.operations {
+        if op.ID == id {
+            return op.Attempts
+        }
+    }
+    return 0
+}
+
+func TestFanOutFirstSuccessStopsSiblings(t *testing.T) {
+    a, fa, fc := newFanOutApp("first", "late", "failed")
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveFanOutOperation(fanOutParams(request, "first", "late", "failed"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    release(fa.loginGates, nil, "first", "late", "failed")
+    advanceUntil(t, fc, "every account to attempt", func() bool { return attempts(a, id) == 3 })
+
+    // first books, then late books before it could be stopped
+    // and failed doesn't get a table
+    fa.reserveGates["first"] <- nil
+    fa.reserveGates["late"] <- nil
+    fa.reserveGates["failed"] <- errors.New("sold out")
+
+    eventually(t, "the fan out to finish", func() bool { return finished(t, a, id) })
+    status, res := opState(a, id)
+    if status != SuccessStatusType || res == nil || res.Err != nil {
+        t.Fatalf("status %v result %v, want a success", status, res)
+    }
+    // which of the two comes first is up to the scheduler
+    resp := res.Response.(ReserveFanOutResponse)
+    booked := append([]string{resp.Account}, resp.AlsoBooked...)
+    sort.Strings(booked)
+    if strings.Join(booked, ",") != "first,late" {
+        t.Errorf("booked by %q, also %v, want first and late", resp.Account, resp.AlsoBooked)
+    }
+}
+
+// A member told to stop while logging in never reserves,
+// which is how the fan out stops siblings still getting ready
+func TestFanOutMemberStoppedDuringLogin(t *testing.T) {
+    a, fa, fc := newFanOutApp("slow")
+    request := testStart.Add(3 * time.Hour)
+    events, unsubscribe := a.Subscribe(0)
+    defer unsubscribe()
+    stop := make(chan bool)
+    output := make(chan OperationResult, 1)
+    go a.reserveAtTime(0, ReserveAtTimeParam{
+        Account: "slow",
+        VenueID: 1,
+        ReservationTimes: []time.Time{request.Add(time.Hour)},
+        RequestTime: request,
+    }, stop, output)
+    fc.BlockUntil(1)
+    fc.Advance(2 * time.Hour)
+    for event := range events {
+        if event.Type == LoginStartedEvent {
+            break
+        }
+    }
+    // the login is under way, the request time comes and the
+    // member is stopped before the login returns
+    fc.Advance(time.Hour)
+    close(stop)
+    fa.loginGates["slow"] <- nil
+    select {
+    case res := <-output:
+        if res.Err != ErrCancel {
+            t.Errorf("reported %v, want ErrCancel", res.Err)
+        }
+    case <-time.After(testTimeout):
+        t.Fatal("timed out waiting for the member")
+    }
+    if _, reserves := fa.calls(); len(reserves) != 0 {
+        t.Errorf("stopped member reserved %d times", len(reserves))
+    }
+}
+
+/*
+Name: release 
+Type: Test Func
+Purpose: Let the gated calls of the given emails go
+on with err, without waiting for them to be made
+*/
+func release(gates map[string]chan error, err error, emails ...string) {
+    for _, email := range emails {
+        go func(gate chan error) {
+            gate <- err
+        }(gates[email])
+    }
+}
+
+func TestFanOutSuccessBeatsLateCancel(t *testing.T) {
+    a, fa, fc := newFanOutApp("one", "two")
+    notifier := make(recordNotifier, 1)
+    request := testStart.Add(3 * time.Hour)
+    params := fanOutParams(request, "one", "two")
+    params.Notifier = notifier
+    id, err := a.ScheduleReserveFanOutOperation(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    release(fa.loginGates, nil, "one", "two")
+    advanceUntil(t, fc, "both accounts to attempt", func() bool { return attempts(a, id) == 2 })
+
+    // the cancel lands while both bookings are in flight
+    if err := a.CancelOperation(id); err != nil {
+        t.Fatal(err)
+    }
+    fa.reserveGates["one"] <- errors.New("sold out")
+    fa.reserveGates["two"] <- nil
+
+    select {
+    case n := <-notifier:
+        if n.Outcome != notify.Succeeded {
+            t.Errorf("notified %v, want a success", n.Outcome)
+        }
+    case <-time.After(testTimeout):
+        t.Fatal("timed out waiting for the notification")
+    }
+    status, res := opState(a, id)
+    if status != SuccessStatusType || res == nil || res.Err != nil {
+        t.Fatalf("status %v result %v, want the success to win", status, res)
+    }
+    if resp := res.Response.(ReserveFanOutResponse); resp.Account != "two" {
+        t.Errorf("booked by %q, want two", resp.Account)
+    }
+}
+
+func TestFanOutCancelAfterSuccess(t *testing.T) {
+    a, fa, fc := newFanOutApp("one", "two")
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveFanOutOperation(fanOutParams(request, "one", "two"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    release(fa.loginGates, nil, "one", "two")
+    advanceUntil(t, fc, "both accounts to attempt", func() bool { return attempts(a, id) == 2 })
+    fa.reserveGates["one"] <- nil
+    fa.reserveGates["two"] <- errors.New("sold out")
+    eventually(t, "the fan out to finish", func() bool { return finished(t, a, id) })
+
+    // the booking is recorded first, so the cancel is refused
+    if err := a.CancelOperation(id); err != ErrFinOp {
+        t.Errorf("late cancel: err = %v, want ErrFinOp", err)
+    }
+    if status, res := opState(a, id); status != SuccessStatusType || res.Response.(ReserveFanOutResponse).Account != "one" {
+        t.Errorf("status %v result %v, want one's booking", status, res)
+    }
+}
+
+func TestFanOutCancelWithoutBooking(t *testing.T) {
+    a, fa, fc := newFanOutApp("one", "two")
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveFanOutOperation(fanOutParams(request, "one", "two"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    release(fa.loginGates, nil, "one", "two")
+    advanceUntil(t, fc, "both accounts to attempt", func() bool { return attempts(a, id) == 2 })
+    if err := a.CancelOperation(id); err != nil {
+        t.Fatal(err)
+    }
+    fa.reserveGates["one"] <- errors.New("sold out")
+    fa.reserveGates["two"] <- errors.New("sold out")
+    eventually(t, "the fan out to finish", func() bool {
+        _, reserves := fa.calls()
+        status, _ := opState(a, id)
+        return len(reserves) == 0 && status == CancelStatusType
+    })
+    a.mu.Lock()
+    var output <-chan OperationResult
+    for _, op := range a.operations {
+        if op.ID == id {
+            output = op.Output
+        }
+    }
+    a.mu.Unlock()
+    select {
+    case res := <-output:
+        if res.Err != ErrCancel {
+            t.Errorf("reported %v, want ErrCancel", res.Err)
+        }
+    case <-time.After(testTimeout):
+        t.Fatal("timed out waiting for the fan out")
+    }
+}
+
+/*
+Name: groupOf 
+Type: Test Func
+Purpose: The ids of the members of a group and the
+requests they made so far
+*/
+func groupOf(a *AppCtx, group int64) ([]int64, int) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    var ids []int64
+    made := 0
+    for _, op := range a.operations {
+        if op.Group == group {
+            ids = append(ids, op.ID)
+            made += op.Attempts
+        }
+    }
+    return ids, made
+}
+
+func groupParams(request time.Time, emails ...string) (ReserveGroupParam) {
+    var params ReserveGroupParam
+    for _, email := range emails {
+        atTime := atTimeParams(request)
+        atTime.Login = LoginParam{Email: email, Password: "password"}
+        params.Members = append(params.Members, ReserveGroupMember{AtTime: &atTime})
+    }
+    return params
+}
+
+func TestGroupMembersBookTogether(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    fa.reserveGates = map[string]chan error{"a": make(chan error), "b": make(chan error)}
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveGroupOperation(groupParams(request, "a", "b"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    advanceUntil(t, fc, "both members to attempt", func() bool {
+        _, made := groupOf(a, id)
+        return made == 2
+    })
+    // both bookings go through before either is stopped
+    fa.reserveGates["a"] <- nil
+    fa.reserveGates["b"] <- nil
+
+    eventually(t, "the group to finish", func() bool { return finished(t, a, id) })
+    status, res := opState(a, id)
+    if status != SuccessStatusType || res == nil || res.Err != nil {
+        t.Fatalf("status %v result %v, want a success", status, res)
+    }
+    members, _ := groupOf(a, id)
+    resp := res.Response.(ReserveGroupResponse)
+    booked := append([]int64{resp.MemberID}, resp.AlsoBooked...)
+    if len(booked) != 2 || booked[0] == booked[1] {
+        t.Errorf("booked by %d, also %v, want both of %v", resp.MemberID, resp.AlsoBooked, members)
+    }
+    // the member that lost the race still shows it booked
+    for _, member := range members {
+        eventually(t, "the member to finish", func() bool { return finished(t, a, member) })
+        if status, _ := opState(a, member); status != SuccessStatusType {
+            t.Errorf("member %d status %v, want a success", member, status)
+        }
+    }
+    if str, _ := a.OperationsToString(); !strings.Contains(str, "Also Booked: ") {
+        t.Errorf("operations don't list the other booking:\n%s", str)
+    }
+}
+
+func TestGroupBookingBeatsLateCancel(t *testing.T) {
+    a, fa, fc := newTestApp(time.Hour)
+    fa.reserveGates = map[string]chan error{"a": make(chan error), "b": make(chan error)}
+    request := testStart.Add(3 * time.Hour)
+    id, err := a.ScheduleReserveGroupOperation(groupParams(request, "a", "b"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    advanceUntil(t, fc, "both members to attempt", func() bool {
+        _, made := groupOf(a, id)
+        return made == 2
+    })
+    if err := a.CancelOperation(id); err != nil {
+        t.Fatal(err)
+    }
+    fa.reserveGates["a"] <- errors.New("sold out")
+    fa.reserveGates["b"] <- nil
+
+    eventually(t, "the group to finish", func() bool {
+        status, _ := opState(a, id)
+        return status == SuccessStatusType
+    })
+    _, res := opState(a, id)
+    resp := res.Response.(ReserveGroupResponse)
+    members, _ := groupOf(a, id)
+    if resp.MemberID != members[1] || len(resp.AlsoBooked) != 0 {
+        t.Errorf("booked by %d, also %v, want only member %d", resp.MemberID, resp.AlsoBooked, members[1])
+    }
+}
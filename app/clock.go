@@ -0,0 +1,188 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/clock"
+    "errors"
+    "time"
+)
+
+const (
+    // Longest a scheduled wait sleeps before looking at the
+    // clock offset and lead time again
+    maxWaitStep = time.Minute
+    defaultClockSamples = 8
+)
+
+var (
+    ErrNoClockSource = errors.New("no clock source provided")
+)
+
+// Hide as much clock layer details as permissible 
+type ClockSkew clock.Skew
+
+/*
+Name: ClockSyncParam
+Type: App api func input parameters
+Purpose: Provide a means to measure the clock
+offset against the provider by a consumer. If 
+NTPServer is set it is used instead of URL
+*/
+type ClockSyncParam struct {
+    URL         string
+    NTPServer   string
+    Samples     int
+}
+
+/*
+Name: SyncClock 
+Type: External App Func
+Purpose: This function measures the offset of the local
+clock against the provider or an NTP server, and applies
+it to every scheduled fire from then on
+*/
+func (a *AppCtx) SyncClock(params ClockSyncParam) (*ClockSkew, error) {
+    samples := params.Samples
+    if samples <= 0 {
+        samples = defaultClockSamples
+    }
+    var skew *clock.Skew
+    var err error
+    switch {
+    case params.NTPServer != "":
+        skew, err = clock.SampleNTP(params.NTPServer, samples)
+    case params.URL != "":
+        skew, err = clock.SampleHTTP(params.URL, samples)
+    default:
+        return nil, ErrNoClockSource
+    }
+    if err != nil {
+        return nil, err
+    }
+    appSkew := ClockSkew(*skew)
+    a.mu.Lock()
+    a.clockSkew = &appSkew
+    a.mu.Unlock()
+    return &appSkew, nil
+}
+
+/*
+Name: SetLeadTime 
+Type: External App Func
+Purpose: This function sets how long before the scheduled
+instant requests are fired, to make up for the time the 
+request spends on the network
+*/
+func (a *AppCtx) SetLeadTime(lead time.Duration) {
+    a.mu.Lock()
+    a.leadTime = lead
+    a.mu.Unlock()
+}
+
+/*
+Name: ClockStatus 
+Type: External App Func
+Purpose: This function returns the last measured clock
+skew, nil if the clock was never synced, and the lead time
+*/
+func (a *AppCtx) ClockStatus() (*ClockSkew, time.Duration) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.clockSkew == nil {
+        return nil, a.leadTime
+    }
+    skew := *a.clockSkew
+    return &skew, a.leadTime
+}
+
+/*
+Name: clock 
+Type: Internal Func
+Purpose: The clock operations schedule on, the 
+real one unless AppCtx.Clock is set
+*/
+func (a *AppCtx) clock() (clock.Clock) {
+    if a.Clock == nil {
+        return clock.Real{}
+    }
+    return a.Clock
+}
+
+/*
+Name: now 
+Type: Internal Func
+Purpose: The current time on the provider's clock, 
+as best we know it
+*/
+func (a *AppCtx) now() (time.Time) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return a.nowLocked()
+}
+
+/*
+Name: nowLocked 
+Type: Internal Func
+Purpose: Same as now, for callers already holding a.mu
+*/
+func (a *AppCtx) nowLocked() (time.Time) {
+    if a.clockSkew == nil {
+        return a.clock().Now()
+    }
+    return a.clock().Now().Add(a.clockSkew.Offset)
+}
+
+/*
+Name: waitUntil 
+Type: Internal Func
+Purpose: Sleep with ability to cancel until t on the provider's 
+clock, less the lead time if fire is set. Long waits are split in
+steps so a clock sync or lead change made meanwhile still counts.
+Returns false if cancelled
+*/
+func (a *AppCtx) waitUntil(t time.Time, fire bool, cancel <-chan bool) (bool) {
+    return a.waitOrEdit(t, fire, cancel, nil) == waitReached
+}
+
+// How a waitOrEdit ended
+const (
+    waitReached = iota
+    waitCancelled
+    waitEdited
+)
+
+/*
+Name: waitOrEdit 
+Type: Internal Func
+Purpose: Same as waitUntil, but also wakes up when edited is
+closed, so the caller can wait on the edited time instead
+*/
+func (a *AppCtx) waitOrEdit(t time.Time, fire bool, cancel <-chan bool, edited <-chan bool) (int) {
+    for {
+        target := t
+        if fire {
+            a.mu.Lock()
+            target = t.Add(-a.leadTime)
+            a.mu.Unlock()
+        }
+        d := target.Sub(a.now())
+        if d <= 0 {
+            // a cancel that already came in still wins
+            select {
+            case <-cancel:
+                return waitCancelled
+            default:
+                return waitReached
+            }
+        }
+        if d > maxWaitStep {
+            d = maxWaitStep
+        }
+        select {
+        case <-a.clock().After(d):
+        case <-cancel:
+            return waitCancelled
+        case <-edited:
+            return waitEdited
+        }
+    }
+}
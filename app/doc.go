@@ -34,8 +34,10 @@ Public API:
               specifying the date and times to reserve at, the 
               restaurant to reserve at, party size, and a time
               to send the request to the external API at. This time
-              must be in UTC. The func returns an id of the running 
-              operation on success
+              must be in UTC. If the time is left zero, it is 
+              computed from the venue booking policy, see 
+              RequestTimeFromPolicy. The func returns an id of the
+              running operation on success
 
         3. CancelOperation(int64)(error) 
 
@@ -46,8 +48,12 @@ Public API:
 
             - Description: This func takes in a set of login params 
               and attempts to call the login function of the external 
-              api on it. If that call succeeds, it will store the 
-              login params and use them as defaults in future requests
+              api on it. If that call succeeds, it will keep the 
+              login params in memory under the email and make that
+              the default account of future requests, so operations
+              only hold the email and never a copy of the password.
+              SaveLogin(LoginParam)(error) does the same but keeps 
+              them in the credential store under the email
 
         5. Search(SearchParam)(*SearchResponse, error)
 
@@ -64,7 +70,9 @@ Public API:
 
         7. Logout()(error) 
 
-            - Description: Removes any saved login defaults
+            - Description: Clears the default account. A login
+              given to Login stays in memory for the operations
+              already scheduled with it
 
         8. OperationsToString()(string, error) 
 
@@ -76,6 +84,218 @@ Public API:
             - Description: Returns status corresponding to
               operation
 
+        10. SaveAccount(string, LoginParam)(error)
+
+            - Description: This func takes in an account name and
+              a set of login params, checks them with the login 
+              function of the external api and saves them in the
+              credential store under the name
+
+        11. UseAccount(string)(error)
+
+            - Description: Makes a saved account the login 
+              default used by future requests
+
+        12. DeleteAccount(string)(error)
+
+            - Description: Removes a saved account from the
+              credential store
+
+        13. Accounts()([]string, error)
+
+            - Description: Lists the names of saved accounts
+
+        14. ScheduleReserveFanOutOperation(ReserveFanOutParam)(int64, error)
+
+            - Description: This func takes in the same target as
+              a reserve at time operation along with a list of 
+              saved account names, and runs the reserve at time 
+              logic for every account at once. As soon as one 
+              account books, the rest are stopped, and the result
+              reports which account succeeded. Returns an id of 
+              the running operation on success
+
+        16. BookingPolicy(int64)(*BookingPolicy, error)
+
+            - Description: This func takes in a venue id and
+              returns the booking window policy of the venue as
+              published by the external api: days in advance,
+              release time of day and venue timezone
+
+        17. RequestTimeFromPolicy(BookingPolicy, time.Time)(time.Time, error)
+
+            - Description: This func takes in a booking policy 
+              and a reservation day and returns the instant in UTC
+              tables for that day are released. Fails with 
+              ErrNoRelease if the policy has no release time 
+
+        18. VenueLocation(int64)(*time.Location, error)
+
+            - Description: This func takes in a venue id and 
+              returns the venue timezone as published by the 
+              external api. Reservation times should be built in
+              this timezone, since the api matches them against
+              venue slots by hour and minute. Lookups are cached
+
+        19. SyncClock(ClockSyncParam)(*ClockSkew, error)
+
+            - Description: This func measures the offset of the
+              local clock against the provider, by sampling HTTP
+              Date headers from the URL, or against an NTP server
+              if one is given. The offset is applied to every 
+              scheduled fire from then on, and the measured skew
+              is returned
+
+        20. SetLeadTime(time.Duration)
+
+            - Description: Sets how long before the scheduled 
+              instant requests are fired
+
+        21. ClockStatus()(*ClockSkew, time.Duration)
+
+            - Description: Returns the last measured skew, nil if
+              the clock was never synced, and the lead time
+
+        22. ScheduleWatchOperation(WatchParam)(int64, error)
+
+            - Description: This func schedules an operation which 
+              polls the venue's availability on an interval, like
+              a reserve at interval operation, but never books. 
+              Matching slots that weren't offered on the previous
+              poll are published as a slot-found event(see 
+              Subscribe). The operation succeeds with the slots on the 
+              first match, or with Keep set goes on watching until
+              the last reservation time passes or it is cancelled
+
+        23. ScheduleSnipeOperation(SnipeParam)(int64, error)
+
+            - Description: This func schedules an operation which 
+              hunts for cancellations. It keeps the full set of 
+              slots the venue offers on the day, diffs every poll
+              against the last, and books new slots matching the
+              params right away. Polling runs at MinInterval inside
+              the 24 to 48 hour window before the reservation, when
+              most cancellations come in, and slows linearly to
+              MaxInterval further from it
+
+        24. SetNotifier(notify.Notifier)
+
+            - Description: Sets the notifier told when operations
+              succeed, fail or are cancelled. Operations given their
+              own notifier, through the Notifier field of their 
+              params or SetOperationNotifier, use that instead. Group
+              members are only announced through their own notifier,
+              the group itself covers them. Failing notifiers don't
+              affect the operation, the error is shown in the list
+
+        25. SetOperationNotifier(int64, notify.Notifier)(error)
+
+            - Description: Sets the notifier of an operation that is
+              still in progress
+
+        26. Subscribe(int)(<-chan Event, func())
+
+            - Description: Returns a channel receiving the lifecycle
+              events of every operation from then on, and a func to
+              unsubscribe. The event types are scheduled, 
+              login-started, waiting, attempt, edited, slot-found, booked,
+              watched(a watch that found slots), failed, cancelled 
+              and cleaned. Events carry a sequence number, and are
+              dropped instead of holding up operations when the 
+              channel's buffer is full
+
+        27. ListOperations(OperationFilter)([]OperationSnapshot)
+
+            - Description: Returns a copy of each operation matching 
+              the filter: its type, the params it was scheduled with
+              (without passwords or notifiers), status, venue, 
+              accounts, attempts made, when it was created, made 
+              its first call and finished, a log of every login,
+              reserve and availability call it made with latency,
+              outcome and slots seen, and its result or error. Zero filter fields
+              match everything, a group matches a venue or account
+              when any member does. Snapshots encode as JSON
+
+        28. GetOperation(int64)(*OperationSnapshot, error)
+
+            - Description: Returns a copy of the operation with the
+              given id, ErrIdOp if there is none
+
+        29. SnapshotsToString([]OperationSnapshot)(string)
+
+            - Description: Stringifies snapshots the same way
+              OperationsToString does
+
+        30. HistoryToString(OperationSnapshot)(string)
+
+            - Description: Stringifies one snapshot in full, with
+              its timestamps and attempt log to the millisecond
+
+        31. ScheduleTemplate(RecurringParam)(int64, error)
+
+            - Description: This func makes a recurring template
+              for a standing reservation. For every reservation 
+              day on one of the weekdays, a reserve at time 
+              operation is scheduled for the times of day, requested
+              DaysAhead days before at ReleaseTime, or by the venue
+              booking policy when both are zero. One operation is 
+              scheduled at a time, the next once the request of the
+              last went out. Returns the id of the template
+
+        32. Templates()([]Template)
+
+            - Description: Returns a copy of every template, with
+              the ids of the operations it scheduled and its next 
+              request time
+
+        33. PauseTemplate(int64)(error), ResumeTemplate(int64)(error)
+
+            - Description: Stop a template from scheduling, which 
+              cancels the operation waiting on its next occurrence,
+              and let it schedule again from the next occurrence 
+              still ahead
+
+        34. DeleteTemplate(int64)(error)
+
+            - Description: Pauses a template and removes it, the
+              operations it scheduled are kept
+
+        35. TemplatesToString()(string, error)
+
+            - Description: Stringifies the templates
+
+        36. UpdateOperation(int64, OperationUpdate)(error)
+
+            - Description: This func changes the reservation times,
+              table types, party size, repeat interval or request 
+              time of an in progress operation in one step, keeping
+              its id and history. Zero fields of the update are 
+              kept. An operation waiting on its request time waits 
+              on the new one, logging in again if the old token 
+              would expire first, and other changes are used from
+              the next request on. Fails with ErrFired once the 
+              request time was reached, ErrBadUpdate for fields the
+              operation doesn't have, and ErrFinOp when it is over.
+              Group operations are edited through their members
+
+        37. Notifying()(bool)
+
+            - Description: Reports whether a finished operation is
+              still sending its notification. The outcome event is
+              published once the notifier returns, so a process
+              should wait for this to be false before exiting
+
+        15. ScheduleReserveGroupOperation(ReserveGroupParam)(int64, error)
+
+            - Description: This func takes in a list of members,
+              each a set of reserve at time or reserve at interval
+              parameters, and schedules every member as its own 
+              operation belonging to a group operation. As soon as
+              one member books, the rest are cancelled. Cancelling
+              the group cancels every member, and cleaning the 
+              group cleans every member. Returns the id of the
+              group operation on success
+
 
 **********************************************************************
 
@@ -121,7 +341,122 @@ App Internals:
         which will cause the thread to block until one of those two
         channels activates. In the cancel case, we report an error of
         cancelled, and in the time.After() case, we continue execution.
+
+        The cancel channel is only ever closed, never sent on, through
+        the internal method 'AppCtx.stopOperation'. A close wakes the
+        select like a send would, but never blocks on a thread that is
+        busy talking to the api, and the status check in stopOperation
+        keeps a channel from being closed twice.
+
+    Announcing Outcomes:
+
+        Operation threads never write to the output channel the
+        AppCtx reads results from. 'AppCtx.startOperation' hands 
+        them an inner channel, and 'AppCtx.finishOperation' passes
+        the result on and then tells the notifier, so no operation
+        has to remember to. The notifier is looked up before the 
+        result is passed on, since once it is the operation can be
+        cleaned away. The outcome event is only published after the
+        notifier returns, and AppCtx.notifying counts the results 
+        passed on but not yet notified, so a caller that exits once
+        nothing is in progress and Notifying is false never cuts a
+        notification off.
+
+    Publishing Events:
+
+        Events go through 'AppCtx.publish', which sends to every
+        subscriber without blocking. The subscriber list has its own
+        lock, which is never held while taking a.mu, so events can be
+        published with or without a.mu held. For the same reason 
+        events are stamped with the clock directly and not with 
+        'AppCtx.now'. Operation threads publish their own progress,
+        and the outcome is published by 'AppCtx.finishOperation'.
+
+    Scheduled Waits:
+
+        Operations waiting for a set instant should use the internal
+        method 'AppCtx.waitUntil' instead of selecting on time.After
+        themselves. It measures time on the provider's clock using 
+        the skew from the last SyncClock, subtracts the lead time 
+        for request fires, and wakes up at least once a minute to 
+        pick up a newer skew or lead time. Time itself always comes
+        from 'AppCtx.clock', which is the AppCtx.Clock field or the
+        real clock when unset, never from the time pkg directly, so
+        the scheduler tests can run operations on a fake clock.
+
+    Recording Calls:
+
+        Operation threads call the api through 'AppCtx.opLogin',
+        'AppCtx.opReserve' and 'AppCtx.opAvailability' instead of 
+        AppCtx.API, which time each call and append it to the 
+        attempt log of the operation. The first call marks the 
+        operation started. New operation types should do the same,
+        or 'show' will have nothing to say about them. A call with
+        the same outcome as the record before it only bumps that
+        record's Repeats and Last, and the log keeps the latest 200
+        records, counting the ones it drops in Dropped, so a watch
+        or snipe polling for days stays small.
+
+    Editing Operations:
+
+        Operation threads keep their params by value, so an edit
+        can't reach them directly. 'AppCtx.UpdateOperation' writes
+        the new fields to Operation.Params, then closes and replaces
+        the operation's edited channel. Threads copy the fields back
+        with 'AppCtx.editedParams' before each request, and wait with
+        'AppCtx.waitOrEdit' so an edit wakes them up to start over.
+        Fan out members share the channel of the fan out, which is
+        why it is closed instead of sent on.
+
+    Recurring Templates:
+
+        A template is not an operation. It has its own thread, run
+        by 'AppCtx.runTemplate', which schedules a reserve at time
+        operation for the next occurrence like a consumer would, 
+        then waits for its request time on a stop channel that is 
+        closed on pause. A resumed template gets a new thread and
+        stop channel, so a paused thread still scheduling checks its
+        own channel and cancels what it scheduled.
+
+    Locking:
+
+        Group threads update the operations of their members, so the
+        operations list, id generator and login defaults are guarded
+        by 'AppCtx.mu'. Every external func takes the lock, and
+        internal funcs noted with "Must hold a.mu" expect it held.
+        Operation threads never hold the lock while talking to the 
+        api. New operations should be registered through the internal
+        method 'AppCtx.startOperation', which also starts the thread.
+
+    Group Operations:
+
+        A group is an operation whose members are operations too, with
+        their 'Group' field set to the id of the group. Each member
+        thread runs a reserveAtTime or reserveAtInterval on an inner
+        channel, passes the result on to its own output and then 
+        notifies the group thread. On the first booking the group 
+        thread cancels the remaining members through stopMembers.
     
+    Fan Out Operations:
+
+        A fan out operation is one operation in the AppCtx whose 
+        thread starts a 'reserveAtTime' thread per account. Each 
+        member gets its own cancel channel and a buffered output 
+        channel, so a member never blocks on reporting. Members are
+        stopped by closing their cancel channel, which wakes the 
+        select statement just like a send would.
+
+    Login Credentials:
+
+        If the AppCtx has a 'Credentials' store(see the vault pkg), no
+        password is ever kept on the AppCtx or in operation params.
+        Login defaults are stored as an account name, and operations
+        carry the name in their 'Account' field. The operation thread
+        calls the internal method 'AppCtx.login', which fetches the 
+        credentials from the store right before calling the api. 
+        Without a store, we fall back to keeping the login defaults
+        in memory as before.
+
     Writing Code For App Layer:
 
         If you are writing an internal function for the app layer, 
@@ -0,0 +1,186 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "errors"
+    "time"
+)
+
+var (
+    ErrBadUpdate = errors.New("operation does not have the edited field")
+    ErrFired = errors.New("request was already sent")
+)
+
+/*
+Name: OperationUpdate
+Type: App api func input parameters
+Purpose: The fields of an in progress operation to change,
+zero fields are kept. RequestTime only applies to reserve
+at time and fan out operations, RepeatInterval to reserve
+at interval and watch operations
+*/
+type OperationUpdate struct {
+    ReservationTimes []time.Time
+    TableTypes       []api.TableType
+    PartySize        int
+    RepeatInterval   time.Duration
+    RequestTime      time.Time
+}
+
+/*
+Name: editable
+Type: Internal Struct
+Purpose: The fields an update can change, pulled
+out of whichever param struct holds them
+*/
+type editable struct {
+    times       []time.Time
+    tableTypes  []api.TableType
+    partySize   int
+    interval    time.Duration
+    request     time.Time
+}
+
+/*
+Name: UpdateOperation
+Type: External App Func
+Purpose: Swap the params of an in progress operation in
+one step, keeping its id and history. A thread waiting
+on the old request time wakes up and waits on the new one,
+other changes are picked up before the next request
+*/
+func (a *AppCtx) UpdateOperation(id int64, update OperationUpdate) (error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    err := a.updateOperationResult(id)
+    if err != nil {
+        return err
+    }
+    for i, operation := range a.operations {
+        if operation.ID != id {
+            continue
+        }
+        if operation.Status != InProgressStatusType {
+            return ErrFinOp
+        }
+        fields, ok := editableOf(operation.Params)
+        if !ok {
+            return ErrBadUpdate
+        }
+        now := a.nowLocked()
+        if !update.RequestTime.IsZero() {
+            if fields.request.IsZero() {
+                return ErrBadUpdate
+            }
+            // the request goes out lead time early
+            if !fields.request.Add(-a.leadTime).After(now) {
+                return ErrFired
+            }
+            if !update.RequestTime.After(now) {
+                return ErrTimeFut
+            }
+            fields.request = update.RequestTime
+        }
+        if update.RepeatInterval != 0 {
+            if fields.interval == 0 {
+                return ErrBadUpdate
+            }
+            fields.interval = update.RepeatInterval
+        }
+        if update.ReservationTimes != nil {
+            if len(update.ReservationTimes) == 0 {
+                return api.ErrTimeNull
+            }
+            fields.times = update.ReservationTimes
+        }
+        if update.TableTypes != nil {
+            fields.tableTypes = update.TableTypes
+        }
+        if update.PartySize != 0 {
+            fields.partySize = update.PartySize
+        }
+
+        a.operations[i].Params = withEditable(operation.Params, fields)
+        a.operations[i].RequestTime = fields.request
+        a.operations[i].Location = timesLocation(fields.times)
+        // wake every thread of the operation
+        close(a.operations[i].edited)
+        a.operations[i].edited = make(chan bool)
+        a.publish(Event{Type: EditedEvent, OperationID: id})
+        return nil
+    }
+    return ErrIdOp
+}
+
+/*
+Name: editedParams
+Type: Internal Func
+Purpose: Copy the editable fields of the operation with
+the given id into params, and return the channel closed
+on its next edit. Unknown operations leave params as is
+and return a nil channel, which never fires
+*/
+func (a *AppCtx) editedParams(id int64, params interface{}) (interface{}, <-chan bool) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    for _, operation := range a.operations {
+        if operation.ID != id {
+            continue
+        }
+        fields, ok := editableOf(operation.Params)
+        if !ok {
+            return params, nil
+        }
+        return withEditable(params, fields), operation.edited
+    }
+    return params, nil
+}
+
+/*
+Name: editableOf
+Type: Internal Func
+Purpose: Pull the editable fields out of params, false
+for params that can't be edited
+*/
+func editableOf(params interface{}) (editable, bool) {
+    switch p := params.(type) {
+        case ReserveAtTimeParam:
+            return editable{p.ReservationTimes, p.TableTypes, p.PartySize, 0, p.RequestTime}, true
+        case ReserveAtIntervalParam:
+            return editable{p.ReservationTimes, p.TableTypes, p.PartySize, p.RepeatInterval, time.Time{}}, true
+        case ReserveFanOutParam:
+            return editable{p.ReservationTimes, p.TableTypes, p.PartySize, 0, p.RequestTime}, true
+        case WatchParam:
+            return editable{p.ReservationTimes, p.TableTypes, p.PartySize, p.RepeatInterval, time.Time{}}, true
+        case SnipeParam:
+            return editable{p.ReservationTimes, p.TableTypes, p.PartySize, 0, time.Time{}}, true
+    }
+    return editable{}, false
+}
+
+/*
+Name: withEditable
+Type: Internal Func
+Purpose: Return a copy of params with the editable
+fields it has set from fields
+*/
+func withEditable(params interface{}, fields editable) (interface{}) {
+    switch p := params.(type) {
+        case ReserveAtTimeParam:
+            p.ReservationTimes, p.TableTypes, p.PartySize, p.RequestTime = fields.times, fields.tableTypes, fields.partySize, fields.request
+            return p
+        case ReserveAtIntervalParam:
+            p.ReservationTimes, p.TableTypes, p.PartySize, p.RepeatInterval = fields.times, fields.tableTypes, fields.partySize, fields.interval
+            return p
+        case ReserveFanOutParam:
+            p.ReservationTimes, p.TableTypes, p.PartySize, p.RequestTime = fields.times, fields.tableTypes, fields.partySize, fields.request
+            return p
+        case WatchParam:
+            p.ReservationTimes, p.TableTypes, p.PartySize, p.RepeatInterval = fields.times, fields.tableTypes, fields.partySize, fields.interval
+            return p
+        case SnipeParam:
+            p.ReservationTimes, p.TableTypes, p.PartySize = fields.times, fields.tableTypes, fields.partySize
+            return p
+    }
+    return params
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "sync"
+    "time"
+)
+
+/*
+Name: EventType 
+Type: App Type
+Purpose: The lifecycle changes of an operation 
+*/
+type EventType string
+
+const (
+    // Operation registered
+    ScheduledEvent    EventType = "scheduled"
+    // Logging in to the service, Account is set
+    // when logging in with a saved account
+    LoginStartedEvent EventType = "login-started"
+    // Sleeping until Until
+    WaitingEvent      EventType = "waiting"
+    // Asking the service, Attempt counts from 1
+    AttemptEvent      EventType = "attempt"
+    // Params changed through UpdateOperation
+    EditedEvent       EventType = "edited"
+    // A watch or snipe saw the Slots open up
+    SlotFoundEvent    EventType = "slot-found"
+    // The operation ended, see ReservationTime or Error.
+    // A watch that found slots ends in watched instead
+    BookedEvent       EventType = "booked"
+    WatchedEvent      EventType = "watched"
+    FailedEvent       EventType = "failed"
+    CancelledEvent    EventType = "cancelled"
+    // Operation removed from the list
+    CleanedEvent      EventType = "cleaned"
+)
+
+// Events buffered per subscriber when none is asked for
+const defaultEventBuffer = 64
+
+/*
+Name: Event 
+Type: struct
+Purpose: A change in the lifecycle of an operation. Seq 
+grows by one per event published, so a gap tells a 
+subscriber it missed events. Only the fields that go with
+the Type are set
+*/
+type Event struct {
+    Seq             int64       `json:"seq"`
+    Type            EventType   `json:"type"`
+    OperationID     int64       `json:"operation_id"`
+    Time            time.Time   `json:"time"`
+    Account         string      `json:"account,omitempty"`
+    Until           *time.Time  `json:"until,omitempty"`
+    Attempt         int         `json:"attempt,omitempty"`
+    Slots           []api.Slot  `json:"slots,omitempty"`
+    ReservationTime *time.Time  `json:"reservation_time,omitempty"`
+    Error           string      `json:"error,omitempty"`
+}
+
+/*
+Name: eventHub 
+Type: Internal Struct
+Purpose: The subscribers to the event stream. It has its 
+own lock, which is never held while taking AppCtx.mu, so
+events can be published with or without AppCtx.mu held
+*/
+type eventHub struct {
+    mu          sync.Mutex
+    seq         int64
+    nextSub     int
+    subs        map[int]chan Event
+}
+
+/*
+Name: Subscribe 
+Type: External App Func
+Purpose: This function returns a channel receiving every
+event published from now on, and a func to unsubscribe,
+which closes the channel. Events are dropped rather than
+holding up operations if the channel's buffer of the given
+size, or a default one if not positive, is full
+*/
+func (a *AppCtx) Subscribe(buffer int) (<-chan Event, func()) {
+    if buffer <= 0 {
+        buffer = defaultEventBuffer
+    }
+    hub := &a.events
+    hub.mu.Lock()
+    defer hub.mu.Unlock()
+    if hub.subs == nil {
+        hub.subs = make(map[int]chan Event)
+    }
+    id := hub.nextSub
+    hub.nextSub += 1
+    events := make(chan Event, buffer)
+    hub.subs[id] = events
+    var once sync.Once
+    unsubscribe := func() {
+        once.Do(func() {
+            hub.mu.Lock()
+            defer hub.mu.Unlock()
+            delete(hub.subs, id)
+            close(events)
+        })
+    }
+    return events, unsubscribe
+}
+
+/*
+Name: publish 
+Type: Internal Func
+Purpose: Stamp an event and hand it to every subscriber
+without blocking
+*/
+func (a *AppCtx) publish(event Event) {
+    // not a.now, which needs AppCtx.mu
+    event.Time = a.clock().Now()
+    hub := &a.events
+    hub.mu.Lock()
+    defer hub.mu.Unlock()
+    hub.seq += 1
+    event.Seq = hub.seq
+    for _, events := range hub.subs {
+        select {
+        case events<- event:
+        default:
+        }
+    }
+}
+
+/*
+Name: publishWaiting 
+Type: Internal Func
+Purpose: Publish that an operation sleeps until t
+*/
+func (a *AppCtx) publishWaiting(id int64, t time.Time) {
+    a.publish(Event{Type: WaitingEvent, OperationID: id, Until: &t})
+}
+
+/*
+Name: publishAttempt 
+Type: Internal Func
+Purpose: Count a request to the service against the
+operation and publish it. Must not hold a.mu
+*/
+func (a *AppCtx) publishAttempt(id int64, attempt int) {
+    a.mu.Lock()
+    for i, operation := range a.operations {
+        if operation.ID == id {
+            a.operations[i].Attempts++
+        }
+    }
+    a.mu.Unlock()
+    a.publish(Event{Type: AttemptEvent, OperationID: id, Attempt: attempt})
+}
+
+/*
+Name: publishResult 
+Type: Internal Func
+Purpose: Publish how an operation ended
+*/
+func (a *AppCtx) publishResult(id int64, res OperationResult) {
+    _, watched := res.Response.(WatchResponse)
+    switch {
+    case res.Err == nil && watched:
+        a.publish(Event{Type: WatchedEvent, OperationID: id, Slots: res.Response.(WatchResponse).Slots})
+    case res.Err == nil:
+        resTime := res.Response.Time()
+        a.publish(Event{Type: BookedEvent, OperationID: id, ReservationTime: &resTime})
+    case res.Err == ErrCancel:
+        a.publish(Event{Type: CancelledEvent, OperationID: id})
+    default:
+        a.publish(Event{Type: FailedEvent, OperationID: id, Error: res.Err.Error()})
+    }
+}
@@ -0,0 +1,250 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/notify"
+    "errors"
+    "time"
+)
+
+var (
+    ErrNoMembers = errors.New("no group members provided")
+    ErrBadMember = errors.New("group member must be exactly one of reserve at time or reserve at interval")
+)
+
+/*
+Name: ReserveGroupMember
+Type: App api func input parameters
+Purpose: Describe one target of a reserve group
+operation. Exactly one of the fields must be set
+*/
+type ReserveGroupMember struct {
+    AtTime      *ReserveAtTimeParam
+    AtInterval  *ReserveAtIntervalParam
+}
+
+/*
+Name: ReserveGroupParam
+Type: App api func input parameters
+Purpose: Provide a means to make a reserve
+group operation by a consumer
+*/
+type ReserveGroupParam struct {
+    Members     []ReserveGroupMember
+    Notifier    notify.Notifier `json:"-"`
+}
+
+/*
+Name: ReserveGroupResponse 
+Type: struct
+Purpose: Define the data that should be returned on a
+successful reserve group response. AlsoBooked lists the
+ids of any other members that finished booking before
+they could be stopped
+*/
+type ReserveGroupResponse struct {
+    ReservationTime time.Time
+    MemberID        int64
+    VenueID         int64
+    AlsoBooked      []int64
+}
+
+/*
+Name: Time 
+Type: interface method
+Purpose: Satisfy the Timetable interface
+*/
+func (r ReserveGroupResponse) Time() (time.Time) {
+    return r.ReservationTime
+}
+
+/*
+Name: memberResult 
+Type: Internal Struct
+Purpose: Tag the result of a group member with 
+the member it came from
+*/
+type memberResult struct {
+    id          int64
+    venueID     int64
+    result      OperationResult
+}
+
+/*
+Name: ScheduleReserveGroupOperation
+Type: External App Func
+Purpose: Used to Schedule a group of reserve at time and reserve
+at interval operations, where the first member to book cancels 
+the rest. Every member is its own operation which can be listed
+and cancelled, returns the ID of the group 
+*/
+func (a *AppCtx) ScheduleReserveGroupOperation(params ReserveGroupParam) (int64, error) {
+    if len(params.Members) == 0 {
+        return 0, ErrNoMembers
+    }
+    // request times come from the api, so fill them in
+    // before taking the lock, on a copy of the caller's slice
+    params.Members = append([]ReserveGroupMember(nil), params.Members...)
+    for i, member := range params.Members {
+        if member.AtTime == nil {
+            continue
+        }
+        atTime := *member.AtTime
+        err := a.fillRequestTime(&atTime)
+        if err != nil {
+            return 0, err
+        }
+        params.Members[i].AtTime = &atTime
+    }
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    // resolve every member's login before starting anything,
+    // so a group is started whole or not at all
+    members := make([]ReserveGroupMember, len(params.Members))
+    for i, member := range params.Members {
+        if (member.AtTime == nil) == (member.AtInterval == nil) {
+            return 0, ErrBadMember
+        }
+        if member.AtTime != nil {
+            atTime := *member.AtTime
+            login, account, err := a.loginDefaults(atTime.Login, atTime.Account)
+            if err != nil {
+                return 0, err
+            }
+            atTime.Login = login
+            atTime.Account = account
+            members[i].AtTime = &atTime
+        } else {
+            atInterval := *member.AtInterval
+            login, account, err := a.loginDefaults(atInterval.Login, atInterval.Account)
+            if err != nil {
+                return 0, err
+            }
+            atInterval.Login = login
+            atInterval.Account = account
+            members[i].AtInterval = &atInterval
+        }
+    }
+
+    done := make(chan memberResult, len(members))
+    groupOp := Operation{
+        Group: NoGroup,
+        Params: ReserveGroupParam{Members: members},
+        Notifier: params.Notifier,
+    }
+    groupID := a.startOperation(groupOp, func(id int64, cancel <-chan bool, output chan<- OperationResult) {
+        a.reserveGroup(id, len(members), done, cancel, output)
+    })
+    for _, member := range members {
+        member := member
+        op := Operation{Group: groupID}
+        if member.AtTime != nil {
+            op.Params = *member.AtTime
+            op.Location = timesLocation(member.AtTime.ReservationTimes)
+            op.RequestTime = member.AtTime.RequestTime
+            op.Notifier = member.AtTime.Notifier
+        } else {
+            op.Params = *member.AtInterval
+            op.Location = timesLocation(member.AtInterval.ReservationTimes)
+            op.Notifier = member.AtInterval.Notifier
+        }
+        a.startOperation(op, func(id int64, cancel <-chan bool, output chan<- OperationResult) {
+            // run the member on an inner channel so we can pass
+            // its result on to both the group and its own output
+            inner := make(chan OperationResult, 1)
+            var venueID int64
+            if member.AtTime != nil {
+                venueID = member.AtTime.VenueID
+                a.reserveAtTime(id, *member.AtTime, cancel, inner)
+            } else {
+                venueID = member.AtInterval.VenueID
+                a.reserveAtInterval(id, *member.AtInterval, cancel, inner)
+            }
+            res := <-inner
+            output<- res
+            close(output)
+            done<- memberResult{id: id, venueID: venueID, result: res}
+        })
+    }
+    return groupID, nil
+}
+
+/*
+Name: reserveGroup 
+Type: Internal App Func
+Purpose: This function is intended to run on a separate thread. It 
+waits on the members of a group, cancelling the rest as soon as 
+one books. Members that book before they could be stopped are
+waited for and reported, and win over a cancel of the group
+*/
+func (a *AppCtx) reserveGroup(id int64, count int, done <-chan memberResult, cancel <-chan bool, output chan<- OperationResult) {
+    var firstErr error
+    cancelled := false
+    for remaining := count; remaining > 0; {
+        select {
+        case res := <-done:
+            remaining -= 1
+            if res.result.Err != nil {
+                if firstErr == nil && res.result.Err != ErrCancel {
+                    firstErr = res.result.Err
+                }
+                continue
+            }
+            a.mu.Lock()
+            a.stopMembers(id, res.id)
+            a.mu.Unlock()
+            response := ReserveGroupResponse{
+                ReservationTime: res.result.Response.Time(),
+                MemberID: res.id,
+                VenueID: res.venueID,
+            }
+            // wait for the rest, any may have booked already
+            for ; remaining > 0; remaining-- {
+                other := <-done
+                if other.result.Err == nil {
+                    response.AlsoBooked = append(response.AlsoBooked, other.id)
+                }
+            }
+            output<- OperationResult{Response: response, Err: nil}
+            close(output)
+            return
+        case <-cancel:
+            // stop the members but keep collecting them, one
+            // may have booked already. A nil channel keeps us
+            // from reading cancel again
+            a.mu.Lock()
+            a.stopMembers(id, NoGroup)
+            a.mu.Unlock()
+            cancel = nil
+            cancelled = true
+        }
+    }
+    // every member failed or was cancelled on its own 
+    if firstErr == nil || cancelled {
+        firstErr = ErrCancel
+    }
+    output<- OperationResult{Response: nil, Err: firstErr}
+    close(output)
+}
+
+/*
+Name: stopMembers 
+Type: Internal Func
+Purpose: Cancel every in progress member of a group except
+the member with id except, which may be NoGroup to cancel
+all of them. Members that already finished, booked or not,
+are left as they are. Must hold a.mu
+*/
+func (a *AppCtx) stopMembers(group int64, except int64) {
+    for i, operation := range a.operations {
+        if operation.Group != group || operation.ID == except {
+            continue
+        }
+        // a member may have finished since we last looked
+        a.updateOperationResult(operation.ID)
+        if a.operations[i].Status != InProgressStatusType {
+            continue
+        }
+        a.stopOperation(i)
+    }
+}
@@ -0,0 +1,217 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "strconv"
+    "time"
+)
+
+/*
+Name: CallType
+Type: App Type
+Purpose: The api call an attempt record is of
+*/
+type CallType string
+
+const (
+    LoginCall        CallType = "login"
+    ReserveCall      CallType = "reserve"
+    AvailabilityCall CallType = "availability"
+)
+
+// Most records an operation keeps, older ones are
+// dropped and counted once the log is full
+const maxLogRecords = 200
+
+/*
+Name: AttemptRecord
+Type: struct
+Purpose: One call an operation made to the service. Time
+is when the call was made on the local clock. Slots is
+what an availability call saw, ReservationTime what a
+reserve call booked, Error why the call failed. Calls
+with the same outcome as the one before fold into its
+record, Repeats counting them and Last when the latest
+was made
+*/
+type AttemptRecord struct {
+    Call            CallType        `json:"call"`
+    Account         string          `json:"account,omitempty"`
+    Time            time.Time       `json:"time"`
+    Latency         time.Duration   `json:"latency"`
+    Slots           []api.Slot      `json:"slots,omitempty"`
+    ReservationTime *time.Time      `json:"reservation_time,omitempty"`
+    Error           string          `json:"error,omitempty"`
+    Repeats         int             `json:"repeats,omitempty"`
+    Last            *time.Time      `json:"last,omitempty"`
+}
+
+/*
+Name: sameOutcome
+Type: Internal Func
+Purpose: Report whether two records are the same call
+with the same outcome, so the second can fold into the
+first. Bookings never fold
+*/
+func sameOutcome(a, b AttemptRecord) (bool) {
+    if a.Call != b.Call || a.Account != b.Account || a.Error != b.Error {
+        return false
+    }
+    if a.ReservationTime != nil || b.ReservationTime != nil || len(a.Slots) != len(b.Slots) {
+        return false
+    }
+    for i := range a.Slots {
+        if !a.Slots[i].Time.Equal(b.Slots[i].Time) || a.Slots[i].TableType != b.Slots[i].TableType {
+            return false
+        }
+    }
+    return true
+}
+
+/*
+Name: opLogin
+Type: Internal Func
+Purpose: Log in for the operation with the given id,
+publishing and recording the call
+*/
+func (a *AppCtx) opLogin(id int64, login LoginParam, account string) (*api.LoginResponse, error) {
+    a.publish(Event{Type: LoginStartedEvent, OperationID: id, Account: account})
+    start := a.clock().Now()
+    loginResp, err := a.login(login, account)
+    record := AttemptRecord{Call: LoginCall, Account: account, Time: start}
+    if record.Account == "" {
+        record.Account = login.Email
+    }
+    a.record(id, record, start, err)
+    return loginResp, err
+}
+
+/*
+Name: opReserve
+Type: Internal Func
+Purpose: Reserve for the operation with the given id,
+recording the call
+*/
+func (a *AppCtx) opReserve(id int64, params api.ReserveParam) (*api.ReserveResponse, error) {
+    start := a.clock().Now()
+    reserveResp, err := a.API.Reserve(params)
+    record := AttemptRecord{Call: ReserveCall, Account: params.LoginResp.Email, Time: start}
+    if err == nil {
+        reservationTime := reserveResp.ReservationTime
+        record.ReservationTime = &reservationTime
+    }
+    a.record(id, record, start, err)
+    return reserveResp, err
+}
+
+/*
+Name: opAvailability
+Type: Internal Func
+Purpose: Look up availability for the operation with
+the given id, recording the call and the slots seen
+*/
+func (a *AppCtx) opAvailability(id int64, params api.AvailabilityParam) (*api.AvailabilityResponse, error) {
+    start := a.clock().Now()
+    availResp, err := a.API.Availability(params)
+    record := AttemptRecord{Call: AvailabilityCall, Account: params.LoginResp.Email, Time: start}
+    if err == nil {
+        record.Slots = availResp.Slots
+    }
+    a.record(id, record, start, err)
+    return availResp, err
+}
+
+/*
+Name: record
+Type: Internal Func
+Purpose: Append a call started at start to the log of
+the operation with the given id, and mark the operation
+started if it is the first. A call with the same outcome
+as the last record folds into it, and once the log holds
+maxLogRecords the oldest record is dropped and counted,
+so long polls don't grow it forever. Must not hold a.mu
+*/
+func (a *AppCtx) record(id int64, record AttemptRecord, start time.Time, err error) {
+    record.Latency = a.clock().Now().Sub(start)
+    if err != nil {
+        record.Error = err.Error()
+    }
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    for i, operation := range a.operations {
+        if operation.ID == id {
+            if a.operations[i].Started.IsZero() {
+                a.operations[i].Started = start
+            }
+            log := a.operations[i].Log
+            if n := len(log); n > 0 && sameOutcome(log[n-1], record) {
+                last := record.Time
+                log[n-1].Repeats++
+                log[n-1].Last = &last
+                log[n-1].Latency = record.Latency
+                continue
+            }
+            if len(log) >= maxLogRecords {
+                drop := len(log) - maxLogRecords + 1
+                log = append(log[:0], log[drop:]...)
+                a.operations[i].Dropped += drop
+            }
+            a.operations[i].Log = append(log, record)
+        }
+    }
+}
+
+/*
+Name: HistoryToString
+Type: External App Func
+Purpose: This function stringifies one operation snapshot
+along with its timestamps and attempt log, to the
+millisecond and in local time
+*/
+func HistoryToString(snap OperationSnapshot) (string) {
+    layout := "2006-01-02 15:04:05.000 MST"
+    histStr := "Operation: \n\n" + snapshotToString(snap)
+    histStr += "\tCreated: " + snap.Created.In(time.Local).Format(layout) + "\n"
+    if snap.Started != nil {
+        histStr += "\tStarted: " + snap.Started.In(time.Local).Format(layout) + "\n"
+    }
+    if snap.Finished != nil {
+        histStr += "\tFinished: " + snap.Finished.In(time.Local).Format(layout) + "\n"
+    }
+    if len(snap.Log) == 0 {
+        return histStr
+    }
+    histStr += "\tLog: \n"
+    if snap.Dropped != 0 {
+        histStr += "\t\t(" + strconv.Itoa(snap.Dropped) + " older records dropped)\n"
+    }
+    for i, record := range snap.Log {
+        histStr += "\t\t" + strconv.Itoa(snap.Dropped + i + 1) + ". " + record.Time.In(time.Local).Format(layout)
+        histStr += " " + string(record.Call)
+        if record.Account != "" {
+            histStr += " as " + record.Account
+        }
+        histStr += " took " + record.Latency.Round(time.Millisecond).String() + ": "
+        switch {
+            case record.Error != "":
+                histStr += record.Error
+            case record.ReservationTime != nil:
+                histStr += "booked " + venueAndLocal(*record.ReservationTime, snap.Location)
+            case record.Call == AvailabilityCall && len(record.Slots) == 0:
+                histStr += "saw no slots"
+            case record.Call == AvailabilityCall:
+                histStr += "saw " + slotsToString(record.Slots)
+            default:
+                histStr += "ok"
+        }
+        if record.Repeats != 0 {
+            histStr += " (repeated " + strconv.Itoa(record.Repeats) + " more times"
+            if record.Last != nil {
+                histStr += ", last " + record.Last.In(time.Local).Format(layout)
+            }
+            histStr += ")"
+        }
+        histStr += "\n"
+    }
+    return histStr
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/notify"
+    "time"
+)
+
+/*
+Name: SetNotifier 
+Type: External App Func
+Purpose: This function sets the notifier told how 
+operations end, unless they have their own. Members of
+a group are left out, only the group is announced. A 
+nil notifier turns global notifications off
+*/
+func (a *AppCtx) SetNotifier(n notify.Notifier) {
+    a.mu.Lock()
+    a.notifier = n
+    a.mu.Unlock()
+}
+
+/*
+Name: SetOperationNotifier 
+Type: External App Func
+Purpose: This function sets the notifier of an operation
+still in progress, used instead of the global one. A nil
+notifier goes back to the global one
+*/
+func (a *AppCtx) SetOperationNotifier(id int64, n notify.Notifier) (error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    for i, operation := range a.operations {
+        if operation.ID == id {
+            a.updateOperationResult(id)
+            if a.operations[i].Status != InProgressStatusType {
+                return ErrFinOp
+            }
+            a.operations[i].Notifier = n
+            return nil
+        }
+    }
+    return ErrIdOp
+}
+
+/*
+Name: finishOperation 
+Type: Internal Func
+Purpose: Intended to run on a separate thread, passing the 
+result of an operation thread from inner to output and then
+announcing it
+*/
+func (a *AppCtx) finishOperation(id int64, inner <-chan OperationResult, output chan<- OperationResult) {
+    res := <-inner
+    // look the notifier up before passing the result on, after 
+    // that the operation may be cleaned at any moment. The result
+    // is passed on under the lock too, so a cancel either sees it
+    // or is seen below
+    a.mu.Lock()
+    var operation Operation
+    for i, op := range a.operations {
+        if op.ID == id {
+            a.operations[i].Finished = a.clock().Now()
+            // a cancel that came in after the booking was made
+            // can't undo it, so the success wins
+            if op.Status == CancelStatusType && res.Err == nil {
+                a.operations[i].Status = SuccessStatusType
+                a.operations[i].Result = &res
+            }
+            operation = op
+        }
+    }
+    notifier := operation.Notifier
+    if notifier == nil && operation.Group == NoGroup {
+        notifier = a.notifier
+    }
+    // output is buffered, so this never blocks. Counted as
+    // notifying in the same lock, so a caller that sees the
+    // operation finished also sees the notification pending
+    output<- res
+    close(output)
+    a.notifying++
+    a.mu.Unlock()
+
+    var err error
+    if notifier != nil {
+        err = notifier.Notify(notification(id, res, operation.Location, a.now()))
+    }
+    a.mu.Lock()
+    a.notifying--
+    for i := range a.operations {
+        if a.operations[i].ID == id && err != nil {
+            a.operations[i].NotifyErr = err
+        }
+    }
+    a.mu.Unlock()
+    // published once notified, so whoever waits on the
+    // event can exit without cutting the notification off
+    a.publishResult(id, res)
+}
+
+/*
+Name: Notifying
+Type: External App Func
+Purpose: This function reports whether a finished
+operation is still sending its notification, which
+it publishes its outcome event after
+*/
+func (a *AppCtx) Notifying() (bool) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return a.notifying != 0
+}
+
+/*
+Name: notification 
+Type: Internal Func
+Purpose: Build the notification for an operation result
+*/
+func notification(id int64, res OperationResult, loc *time.Location, now time.Time) (notify.Notification) {
+    n := notify.Notification{OperationID: id, Time: now}
+    switch {
+    case res.Err == nil:
+        n.Outcome = notify.Succeeded
+        n.Result = venueAndLocal(res.Response.Time(), loc)
+    case res.Err == ErrCancel:
+        n.Outcome = notify.Cancelled
+    default:
+        n.Outcome = notify.Failed
+        n.Error = res.Err.Error()
+    }
+    return n
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "errors"
+    "time"
+)
+
+var (
+    ErrNoRelease = errors.New("venue booking policy has no release time, provide one or a request time")
+)
+
+// The venue id SetReleaseTime takes for the release time
+// of every venue without its own
+const AnyVenue int64 = -1
+
+// Hide as much api layer details as permissible 
+type BookingPolicy api.BookingPolicyResponse
+
+/*
+Name: BookingPolicy 
+Type: External App Func
+Purpose: This function looks up the booking window
+policy of a venue using the underlying api. A release
+time set with SetReleaseTime fills in for one the api
+doesn't publish
+*/
+func (a *AppCtx) BookingPolicy(venueID int64) (*BookingPolicy, error) {
+    resp, err := a.API.BookingPolicy(api.BookingPolicyParam{VenueID: venueID})
+    if err != nil {
+        return nil, err
+    }
+    policy := BookingPolicy(*resp)
+    if policy.ReleaseKnown {
+        return &policy, nil
+    }
+    a.mu.Lock()
+    release, ok := a.releases[venueID]
+    if !ok {
+        release, ok = a.releases[AnyVenue]
+    }
+    a.mu.Unlock()
+    if ok {
+        policy.ReleaseHour = int(release / time.Hour)
+        policy.ReleaseMinute = int(release % time.Hour / time.Minute)
+        policy.ReleaseKnown = true
+    }
+    return &policy, nil
+}
+
+/*
+Name: SetReleaseTime 
+Type: External App Func
+Purpose: This function sets the time of day, in the venue
+timezone, a venue releases tables at, for apis that don't 
+publish it, like one the user found out. AnyVenue sets it
+for every venue without one of its own
+*/
+func (a *AppCtx) SetReleaseTime(venueID int64, release time.Duration) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.releases == nil {
+        a.releases = make(map[int64]time.Duration)
+    }
+    a.releases[venueID] = release
+}
+
+/*
+Name: RequestTimeFromPolicy 
+Type: External App Func
+Purpose: Compute the instant tables for the given reservation
+day are released under a booking policy, in UTC. The day is 
+read in the venue timezone when the policy has one
+*/
+func RequestTimeFromPolicy(policy BookingPolicy, day time.Time) (time.Time, error) {
+    if !policy.ReleaseKnown {
+        return time.Time{}, ErrNoRelease
+    }
+    loc := policy.Location
+    if loc == nil {
+        loc = day.Location()
+    }
+    // the day the venue releases for, which may not be
+    // the day the caller's timezone gives
+    day = day.In(loc)
+    // time.Date normalizes a day before the first of the month
+    release := time.Date(day.Year(), day.Month(), day.Day() - policy.DaysInAdvance, 
+        policy.ReleaseHour, policy.ReleaseMinute, 0, 0, loc)
+    return release.UTC(), nil
+}
+
+/*
+Name: fillRequestTime 
+Type: Internal Func
+Purpose: If a reserve at time operation has no request 
+time, compute it from the venue booking policy. Talks to 
+the api, so must not hold a.mu
+*/
+func (a *AppCtx) fillRequestTime(params *ReserveAtTimeParam) (error) {
+    if !params.RequestTime.IsZero() {
+        return nil
+    }
+    if len(params.ReservationTimes) == 0 {
+        return api.ErrTimeNull
+    }
+    policy, err := a.BookingPolicy(params.VenueID)
+    if err != nil {
+        return err
+    }
+    requestTime, err := RequestTimeFromPolicy(*policy, params.ReservationTimes[0])
+    if err != nil {
+        return err
+    }
+    params.RequestTime = requestTime
+    return nil
+}
+
+/*
+Name: VenueLocation 
+Type: External App Func
+Purpose: This function looks up the timezone of a venue 
+using the underlying api. Timezones don't change, so each
+venue is only looked up once
+*/
+func (a *AppCtx) VenueLocation(venueID int64) (*time.Location, error) {
+    a.mu.Lock()
+    loc, ok := a.locations[venueID]
+    a.mu.Unlock()
+    if ok {
+        return loc, nil
+    }
+    resp, err := a.API.Timezone(api.TimezoneParam{VenueID: venueID})
+    if err != nil {
+        return nil, err
+    }
+    a.mu.Lock()
+    if a.locations == nil {
+        a.locations = make(map[int64]*time.Location)
+    }
+    a.locations[venueID] = resp.Location
+    a.mu.Unlock()
+    return resp.Location, nil
+}
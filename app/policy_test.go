@@ -0,0 +1,192 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "testing"
+    "time"
+)
+
+func mustLoad(t *testing.T, name string) (*time.Location) {
+    t.Helper()
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        t.Skipf("no tz database: %v", err)
+    }
+    return loc
+}
+
+func TestRequestTimeFromPolicy(t *testing.T) {
+    ny := mustLoad(t, "America/New_York")
+    chicago := mustLoad(t, "America/Chicago")
+    tests := []struct {
+        name    string
+        policy  BookingPolicy
+        day     time.Time
+        want    time.Time
+    }{
+        {
+            "days in advance",
+            BookingPolicy{DaysInAdvance: 14, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            time.Date(2023, 9, 15, 19, 0, 0, 0, ny),
+            time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC),
+        },
+        {
+            "same day",
+            BookingPolicy{DaysInAdvance: 0, ReleaseHour: 9, ReleaseMinute: 30, ReleaseKnown: true, Location: ny},
+            time.Date(2023, 9, 15, 19, 0, 0, 0, ny),
+            time.Date(2023, 9, 15, 13, 30, 0, 0, time.UTC),
+        },
+        {
+            "month rollover",
+            BookingPolicy{DaysInAdvance: 7, ReleaseHour: 0, ReleaseKnown: true, Location: ny},
+            time.Date(2023, 3, 5, 20, 0, 0, 0, ny),
+            time.Date(2023, 2, 26, 5, 0, 0, 0, time.UTC),
+        },
+        {
+            "leap day",
+            BookingPolicy{DaysInAdvance: 1, ReleaseHour: 12, ReleaseKnown: true, Location: time.UTC},
+            time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC),
+            time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+        },
+        {
+            "year rollover",
+            BookingPolicy{DaysInAdvance: 30, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            time.Date(2024, 1, 3, 19, 0, 0, 0, ny),
+            time.Date(2023, 12, 4, 15, 0, 0, 0, time.UTC),
+        },
+        // the release is on standard time, the reservation on
+        // daylight time, and the other way around
+        {
+            "into daylight time",
+            BookingPolicy{DaysInAdvance: 14, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            time.Date(2024, 3, 20, 19, 0, 0, 0, ny),
+            time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC),
+        },
+        {
+            "out of daylight time",
+            BookingPolicy{DaysInAdvance: 14, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            time.Date(2023, 11, 10, 19, 0, 0, 0, ny),
+            time.Date(2023, 10, 27, 14, 0, 0, 0, time.UTC),
+        },
+        // 23:30 in Chicago is already the next day at the venue
+        {
+            "venue timezone",
+            BookingPolicy{DaysInAdvance: 14, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            time.Date(2023, 9, 14, 23, 30, 0, 0, chicago),
+            time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC),
+        },
+        {
+            "utc day",
+            BookingPolicy{DaysInAdvance: 14, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            time.Date(2023, 9, 15, 2, 0, 0, 0, time.UTC),
+            time.Date(2023, 8, 31, 14, 0, 0, 0, time.UTC),
+        },
+        // without a venue timezone the day's own is used
+        {
+            "no venue timezone",
+            BookingPolicy{DaysInAdvance: 14, ReleaseHour: 10, ReleaseKnown: true},
+            time.Date(2023, 9, 14, 23, 30, 0, 0, chicago),
+            time.Date(2023, 8, 31, 15, 0, 0, 0, time.UTC),
+        },
+    }
+    for _, test := range tests {
+        got, err := RequestTimeFromPolicy(test.policy, test.day)
+        if err != nil || !got.Equal(test.want) || got.Location() != time.UTC {
+            t.Errorf("%s: got %v, %v, want %v", test.name, got, err, test.want)
+        }
+    }
+    _, err := RequestTimeFromPolicy(BookingPolicy{DaysInAdvance: 14, Location: ny}, time.Now())
+    if err != ErrNoRelease {
+        t.Errorf("unknown release: err = %v, want ErrNoRelease", err)
+    }
+}
+
+func TestFillRequestTime(t *testing.T) {
+    ny := mustLoad(t, "America/New_York")
+    day := time.Date(2023, 9, 15, 19, 0, 0, 0, ny)
+    tests := []struct {
+        name    string
+        policy  *api.BookingPolicyResponse
+        params  ReserveAtTimeParam
+        want    time.Time
+        err     error
+    }{
+        {
+            "from the policy",
+            &api.BookingPolicyResponse{DaysInAdvance: 14, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            ReserveAtTimeParam{ReservationTimes: []time.Time{day}},
+            time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC),
+            nil,
+        },
+        {
+            "the first reservation time decides",
+            &api.BookingPolicyResponse{DaysInAdvance: 1, ReleaseHour: 10, ReleaseKnown: true, Location: ny},
+            ReserveAtTimeParam{ReservationTimes: []time.Time{day, day.AddDate(0, 0, 1)}},
+            time.Date(2023, 9, 14, 14, 0, 0, 0, time.UTC),
+            nil,
+        },
+        {
+            "request time kept",
+            nil,
+            ReserveAtTimeParam{RequestTime: day},
+            day,
+            nil,
+        },
+        {
+            "no reservation times",
+            &api.BookingPolicyResponse{ReleaseKnown: true},
+            ReserveAtTimeParam{},
+            time.Time{},
+            api.ErrTimeNull,
+        },
+        {
+            "no policy",
+            nil,
+            ReserveAtTimeParam{ReservationTimes: []time.Time{day}},
+            time.Time{},
+            api.ErrNoPolicy,
+        },
+        {
+            "no release time",
+            &api.BookingPolicyResponse{DaysInAdvance: 14, Location: ny},
+            ReserveAtTimeParam{ReservationTimes: []time.Time{day}},
+            time.Time{},
+            ErrNoRelease,
+        },
+    }
+    for _, test := range tests {
+        a, fa, _ := newTestApp(time.Hour)
+        fa.policy = test.policy
+        params := test.params
+        err := a.fillRequestTime(&params)
+        if err != test.err || !params.RequestTime.Equal(test.want) {
+            t.Errorf("%s: got %v, %v, want %v, %v", test.name, params.RequestTime, err, test.want, test.err)
+        }
+    }
+}
+
+func TestSetReleaseTime(t *testing.T) {
+    ny := mustLoad(t, "America/New_York")
+    day := time.Date(2023, 9, 15, 19, 0, 0, 0, ny)
+    a, fa, _ := newTestApp(time.Hour)
+    // like resy, the time of day isn't published
+    fa.policy = &api.BookingPolicyResponse{DaysInAdvance: 14, Location: ny}
+
+    a.SetReleaseTime(AnyVenue, 10 * time.Hour)
+    a.SetReleaseTime(2, 9 * time.Hour + 30 * time.Minute)
+    params := ReserveAtTimeParam{VenueID: 1, ReservationTimes: []time.Time{day}}
+    if err := a.fillRequestTime(&params); err != nil || !params.RequestTime.Equal(time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC)) {
+        t.Errorf("any venue: got %v, %v, want the default release", params.RequestTime, err)
+    }
+    params = ReserveAtTimeParam{VenueID: 2, ReservationTimes: []time.Time{day}}
+    if err := a.fillRequestTime(&params); err != nil || !params.RequestTime.Equal(time.Date(2023, 9, 1, 13, 30, 0, 0, time.UTC)) {
+        t.Errorf("venue's own: got %v, %v, want its release", params.RequestTime, err)
+    }
+
+    // a published release time wins
+    fa.policy = &api.BookingPolicyResponse{DaysInAdvance: 14, ReleaseHour: 11, ReleaseKnown: true, Location: ny}
+    policy, err := a.BookingPolicy(2)
+    if err != nil || policy.ReleaseHour != 11 || policy.ReleaseMinute != 0 {
+        t.Errorf("published policy = %+v, %v, want 11:00 kept", policy, err)
+    }
+}
@@ -0,0 +1,411 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/notify"
+    "errors"
+    "strconv"
+    "strings"
+    "time"
+)
+
+var (
+    ErrIdTemplate = errors.New("no template has specified id")
+    ErrNoWeekdays = errors.New("no weekdays provided")
+    ErrPaused = errors.New("template is paused")
+    ErrNotPaused = errors.New("template is not paused")
+    ErrNoTemplate = errors.New("no templates")
+)
+
+/*
+Name: RecurringParam
+Type: App api func input parameters
+Purpose: Provide a means to make a recurring template by
+a consumer. Every reservation day falling on one of the
+Weekdays gets a reserve at time operation, requested
+DaysAhead days before at ReleaseTime. ReservationTimes
+and ReleaseTime are times of day, read in Location, the
+venue timezone when nil. With DaysAhead and ReleaseTime
+both zero the venue booking policy is used
+*/
+type RecurringParam struct {
+    Login            LoginParam
+    Account          string
+    VenueID          int64
+    Weekdays         []time.Weekday
+    ReservationTimes []time.Duration
+    PartySize        int
+    DaysAhead        int
+    ReleaseTime      time.Duration
+    Location         *time.Location
+    TableTypes 	     []api.TableType
+    Notifier         notify.Notifier `json:"-"`
+}
+
+/*
+Name: Template
+Type: struct
+Purpose: The state of a recurring template. Operations
+holds the ids of the operations it spawned, oldest first,
+Next the request time of the next one while not paused,
+and Err why spawning last failed, if it did
+*/
+type Template struct {
+    ID          int64
+    Params      RecurringParam
+    Paused      bool
+    Operations  []int64
+    Next        time.Time
+    Err         error
+
+    // Closed to stop the thread spawning operations
+    stop        chan bool
+}
+
+/*
+Name: ScheduleTemplate
+Type: External App Func
+Purpose: Used to make a recurring template, which spawns
+a reserve at time operation for each occurrence until
+paused or deleted, returns the template ID
+*/
+func (a *AppCtx) ScheduleTemplate(params RecurringParam) (int64, error) {
+    if len(params.Weekdays) == 0 {
+        return 0, ErrNoWeekdays
+    }
+    if len(params.ReservationTimes) == 0 {
+        return 0, api.ErrTimeNull
+    }
+    // talk to the api before taking a.mu
+    if params.Location == nil {
+        loc, err := a.VenueLocation(params.VenueID)
+        if err != nil {
+            return 0, err
+        }
+        params.Location = loc
+    }
+    if params.DaysAhead == 0 && params.ReleaseTime == 0 {
+        policy, err := a.BookingPolicy(params.VenueID)
+        if err != nil {
+            return 0, err
+        }
+        if !policy.ReleaseKnown {
+            return 0, ErrNoRelease
+        }
+        params.DaysAhead = policy.DaysInAdvance
+        params.ReleaseTime = time.Duration(policy.ReleaseHour) * time.Hour + time.Duration(policy.ReleaseMinute) * time.Minute
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    login, account, err := a.loginDefaults(params.Login, params.Account)
+    if err != nil {
+        return 0, err
+    }
+    params.Login = login
+    params.Account = account
+    id := a.templateIDGen
+    a.templateIDGen++
+    a.templates = append(a.templates, Template{ID: id, Params: params})
+    a.startTemplate(len(a.templates) - 1)
+    return id, nil
+}
+
+/*
+Name: Templates
+Type: External App Func
+Purpose: This function returns a copy of every
+recurring template, oldest first
+*/
+func (a *AppCtx) Templates() ([]Template) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    templates := make([]Template, len(a.templates))
+    for i, template := range a.templates {
+        template.Params.Login.Password = ""
+        template.Operations = append([]int64(nil), template.Operations...)
+        template.stop = nil
+        templates[i] = template
+    }
+    return templates
+}
+
+/*
+Name: PauseTemplate
+Type: External App Func
+Purpose: Stop a template from spawning operations,
+cancelling the operation waiting on its next occurrence
+*/
+func (a *AppCtx) PauseTemplate(id int64) (error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    i, err := a.findTemplate(id)
+    if err != nil {
+        return err
+    }
+    if a.templates[i].Paused {
+        return ErrPaused
+    }
+    a.stopTemplate(i)
+    return nil
+}
+
+/*
+Name: ResumeTemplate
+Type: External App Func
+Purpose: Let a paused template spawn operations again,
+starting from the next occurrence still ahead
+*/
+func (a *AppCtx) ResumeTemplate(id int64) (error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    i, err := a.findTemplate(id)
+    if err != nil {
+        return err
+    }
+    if !a.templates[i].Paused {
+        return ErrNotPaused
+    }
+    a.startTemplate(i)
+    return nil
+}
+
+/*
+Name: DeleteTemplate
+Type: External App Func
+Purpose: Stop a template like PauseTemplate and remove it.
+The operations it spawned are kept
+*/
+func (a *AppCtx) DeleteTemplate(id int64) (error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    i, err := a.findTemplate(id)
+    if err != nil {
+        return err
+    }
+    if !a.templates[i].Paused {
+        a.stopTemplate(i)
+    }
+    a.templates = append(a.templates[:i], a.templates[i+1:]...)
+    return nil
+}
+
+/*
+Name: TemplatesToString
+Type: External App Func
+Purpose: This function stringifies recurring templates
+in a use independent manner
+*/
+func (a *AppCtx) TemplatesToString() (string, error) {
+    templates := a.Templates()
+    if len(templates) == 0 {
+        return "", ErrNoTemplate
+    }
+    tmplStr := "Templates: \n\n"
+    for i, template := range templates {
+        params := template.Params
+        weekdays := make([]string, len(params.Weekdays))
+        for j, weekday := range params.Weekdays {
+            weekdays[j] = weekday.String()
+        }
+        times := make([]string, len(params.ReservationTimes))
+        for j, t := range params.ReservationTimes {
+            times[j] = timeOfDayToString(t)
+        }
+        tmplStr += "\tID: " + strconv.FormatInt(template.ID, 10) + "\n"
+        tmplStr += "\tVenue: " + strconv.FormatInt(params.VenueID, 10) + "\n"
+        tmplStr += "\tEvery: " + strings.Join(weekdays, ", ") + " at " + strings.Join(times, ", ") + "\n"
+        tmplStr += "\tRequested: " + strconv.Itoa(params.DaysAhead) + " days ahead at " + timeOfDayToString(params.ReleaseTime) + " " + params.Location.String() + "\n"
+        if len(template.Operations) != 0 {
+            ids := make([]string, len(template.Operations))
+            for j, id := range template.Operations {
+                ids[j] = strconv.FormatInt(id, 10)
+            }
+            tmplStr += "\tOperations: " + strings.Join(ids, ", ") + "\n"
+        }
+        if template.Paused {
+            tmplStr += "\tStatus: Paused\n"
+        } else {
+            tmplStr += "\tStatus: Active\n"
+            if !template.Next.IsZero() {
+                tmplStr += "\tNext Request: " + venueAndLocal(template.Next, params.Location) + "\n"
+            }
+        }
+        if template.Err != nil {
+            tmplStr += "\tError: " + template.Err.Error() + "\n"
+        }
+        if i != (len(templates) - 1) {
+            tmplStr += "\n"
+        }
+    }
+    return tmplStr, nil
+}
+
+/*
+Name: findTemplate
+Type: Internal Func
+Purpose: Index of the template with the given id.
+Must hold a.mu
+*/
+func (a *AppCtx) findTemplate(id int64) (int, error) {
+    for i, template := range a.templates {
+        if template.ID == id {
+            return i, nil
+        }
+    }
+    return 0, ErrIdTemplate
+}
+
+/*
+Name: startTemplate
+Type: Internal Func
+Purpose: Start the thread spawning the operations of
+the template at index i. Must hold a.mu
+*/
+func (a *AppCtx) startTemplate(i int) {
+    stop := make(chan bool)
+    a.templates[i].Paused = false
+    a.templates[i].stop = stop
+    go a.runTemplate(a.templates[i].ID, a.templates[i].Params, stop)
+}
+
+/*
+Name: stopTemplate
+Type: Internal Func
+Purpose: Stop the thread of the template at index i and
+cancel the operation waiting on its next occurrence, 
+unless the request already went out. Must hold a.mu
+*/
+func (a *AppCtx) stopTemplate(i int) {
+    close(a.templates[i].stop)
+    a.templates[i].Paused = true
+    a.templates[i].Next = time.Time{}
+    ops := a.templates[i].Operations
+    if len(ops) == 0 {
+        return
+    }
+    last := ops[len(ops) - 1]
+    for _, operation := range a.operations {
+        if operation.ID == last && operation.RequestTime.After(a.nowLocked()) {
+            a.cancelOperationLocked(last)
+        }
+    }
+}
+
+/*
+Name: runTemplate
+Type: Internal App Func
+Purpose: This function is intended to run on a separate thread. It
+schedules a reserve at time operation for the next occurrence of
+the template, waits for its request time to pass and repeats
+*/
+func (a *AppCtx) runTemplate(id int64, params RecurringParam, stop <-chan bool) {
+    for {
+        day, request := nextOccurrence(a.now(), params)
+        times := make([]time.Time, len(params.ReservationTimes))
+        for i, t := range params.ReservationTimes {
+            times[i] = atTimeOfDay(day, t)
+        }
+        opID, err := a.ScheduleReserveAtTimeOperation(ReserveAtTimeParam{
+            Login: params.Login,
+            Account: params.Account,
+            VenueID: params.VenueID,
+            ReservationTimes: times,
+            PartySize: params.PartySize,
+            RequestTime: request.UTC(),
+            TableTypes: params.TableTypes,
+            Notifier: params.Notifier,
+        })
+
+        a.mu.Lock()
+        i, findErr := a.findTemplate(id)
+        select {
+        case <-stop:
+            // paused while scheduling, don't leave the operation behind
+            if err == nil {
+                a.cancelOperationLocked(opID)
+            }
+            a.mu.Unlock()
+            return
+        default:
+        }
+        if findErr == nil {
+            a.templates[i].Err = err
+            a.templates[i].Next = request
+            if err == nil {
+                a.templates[i].Operations = append(a.templates[i].Operations, opID)
+            }
+        }
+        a.mu.Unlock()
+
+        if !a.waitUntil(request, false, stop) {
+            return
+        }
+    }
+}
+
+/*
+Name: cancelOperationLocked
+Type: Internal Func
+Purpose: Cancel the operation with the given id if it
+is still in progress. Must hold a.mu
+*/
+func (a *AppCtx) cancelOperationLocked(id int64) {
+    for i, operation := range a.operations {
+        if operation.ID == id {
+            a.updateOperationResult(id)
+            a.stopOperation(i)
+        }
+    }
+}
+
+/*
+Name: nextOccurrence
+Type: Internal Func
+Purpose: Find the first reservation day of a template
+whose request time is after now, returning the day at
+midnight and the request time, both in the template
+location
+*/
+func nextOccurrence(now time.Time, params RecurringParam) (time.Time, time.Time) {
+    local := now.In(params.Location)
+    for i := 0; ; i++ {
+        day := time.Date(local.Year(), local.Month(), local.Day() + i, 0, 0, 0, 0, params.Location)
+        matches := false
+        for _, weekday := range params.Weekdays {
+            if day.Weekday() == weekday {
+                matches = true
+            }
+        }
+        if !matches {
+            continue
+        }
+        // time.Date normalizes a day before the first of the month
+        requestDay := time.Date(day.Year(), day.Month(), day.Day() - params.DaysAhead, 0, 0, 0, 0, params.Location)
+        request := atTimeOfDay(requestDay, params.ReleaseTime)
+        if request.After(now) {
+            return day, request
+        }
+    }
+}
+
+/*
+Name: atTimeOfDay
+Type: Internal Func
+Purpose: The wall clock time of day t on the given day,
+which holds across daylight saving changes
+*/
+func atTimeOfDay(day time.Time, t time.Duration) (time.Time) {
+    hour := int(t / time.Hour)
+    minute := int(t % time.Hour / time.Minute)
+    return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+/*
+Name: timeOfDayToString
+Type: Internal Func
+Purpose: Stringify a time of day in hh:mm format
+*/
+func timeOfDayToString(t time.Duration) (string) {
+    return atTimeOfDay(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), t).Format("15:04")
+}
@@ -0,0 +1,433 @@
+package app
+
+import (
+    "strconv"
+    "strings"
+    "time"
+)
+
+/*
+Name: OperationType
+Type: App Type
+Purpose: The kind of operation, named after the
+schedule func that made it
+*/
+type OperationType string
+
+const (
+    ReserveAtTimeType     OperationType = "reserve-at-time"
+    ReserveAtIntervalType OperationType = "reserve-at-interval"
+    ReserveFanOutType     OperationType = "reserve-fan-out"
+    ReserveGroupType      OperationType = "reserve-group"
+    WatchType             OperationType = "watch"
+    SnipeType             OperationType = "snipe"
+)
+
+/*
+Name: OperationStatus.String
+Type: Stringer interface func
+Purpose: Name the status the way it is shown to users
+*/
+func (s OperationStatus) String() (string) {
+    switch s {
+        case InProgressStatusType:
+            return "in-progress"
+        case SuccessStatusType:
+            return "succeeded"
+        case FailStatusType:
+            return "failed"
+        case CancelStatusType:
+            return "cancelled"
+    }
+    return "unknown"
+}
+
+/*
+Name: OperationStatus.MarshalText
+Type: TextMarshaler interface func
+Purpose: Encode the status by name instead of number
+*/
+func (s OperationStatus) MarshalText() ([]byte, error) {
+    return []byte(s.String()), nil
+}
+
+/*
+Name: ParseOperationStatus
+Type: External App Func
+Purpose: The inverse of OperationStatus.String
+*/
+func ParseOperationStatus(s string) (OperationStatus, error) {
+    for _, status := range []OperationStatus{InProgressStatusType, SuccessStatusType, FailStatusType, CancelStatusType} {
+        if status.String() == s {
+            return status, nil
+        }
+    }
+    return 0, ErrBadStatus
+}
+
+/*
+Name: OperationSnapshot
+Type: struct
+Purpose: A copy of the state of an operation at one
+moment, safe to keep and encode. Params is the param
+struct the operation was scheduled with, passwords and
+notifiers left out. Result is set once the operation
+succeeded, Error once it failed
+*/
+type OperationSnapshot struct {
+    ID          int64           `json:"id"`
+    Group       int64           `json:"group"`
+    Members     []int64         `json:"members,omitempty"`
+    Type        OperationType   `json:"type"`
+    Params      interface{}     `json:"params"`
+    Status      OperationStatus `json:"status"`
+    VenueID     int64           `json:"venue_id,omitempty"`
+    Accounts    []string        `json:"accounts,omitempty"`
+    Attempts    int             `json:"attempts"`
+    Created     time.Time       `json:"created"`
+    Started     *time.Time      `json:"started,omitempty"`
+    Finished    *time.Time      `json:"finished,omitempty"`
+    Log         []AttemptRecord `json:"log,omitempty"`
+    Dropped     int             `json:"dropped,omitempty"`
+    RequestTime *time.Time      `json:"request_time,omitempty"`
+    Location    *time.Location  `json:"-"`
+    Result      Timetable       `json:"result,omitempty"`
+    Error       string          `json:"error,omitempty"`
+    NotifyError string          `json:"notify_error,omitempty"`
+}
+
+/*
+Name: OperationFilter
+Type: App api func input parameters
+Purpose: Narrow down the operations listed, zero
+fields match everything. A group matches a venue or
+account when any of its members does
+*/
+type OperationFilter struct {
+    Statuses    []OperationStatus
+    VenueID     int64
+    Account     string
+}
+
+/*
+Name: ListOperations
+Type: External App Func
+Purpose: This function returns snapshots of the
+operations matching filter, oldest first
+*/
+func (a *AppCtx) ListOperations(filter OperationFilter) ([]OperationSnapshot) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    snaps := make([]OperationSnapshot, 0)
+    for _, operation := range a.operations {
+        snap := a.snapshot(operation.ID)
+        if filter.matches(snap) {
+            snaps = append(snaps, snap)
+        }
+    }
+    return snaps
+}
+
+/*
+Name: GetOperation
+Type: External App Func
+Purpose: This function returns a snapshot of the
+operation with the given id
+*/
+func (a *AppCtx) GetOperation(id int64) (*OperationSnapshot, error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    err := a.updateOperationResult(id)
+    if err != nil {
+        return nil, err
+    }
+    snap := a.snapshot(id)
+    return &snap, nil
+}
+
+/*
+Name: snapshot
+Type: Internal Func
+Purpose: Copy out the operation with the given id,
+which must exist, after updating its result. Must
+hold a.mu
+*/
+func (a *AppCtx) snapshot(id int64) (OperationSnapshot) {
+    a.updateOperationResult(id)
+    var operation Operation
+    for _, op := range a.operations {
+        if op.ID == id {
+            operation = op
+        }
+    }
+    snap := OperationSnapshot{
+        ID: operation.ID,
+        Group: operation.Group,
+        Type: operation.Type,
+        Params: operation.Params,
+        Status: operation.Status,
+        VenueID: operation.VenueID,
+        Accounts: operation.Accounts,
+        Attempts: operation.Attempts,
+        Created: operation.Created,
+        Log: append([]AttemptRecord(nil), operation.Log...),
+        Dropped: operation.Dropped,
+        Location: operation.Location,
+    }
+    if !operation.Started.IsZero() {
+        started := operation.Started
+        snap.Started = &started
+    }
+    for _, op := range a.operations {
+        if op.Group == id {
+            snap.Members = append(snap.Members, op.ID)
+        }
+    }
+    if !operation.RequestTime.IsZero() {
+        requestTime := operation.RequestTime
+        snap.RequestTime = &requestTime
+    }
+    // Finished is set when the result is forwarded, which
+    // may be before it is read into the operation
+    if operation.Status != InProgressStatusType && !operation.Finished.IsZero() {
+        finished := operation.Finished
+        snap.Finished = &finished
+    }
+    if operation.Result != nil {
+        if operation.Result.Err != nil {
+            snap.Error = operation.Result.Err.Error()
+        } else {
+            snap.Result = operation.Result.Response
+        }
+    }
+    if operation.NotifyErr != nil {
+        snap.NotifyError = operation.NotifyErr.Error()
+    }
+    return snap
+}
+
+/*
+Name: matches
+Type: Internal Func
+Purpose: Report whether a snapshot passes the filter
+*/
+func (f OperationFilter) matches(snap OperationSnapshot) (bool) {
+    if len(f.Statuses) != 0 {
+        found := false
+        for _, status := range f.Statuses {
+            if snap.Status == status {
+                found = true
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    if f.VenueID != 0 && snap.VenueID != f.VenueID && !groupHasVenue(snap, f.VenueID) {
+        return false
+    }
+    if f.Account != "" {
+        found := false
+        for _, account := range snap.Accounts {
+            if account == f.Account {
+                found = true
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    return true
+}
+
+/*
+Name: groupHasVenue
+Type: Internal Func
+Purpose: Report whether any member of a group snapshot
+books at the venue
+*/
+func groupHasVenue(snap OperationSnapshot, venueID int64) (bool) {
+    group, ok := snap.Params.(ReserveGroupParam)
+    if !ok {
+        return false
+    }
+    for _, member := range group.Members {
+        if member.AtTime != nil && member.AtTime.VenueID == venueID {
+            return true
+        }
+        if member.AtInterval != nil && member.AtInterval.VenueID == venueID {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+Name: describeParams
+Type: Internal Func
+Purpose: Work out the type, venue and accounts of an
+operation from the params it was scheduled with, and
+return a copy of them without passwords
+*/
+func describeParams(params interface{}) (OperationType, int64, []string, interface{}) {
+    switch p := params.(type) {
+        case ReserveAtTimeParam:
+            p.Login.Password = ""
+            return ReserveAtTimeType, p.VenueID, accountsOf(p.Login, p.Account), p
+        case ReserveAtIntervalParam:
+            p.Login.Password = ""
+            return ReserveAtIntervalType, p.VenueID, accountsOf(p.Login, p.Account), p
+        case ReserveFanOutParam:
+            return ReserveFanOutType, p.VenueID, p.Accounts, p
+        case WatchParam:
+            p.Login.Password = ""
+            return WatchType, p.VenueID, accountsOf(p.Login, p.Account), p
+        case SnipeParam:
+            p.Login.Password = ""
+            return SnipeType, p.VenueID, accountsOf(p.Login, p.Account), p
+        case ReserveGroupParam:
+            members := make([]ReserveGroupMember, len(p.Members))
+            accounts := make([]string, 0)
+            for i, member := range p.Members {
+                var memberParams interface{}
+                if member.AtTime != nil {
+                    memberParams = *member.AtTime
+                } else {
+                    memberParams = *member.AtInterval
+                }
+                _, _, memberAccounts, scrubbed := describeParams(memberParams)
+                if atTime, ok := scrubbed.(ReserveAtTimeParam); ok {
+                    members[i].AtTime = &atTime
+                }
+                if atInterval, ok := scrubbed.(ReserveAtIntervalParam); ok {
+                    members[i].AtInterval = &atInterval
+                }
+                for _, account := range memberAccounts {
+                    if !containsString(accounts, account) {
+                        accounts = append(accounts, account)
+                    }
+                }
+            }
+            p.Members = members
+            return ReserveGroupType, 0, accounts, p
+    }
+    return "", 0, nil, params
+}
+
+/*
+Name: accountsOf
+Type: Internal Func
+Purpose: Name the account an operation logs in with,
+the saved account when there is one, else the email
+*/
+func accountsOf(login LoginParam, account string) ([]string) {
+    if account != "" {
+        return []string{account}
+    }
+    if login.Email != "" {
+        return []string{login.Email}
+    }
+    return nil
+}
+
+/*
+Name: containsString
+Type: Internal Func
+Purpose: Report whether s is in lst
+*/
+func containsString(lst []string, s string) (bool) {
+    for _, elem := range lst {
+        if elem == s {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+Name: SnapshotsToString
+Type: External App Func
+Purpose: This function stringifies operation snapshots
+in a use independent manner
+*/
+func SnapshotsToString(snaps []OperationSnapshot) (string) {
+    opLstStr := "Operations: \n\n"
+    for i, snap := range snaps {
+        opLstStr += snapshotToString(snap)
+        if i != (len(snaps) - 1) {
+            opLstStr += "\n"
+        }
+    }
+    return opLstStr
+}
+
+/*
+Name: snapshotToString
+Type: Internal Func
+Purpose: Stringify the summary of one snapshot
+*/
+func snapshotToString(snap OperationSnapshot) (string) {
+    opLstStr := "\tID: " + strconv.FormatInt(snap.ID, 10) + "\n"
+    if snap.Type != "" {
+        opLstStr += "\tType: " + string(snap.Type) + "\n"
+    }
+    if snap.Group != NoGroup {
+        opLstStr += "\tGroup: " + strconv.FormatInt(snap.Group, 10) + "\n"
+    }
+    if len(snap.Members) != 0 {
+        members := make([]string, len(snap.Members))
+        for j, member := range snap.Members {
+            members[j] = strconv.FormatInt(member, 10)
+        }
+        opLstStr += "\tMembers: " + strings.Join(members, ", ") + "\n"
+    }
+    if snap.VenueID != 0 {
+        opLstStr += "\tVenue: " + strconv.FormatInt(snap.VenueID, 10) + "\n"
+    }
+    if len(snap.Accounts) != 0 {
+        opLstStr += "\tAccounts: " + strings.Join(snap.Accounts, ", ") + "\n"
+    }
+    if snap.RequestTime != nil {
+        opLstStr += "\tRequest Time: " + venueAndLocal(*snap.RequestTime, snap.Location) + "\n"
+    }
+    opLstStr += "\tStatus: "
+    switch snap.Status {
+        case InProgressStatusType:
+            opLstStr += "In Progress"
+        case SuccessStatusType:
+            opLstStr += "Succeeded\n"
+            opLstStr += "\tResult: " + venueAndLocal(snap.Result.Time(), snap.Location)
+            if group, ok := snap.Result.(ReserveGroupResponse); ok {
+                opLstStr += "\n\tBooked By: " + strconv.FormatInt(group.MemberID, 10)
+                if len(group.AlsoBooked) != 0 {
+                    also := make([]string, len(group.AlsoBooked))
+                    for i, member := range group.AlsoBooked {
+                        also[i] = strconv.FormatInt(member, 10)
+                    }
+                    opLstStr += "\n\tAlso Booked: " + strings.Join(also, ", ")
+                }
+            }
+            if snipe, ok := snap.Result.(SnipeResponse); ok {
+                opLstStr += "\n\tCancellations Seen: " + strconv.Itoa(snipe.Cancellations)
+            }
+            if watch, ok := snap.Result.(WatchResponse); ok {
+                opLstStr += "\n\tSlots Found: " + slotsToString(watch.Slots)
+            }
+            if fanOut, ok := snap.Result.(ReserveFanOutResponse); ok {
+                opLstStr += "\n\tAccount: " + fanOut.Account
+                if len(fanOut.AlsoBooked) != 0 {
+                    opLstStr += "\n\tAlso Booked: " + strings.Join(fanOut.AlsoBooked, ", ")
+                }
+            }
+        case FailStatusType:
+            opLstStr += "Failed\n"
+            opLstStr += "\tResult: " + snap.Error
+        case CancelStatusType:
+            opLstStr += "Cancelled"
+    }
+    if snap.NotifyError != "" {
+        opLstStr += "\n\tNotify Error: " + snap.NotifyError
+    }
+    opLstStr += "\n"
+    return opLstStr
+}
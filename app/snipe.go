@@ -0,0 +1,266 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/notify"
+    "time"
+)
+
+const (
+    defaultSnipeMinInterval = 30 * time.Second
+    defaultSnipeMaxInterval = 10 * time.Minute
+    // How far outside a cancellation window polling
+    // slows all the way down to the max interval
+    snipeRamp = 72 * time.Hour
+)
+
+/*
+Name: cancelWindows
+Type: Internal Var
+Purpose: Spans before a reservation when most cancellations
+come in, as people drop plans ahead of the 24 and 48 hour 
+cancellation fee cutoffs venues commonly set
+*/
+var cancelWindows = [][2]time.Duration{
+    {24 * time.Hour, 48 * time.Hour},
+}
+
+/*
+Name: SnipeParam
+Type: App api func input parameters
+Purpose: Provide a means to make a cancellation snipe 
+operation by a consumer. Polling speeds up to MinInterval
+near cancellation windows and backs off to MaxInterval 
+away from them, zero values use defaults
+*/
+type SnipeParam struct {
+    Login            LoginParam
+    Account          string
+    VenueID          int64
+    ReservationTimes []time.Time
+    PartySize        int
+    TableTypes 	     []api.TableType
+    MinInterval      time.Duration
+    MaxInterval      time.Duration
+    Notifier         notify.Notifier `json:"-"`
+}
+
+/*
+Name: SnipeResponse 
+Type: struct
+Purpose: Define the data that should be returned on a
+successful snipe, with how many slots opened up while
+sniping, matching or not
+*/
+type SnipeResponse struct {
+    ReservationTime time.Time
+    Cancellations   int
+}
+
+/*
+Name: SnipeResponse.Time 
+Type: Timetable interface func 
+Purpose: Implement the Timetable interface 
+*/
+func (r SnipeResponse) Time() (time.Time) {
+    return r.ReservationTime
+}
+
+/*
+Name: ScheduleSnipeOperation
+Type: External App Func
+Purpose: Used to Schedule a cancellation snipe operation, returns ID 
+*/
+func (a *AppCtx) ScheduleSnipeOperation(params SnipeParam) (int64, error) {
+    if params.MinInterval <= 0 {
+        params.MinInterval = defaultSnipeMinInterval
+    }
+    if params.MaxInterval < params.MinInterval {
+        params.MaxInterval = defaultSnipeMaxInterval
+        if params.MaxInterval < params.MinInterval {
+            params.MaxInterval = params.MinInterval
+        }
+    }
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    login, account, err := a.loginDefaults(params.Login, params.Account)
+    if err != nil {
+        return 0, err
+    }
+    params.Login = login
+    params.Account = account
+
+    op := Operation{
+        Group: NoGroup,
+        Params: params,
+        Location: timesLocation(params.ReservationTimes),
+        Notifier: params.Notifier,
+    }
+    id := a.startOperation(op, func(id int64, cancel <-chan bool, output chan<- OperationResult) {
+        a.snipe(id, params, cancel, output)
+    })
+    return id, nil
+}
+
+/*
+Name: snipe
+Type: Internal App Func
+Purpose: This function is intended to run on a separate thread. It 
+keeps the full set of slots the venue offers on the day, and on every
+poll diffs it against the last one. Slots that newly appear and match
+the params are booked right away
+*/
+func (a *AppCtx) snipe(id int64, params SnipeParam, cancel <-chan bool, output chan<- OperationResult) {
+
+    var loginResp *api.LoginResponse
+    var loginTime time.Time
+    var firstTime time.Time
+    // nil until the first poll, whose slots aren't cancellations
+    var snapshot map[api.Slot]bool
+    cancellations := 0
+    for attempt := 1; ; attempt++ {
+        // pick up edits made since the last poll
+        edited, _ := a.editedParams(id, params)
+        params = edited.(SnipeParam)
+        lastTime, err := findLastTime(params.ReservationTimes)
+        if err != nil {
+            output<-OperationResult{Response: nil, Err: err}     
+            close(output)
+            return
+        }
+        prevTime := firstTime
+        firstTime = params.ReservationTimes[0]
+        for _, t := range params.ReservationTimes {
+            if t.Before(firstTime) {
+                firstTime = t
+            }
+        }
+        // slots of another day are no baseline for cancellations
+        if !sameDay(prevTime, firstTime) {
+            snapshot = nil
+        }
+
+        loginResp, loginTime, err = a.refreshLogin(id, params.Login, params.Account, loginResp, loginTime)
+        if err != nil {
+            output<-OperationResult{Response: nil, Err: err}     
+            close(output)
+            return
+        }
+
+        a.publishAttempt(id, attempt)
+        availResp, err := a.opAvailability(id, 
+            api.AvailabilityParam{
+                VenueID: params.VenueID,
+                Day: firstTime,
+                PartySize: params.PartySize,
+                LoginResp: *loginResp,
+            })
+        if err != nil {
+            output<-OperationResult{Response: nil, Err: err}     
+            close(output)
+            return
+        }
+
+        // diff against the last snapshot
+        fresh := make([]api.Slot, 0)
+        current := make(map[api.Slot]bool)
+        for _, slot := range availResp.Slots {
+            current[slot] = true
+            if !snapshot[slot] {
+                fresh = append(fresh, slot)
+            }
+        }
+        if snapshot != nil {
+            cancellations += len(fresh)
+        }
+        snapshot = current
+
+        matches := matchSlots(fresh, params.ReservationTimes, params.TableTypes)
+        if len(matches) != 0 {
+            a.publish(Event{Type: SlotFoundEvent, OperationID: id, Slots: matches})
+            // only try the times that just opened, in priority order
+            times := make([]time.Time, len(matches))
+            for i, slot := range matches {
+                times[i] = slot.Time
+            }
+            reserveResp, err := a.opReserve(id, 
+                api.ReserveParam{
+                    LoginResp: *loginResp,
+                    ReservationTimes: times,
+                    PartySize: params.PartySize,
+                    VenueID: params.VenueID,
+                    TableTypes: params.TableTypes,
+                })
+            // someone else may have been quicker, keep sniping
+            if err != nil && err != api.ErrNoTable {
+                output<-OperationResult{Response: nil, Err: err}     
+                close(output)
+                return
+            }
+            if err == nil {
+                output<-OperationResult{
+                    Response: SnipeResponse{ReservationTime: reserveResp.ReservationTime, Cancellations: cancellations},
+                    Err: nil,
+                }
+                close(output)
+                return
+            }
+        }
+
+        now := a.now()
+        if !lastTime.After(now) {
+            output<-OperationResult{Response: nil, Err: api.ErrPastDate}     
+            close(output)
+            return
+        }
+
+        interval := snipeInterval(firstTime.Sub(now), params.MinInterval, params.MaxInterval)
+        a.publishWaiting(id, now.Add(interval))
+        select {
+        case <-a.clock().After(interval):
+        case <-cancel:
+            output<-OperationResult{Response: nil, Err: ErrCancel}     
+            close(output)
+            return
+        }
+    }
+}
+
+/*
+Name: snipeInterval
+Type: Internal Func
+Purpose: How long to wait before the next poll, given how long 
+until the reservation. Inside a cancellation window we poll at
+min, and the interval grows linearly with the distance to the 
+nearest window, reaching max snipeRamp away from it
+*/
+func snipeInterval(until time.Duration, min time.Duration, max time.Duration) (time.Duration) {
+    distance := time.Duration(-1)
+    for _, window := range cancelWindows {
+        d := time.Duration(0)
+        if until < window[0] {
+            d = window[0] - until
+        } else if until > window[1] {
+            d = until - window[1]
+        }
+        if distance < 0 || d < distance {
+            distance = d
+        }
+    }
+    if distance >= snipeRamp {
+        return max
+    }
+    return min + time.Duration(float64(max - min) * float64(distance) / float64(snipeRamp))
+}
+
+/*
+Name: sameDay
+Type: Internal Func
+Purpose: Report whether two times fall on the same
+calendar day, in the location of the first
+*/
+func sameDay(t1 time.Time, t2 time.Time) (bool) {
+    y1, m1, d1 := t1.Date()
+    y2, m2, d2 := t2.In(t1.Location()).Date()
+    return y1 == y2 && m1 == m2 && d1 == d2
+}
@@ -0,0 +1,238 @@
+package app
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/notify"
+    "strings"
+    "time"
+)
+
+/*
+Name: WatchParam
+Type: App api func input parameters
+Purpose: Provide a means to make a watch operation by 
+a consumer. A watch polls availability on an interval
+like a reserve at interval operation, but only reports
+matching slots instead of booking them. With Keep set 
+it goes on watching after the first match
+*/
+type WatchParam struct {
+    Login            LoginParam
+    Account          string
+    VenueID          int64
+    ReservationTimes []time.Time
+    PartySize        int
+    RepeatInterval   time.Duration
+    TableTypes 	     []api.TableType
+    Keep             bool
+    Notifier         notify.Notifier `json:"-"`
+}
+
+/*
+Name: WatchResponse 
+Type: struct
+Purpose: Define the data that should be returned on a
+watch that saw matching slots, in the order they were seen
+*/
+type WatchResponse struct {
+    Slots   []api.Slot
+}
+
+/*
+Name: WatchResponse.Time 
+Type: Timetable interface func 
+Purpose: Implement the Timetable interface with the
+first slot seen 
+*/
+func (r WatchResponse) Time() (time.Time) {
+    return r.Slots[0].Time
+}
+
+/*
+Name: ScheduleWatchOperation
+Type: External App Func
+Purpose: Used to Schedule a watch operation, returns ID 
+*/
+func (a *AppCtx) ScheduleWatchOperation(params WatchParam) (int64, error) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    login, account, err := a.loginDefaults(params.Login, params.Account)
+    if err != nil {
+        return 0, err
+    }
+    params.Login = login
+    params.Account = account
+
+    op := Operation{
+        Group: NoGroup,
+        Params: params,
+        Location: timesLocation(params.ReservationTimes),
+        Notifier: params.Notifier,
+    }
+    id := a.startOperation(op, func(id int64, cancel <-chan bool, output chan<- OperationResult) {
+        a.watch(id, params, cancel, output)
+    })
+    return id, nil
+}
+
+/*
+Name: watch
+Type: Internal App Func
+Purpose: This function is intended to run on a separate thread, and 
+polls availability at a given interval of time, reporting slots 
+matching the params as they appear
+*/
+func (a *AppCtx) watch(id int64, params WatchParam, cancel <-chan bool, output chan<- OperationResult) {
+
+    // find and store last time from time priority list
+    lastTime, err := findLastTime(params.ReservationTimes)
+    if err != nil {
+        output<-OperationResult{Response: nil, Err: err}     
+        close(output)
+        return
+    }
+
+    var loginResp *api.LoginResponse
+    var loginTime time.Time
+    // slots matched on the previous poll, so only new ones are reported
+    seen := make(map[api.Slot]bool)
+    found := make([]api.Slot, 0)
+    for attempt := 1; ; attempt++ {
+        // pick up edits made since the last poll
+        edited, _ := a.editedParams(id, params)
+        params = edited.(WatchParam)
+        lastTime, err = findLastTime(params.ReservationTimes)
+        if err != nil {
+            output<-OperationResult{Response: nil, Err: err}     
+            close(output)
+            return
+        }
+
+        loginResp, loginTime, err = a.refreshLogin(id, params.Login, params.Account, loginResp, loginTime)
+        if err != nil {
+            output<-OperationResult{Response: nil, Err: err}     
+            close(output)
+            return
+        }
+
+        a.publishAttempt(id, attempt)
+        availResp, err := a.opAvailability(id, 
+            api.AvailabilityParam{
+                VenueID: params.VenueID,
+                Day: params.ReservationTimes[0],
+                PartySize: params.PartySize,
+                LoginResp: *loginResp,
+            })
+        if err != nil {
+            output<-OperationResult{Response: nil, Err: err}     
+            close(output)
+            return
+        }
+
+        matches := matchSlots(availResp.Slots, params.ReservationTimes, params.TableTypes)
+        fresh := make([]api.Slot, 0)
+        current := make(map[api.Slot]bool)
+        for _, slot := range matches {
+            current[slot] = true
+            if !seen[slot] {
+                fresh = append(fresh, slot)
+            }
+        }
+        seen = current
+
+        if len(fresh) != 0 {
+            found = append(found, fresh...)
+            a.publish(Event{Type: SlotFoundEvent, OperationID: id, Slots: fresh})
+            if !params.Keep {
+                output<-OperationResult{Response: WatchResponse{Slots: found}, Err: nil}     
+                close(output)
+                return
+            }
+        }
+
+        // no point watching once the last time has passed
+        if !lastTime.After(a.now()) {
+            if len(found) != 0 {
+                output<-OperationResult{Response: WatchResponse{Slots: found}, Err: nil}     
+            } else {
+                output<-OperationResult{Response: nil, Err: api.ErrPastDate}     
+            }
+            close(output)
+            return
+        }
+
+        a.publishWaiting(id, a.now().Add(params.RepeatInterval))
+        select {
+        case <-a.clock().After(params.RepeatInterval):
+        case <-cancel:
+            output<-OperationResult{Response: nil, Err: ErrCancel}     
+            close(output)
+            return
+        }
+    }
+}
+
+/*
+Name: refreshLogin
+Type: Internal App Func
+Purpose: For operations polling over a long time, login
+only if there is no token yet or the token from loginTime
+may have expired, returning the token and its login time
+*/
+func (a *AppCtx) refreshLogin(id int64, login LoginParam, account string, loginResp *api.LoginResponse, loginTime time.Time) (*api.LoginResponse, time.Time, error) {
+    authExpire := a.API.AuthMinExpire()
+    if loginResp != nil && (authExpire == 0 || a.now().Before(loginTime.Add(authExpire))) {
+        return loginResp, loginTime, nil
+    }
+    loginResp, err := a.opLogin(id, login, account)
+    if err != nil {
+        return nil, loginTime, err
+    }
+    return loginResp, a.now(), nil
+}
+
+/*
+Name: matchSlots
+Type: Internal Func
+Purpose: Pick the slots at one of the times, in time priority
+order, and of one of the table types if any are given. Like 
+Reserve, times are compared by hour and minute and table types
+by containment
+*/
+func matchSlots(slots []api.Slot, times []time.Time, tableTypes []api.TableType) ([]api.Slot) {
+    matches := make([]api.Slot, 0)
+    for _, t := range times {
+        for _, slot := range slots {
+            if slot.Time.Hour() != t.Hour() || slot.Time.Minute() != t.Minute() {
+                continue
+            }
+            if len(tableTypes) == 0 {
+                matches = append(matches, slot)
+                continue
+            }
+            for _, tableType := range tableTypes {
+                if strings.Contains(slot.TableType, string(tableType)) {
+                    matches = append(matches, slot)
+                    break
+                }
+            }
+        }
+    }
+    return matches
+}
+
+/*
+Name: slotsToString
+Type: Internal Func
+Purpose: Render slots as their venue times and table types
+*/
+func slotsToString(slots []api.Slot) (string) {
+    slotStrs := make([]string, len(slots))
+    for i, slot := range slots {
+        slotStrs[i] = slot.Time.Format("2006-01-02 15:04 MST")
+        if slot.TableType != "" {
+            slotStrs[i] += " (" + slot.TableType + ")"
+        }
+    }
+    return strings.Join(slotStrs, ", ")
+}
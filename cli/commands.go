@@ -0,0 +1,241 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "sort"
+    "strings"
+)
+
+/*
+Name: matches
+Type: Internal CLI func
+Purpose: Report whether word names cmd, by its
+name or one of its aliases
+*/
+func (cmd *Command) matches(word string) (bool) {
+    if cmd.Name == word {
+        return true
+    }
+    for _, alias := range cmd.Aliases {
+        if alias == word {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+Name: findCommand
+Type: Internal CLI func
+Purpose: The command of cmds word names, nil
+when it names none
+*/
+func findCommand(cmds []Command, word string) (*Command) {
+    for i := range cmds {
+        if cmds[i].matches(word) {
+            return &cmds[i]
+        }
+    }
+    return nil
+}
+
+/*
+Name: commandNames
+Type: Internal CLI func
+Purpose: Every name and alias of cmds
+*/
+func commandNames(cmds []Command) ([]string) {
+    names := make([]string, 0, len(cmds))
+    for _, cmd := range cmds {
+        names = append(names, cmd.Name)
+        names = append(names, cmd.Aliases...)
+    }
+    return names
+}
+
+/*
+Name: Walk
+Type: External CLI func
+Purpose: Call f on every command, parents before
+their subcommands, with the words naming it like
+"account add"
+*/
+func (pc *ParseCtx) Walk(f func(path string, cmd *Command)) {
+    walkCommands(pc.Commands, "", f)
+}
+
+/*
+Name: walkCommands
+Type: Internal CLI func
+Purpose: Walk cmds, which are named after parent
+*/
+func walkCommands(cmds []Command, parent string, f func(path string, cmd *Command)) {
+    for i := range cmds {
+        path := cmds[i].Name
+        if parent != "" {
+            path = parent + " " + path
+        }
+        f(path, &cmds[i])
+        walkCommands(cmds[i].Subcommands, path, f)
+    }
+}
+
+/*
+Name: Path
+Type: External CLI func
+Purpose: The words naming cmd, a command of pc
+like the one Resolve returns, empty when cmd
+isn't one of pc's
+*/
+func (pc *ParseCtx) Path(cmd *Command) (string) {
+    path := ""
+    pc.Walk(func(p string, c *Command) {
+        if c == cmd {
+            path = p
+        }
+    })
+    return path
+}
+
+/*
+Name: descend
+Type: Internal CLI func
+Purpose: Follow words from the top level commands
+down through their subcommands, returning each
+command named until a word names none
+*/
+func (pc *ParseCtx) descend(words []string) ([]*Command) {
+    chain := make([]*Command, 0)
+    cmds := pc.Commands
+    for _, word := range words {
+        cmd := findCommand(cmds, word)
+        if cmd == nil {
+            break
+        }
+        chain = append(chain, cmd)
+        cmds = cmd.Subcommands
+    }
+    return chain
+}
+
+/*
+Name: lookup
+Type: Internal CLI func
+Purpose: The commands named by name, like
+"account add" or an alias, from the top level
+down. Failures are a *ParseError without a
+position, suggesting what was likely meant
+*/
+func (pc *ParseCtx) lookup(name string) ([]*Command, *ParseError) {
+    words := strings.Fields(name)
+    if len(words) == 0 {
+        return nil, &ParseError{Err: ErrNoCmd, Column: -1}
+    }
+    if findCommand(pc.Commands, words[0]) == nil {
+        if expansion, ok := pc.Aliases[words[0]]; ok {
+            words = append(strings.Fields(expansion), words[1:]...)
+        }
+    }
+    chain := pc.descend(words)
+    if len(chain) == len(words) {
+        return chain, nil
+    }
+    perr := &ParseError{Err: ErrNoCmd, Token: words[len(chain)], Column: -1}
+    if len(chain) == 0 {
+        perr.Suggestions = suggest(perr.Token, pc.topNames())
+    } else {
+        perr.Command = chainPath(chain)
+        perr.Suggestions = suggest(perr.Token, commandNames(chain[len(chain)-1].Subcommands))
+    }
+    return nil, perr
+}
+
+/*
+Name: chainPath
+Type: Internal CLI func
+Purpose: The words naming the last command of chain
+*/
+func chainPath(chain []*Command) (string) {
+    names := make([]string, len(chain))
+    for i, cmd := range chain {
+        names[i] = cmd.Name
+    }
+    return strings.Join(names, " ")
+}
+
+/*
+Name: withInherited
+Type: Internal CLI func
+Purpose: The last command of chain with the
+Persistent flags of the commands above it and
+then pc.Globals added, its own flag winning
+when two share a name. A group that can't run
+on its own takes no Globals
+*/
+func (pc *ParseCtx) withInherited(chain []*Command) (Command) {
+    cmd := *chain[len(chain)-1]
+    cmd.Flags = append([]Flag{}, cmd.Flags...)
+    for i := len(chain) - 2; i >= 0; i-- {
+        for _, flag := range chain[i].Flags {
+            if flag.Persistent && lookupFlag(&cmd, "-" + flag.Name) == nil {
+                cmd.Flags = append(cmd.Flags, flag)
+            }
+        }
+    }
+    if len(cmd.Subcommands) != 0 && cmd.Handler == nil {
+        return cmd
+    }
+    for _, flag := range pc.Globals {
+        if lookupFlag(&cmd, "-" + flag.Name) == nil {
+            cmd.Flags = append(cmd.Flags, flag)
+        }
+    }
+    return cmd
+}
+
+/*
+Name: expandAlias
+Type: Internal CLI func
+Purpose: Swap a first word that is one of
+pc.Aliases for the words it stands for, each
+read from where the alias was. Commands win
+over aliases of the same name
+*/
+func (pc *ParseCtx) expandAlias(spans []token) ([]token) {
+    if len(spans) == 0 || findCommand(pc.Commands, spans[0].text) != nil {
+        return spans
+    }
+    expansion, ok := pc.Aliases[spans[0].text]
+    if !ok {
+        return spans
+    }
+    words, perr := pc.tokenize(expansion)
+    if perr != nil || len(words) == 0 {
+        return spans
+    }
+    out := make([]token, 0, len(words) + len(spans) - 1)
+    for _, word := range words {
+        out = append(out, token{word.text, spans[0].start, spans[0].end})
+    }
+    return append(out, spans[1:]...)
+}
+
+/*
+Name: topNames
+Type: Internal CLI func
+Purpose: Every word a line can start with, for
+suggestions
+*/
+func (pc *ParseCtx) topNames() ([]string) {
+    names := commandNames(pc.Commands)
+    aliases := make([]string, 0, len(pc.Aliases))
+    for alias := range pc.Aliases {
+        aliases = append(aliases, alias)
+    }
+    // map order would make suggestions of the same distance flip
+    sort.Strings(aliases)
+    return append(names, aliases...)
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func groupedParseCtx() (*ParseCtx) {
+    idFlag := Flag{Name: "i", LongName: "id", ValidationCtx: FlagValidationCtx{Type: IntType, Required: true, MinArgs: 1, MaxArgs: InfiniteArgs}}
+    handler := func(in Values) (interface{}, error) { return nil, nil }
+    return &ParseCtx{
+        Commands: []Command{
+            Command{
+                Name: "op",
+                Flags: []Flag{
+                    Flag{Name: "v", LongName: "verbose", Persistent: true, ValidationCtx: FlagValidationCtx{MaxArgs: 0}},
+                },
+                Subcommands: []Command{
+                    Command{Name: "list", Aliases: []string{"ls"}, Handler: handler, Flags: []Flag{
+                        Flag{Name: "s", LongName: "status", ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: InfiniteArgs}},
+                    }},
+                    Command{Name: "cancel", Handler: handler, Flags: []Flag{idFlag}},
+                },
+            },
+            Command{
+                Name: "recur",
+                Handler: handler,
+                Flags: []Flag{
+                    Flag{Name: "wd", LongName: "weekdays", ValidationCtx: FlagValidationCtx{Required: true, MinArgs: 1, MaxArgs: InfiniteArgs}},
+                },
+                Subcommands: []Command{
+                    Command{Name: "rm", Handler: handler, Flags: []Flag{idFlag}},
+                },
+            },
+            Command{Name: "quit", Aliases: []string{"exit"}, Handler: handler},
+        },
+        OpenDelim: "[",
+        CloseDelim: "]",
+        Aliases: map[string]string{
+            "cancel": "op cancel",
+            "recur-rm": "recur rm",
+            // commands win over aliases
+            "quit": "op list",
+        },
+    }
+}
+
+func TestResolveSubcommands(t *testing.T) {
+    pc := groupedParseCtx()
+    tests := []struct {
+        line    string
+        path    string
+        want    Values
+    }{
+        {"op list -s failed", "op list", Values{"s": {"failed"}}},
+        {"op ls", "op list", Values{}},
+        // persistent flags of the group reach its subcommands
+        {"op cancel -v -i 3", "op cancel", Values{"v": {}, "i": {"3"}}},
+        {"recur -wd th", "recur", Values{"wd": {"th"}}},
+        // flags that aren't persistent stay with their command
+        {"recur rm -i 3", "recur rm", Values{"i": {"3"}}},
+        {"exit", "quit", Values{}},
+        {"cancel -i 1 2", "op cancel", Values{"i": {"1", "2"}}},
+        {"recur-rm -i 3", "recur rm", Values{"i": {"3"}}},
+        {"quit", "quit", Values{}},
+    }
+    for _, test := range tests {
+        cmd, got, err := pc.Resolve(test.line)
+        if err != nil {
+            t.Errorf("%q: err = %v", test.line, err)
+            continue
+        }
+        if path := pc.Path(cmd); path != test.path {
+            t.Errorf("%q: resolved %q, want %q", test.line, path, test.path)
+        }
+        if !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%q: flags = %v, want %v", test.line, got, test.want)
+        }
+    }
+}
+
+func TestResolveSubcommandErrors(t *testing.T) {
+    pc := groupedParseCtx()
+    tests := []struct {
+        line        string
+        err         error
+        command     string
+        token       string
+        column      int
+        suggestions []string
+    }{
+        {"op", ErrNoSubCmd, "op", "", 2, []string{"list", "cancel"}},
+        {"op -v", ErrNoSubCmd, "op", "", 5, []string{"list", "cancel"}},
+        {"op lst", ErrNoCmd, "op", "lst", 3, []string{"list", "ls"}},
+        {"recur rn -i 3", ErrNoCmd, "recur", "rn", 6, []string{"rm"}},
+        {"recur rm -v", ErrNoFlg, "recur rm", "-v", 9, nil},
+        {"recur -i 3", ErrNoFlg, "recur", "-i", 6, nil},
+        {"exti", ErrNoCmd, "", "exti", 0, []string{"exit"}},
+        // an alias points its errors at itself
+        {"cancel -i x", ErrInvInt, "op cancel", "x", 10, nil},
+        {"cancel", ErrMissReq, "op cancel", "", 6, nil},
+    }
+    for _, test := range tests {
+        _, _, err := pc.Resolve(test.line)
+        var perr *ParseError
+        if !errors.As(err, &perr) {
+            t.Errorf("%q: err = %v, want a ParseError", test.line, err)
+            continue
+        }
+        if !errors.Is(err, test.err) || perr.Command != test.command {
+            t.Errorf("%q: err = %v for %q, want %v for %q", test.line, err, perr.Command, test.err, test.command)
+        }
+        if perr.Token != test.token || perr.Column != test.column {
+            t.Errorf("%q: at %q column %d, want %q column %d", test.line, perr.Token, perr.Column, test.token, test.column)
+        }
+        if len(perr.Suggestions) != 0 || len(test.suggestions) != 0 {
+            if !reflect.DeepEqual(perr.Suggestions, test.suggestions) {
+                t.Errorf("%q: suggestions = %v, want %v", test.line, perr.Suggestions, test.suggestions)
+            }
+        }
+    }
+    _, _, err := pc.Resolve("op")
+    if got, want := err.Error(), "missing subcommand for op, want list or cancel"; got != want {
+        t.Errorf("Error = %q, want %q", got, want)
+    }
+}
+
+func TestResolveFlagsPath(t *testing.T) {
+    pc := groupedParseCtx()
+    for _, name := range []string{"op cancel", "cancel"} {
+        cmd, err := pc.ResolveFlags(name, Values{"i": {"3"}, "v": {}})
+        if err != nil || pc.Path(cmd) != "op cancel" {
+            t.Errorf("ResolveFlags(%q) = %v, %v, want op cancel", name, cmd, err)
+        }
+    }
+    if _, err := pc.ResolveFlags("op", Values{}); !errors.Is(err, ErrNoSubCmd) {
+        t.Errorf("ResolveFlags of a group: err = %v, want ErrNoSubCmd", err)
+    }
+    if _, err := pc.ResolveFlags("op nope", Values{}); !errors.Is(err, ErrNoCmd) {
+        t.Errorf("ResolveFlags of an unknown subcommand: err = %v, want ErrNoCmd", err)
+    }
+}
+
+func TestWalk(t *testing.T) {
+    pc := groupedParseCtx()
+    paths := make([]string, 0)
+    pc.Walk(func(path string, cmd *Command) {
+        paths = append(paths, path)
+        if pc.Path(cmd) != path {
+            t.Errorf("Path = %q, want %q", pc.Path(cmd), path)
+        }
+    })
+    want := "op,op list,op cancel,recur,recur rm,quit"
+    if strings.Join(paths, ",") != want {
+        t.Errorf("Walk visited %q, want %q", paths, want)
+    }
+    if path := pc.Path(&Command{Name: "op"}); path != "" {
+        t.Errorf("Path of a foreign command = %q, want empty", path)
+    }
+}
+
+func TestCompleteSubcommands(t *testing.T) {
+    tests := []struct {
+        line    string
+        start   int
+        want    []string
+    }{
+        {"", 0, []string{"op", "recur", "quit", "exit"}},
+        {"op ", 3, []string{"list", "ls", "cancel"}},
+        {"op l", 3, []string{"list", "ls"}},
+        {"op list ", 8, []string{"-s", "-v"}},
+        {"op cancel -", 10, []string{"-i", "-v"}},
+        // a command running on its own offers flags when no subcommand matches
+        {"recur ", 6, []string{"rm"}},
+        {"recur -", 6, []string{"-wd"}},
+        {"cancel -", 7, []string{"-i", "-v"}},
+        {"op -", 0, []string{}},
+    }
+    pc := groupedParseCtx()
+    for _, test := range tests {
+        start, comps := pc.Complete(test.line)
+        got := make([]string, len(comps))
+        for i, comp := range comps {
+            got[i] = comp.Value
+        }
+        if (len(got) != 0 || len(test.want) != 0) && (start != test.start || !reflect.DeepEqual(got, test.want)) {
+            t.Errorf("Complete(%q) = %d %q, want %d %q", test.line, start, got, test.start, test.want)
+        }
+    }
+}
+
+func TestResolveGlobals(t *testing.T) {
+    pc := groupedParseCtx()
+    pc.Globals = []Flag{
+        Flag{Name: "o", LongName: "output", ValidationCtx: FlagValidationCtx{Type: EnumType, Choices: Outputs, MinArgs: 1, MaxArgs: 1}},
+        // op's persistent -v wins over this one
+        Flag{Name: "v", LongName: "version", ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1}},
+    }
+    tests := []struct {
+        line    string
+        want    Values
+    }{
+        {"op list --output json -s failed", Values{"o": {"json"}, "s": {"failed"}}},
+        {"op list -s failed -o YA", Values{"s": {"failed"}, "o": {"yaml"}}},
+        {"cancel -o table -i 3", Values{"o": {"table"}, "i": {"3"}}},
+        {"op cancel -v -i 3", Values{"v": {}, "i": {"3"}}},
+        {"exit -v 2", Values{"v": {"2"}}},
+    }
+    for _, test := range tests {
+        _, got, err := pc.Resolve(test.line)
+        if err != nil {
+            t.Errorf("%q: err = %v", test.line, err)
+            continue
+        }
+        if !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%q: flags = %v, want %v", test.line, got, test.want)
+        }
+    }
+    if _, _, err := pc.Resolve("op list -o xml"); !errors.Is(err, ErrNoChoice) {
+        t.Errorf("bad format: err = %v, want ErrNoChoice", err)
+    }
+    if _, err := pc.ResolveFlags("recur rm", Values{"i": {"3"}, "o": {"json"}}); err != nil {
+        t.Errorf("ResolveFlags with a global: err = %v", err)
+    }
+    if _, comps := pc.Complete("quit --"); !reflect.DeepEqual(comps, []Completion{{Value: "--output"}, {Value: "--version"}}) {
+        t.Errorf("completing globals = %v", comps)
+    }
+}
@@ -0,0 +1,224 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "strings"
+)
+
+/*
+Name: Completion
+Type: External CLI struct
+Purpose: One way to finish the word being typed.
+Value replaces the word, quoted as needed, and
+Note is shown next to it when listing choices
+*/
+type Completion struct {
+    Value   string
+    Note    string
+}
+
+/*
+Name: Complete
+Type: External CLI func
+Purpose: Offer ways to finish the last word of line,
+returning the byte offset in line where that word
+starts. The first word completes to a command name,
+a word after a command with subcommands to one of
+those, a word starting with '-' to a flag the command hasn't
+been given yet, and an argument to one of the Choices
+of its flag or what the Complete func of the flag
+offers. A quote or group left open is kept open in
+the same style, and closed when there's one match
+*/
+func (pc *ParseCtx) Complete(line string) (int, []Completion) {
+    spans, perr := pc.tokenize(line)
+    if perr != nil {
+        // finish whatever was left open and complete inside it
+        closer := ""
+        switch perr.Err {
+            case ErrNoQuote:
+                closer = perr.Token
+            case ErrNoGrp:
+                closer = pc.CloseDelim
+            default:
+                return 0, nil
+        }
+        spans, perr = pc.tokenize(line + closer)
+        if perr != nil {
+            return 0, nil
+        }
+    } else if len(spans) == 0 || spans[len(spans)-1].end < len(line) {
+        // a new word after the whitespace
+        spans = append(spans, token{"", len(line), len(line)})
+    }
+    word := spans[len(spans)-1]
+    before := pc.expandAlias(spans[:len(spans)-1])
+    words := make([]string, len(before))
+    for i, tok := range before {
+        words[i] = tok.text
+    }
+    quote := pc.quoteLike(line[word.start:])
+
+    if len(words) == 0 {
+        return word.start, completeCommands(pc.Commands, word.text, quote)
+    }
+    chain := pc.descend(words)
+    if len(chain) == 0 {
+        return 0, nil
+    }
+    cmd := chain[len(chain)-1]
+    isFlag := strings.HasPrefix(word.text, "-")
+    if len(chain) == len(words) && len(cmd.Subcommands) != 0 && !isFlag {
+        comps := completeCommands(cmd.Subcommands, word.text, quote)
+        // a command running on its own may take flags instead
+        if len(comps) != 0 || cmd.Handler == nil || word.text != "" {
+            return word.start, comps
+        }
+    }
+    if len(cmd.Subcommands) != 0 && cmd.Handler == nil {
+        return 0, nil
+    }
+    flagged := pc.withInherited(chain)
+
+    // find the flag the word would be an argument of
+    given := make(map[string]bool)
+    var curr *Flag
+    count := 0
+    for _, w := range words[len(chain):] {
+        if flag := lookupFlag(&flagged, w); flag != nil {
+            given[flag.Name] = true
+            curr = flag
+            count = 0
+            continue
+        }
+        count++
+    }
+    if curr == nil {
+        // words before any flag go to the positional flag
+        curr = positional(flagged)
+        if curr != nil && count != 0 {
+            given[curr.Name] = true
+        }
+    }
+    if curr != nil && !isFlag {
+        ctx := curr.ValidationCtx
+        if ctx.MaxArgs == InfiniteArgs || count < ctx.MaxArgs {
+            comps := completeValues(*curr, word.text, quote)
+            // past the arguments it needs, a blank word may be a flag
+            if len(comps) != 0 || word.text != "" || count < ctx.MinArgs {
+                return word.start, comps
+            }
+        }
+    }
+    if word.text != "" && !isFlag {
+        return 0, nil
+    }
+    comps := make([]Completion, 0)
+    for _, flag := range flagged.Flags {
+        if given[flag.Name] {
+            continue
+        }
+        short, long := "-" + flag.Name, ""
+        if flag.LongName != "" {
+            long = "--" + flag.LongName
+        }
+        switch {
+            case !strings.HasPrefix(word.text, "--") && strings.HasPrefix(short, word.text):
+                comps = append(comps, Completion{Value: quote(short), Note: long})
+            case long != "" && strings.HasPrefix(long, word.text):
+                comps = append(comps, Completion{Value: quote(long)})
+        }
+    }
+    return word.start, comps
+}
+
+/*
+Name: completeCommands
+Type: Internal CLI func
+Purpose: The names and aliases of cmds starting
+with prefix
+*/
+func completeCommands(cmds []Command, prefix string, quote func(string) (string)) ([]Completion) {
+    comps := make([]Completion, 0)
+    for _, name := range commandNames(cmds) {
+        if hasPrefixFold(name, prefix) {
+            comps = append(comps, Completion{Value: quote(name)})
+        }
+    }
+    return comps
+}
+
+/*
+Name: completeValues
+Type: Internal CLI func
+Purpose: The choices of flag starting with prefix
+and whatever its Complete func offers
+*/
+func completeValues(flag Flag, prefix string, quote func(string) (string)) ([]Completion) {
+    comps := make([]Completion, 0)
+    for _, choice := range flag.ValidationCtx.Choices {
+        if hasPrefixFold(choice, prefix) {
+            comps = append(comps, Completion{Value: quote(choice)})
+        }
+    }
+    if flag.Complete != nil {
+        for _, comp := range flag.Complete(prefix) {
+            comps = append(comps, Completion{Value: quote(comp.Value), Note: comp.Note})
+        }
+    }
+    return comps
+}
+
+/*
+Name: lookupFlag
+Type: Internal CLI func
+Purpose: The flag of cmd token names as -short or
+--long, nil when it names none
+*/
+func lookupFlag(cmd *Command, token string) (*Flag) {
+    for i, flag := range cmd.Flags {
+        if token == "-" + flag.Name || (flag.LongName != "" && token == "--" + flag.LongName) {
+            return &cmd.Flags[i]
+        }
+    }
+    return nil
+}
+
+/*
+Name: quoteLike
+Type: Internal CLI func
+Purpose: A func quoting a completion the way the
+word it replaces, which word starts, was opened
+*/
+func (pc *ParseCtx) quoteLike(word string) (func(string) (string)) {
+    switch {
+        case strings.HasPrefix(word, "'"):
+            return func(s string) (string) {
+                return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
+            }
+        case strings.HasPrefix(word, "\""):
+            return func(s string) (string) {
+                s = strings.ReplaceAll(s, "\\", "\\\\")
+                return "\"" + strings.ReplaceAll(s, "\"", "\\\"") + "\""
+            }
+        case pc.grouping() && strings.HasPrefix(word, pc.OpenDelim):
+            return func(s string) (string) {
+                s = strings.ReplaceAll(s, "\\", "\\\\")
+                return pc.OpenDelim + strings.ReplaceAll(s, pc.CloseDelim, "\\" + pc.CloseDelim) + pc.CloseDelim
+            }
+    }
+    return pc.Quote
+}
+
+/*
+Name: hasPrefixFold
+Type: Internal CLI func
+Purpose: Report whether s starts with prefix,
+ignoring case
+*/
+func hasPrefixFold(s string, prefix string) (bool) {
+    return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func completeParseCtx() (*ParseCtx) {
+    venues := func(prefix string) ([]Completion) {
+        comps := make([]Completion, 0)
+        for _, comp := range []Completion{{"1505", "Carbone"}, {"2000", "Double Chicken Please"}} {
+            if strings.HasPrefix(comp.Value, prefix) || hasPrefixFold(comp.Note, prefix) {
+                comps = append(comps, comp)
+            }
+        }
+        return comps
+    }
+    return &ParseCtx{
+        Commands: []Command{
+            Command{
+                Name: "rats",
+                Flags: []Flag{
+                    Flag{Name: "v", LongName: "venue-id", Complete: venues, ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1}},
+                    Flag{Name: "t", LongName: "table", ValidationCtx: FlagValidationCtx{
+                        Type: EnumType, Choices: []string{"dining", "indoor", "outdoor"}, MinArgs: 1, MaxArgs: InfiniteArgs,
+                    }},
+                    Flag{Name: "resD", LongName: "reservation-day", ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1}},
+                    Flag{Name: "resT", LongName: "reservation-times", ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: InfiniteArgs}},
+                },
+            },
+            Command{Name: "rais"},
+            Command{Name: "search", Flags: []Flag{
+                Flag{Name: "n", LongName: "name", Complete: func(prefix string) ([]Completion) {
+                    comps := make([]Completion, 0)
+                    for _, name := range []string{"Joe's Pizza", "Joe's Shanghai"} {
+                        if hasPrefixFold(name, prefix) {
+                            comps = append(comps, Completion{Value: name})
+                        }
+                    }
+                    return comps
+                }, ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1}},
+            }},
+        },
+        OpenDelim: "[",
+        CloseDelim: "]",
+    }
+}
+
+func TestComplete(t *testing.T) {
+    tests := []struct {
+        line    string
+        start   int
+        want    []string
+    }{
+        {"", 0, []string{"rats", "rais", "search"}},
+        {"ra", 0, []string{"rats", "rais"}},
+        {"  RAT", 2, []string{"rats"}},
+        {"rats ", 5, []string{"-v", "-t", "-resD", "-resT"}},
+        {"rats -re", 5, []string{"-resD", "-resT"}},
+        {"rats --re", 5, []string{"--reservation-day", "--reservation-times"}},
+        // flags given already aren't offered again
+        {"rats -v 1505 -", 13, []string{"-t", "-resD", "-resT"}},
+        {"rats -t ", 8, []string{"dining", "indoor", "outdoor"}},
+        {"rats -t OUT", 8, []string{"outdoor"}},
+        // -t takes more, -v is full so flags come next
+        {"rats -t indoor o", 15, []string{"outdoor"}},
+        {"rats -v 1505 ", 13, []string{"-t", "-resD", "-resT"}},
+        // dynamic values match however the func likes
+        {"rats -v ", 8, []string{"1505", "2000"}},
+        {"rats -v carb", 8, []string{"1505"}},
+        // no values to offer for a flag that still needs one
+        {"rats -resD ", 11, []string{}},
+        {"search -n ", 10, []string{"'Joe'\\''s Pizza'", "'Joe'\\''s Shanghai'"}},
+        // an open quote or group is completed in its own style
+        {"search -n \"Jo", 10, []string{"\"Joe's Pizza\"", "\"Joe's Shanghai\""}},
+        {"search -n [Jo", 10, []string{"[Joe's Pizza]", "[Joe's Shanghai]"}},
+    }
+    pc := completeParseCtx()
+    for _, test := range tests {
+        start, comps := pc.Complete(test.line)
+        got := make([]string, len(comps))
+        for i, comp := range comps {
+            got[i] = comp.Value
+        }
+        if start != test.start || !reflect.DeepEqual(got, test.want) {
+            t.Errorf("Complete(%q) = %d %q, want %d %q", test.line, start, got, test.start, test.want)
+        }
+    }
+}
+
+func TestCompleteNothing(t *testing.T) {
+    pc := completeParseCtx()
+    for _, line := range []string{"nope ", "rats stray", "rats -v 1505 x", "trailing\\"} {
+        if _, comps := pc.Complete(line); len(comps) != 0 {
+            t.Errorf("Complete(%q) = %v, want nothing", line, comps)
+        }
+    }
+}
+
+func TestCompleteNotes(t *testing.T) {
+    pc := completeParseCtx()
+    _, comps := pc.Complete("rats -v")
+    if len(comps) != 1 || comps[0] != (Completion{"-v", "--venue-id"}) {
+        t.Errorf("Complete = %v, want -v with its long name", comps)
+    }
+    _, comps = pc.Complete("rats -v 2")
+    if len(comps) != 1 || comps[0].Note != "Double Chicken Please" {
+        t.Errorf("Complete = %v, want the venue name as a note", comps)
+    }
+}
@@ -0,0 +1,428 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode"
+)
+
+var (
+    ErrConfig = errors.New("invalid config")
+)
+
+/*
+Name: Source
+Type: External CLI struct
+Purpose: Somewhere the flags left out on the
+command line are read from, like the environment
+or a config file. Lookup gives the arguments of a
+setting like "party-size" and where exactly they
+were read, like the variable or file
+*/
+type Source struct {
+    Name    string
+    Lookup  func(key string) (values []string, where string, ok bool)
+}
+
+/*
+Name: Setting
+Type: External CLI func
+Purpose: The arguments of the setting key from
+the first of pc.Sources that has it, and where
+they were read
+*/
+func (pc *ParseCtx) Setting(key string) ([]string, string, bool) {
+    for _, src := range pc.Sources {
+        if values, where, ok := src.Lookup(key); ok {
+            return values, where, true
+        }
+    }
+    return nil, "", false
+}
+
+/*
+Name: fill
+Type: Internal CLI func
+Purpose: Give the flags of cmd left out of in
+the arguments of their Setting, returning where
+each flag filled was read
+*/
+func (pc *ParseCtx) fill(cmd Command, in Values) (map[string]string) {
+    from := make(map[string]string)
+    for _, flag := range cmd.Flags {
+        if flag.Setting == "" || in[flag.Name] != nil {
+            continue
+        }
+        if values, where, ok := pc.Setting(flag.Setting); ok {
+            in[flag.Name] = append([]string{}, values...)
+            from[flag.Name] = where
+        }
+    }
+    return from
+}
+
+/*
+Name: EnvName
+Type: External CLI func
+Purpose: The environment variable a setting is
+read from, like RESOLVED_PARTY_SIZE for party-size
+*/
+func EnvName(prefix string, key string) (string) {
+    return prefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+/*
+Name: EnvSource
+Type: External CLI func
+Purpose: A Source reading settings from the
+environment variables EnvName gives, split into
+arguments like a command line. Empty variables
+count as unset
+*/
+func (pc *ParseCtx) EnvSource(prefix string) (Source) {
+    return Source{
+        Name: "environment",
+        Lookup: func(key string) ([]string, string, bool) {
+            name := EnvName(prefix, key)
+            raw := os.Getenv(name)
+            if raw == "" {
+                return nil, "", false
+            }
+            values, err := pc.Tokenize(raw)
+            if err != nil {
+                // let validation say what's wrong with it
+                values = []string{raw}
+            }
+            return values, name, true
+        },
+    }
+}
+
+/*
+Name: Config
+Type: External CLI type
+Purpose: Settings read from a config file, the
+arguments of each key. Keys in a table are
+prefixed by the table name and a dot
+*/
+type Config map[string][]string
+
+/*
+Name: Source
+Type: External CLI func
+Purpose: A Source reading settings from cfg,
+read from the file at path
+*/
+func (cfg Config) Source(path string) (Source) {
+    return Source{
+        Name: "config file",
+        Lookup: func(key string) ([]string, string, bool) {
+            values, ok := cfg[key]
+            if !ok {
+                return nil, "", false
+            }
+            return values, path, true
+        },
+    }
+}
+
+/*
+Name: LoadConfig
+Type: External CLI func
+Purpose: Read the config file at path with
+ParseConfig. A missing file is an empty Config
+*/
+func LoadConfig(path string) (Config, error) {
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return Config{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    cfg, err := ParseConfig(data)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return cfg, nil
+}
+
+/*
+Name: ParseConfig
+Type: External CLI func
+Purpose: Read settings from a JSON object, or
+otherwise from TOML with tables, strings, numbers,
+booleans and arrays of those on one line
+*/
+func ParseConfig(data []byte) (Config, error) {
+    if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '{' {
+        return parseJSONConfig(trimmed)
+    }
+    return parseTOMLConfig(string(data))
+}
+
+/*
+Name: parseJSONConfig
+Type: Internal CLI func
+Purpose: Read settings from a JSON object,
+nested objects becoming tables
+*/
+func parseJSONConfig(data []byte) (Config, error) {
+    dec := json.NewDecoder(bytes.NewReader(data))
+    // keep numbers as they were written
+    dec.UseNumber()
+    var raw map[string]interface{}
+    if err := dec.Decode(&raw); err != nil {
+        return nil, fmt.Errorf("%w: %s", ErrConfig, err.Error())
+    }
+    cfg := make(Config)
+    return cfg, cfg.addJSON("", raw)
+}
+
+/*
+Name: addJSON
+Type: Internal CLI func
+Purpose: Add the keys of obj to cfg, prefixed
+by table
+*/
+func (cfg Config) addJSON(table string, obj map[string]interface{}) (error) {
+    for key, value := range obj {
+        if table != "" {
+            key = table + "." + key
+        }
+        switch v := value.(type) {
+            case map[string]interface{}:
+                if err := cfg.addJSON(key, v); err != nil {
+                    return err
+                }
+            case []interface{}:
+                values := make([]string, len(v))
+                for i, item := range v {
+                    s, ok := jsonScalar(item)
+                    if !ok {
+                        return fmt.Errorf("%w: %s holds something other than text, numbers or booleans", ErrConfig, key)
+                    }
+                    values[i] = s
+                }
+                cfg[key] = values
+            default:
+                s, ok := jsonScalar(v)
+                if !ok {
+                    return fmt.Errorf("%w: %s is null", ErrConfig, key)
+                }
+                cfg[key] = []string{s}
+        }
+    }
+    return nil
+}
+
+/*
+Name: jsonScalar
+Type: Internal CLI func
+Purpose: A JSON string, number or boolean as
+an argument
+*/
+func jsonScalar(value interface{}) (string, bool) {
+    switch v := value.(type) {
+        case string:
+            return v, true
+        case json.Number:
+            return v.String(), true
+        case bool:
+            return strconv.FormatBool(v), true
+    }
+    return "", false
+}
+
+/*
+Name: parseTOMLConfig
+Type: Internal CLI func
+Purpose: Read settings from the TOML ParseConfig
+takes, failing with the line at fault
+*/
+func parseTOMLConfig(text string) (Config, error) {
+    cfg := make(Config)
+    table := ""
+    for i, line := range strings.Split(text, "\n") {
+        line = strings.TrimSpace(stripComment(line))
+        if line == "" {
+            continue
+        }
+        fail := func(why string) (error) {
+            return fmt.Errorf("%w: line %d: %s", ErrConfig, i + 1, why)
+        }
+        if strings.HasPrefix(line, "[") {
+            if !strings.HasSuffix(line, "]") {
+                return nil, fail("unclosed table name")
+            }
+            table = strings.TrimSpace(line[1:len(line)-1])
+            if table == "" {
+                return nil, fail("empty table name")
+            }
+            continue
+        }
+        eq := strings.IndexByte(line, '=')
+        if eq < 0 {
+            return nil, fail("want key = value")
+        }
+        key := strings.Trim(strings.TrimSpace(line[:eq]), "\"'")
+        if key == "" {
+            return nil, fail("empty key")
+        }
+        if table != "" {
+            key = table + "." + key
+        }
+        values, err := tomlValue(strings.TrimSpace(line[eq+1:]))
+        if err != nil {
+            return nil, fail(err.Error())
+        }
+        cfg[key] = values
+    }
+    return cfg, nil
+}
+
+/*
+Name: stripComment
+Type: Internal CLI func
+Purpose: line without a # comment, leaving #
+inside strings be
+*/
+func stripComment(line string) (string) {
+    quote := rune(0)
+    escaped := false
+    for i, r := range line {
+        switch {
+            case escaped:
+                escaped = false
+            case quote == '"' && r == '\\':
+                escaped = true
+            case quote != 0:
+                if r == quote {
+                    quote = 0
+                }
+            case r == '"' || r == '\'':
+                quote = r
+            case r == '#':
+                return line[:i]
+        }
+    }
+    return line
+}
+
+/*
+Name: tomlValue
+Type: Internal CLI func
+Purpose: The arguments of a TOML value, one for
+a scalar and one per item of an array
+*/
+func tomlValue(s string) ([]string, error) {
+    if !strings.HasPrefix(s, "[") {
+        value, rest, err := tomlScalar(s)
+        if err != nil {
+            return nil, err
+        }
+        if strings.TrimSpace(rest) != "" {
+            return nil, errors.New("unexpected " + strconv.Quote(rest) + " after value")
+        }
+        return []string{value}, nil
+    }
+    values := make([]string, 0)
+    rest := strings.TrimSpace(s[1:])
+    for !strings.HasPrefix(rest, "]") {
+        if rest == "" {
+            return nil, errors.New("unclosed array, arrays go on one line")
+        }
+        value, after, err := tomlScalar(rest)
+        if err != nil {
+            return nil, err
+        }
+        values = append(values, value)
+        rest = strings.TrimSpace(after)
+        if strings.HasPrefix(rest, ",") {
+            rest = strings.TrimSpace(rest[1:])
+        } else if rest != "" && !strings.HasPrefix(rest, "]") {
+            return nil, errors.New("want , or ] in array")
+        }
+    }
+    if strings.TrimSpace(rest[1:]) != "" {
+        return nil, errors.New("unexpected " + strconv.Quote(rest[1:]) + " after array")
+    }
+    return values, nil
+}
+
+/*
+Name: tomlScalar
+Type: Internal CLI func
+Purpose: Read the string, number or boolean s
+starts with, returning what follows it
+*/
+func tomlScalar(s string) (string, string, error) {
+    if s == "" {
+        return "", "", errors.New("missing value")
+    }
+    switch s[0] {
+        case '\'':
+            end := strings.IndexByte(s[1:], '\'')
+            if end < 0 {
+                return "", "", errors.New("unclosed string")
+            }
+            return s[1:end+1], s[end+2:], nil
+        case '"':
+            var b strings.Builder
+            escaped := false
+            for i, r := range s[1:] {
+                switch {
+                    case escaped:
+                        escapes := map[rune]rune{'"': '"', '\\': '\\', 'n': '\n', 't': '\t'}
+                        e, ok := escapes[r]
+                        if !ok {
+                            return "", "", errors.New("unknown escape \\" + string(r))
+                        }
+                        b.WriteRune(e)
+                        escaped = false
+                    case r == '\\':
+                        escaped = true
+                    case r == '"':
+                        return b.String(), s[i+2:], nil
+                    default:
+                        b.WriteRune(r)
+                }
+            }
+            return "", "", errors.New("unclosed string")
+    }
+    end := strings.IndexFunc(s, func(r rune) (bool) {
+        return r == ',' || r == ']' || unicode.IsSpace(r)
+    })
+    if end < 0 {
+        end = len(s)
+    }
+    word := s[:end]
+    if _, err := strconv.ParseFloat(word, 64); err != nil && word != "true" && word != "false" {
+        return "", "", errors.New("want a string, number or boolean, not " + strconv.Quote(word))
+    }
+    return word, s[end:], nil
+}
+
+/*
+Name: Keys
+Type: External CLI func
+Purpose: The keys of cfg in order
+*/
+func (cfg Config) Keys() ([]string) {
+    keys := make([]string, 0, len(cfg))
+    for key := range cfg {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
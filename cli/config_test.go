@@ -0,0 +1,169 @@
+package cli
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestParseConfig(t *testing.T) {
+    toml := `
+# defaults for resolved
+account = "me@example.com"   # who books
+party-size = 2
+tz = 'America/New_York'
+quoted = "a # b \"c\""
+
+[rats]
+weekdays = ["mo", 'tu', 3]
+`
+    want := Config{
+        "account": {"me@example.com"},
+        "party-size": {"2"},
+        "tz": {"America/New_York"},
+        "quoted": {"a # b \"c\""},
+        "rats.weekdays": {"mo", "tu", "3"},
+    }
+    got, err := ParseConfig([]byte(toml))
+    if err != nil || !reflect.DeepEqual(got, want) {
+        t.Errorf("TOML = %v, %v, want %v", got, err, want)
+    }
+
+    json := `{"account": "me@example.com", "party-size": 2, "tz": "America/New_York",
+        "quoted": "a # b \"c\"", "rats": {"weekdays": ["mo", "tu", 3]}}`
+    got, err = ParseConfig([]byte(json))
+    if err != nil || !reflect.DeepEqual(got, want) {
+        t.Errorf("JSON = %v, %v, want %v", got, err, want)
+    }
+    if keys := got.Keys(); strings.Join(keys, ",") != "account,party-size,quoted,rats.weekdays,tz" {
+        t.Errorf("Keys = %v", keys)
+    }
+}
+
+func TestParseConfigErrors(t *testing.T) {
+    tests := []struct {
+        data    string
+        want    string
+    }{
+        {"a = 1\nb", "line 2: want key = value"},
+        {"[rats", "line 1: unclosed table name"},
+        {"a = \"open", "line 1: unclosed string"},
+        {"a = [1, 2", "line 1: unclosed array"},
+        {"a = bare", "line 1: want a string, number or boolean"},
+        {"a = 1 2", "line 1: unexpected"},
+        {"a = \"\\q\"", "line 1: unknown escape"},
+        {`{"a": null}`, "a is null"},
+        {`{"a": [{}]}`, "a holds something other than"},
+        {`{"a": `, "invalid config"},
+    }
+    for _, test := range tests {
+        _, err := ParseConfig([]byte(test.data))
+        if !errors.Is(err, ErrConfig) || !strings.Contains(err.Error(), test.want) {
+            t.Errorf("%q: err = %v, want one containing %q", test.data, err, test.want)
+        }
+    }
+}
+
+func TestLoadConfig(t *testing.T) {
+    dir := t.TempDir()
+    cfg, err := LoadConfig(filepath.Join(dir, "missing"))
+    if err != nil || len(cfg) != 0 {
+        t.Errorf("missing file = %v, %v, want an empty config", cfg, err)
+    }
+    path := filepath.Join(dir, "config")
+    if err := os.WriteFile(path, []byte("a ="), 0600); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := LoadConfig(path); !errors.Is(err, ErrConfig) || !strings.HasPrefix(err.Error(), path) {
+        t.Errorf("bad file: err = %v, want ErrConfig naming the file", err)
+    }
+}
+
+func settingsParseCtx() (*ParseCtx) {
+    return &ParseCtx{
+        Commands: []Command{
+            Command{
+                Name: "rats",
+                Flags: []Flag{
+                    Flag{Name: "ps", LongName: "party-size", Setting: "party-size", ValidationCtx: FlagValidationCtx{
+                        Type: IntType, Required: true, MinArgs: 1, MaxArgs: 1,
+                    }},
+                    Flag{Name: "tz", Setting: "timezone", ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1}},
+                },
+                Handler: func(in Values) (interface{}, error) { return nil, nil },
+            },
+        },
+        OpenDelim: "[",
+        CloseDelim: "]",
+    }
+}
+
+func TestResolveSettings(t *testing.T) {
+    pc := settingsParseCtx()
+    t.Setenv("TEST_PARTY_SIZE", "")
+    t.Setenv("TEST_TIMEZONE", "'America/New York'")
+    pc.Sources = []Source{
+        pc.EnvSource("TEST"),
+        Config{"party-size": {"4"}, "timezone": {"UTC"}}.Source("config"),
+    }
+    tests := []struct {
+        line    string
+        want    Values
+    }{
+        // the environment wins over the config, empty variables don't count
+        {"rats", Values{"ps": {"4"}, "tz": {"America/New York"}}},
+        // the command line wins over both
+        {"rats -ps 2 -tz UTC", Values{"ps": {"2"}, "tz": {"UTC"}}},
+    }
+    for _, test := range tests {
+        _, got, err := pc.Resolve(test.line)
+        if err != nil || !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%q: flags = %v, %v, want %v", test.line, got, err, test.want)
+        }
+    }
+    if values, where, ok := pc.Setting("party-size"); !ok || where != "config" || values[0] != "4" {
+        t.Errorf("Setting = %v, %q, %v, want 4 from config", values, where, ok)
+    }
+    if _, _, ok := pc.Setting("account"); ok {
+        t.Errorf("Setting of an unset key is ok")
+    }
+    if help, _ := pc.Help("rats"); !strings.Contains(help, "whole number, from setting party-size\n") {
+        t.Errorf("Help = %q, want the setting", help)
+    }
+}
+
+func TestResolveSettingsErrors(t *testing.T) {
+    pc := settingsParseCtx()
+    t.Setenv("TEST_PARTY_SIZE", "two")
+    pc.Sources = []Source{pc.EnvSource("TEST")}
+    _, _, err := pc.Resolve("rats")
+    var perr *ParseError
+    if !errors.As(err, &perr) || !errors.Is(err, ErrInvInt) {
+        t.Fatalf("err = %v, want ErrInvInt", err)
+    }
+    if perr.Source != "TEST_PARTY_SIZE" || perr.Column != -1 || perr.Caret() != "" {
+        t.Errorf("err from %q column %d, want TEST_PARTY_SIZE without a position", perr.Source, perr.Column)
+    }
+    if !strings.HasSuffix(err.Error(), " (from TEST_PARTY_SIZE)") {
+        t.Errorf("Error = %q, want where the value came from", err.Error())
+    }
+    // a value on the line still points at itself
+    _, _, err = pc.Resolve("rats -ps x")
+    if !errors.As(err, &perr) || perr.Source != "" || perr.Column != 9 {
+        t.Errorf("err = %v, want one at column 9", err)
+    }
+
+    pc.Sources = nil
+    if _, _, err := pc.Resolve("rats"); !errors.Is(err, ErrMissReq) {
+        t.Errorf("without sources: err = %v, want ErrMissReq", err)
+    }
+}
+
+func TestEnvName(t *testing.T) {
+    if got := EnvName("RESOLVED", "party-size"); got != "RESOLVED_PARTY_SIZE" {
+        t.Errorf("EnvName = %q", got)
+    }
+}
@@ -39,7 +39,20 @@ How To Use:
               and CloseDelim="]", we have tokenization yielding 
               ["a", "  b c"]. These are defined on a per-ctx basis
               since different parse syntax may require different
-              escape characters.
+              escape characters. Delims can be more than one 
+              character, and leaving either empty turns groups off.
+
+              Besides groups, the tokenizer reads input like a 
+              shell: tokens are split on any run of whitespace,
+              'single quotes' keep their text as is, "double 
+              quotes" do too except for \" and \\, and a backslash
+              anywhere else keeps the next character as is. Text 
+              in a group is also kept as is, quotes included, and 
+              only \ before CloseDelim or another \ escapes it. 
+              Quoted and grouped text joins what touches it, so 
+              a"b c"d is the one token "ab cd". Quote does the 
+              reverse, writing a string so it reads back as one
+              token.
     
         2. Commands
 
@@ -50,9 +63,9 @@ How To Use:
 
             - This struct defines what command strings should be 
               matched, what flags they take, and how to handle them.
-              The description section adds an optional description,
-              which is severely fucked up in the current arch,
-              and I'll probably fix that soon. 
+              The Description is the one line help shows next to 
+              the command, and Examples are command lines help 
+              shows under its flags.
 
               Each command defines a Handler, which takes in a map 
               representing the validated flag to value map. So for
@@ -60,13 +73,30 @@ How To Use:
               then, assuming the string was a valid command str,
               your handler will be passed a map denoted "in" such that
               in["n"] is a 1 element string slice containing your arg.
-              The output of your handler must be a string since the 
-              "ParseCtx.Parse" method outputs the result of your 
-              handler.
+              Your handler returns a result, a string or any value
+              encoding/json can encode, which Render shows in the 
+              format asked for. "ParseCtx.Parse" outputs the result
+              of your handler rendered as text.
 
               The last field is "Flags", which is a Flag slice,
               discussed in the next section.
 
+              Commands can have Aliases, other names that work
+              the same, and Subcommands, so that "op list" runs 
+              the list subcommand of op. Subcommands take the 
+              flags of their own and the Persistent flags of the
+              commands above them, and a command with Subcommands
+              but no Handler fails with ErrNoSubCmd when none is
+              given. ParseCtx.Aliases maps a word to the command
+              words it stands for, like "ls" to "op list", for 
+              shortcuts or names kept from before a command was
+              moved. Walk visits every command with the words 
+              naming it, and Path gives the words for a command
+              Resolve returned, which ResolveFlags takes back.
+              ParseCtx.Globals are flags every command takes,
+              anywhere on the line, after the ones it has or 
+              inherits, like a format to print the result in.
+
         4. Flag
 
             - The Flag struct defines a Flag to a command. This
@@ -77,8 +107,13 @@ How To Use:
               optionally have a LongName field, like "name" which 
               will allow the flag to be defined using "--name". 
               
-              Flags also have Descriptions, which are severely 
-              screwed up as mentioned with command descriptions.
+              Flags also have Descriptions for help, which 
+              adds whether the flag is required, how many 
+              arguments it takes, their type and the Default 
+              when one is set, so the description only needs to
+              say what the flag is for. A Positional flag takes
+              the words given before any flag, so 'help op list'
+              reads like 'help -c op list'.
 
               Finally, Flags must have a ValidationCtx of type
               FlagValidtionCtx, mentioned in the next section
@@ -93,6 +128,93 @@ How To Use:
               using the cli.InfiniteArgs value. Required is a bool
               which when set to true, will cause the parser to throw
               an error if the flag is missing
+
+              Type declares what each argument holds: StringType
+              (the default), IntType, DurationType(hh:mm or a go
+              duration like 90s), DateType(yyyy:mm:dd), 
+              TimeOfDayType(hh:mm), DateTimeType(yyyy:mm:dd:hh:mm),
+              EnumType or EmailType. IntType flags can be bounded
+              with Range, using cli.NoMax for no upper bound, and 
+              EnumType flags take one of Choices, ignoring case, or 
+              a prefix of only one of them. A bad argument fails
+              parsing with a FlagError naming the flag and value
+
+        6. Values
+
+            - Handlers get the flag map as Values, which can be 
+              indexed like a plain map of arguments. The getters
+              like Int, Duration, Date or TimesOfDay convert them,
+              giving zero values for flags left out. The parser 
+              already checked every argument, but a map built or
+              changed by hand may hold one that doesn't convert,
+              which the getters return as a FlagError rather than
+              read as zero. Enum arguments are rewritten to the
+              choice they matched
+
+        7. ParseError
+
+            - Resolve and Parse fail with a *ParseError, which 
+              unwraps to one of the sentinel errors like ErrNoFlg
+              or to a FlagError. It carries the Command, the Token
+              at fault and its Column in the input, the Flag that
+              was missing or repeated, and Suggestions of the 
+              commands or flags within a couple of typos of an 
+              unknown one. Caret gives a line to print under the 
+              input pointing at the token, like
+
+                  search -nme x
+                         ^~~~
+                  flag unrecognized: "-nme", did you mean --name?
+
+        8. LineEditor
+
+            - A LineEditor reads lines from a terminal with emacs
+              style keys (arrows, ^A, ^E, ^K, ^U, ^W...), history 
+              on up and down that is kept in HistoryFile across 
+              sessions, reverse search on ^R and completion on tab.
+              Lines Private reports stay out of the file. Set 
+              Complete to ParseCtx.Complete to complete command 
+              names, flags, the Choices of enum flags and whatever 
+              the Complete func of a flag offers, like ids that 
+              only exist at run time. The terminal is put in raw 
+              mode with stty while reading, like SetEcho.
+
+        9. Help
+
+            - Help gives the help of one command, with its 
+              synopsis, aliases, subcommands, flags and examples,
+              or a line on every command when given no name. 
+              WriteMarkdown and WriteMan export the help of every
+              command as markdown or a man page, so docs can be 
+              generated from the commands instead of kept by hand.
+
+        10. Settings
+
+            - A flag with a Setting is read from the Sources of 
+              the ParseCtx when it's left off the line, the first
+              source that has the setting winning, so the line 
+              wins over all of them. EnvSource reads settings from
+              variables like RESOLVED_PARTY_SIZE, split like a 
+              command line, and the Source of a Config reads them
+              from a file LoadConfig read. Config files are JSON 
+              objects or TOML with tables, strings, numbers, 
+              booleans and one line arrays, keys in a table being
+              named like "table.key". A bad value from a source 
+              fails with a ParseError naming where it was read 
+              instead of pointing into the line.
+
+        11. Output
+
+            - Render shows a handler result as text, JSON, YAML or
+              a table, the Outputs ParseOutput takes. Text is what
+              a Texter gives, like the form a command always 
+              printed, or the string itself. JSON and YAML encode
+              the result with encoding/json, so json tags name the
+              fields and YAML keeps their order. A table has a row
+              per item of a list and a column per field, or a row
+              per field, unless the result is a Tabler picking its
+              own columns. Scripts ask for JSON and pipe it into 
+              tools like jq instead of scraping the text.
  
 **********************************************************************
 */
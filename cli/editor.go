@@ -0,0 +1,643 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// Lines of history kept when HistorySize is 0
+const DefaultHistorySize = 1000
+
+// Width the completion list is packed into
+const listWidth = 80
+
+var (
+    ErrInterrupt = errors.New("interrupted")
+)
+
+// Keys read from escape sequences, kept out of
+// the range of runes
+const (
+    keyUp rune = -(iota + 1)
+    keyDown
+    keyRight
+    keyLeft
+    keyHome
+    keyEnd
+    keyDelete
+    keyUnknown
+)
+
+// Control keys
+const (
+    ctrlA rune = 1
+    ctrlB rune = 2
+    ctrlC rune = 3
+    ctrlD rune = 4
+    ctrlE rune = 5
+    ctrlF rune = 6
+    ctrlG rune = 7
+    ctrlH rune = 8
+    tab rune = 9
+    ctrlJ rune = 10
+    ctrlK rune = 11
+    ctrlL rune = 12
+    ctrlM rune = 13
+    ctrlN rune = 14
+    ctrlP rune = 16
+    ctrlR rune = 18
+    ctrlU rune = 21
+    ctrlW rune = 23
+    esc rune = 27
+    backspace rune = 127
+)
+
+/*
+Name: LineEditor
+Type: External CLI struct
+Purpose: Read lines from a terminal with emacs
+style editing, history kept across sessions,
+reverse search and tab completion
+*/
+type LineEditor struct {
+    In          io.Reader
+    Out         io.Writer
+    Prompt      string
+    // File history is kept in, none when empty
+    HistoryFile string
+    // Most lines kept, DefaultHistorySize when 0
+    HistorySize int
+    // Reports lines to keep out of the history file,
+    // like ones with passwords, they're still kept
+    // for the session
+    Private     func(line string) (bool)
+    // Offers completions for the line up to the
+    // cursor, like ParseCtx.Complete
+    Complete    func(line string) (int, []Completion)
+
+    history     []string
+}
+
+/*
+Name: editState
+Type: Internal CLI struct
+Purpose: The line being edited
+*/
+type editState struct {
+    line    []rune
+    pos     int
+    // index of the history line shown, len(history)
+    // for the new line
+    hist    int
+    // the new line, kept while browsing history
+    draft   []rune
+}
+
+/*
+Name: LoadHistory
+Type: External CLI func
+Purpose: Read in the history file, trimming it down
+to HistorySize lines. A missing file is no error
+*/
+func (e *LineEditor) LoadHistory() (error) {
+    if e.HistoryFile == "" {
+        return nil
+    }
+    f, err := os.Open(e.HistoryFile)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    lines := make([]string, 0)
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        lines = append(lines, scanner.Text())
+    }
+    f.Close()
+    if err := scanner.Err(); err != nil {
+        return err
+    }
+    e.history = append(lines, e.history...)
+    e.trimHistory()
+    if len(lines) <= e.historySize() {
+        return nil
+    }
+    // rewrite the file so it doesn't grow forever
+    return os.WriteFile(e.HistoryFile, []byte(strings.Join(lines[len(lines)-e.historySize():], "\n") + "\n"), 0600)
+}
+
+/*
+Name: AddHistory
+Type: External CLI func
+Purpose: Remember line, unless it's blank or the
+same as the line before, appending it to the
+history file unless Private reports it
+*/
+func (e *LineEditor) AddHistory(line string) (error) {
+    if strings.TrimSpace(line) == "" {
+        return nil
+    }
+    if len(e.history) != 0 && e.history[len(e.history)-1] == line {
+        return nil
+    }
+    e.history = append(e.history, line)
+    e.trimHistory()
+    if e.HistoryFile == "" || (e.Private != nil && e.Private(line)) {
+        return nil
+    }
+    if err := os.MkdirAll(filepath.Dir(e.HistoryFile), 0700); err != nil {
+        return err
+    }
+    f, err := os.OpenFile(e.HistoryFile, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0600)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    _, err = fmt.Fprintln(f, line)
+    return err
+}
+
+/*
+Name: historySize
+Type: Internal CLI func
+Purpose: The most lines of history kept
+*/
+func (e *LineEditor) historySize() (int) {
+    if e.HistorySize <= 0 {
+        return DefaultHistorySize
+    }
+    return e.HistorySize
+}
+
+/*
+Name: trimHistory
+Type: Internal CLI func
+Purpose: Drop the oldest lines past historySize
+*/
+func (e *LineEditor) trimHistory() {
+    if extra := len(e.history) - e.historySize(); extra > 0 {
+        e.history = append([]string{}, e.history[extra:]...)
+    }
+}
+
+/*
+Name: ReadLine
+Type: External CLI func
+Purpose: Prompt for and read one line. It returns
+io.EOF on ^D at an empty line and ErrInterrupt on
+^C. When the terminal can't be put in raw mode the
+line is read without editing
+*/
+func (e *LineEditor) ReadLine() (string, error) {
+    if f, ok := e.In.(*os.File); ok && IsTerminal(f) {
+        restore, err := MakeRaw(f)
+        if err != nil {
+            fmt.Fprint(e.Out, e.Prompt)
+            return e.readPlain()
+        }
+        defer restore()
+    }
+    s := &editState{hist: len(e.history)}
+    e.refresh(e.Prompt, s.line, s.pos)
+    for {
+        r, err := e.readKey()
+        if err != nil {
+            return "", err
+        }
+        if r == ctrlR {
+            r, err = e.search(s)
+            if err != nil {
+                return "", err
+            }
+        }
+        done, err := e.handleKey(s, r)
+        if done {
+            return string(s.line), err
+        }
+        e.refresh(e.Prompt, s.line, s.pos)
+    }
+}
+
+/*
+Name: handleKey
+Type: Internal CLI func
+Purpose: Apply one key to the line, reporting
+whether the line is finished
+*/
+func (e *LineEditor) handleKey(s *editState, r rune) (bool, error) {
+    switch r {
+        case ctrlM, ctrlJ:
+            fmt.Fprint(e.Out, "\n")
+            return true, nil
+        case ctrlC:
+            fmt.Fprint(e.Out, "^C\n")
+            s.line = nil
+            return true, ErrInterrupt
+        case ctrlD:
+            if len(s.line) == 0 {
+                return true, io.EOF
+            }
+            s.deleteAt(s.pos)
+        case backspace, ctrlH:
+            if s.pos > 0 {
+                s.pos--
+                s.deleteAt(s.pos)
+            }
+        case keyDelete:
+            s.deleteAt(s.pos)
+        case keyLeft, ctrlB:
+            if s.pos > 0 {
+                s.pos--
+            }
+        case keyRight, ctrlF:
+            if s.pos < len(s.line) {
+                s.pos++
+            }
+        case keyHome, ctrlA:
+            s.pos = 0
+        case keyEnd, ctrlE:
+            s.pos = len(s.line)
+        case ctrlK:
+            s.line = s.line[:s.pos]
+        case ctrlU:
+            s.line = append([]rune{}, s.line[s.pos:]...)
+            s.pos = 0
+        case ctrlW:
+            // the spaces before the cursor, then the word before them
+            start := s.pos
+            for start > 0 && unicode.IsSpace(s.line[start-1]) {
+                start--
+            }
+            for start > 0 && !unicode.IsSpace(s.line[start-1]) {
+                start--
+            }
+            s.line = append(s.line[:start], s.line[s.pos:]...)
+            s.pos = start
+        case keyUp, ctrlP:
+            e.browse(s, s.hist - 1)
+        case keyDown, ctrlN:
+            e.browse(s, s.hist + 1)
+        case ctrlL:
+            fmt.Fprint(e.Out, "\x1b[H\x1b[2J")
+        case tab:
+            e.complete(s)
+        default:
+            if r >= ' ' && unicode.IsPrint(r) {
+                s.line = append(s.line[:s.pos], append([]rune{r}, s.line[s.pos:]...)...)
+                s.pos++
+            }
+    }
+    return false, nil
+}
+
+/*
+Name: deleteAt
+Type: Internal CLI func
+Purpose: Delete the rune at i, if there is one
+*/
+func (s *editState) deleteAt(i int) {
+    if i < len(s.line) {
+        s.line = append(s.line[:i], s.line[i+1:]...)
+    }
+}
+
+/*
+Name: browse
+Type: Internal CLI func
+Purpose: Show history line i instead of the line
+being edited, keeping the new line to come back to
+*/
+func (e *LineEditor) browse(s *editState, i int) {
+    if i < 0 || i > len(e.history) {
+        return
+    }
+    if s.hist == len(e.history) {
+        s.draft = s.line
+    }
+    s.hist = i
+    if i == len(e.history) {
+        s.line = s.draft
+    } else {
+        s.line = []rune(e.history[i])
+    }
+    s.pos = len(s.line)
+}
+
+/*
+Name: search
+Type: Internal CLI func
+Purpose: Search the history backwards for what is
+typed, ^R going to the next older match. ^G or ^C
+go back to the line as it was, and any other key
+takes the match and is returned to be handled
+*/
+func (e *LineEditor) search(s *editState) (rune, error) {
+    query := ""
+    match := len(e.history)
+    failed := false
+    for {
+        found := []rune{}
+        cursor := 0
+        if match < len(e.history) {
+            found = []rune(e.history[match])
+            // a failed search keeps showing the last match
+            if idx := strings.Index(e.history[match], query); idx >= 0 {
+                cursor = utf8.RuneCountInString(e.history[match][:idx])
+            }
+        }
+        prompt := "(reverse-i-search)`" + query + "': "
+        if failed {
+            prompt = "(failed " + prompt[1:]
+        }
+        e.refresh(prompt, found, cursor)
+
+        r, err := e.readKey()
+        if err != nil {
+            return 0, err
+        }
+        switch {
+            case r == ctrlR:
+                if i := e.findHistory(query, match - 1); i >= 0 {
+                    match = i
+                }
+            case r == backspace || r == ctrlH:
+                if query != "" {
+                    _, size := utf8.DecodeLastRuneInString(query)
+                    query = query[:len(query)-size]
+                    match = len(e.history)
+                    if i := e.findHistory(query, len(e.history) - 1); i >= 0 && query != "" {
+                        match = i
+                    }
+                    failed = false
+                }
+            case r == ctrlG || r == ctrlC:
+                return keyUnknown, nil
+            case r >= ' ' && unicode.IsPrint(r):
+                query += string(r)
+                // the match shown is kept while it still matches
+                from := match
+                if from == len(e.history) {
+                    from--
+                }
+                i := e.findHistory(query, from)
+                failed = i < 0
+                if !failed {
+                    match = i
+                }
+            default:
+                if match < len(e.history) {
+                    if s.hist == len(e.history) {
+                        s.draft = s.line
+                    }
+                    s.hist = match
+                    s.line = found
+                    s.pos = cursor
+                }
+                return r, nil
+        }
+    }
+}
+
+/*
+Name: findHistory
+Type: Internal CLI func
+Purpose: The newest history line at or before from
+containing query, -1 when there is none
+*/
+func (e *LineEditor) findHistory(query string, from int) (int) {
+    for i := from; i >= 0 && i < len(e.history); i-- {
+        if strings.Contains(e.history[i], query) {
+            return i
+        }
+    }
+    return -1
+}
+
+/*
+Name: complete
+Type: Internal CLI func
+Purpose: Fill in as much of the word before the
+cursor as every completion shares, or list them
+when there's nothing more to fill in
+*/
+func (e *LineEditor) complete(s *editState) {
+    if e.Complete == nil {
+        return
+    }
+    before := string(s.line[:s.pos])
+    start, comps := e.Complete(before)
+    if len(comps) == 0 {
+        return
+    }
+    word := before[start:]
+    common := comps[0].Value
+    for _, comp := range comps[1:] {
+        common = commonPrefix(common, comp.Value)
+    }
+    if len(comps) == 1 {
+        common += " "
+    } else if len(common) <= len(word) || !strings.HasPrefix(common, word) {
+        e.list(comps)
+        return
+    }
+    rest := append([]rune{}, s.line[s.pos:]...)
+    s.line = append(s.line[:utf8.RuneCountInString(before[:start])], []rune(common)...)
+    s.pos = len(s.line)
+    s.line = append(s.line, rest...)
+}
+
+/*
+Name: list
+Type: Internal CLI func
+Purpose: Print completions under the line, one
+a line with their notes or packed into columns
+*/
+func (e *LineEditor) list(comps []Completion) {
+    width := 0
+    notes := false
+    for _, comp := range comps {
+        if n := utf8.RuneCountInString(comp.Value); n > width {
+            width = n
+        }
+        notes = notes || comp.Note != ""
+    }
+    out := "\n"
+    if notes {
+        for _, comp := range comps {
+            out += fmt.Sprintf("%-*s  %s\n", width, comp.Value, comp.Note)
+        }
+        fmt.Fprint(e.Out, out)
+        return
+    }
+    cols := listWidth / (width + 2)
+    if cols < 1 {
+        cols = 1
+    }
+    for i, comp := range comps {
+        out += fmt.Sprintf("%-*s", width + 2, comp.Value)
+        if i % cols == cols - 1 || i == len(comps) - 1 {
+            out = strings.TrimRight(out, " ") + "\n"
+        }
+    }
+    fmt.Fprint(e.Out, out)
+}
+
+/*
+Name: commonPrefix
+Type: Internal CLI func
+Purpose: The longest start a and b share, cut
+on a rune boundary
+*/
+func commonPrefix(a string, b string) (string) {
+    i := 0
+    for i < len(a) && i < len(b) && a[i] == b[i] {
+        i++
+    }
+    for i > 0 && i < len(a) && !utf8.RuneStart(a[i]) {
+        i--
+    }
+    return a[:i]
+}
+
+/*
+Name: refresh
+Type: Internal CLI func
+Purpose: Redraw the prompt and line, leaving the
+cursor at pos
+*/
+func (e *LineEditor) refresh(prompt string, line []rune, pos int) {
+    out := "\r" + prompt + string(line) + "\x1b[K"
+    if back := len(line) - pos; back > 0 {
+        out += fmt.Sprintf("\x1b[%dD", back)
+    }
+    fmt.Fprint(e.Out, out)
+}
+
+/*
+Name: readByte
+Type: Internal CLI func
+Purpose: Read one byte of In. Nothing is read
+ahead, so other readers of In miss nothing
+*/
+func (e *LineEditor) readByte() (byte, error) {
+    var buf [1]byte
+    if _, err := io.ReadFull(e.In, buf[:]); err != nil {
+        return 0, err
+    }
+    return buf[0], nil
+}
+
+/*
+Name: readKey
+Type: Internal CLI func
+Purpose: Read one key, a rune, a control key or
+one of the keys sent as an escape sequence
+*/
+func (e *LineEditor) readKey() (rune, error) {
+    b, err := e.readByte()
+    if err != nil {
+        return 0, err
+    }
+    if rune(b) == esc {
+        return e.readEscape()
+    }
+    if b < utf8.RuneSelf {
+        return rune(b), nil
+    }
+    buf := []byte{b}
+    for !utf8.FullRune(buf) {
+        b, err := e.readByte()
+        if err != nil {
+            return 0, err
+        }
+        buf = append(buf, b)
+    }
+    r, _ := utf8.DecodeRune(buf)
+    return r, nil
+}
+
+/*
+Name: readEscape
+Type: Internal CLI func
+Purpose: Read the rest of an escape sequence,
+ESC [ or ESC O then parameters and a final byte
+*/
+func (e *LineEditor) readEscape() (rune, error) {
+    b, err := e.readByte()
+    if err != nil {
+        return 0, err
+    }
+    if b != '[' && b != 'O' {
+        return keyUnknown, nil
+    }
+    params := ""
+    for {
+        b, err = e.readByte()
+        if err != nil {
+            return 0, err
+        }
+        if b >= 0x40 && b <= 0x7e {
+            break
+        }
+        params += string(b)
+    }
+    switch b {
+        case 'A':
+            return keyUp, nil
+        case 'B':
+            return keyDown, nil
+        case 'C':
+            return keyRight, nil
+        case 'D':
+            return keyLeft, nil
+        case 'H':
+            return keyHome, nil
+        case 'F':
+            return keyEnd, nil
+        case '~':
+            switch params {
+                case "1", "7":
+                    return keyHome, nil
+                case "4", "8":
+                    return keyEnd, nil
+                case "3":
+                    return keyDelete, nil
+            }
+    }
+    return keyUnknown, nil
+}
+
+/*
+Name: readPlain
+Type: Internal CLI func
+Purpose: Read up to a newline without editing
+*/
+func (e *LineEditor) readPlain() (string, error) {
+    line := make([]byte, 0)
+    for {
+        b, err := e.readByte()
+        if err == io.EOF && len(line) != 0 {
+            return string(line), nil
+        }
+        if err != nil {
+            return "", err
+        }
+        if b == '\n' {
+            return strings.TrimSuffix(string(line), "\r"), nil
+        }
+        line = append(line, b)
+    }
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+const (
+    up = "\x1b[A"
+    down = "\x1b[B"
+    left = "\x1b[D"
+    home = "\x1b[H"
+    del = "\x1b[3~"
+)
+
+func TestReadLineEditing(t *testing.T) {
+    tests := []struct {
+        name    string
+        keys    string
+        want    string
+    }{
+        {"plain", "search -n x\r", "search -n x"},
+        {"newline ends", "search\n", "search"},
+        {"backspace", "seaxx\x7f\x7frch\r", "search"},
+        {"insert after moving left", "srch" + left + left + left + "ea\r", "search"},
+        {"home and end", "earch" + home + "s\x05 -n\r", "search -n"},
+        {"ctrl b and f", "ac\x02b\x06d\r", "abcd"},
+        {"delete key", "abxc" + left + left + del + "\r", "abc"},
+        {"ctrl d deletes", "abxc\x02\x02\x04\r", "abc"},
+        {"kill to end", "search -n x\x01\x06\x06\x06\x06\x06\x06\x0b\r", "search"},
+        {"kill to start", "junk search\x01\x06\x06\x06\x06\x06\x15\r", "search"},
+        {"kill word", "search -n carbone\x17\r", "search -n "},
+        {"kill word past spaces", "search -n   \x17\r", "search "},
+        {"unicode", "café\x7fe\r", "cafe"},
+        {"unknown escape ignored", "a\x1b[5~b\r", "ab"},
+    }
+    for _, test := range tests {
+        e := &LineEditor{In: strings.NewReader(test.keys), Out: io.Discard}
+        got, err := e.ReadLine()
+        if err != nil || got != test.want {
+            t.Errorf("%s: ReadLine = %q, %v, want %q", test.name, got, err, test.want)
+        }
+    }
+}
+
+func TestReadLineEnds(t *testing.T) {
+    e := &LineEditor{In: strings.NewReader("\x04"), Out: io.Discard}
+    if _, err := e.ReadLine(); err != io.EOF {
+        t.Errorf("^D on an empty line: err = %v, want io.EOF", err)
+    }
+    e = &LineEditor{In: strings.NewReader("abc\x03"), Out: io.Discard}
+    if line, err := e.ReadLine(); line != "" || !errors.Is(err, ErrInterrupt) {
+        t.Errorf("^C: ReadLine = %q, %v, want ErrInterrupt", line, err)
+    }
+    e = &LineEditor{In: strings.NewReader("abc"), Out: io.Discard}
+    if _, err := e.ReadLine(); err != io.EOF {
+        t.Errorf("input ending mid line: err = %v, want io.EOF", err)
+    }
+}
+
+func TestReadLineHistory(t *testing.T) {
+    tests := []struct {
+        name    string
+        keys    string
+        want    string
+    }{
+        {"up", up + "\r", "list"},
+        {"up twice", up + up + "\r", "search -n carbone"},
+        {"past the oldest", up + up + up + up + up + "\r", "search -n [double chicken please]"},
+        {"ctrl p and n", "\x10\x10\x0e\r", "list"},
+        {"down to the draft", "new" + up + up + down + down + "\r", "new"},
+        {"edit a history line", up + "\x7f\x7f\x7f\x7fclean -i 0\r", "clean -i 0"},
+        {"search", "\x12carb\r", "search -n carbone"},
+        {"search older", "\x12search\x12\r", "search -n [double chicken please]"},
+        {"search then edit", "\x12list\x05 -s failed\r", "list -s failed"},
+        {"search backspace", "\x12listx\x7f\r", "list"},
+        {"search failing keeps the match", "\x12listz\r", "list"},
+        {"search cancel", "draft\x12carb\x07\r", "draft"},
+        {"search then down", "\x12carb" + down + "\r", "list"},
+    }
+    for _, test := range tests {
+        e := &LineEditor{In: strings.NewReader(test.keys), Out: io.Discard}
+        for _, line := range []string{"search -n [double chicken please]", "search -n carbone", "list"} {
+            e.AddHistory(line)
+        }
+        got, err := e.ReadLine()
+        if err != nil || got != test.want {
+            t.Errorf("%s: ReadLine = %q, %v, want %q", test.name, got, err, test.want)
+        }
+    }
+}
+
+func TestHistoryFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "resolved", "history")
+    e := &LineEditor{
+        HistoryFile: path,
+        HistorySize: 3,
+        Private: func(line string) (bool) { return strings.Contains(line, "-p") },
+    }
+    for _, line := range []string{"a", "", "b", "b", "login -e x -p secret", "c", "d"} {
+        if err := e.AddHistory(line); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if want := []string{"login -e x -p secret", "c", "d"}; !reflect.DeepEqual(e.history, want) {
+        t.Errorf("history = %q, want %q", e.history, want)
+    }
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(raw) != "a\nb\nc\nd\n" {
+        t.Errorf("file = %q, want the public lines", raw)
+    }
+
+    // loading trims the file down to size
+    e = &LineEditor{HistoryFile: path, HistorySize: 3}
+    if err := e.LoadHistory(); err != nil {
+        t.Fatal(err)
+    }
+    if want := []string{"b", "c", "d"}; !reflect.DeepEqual(e.history, want) {
+        t.Errorf("history = %q, want %q", e.history, want)
+    }
+    raw, _ = os.ReadFile(path)
+    if string(raw) != "b\nc\nd\n" {
+        t.Errorf("file = %q, want it trimmed", raw)
+    }
+
+    e = &LineEditor{HistoryFile: filepath.Join(t.TempDir(), "none")}
+    if err := e.LoadHistory(); err != nil || len(e.history) != 0 {
+        t.Errorf("missing file: history = %q, err = %v", e.history, err)
+    }
+}
+
+func TestReadLineComplete(t *testing.T) {
+    tests := []struct {
+        name    string
+        keys    string
+        want    string
+    }{
+        {"command", "se\t-n x\r", "search -n x"},
+        {"common prefix", "ra\tt\t\r", "rats "},
+        {"flag", "rats -v 1505 -resD\t\r", "rats -v 1505 -resD "},
+        {"enum", "rats -t o\t\r", "rats -t outdoor "},
+        {"venue name", "rats -v carb\t\r", "rats -v 1505 "},
+        {"quoted", "search -n \"Joe's P\t\r", "search -n \"Joe's Pizza\" "},
+        {"mid line", "rats  -v 1" + left + left + left + left + "-t ind\t\r", "rats  -t indoor -v 1"},
+    }
+    pc := completeParseCtx()
+    for _, test := range tests {
+        e := &LineEditor{In: strings.NewReader(test.keys), Out: io.Discard, Complete: pc.Complete}
+        got, err := e.ReadLine()
+        if err != nil || got != test.want {
+            t.Errorf("%s: ReadLine = %q, %v, want %q", test.name, got, err, test.want)
+        }
+    }
+}
+
+func TestCompletionList(t *testing.T) {
+    pc := completeParseCtx()
+    var out bytes.Buffer
+    e := &LineEditor{In: strings.NewReader("rats -v \t\r"), Out: &out, Complete: pc.Complete}
+    if _, err := e.ReadLine(); err != nil {
+        t.Fatal(err)
+    }
+    if !strings.Contains(out.String(), "\n1505  Carbone\n2000  Double Chicken Please\n") {
+        t.Errorf("output %q doesn't list the venues with their names", out.String())
+    }
+}
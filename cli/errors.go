@@ -0,0 +1,213 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "sort"
+    "strings"
+    "unicode/utf8"
+)
+
+// Suggestions further than this many edits away are dropped
+const maxSuggestDistance = 2
+
+/*
+Name: ParseError
+Type: External CLI struct
+Purpose: Say why and where a command line failed
+to parse. Err is one of the sentinel errors or a
+*FlagError, Token the token at fault and Column
+its byte offset in Input, -1 when there is no
+position. For a missing flag Token is empty and
+Column is the end of Input. Flag names the flag
+at fault, Suggestions what was maybe meant and
+Source where the value at fault was read when it
+wasn't on the line, like a config file
+*/
+type ParseError struct {
+    Err         error
+    Input       string
+    Command     string
+    Token       string
+    Column      int
+    Flag        string
+    Suggestions []string
+    Source      string
+
+    // index of the token at fault, -1 for none
+    token       int
+    // which token of Flag is at fault, 0 for the
+    // flag itself, i for argument i - 1 and -1 for none
+    arg         int
+    // how many runes of Input the token was read
+    // from, the length of Token when 0
+    width       int
+}
+
+func (e *ParseError) Error() (string) {
+    var msg string
+    switch e.Err {
+        case ErrNoCmd, ErrNoFlg:
+            if e.Token == "" {
+                msg = e.Err.Error()
+            } else {
+                msg = e.Err.Error() + ": \"" + e.Token + "\""
+            }
+        case ErrRpFlg, ErrMissReq, ErrNoArg:
+            msg = e.Err.Error() + ": -" + e.Flag
+        case ErrMulArg:
+            msg = "too many arguments for -" + e.Flag + ": \"" + e.Token + "\""
+        case ErrNoSubCmd:
+            // the suggestions are every subcommand there is
+            return e.Err.Error() + " for " + e.Command + ", want " + orList(e.Suggestions)
+        default:
+            msg = e.Err.Error()
+    }
+    if e.Source != "" {
+        msg += " (from " + e.Source + ")"
+    }
+    if len(e.Suggestions) != 0 {
+        msg += ", did you mean " + orList(e.Suggestions) + "?"
+    }
+    return msg
+}
+
+func (e *ParseError) Unwrap() (error) {
+    return e.Err
+}
+
+/*
+Name: Caret
+Type: External CLI func
+Purpose: A line to print under Input with a caret
+under the token at fault, empty when the error
+has no position
+*/
+func (e *ParseError) Caret() (string) {
+    if e.Column < 0 || e.Column > len(e.Input) {
+        return ""
+    }
+    // one space a rune so the caret lines up on screen,
+    // keeping tabs since they don't take one column
+    caret := strings.Map(func(r rune) (rune) {
+        if r == '\t' {
+            return r
+        }
+        return ' '
+    }, e.Input[:e.Column]) + "^"
+    n := e.width
+    if n == 0 {
+        n = utf8.RuneCountInString(e.Token)
+    }
+    if n > 1 {
+        caret += strings.Repeat("~", n - 1)
+    }
+    return caret
+}
+
+/*
+Name: suggestFlags
+Type: Internal CLI func
+Purpose: The flags of cmd close to token, in the
+-short or --long form that was closest
+*/
+func suggestFlags(cmd Command, token string) ([]string) {
+    word := strings.TrimLeft(token, "-")
+    forms := make([]string, 0, len(cmd.Flags))
+    for _, flag := range cmd.Flags {
+        form := "-" + flag.Name
+        if flag.LongName != "" && editDistance(word, flag.LongName) < editDistance(word, flag.Name) {
+            form = "--" + flag.LongName
+        }
+        forms = append(forms, form)
+    }
+    return suggestWith(word, forms, func(c string) (string) { return strings.TrimLeft(c, "-") })
+}
+
+/*
+Name: suggest
+Type: Internal CLI func
+Purpose: The candidates close to word by edit
+distance, closest first, at most three
+*/
+func suggest(word string, candidates []string) ([]string) {
+    return suggestWith(word, candidates, func(c string) (string) { return c })
+}
+
+/*
+Name: suggestWith
+Type: Internal CLI func
+Purpose: Like suggest, comparing word against
+key of each candidate
+*/
+func suggestWith(word string, candidates []string, key func(string) (string)) ([]string) {
+    type scored struct {
+        name    string
+        dist    int
+    }
+    matches := make([]scored, 0)
+    for _, candidate := range candidates {
+        k := key(candidate)
+        dist := editDistance(strings.ToLower(word), strings.ToLower(k))
+        // one letter flags are one edit from everything short
+        if dist <= maxSuggestDistance && dist < len(k) {
+            matches = append(matches, scored{candidate, dist})
+        }
+    }
+    sort.SliceStable(matches, func(i, j int) (bool) {
+        return matches[i].dist < matches[j].dist
+    })
+    out := make([]string, 0, 3)
+    for i := 0; i < len(matches) && i < 3; i++ {
+        out = append(out, matches[i].name)
+    }
+    return out
+}
+
+/*
+Name: editDistance
+Type: Internal CLI func
+Purpose: The Levenshtein distance between a and b,
+the fewest single letter inserts, deletes and
+swaps turning one into the other
+*/
+func editDistance(a string, b string) (int) {
+    ra, rb := []rune(a), []rune(b)
+    prev := make([]int, len(rb) + 1)
+    curr := make([]int, len(rb) + 1)
+    for j := range prev {
+        prev[j] = j
+    }
+    for i := 1; i <= len(ra); i++ {
+        curr[0] = i
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+            curr[j] = prev[j-1] + cost
+            if prev[j] + 1 < curr[j] {
+                curr[j] = prev[j] + 1
+            }
+            if curr[j-1] + 1 < curr[j] {
+                curr[j] = curr[j-1] + 1
+            }
+        }
+        prev, curr = curr, prev
+    }
+    return prev[len(rb)]
+}
+
+/*
+Name: orList
+Type: Internal CLI func
+Purpose: Join words like "a, b or c"
+*/
+func orList(words []string) (string) {
+    if len(words) == 1 {
+        return words[0]
+    }
+    return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestParseErrorPosition(t *testing.T) {
+    pc := typedParseCtx()
+    pc.Commands = append(pc.Commands, Command{
+        Name: "search",
+        Flags: []Flag{
+            Flag{Name: "n", LongName: "name", ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1, Required: true}},
+            Flag{Name: "l", LongName: "limit", ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1}},
+        },
+    })
+    tests := []struct {
+        line        string
+        err         error
+        token       string
+        column      int
+        suggestions []string
+    }{
+        {"serch -n x", ErrNoCmd, "serch", 0, []string{"search"}},
+        {"search -nme x", ErrNoFlg, "-nme", 7, []string{"--name"}},
+        // an unknown flag after a known one reads as its argument
+        {"search -n x -lmit 5", ErrMulArg, "-lmit", 12, []string{"--limit"}},
+        {"search -n x -n y", ErrRpFlg, "-n", 12, nil},
+        {"search -l 5", ErrMissReq, "", 11, nil},
+        {"search -n [a b", ErrNoGrp, "[", 10, nil},
+        {"typed -n 5 -n", ErrRpFlg, "-n", 11, nil},
+        {"typed -c garden", ErrNoChoice, "garden", 9, nil},
+    }
+    for _, test := range tests {
+        _, _, err := pc.Resolve(test.line)
+        var perr *ParseError
+        if !errors.As(err, &perr) {
+            t.Errorf("%q: err = %v, want a ParseError", test.line, err)
+            continue
+        }
+        if !errors.Is(err, test.err) {
+            t.Errorf("%q: err = %v, want %v", test.line, err, test.err)
+        }
+        if perr.Token != test.token || perr.Column != test.column {
+            t.Errorf("%q: at %q column %d, want %q column %d", test.line, perr.Token, perr.Column, test.token, test.column)
+        }
+        if len(perr.Suggestions) != 0 || len(test.suggestions) != 0 {
+            if !reflect.DeepEqual(perr.Suggestions, test.suggestions) {
+                t.Errorf("%q: suggestions = %v, want %v", test.line, perr.Suggestions, test.suggestions)
+            }
+        }
+    }
+}
+
+func TestParseErrorCaret(t *testing.T) {
+    perr := &ParseError{Err: ErrNoFlg, Input: "search -nme x", Token: "-nme", Column: 7}
+    if got, want := perr.Caret(), "       ^~~~"; got != want {
+        t.Errorf("Caret = %q, want %q", got, want)
+    }
+    // columns count runes, not bytes
+    perr = &ParseError{Err: ErrNoFlg, Input: "é x", Token: "x", Column: 3}
+    if got, want := perr.Caret(), "  ^"; got != want {
+        t.Errorf("Caret = %q, want %q", got, want)
+    }
+    perr.Column = -1
+    if perr.Caret() != "" {
+        t.Error("an error without a position should have no caret")
+    }
+}
+
+func TestEditDistance(t *testing.T) {
+    tests := []struct {
+        a, b    string
+        want    int
+    }{
+        {"", "abc", 3},
+        {"serch", "search", 1},
+        {"kitten", "sitting", 3},
+        {"same", "same", 0},
+    }
+    for _, test := range tests {
+        if got := editDistance(test.a, test.b); got != test.want {
+            t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+        }
+    }
+}
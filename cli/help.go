@@ -0,0 +1,473 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "io"
+    "strconv"
+    "strings"
+)
+
+// the column help text is wrapped at
+const helpWidth = 76
+
+// how each type reads in help
+var typeNames = map[FlagType]string{
+    StringType: "text",
+    IntType: "whole number",
+    DurationType: "duration, hh:mm or like 90s",
+    DateType: "date, yyyy:mm:dd",
+    TimeOfDayType: "time of day, hh:mm",
+    DateTimeType: "date and time, yyyy:mm:dd:hh:mm",
+    EnumType: "choice",
+    EmailType: "email address",
+}
+
+/*
+Name: Help
+Type: External CLI func
+Purpose: The help of the command named by name,
+like "op list" or an alias, laid out like a man
+page with its synopsis, aliases, subcommands,
+flags and examples. An empty name gives every
+command with its description
+*/
+func (pc *ParseCtx) Help(name string) (string, error) {
+    if strings.TrimSpace(name) == "" {
+        return pc.overview(), nil
+    }
+    chain, perr := pc.lookup(name)
+    if perr != nil {
+        return "", perr
+    }
+    cmd := chain[len(chain)-1]
+    path := chainPath(chain)
+    flagged := pc.withInherited(chain)
+
+    var b strings.Builder
+    b.WriteString("NAME\n")
+    writeWrapped(&b, path + " - " + cmd.Description, 4)
+    b.WriteString("\nSYNOPSIS\n")
+    for _, parts := range usages(path, flagged) {
+        writeUsage(&b, parts)
+    }
+    if len(cmd.Aliases) != 0 {
+        b.WriteString("\nALIASES\n")
+        b.WriteString("    " + strings.Join(cmd.Aliases, ", ") + "\n")
+    }
+    if len(cmd.Subcommands) != 0 {
+        b.WriteString("\nCOMMANDS\n")
+        writeIndex(&b, cmd.Subcommands, "")
+    }
+    if len(flagged.Flags) != 0 {
+        b.WriteString("\nFLAGS\n")
+        for _, flag := range flagged.Flags {
+            b.WriteString("    " + flagNames(flag) + "\n")
+            writeWrapped(&b, flagSummary(flag), 8)
+            if flag.Description != "" {
+                writeWrapped(&b, flag.Description, 8)
+            }
+        }
+    }
+    if len(cmd.Examples) != 0 {
+        b.WriteString("\nEXAMPLES\n")
+        for _, example := range cmd.Examples {
+            b.WriteString("    " + example + "\n")
+        }
+    }
+    return b.String(), nil
+}
+
+/*
+Name: overview
+Type: Internal CLI func
+Purpose: Every command with its description, one
+per line
+*/
+func (pc *ParseCtx) overview() (string) {
+    var b strings.Builder
+    b.WriteString("COMMANDS\n")
+    paths := make([]string, 0)
+    cmds := make([]*Command, 0)
+    pc.Walk(func(path string, cmd *Command) {
+        paths = append(paths, path)
+        cmds = append(cmds, cmd)
+    })
+    writeColumns(&b, paths, cmds)
+    return b.String()
+}
+
+/*
+Name: writeIndex
+Type: Internal CLI func
+Purpose: Write cmds one per line with their
+descriptions, named after parent
+*/
+func writeIndex(b *strings.Builder, cmds []Command, parent string) {
+    paths := make([]string, len(cmds))
+    ptrs := make([]*Command, len(cmds))
+    for i := range cmds {
+        paths[i] = strings.TrimSpace(parent + " " + cmds[i].Name)
+        ptrs[i] = &cmds[i]
+    }
+    writeColumns(b, paths, ptrs)
+}
+
+/*
+Name: writeColumns
+Type: Internal CLI func
+Purpose: Write each path with the aliases of its
+command, then the descriptions lined up after
+*/
+func writeColumns(b *strings.Builder, paths []string, cmds []*Command) {
+    names := make([]string, len(paths))
+    width := 0
+    for i, path := range paths {
+        names[i] = strings.Join(append([]string{path}, cmds[i].Aliases...), ", ")
+        if len(names[i]) > width {
+            width = len(names[i])
+        }
+    }
+    for i, name := range names {
+        b.WriteString("    " + name + strings.Repeat(" ", width - len(name) + 2) + cmds[i].Description + "\n")
+    }
+}
+
+/*
+Name: synopsis
+Type: Internal CLI func
+Purpose: The usage lines of cmd, one with its
+flags when it runs on its own and one with its
+subcommands when it has any. Flags left out of
+brackets are required
+*/
+func synopsis(path string, cmd Command) ([]string) {
+    lines := make([]string, 0, 2)
+    for _, parts := range usages(path, cmd) {
+        lines = append(lines, strings.Join(parts, " "))
+    }
+    return lines
+}
+
+/*
+Name: writeUsage
+Type: Internal CLI func
+Purpose: Write a usage line wrapped at helpWidth,
+never inside a flag, with the lines after the
+first indented under the command
+*/
+func writeUsage(b *strings.Builder, parts []string) {
+    line := "    " + parts[0]
+    for _, part := range parts[1:] {
+        if len(line) + 1 + len(part) > helpWidth {
+            b.WriteString(line + "\n")
+            line = "        " + part
+            continue
+        }
+        line += " " + part
+    }
+    b.WriteString(line + "\n")
+}
+
+/*
+Name: usages
+Type: Internal CLI func
+Purpose: The usage lines of cmd split into the
+command and each flag
+*/
+func usages(path string, cmd Command) ([][]string) {
+    lines := make([][]string, 0, 2)
+    if cmd.Handler != nil || len(cmd.Subcommands) == 0 {
+        parts := []string{path}
+        if flag := positional(cmd); flag != nil {
+            parts = append(parts, bracket(*flag, strings.TrimSpace(argHint(*flag))))
+        }
+        for _, flag := range cmd.Flags {
+            if !flag.Positional {
+                parts = append(parts, bracket(flag, "-" + flag.Name + argHint(flag)))
+            }
+        }
+        lines = append(lines, parts)
+    }
+    if len(cmd.Subcommands) != 0 {
+        names := make([]string, len(cmd.Subcommands))
+        for i, sub := range cmd.Subcommands {
+            names[i] = sub.Name
+        }
+        lines = append(lines, []string{path, "<" + strings.Join(names, "|") + ">", "..."})
+    }
+    return lines
+}
+
+/*
+Name: bracket
+Type: Internal CLI func
+Purpose: Put usage in brackets unless flag
+is required
+*/
+func bracket(flag Flag, usage string) (string) {
+    if flag.ValidationCtx.Required {
+        return usage
+    }
+    return "[" + usage + "]"
+}
+
+/*
+Name: argHint
+Type: Internal CLI func
+Purpose: What follows a flag in a synopsis, its
+long name standing in for its arguments
+*/
+func argHint(flag Flag) (string) {
+    ctx := flag.ValidationCtx
+    if ctx.MaxArgs == 0 {
+        return ""
+    }
+    hint := flag.LongName
+    if hint == "" {
+        hint = flag.Name
+    }
+    if ctx.MaxArgs != 1 {
+        hint += "..."
+    }
+    if ctx.MinArgs == 0 {
+        return " [" + hint + "]"
+    }
+    return " " + hint
+}
+
+/*
+Name: flagNames
+Type: Internal CLI func
+Purpose: Both names of flag as they are typed
+*/
+func flagNames(flag Flag) (string) {
+    names := "-" + flag.Name
+    if flag.LongName != "" {
+        names += ", --" + flag.LongName
+    }
+    return names
+}
+
+/*
+Name: flagSummary
+Type: Internal CLI func
+Purpose: Whether flag is required, how many
+arguments it takes and of what, its default and
+the setting it's read from
+*/
+func flagSummary(flag Flag) (string) {
+    ctx := flag.ValidationCtx
+    parts := []string{"optional"}
+    if ctx.Required {
+        parts[0] = "required"
+    }
+    parts = append(parts, argCount(ctx))
+    if ctx.MaxArgs != 0 {
+        parts = append(parts, valueKind(flag))
+    }
+    if flag.Default != "" {
+        parts = append(parts, "default " + flag.Default)
+    }
+    if flag.Setting != "" {
+        parts = append(parts, "from setting " + flag.Setting)
+    }
+    if flag.Positional {
+        parts = append(parts, "can be given first without the flag")
+    }
+    return strings.Join(parts, ", ")
+}
+
+/*
+Name: argCount
+Type: Internal CLI func
+Purpose: How many arguments a flag takes, in words
+*/
+func argCount(ctx FlagValidationCtx) (string) {
+    min := strconv.Itoa(ctx.MinArgs)
+    switch {
+        case ctx.MaxArgs == 0:
+            return "no value"
+        case ctx.MinArgs == ctx.MaxArgs && ctx.MaxArgs == 1:
+            return "1 value"
+        case ctx.MinArgs == ctx.MaxArgs:
+            return min + " values"
+        case ctx.MaxArgs == InfiniteArgs:
+            return min + " or more values"
+    }
+    return min + " to " + strconv.Itoa(ctx.MaxArgs) + " values"
+}
+
+/*
+Name: valueKind
+Type: Internal CLI func
+Purpose: What each argument of flag holds, with
+its bounds or choices
+*/
+func valueKind(flag Flag) (string) {
+    ctx := flag.ValidationCtx
+    kind := typeNames[ctx.Type]
+    switch {
+        case ctx.Type == IntType && ctx.Range != nil && ctx.Range.Max == NoMax:
+            kind += " from " + strconv.Itoa(ctx.Range.Min)
+        case ctx.Type == IntType && ctx.Range != nil:
+            kind += " from " + strconv.Itoa(ctx.Range.Min) + " to " + strconv.Itoa(ctx.Range.Max)
+        case ctx.Type == EnumType && len(ctx.Choices) != 0:
+            kind = "one of " + orList(ctx.Choices)
+    }
+    return kind
+}
+
+/*
+Name: writeWrapped
+Type: Internal CLI func
+Purpose: Write text wrapped at helpWidth, every
+line indented by indent spaces
+*/
+func writeWrapped(b *strings.Builder, text string, indent int) {
+    pad := strings.Repeat(" ", indent)
+    line := ""
+    for _, word := range strings.Fields(text) {
+        if line != "" && indent + len(line) + 1 + len(word) > helpWidth {
+            b.WriteString(pad + line + "\n")
+            line = ""
+        }
+        if line != "" {
+            line += " "
+        }
+        line += word
+    }
+    if line != "" {
+        b.WriteString(pad + line + "\n")
+    }
+}
+
+/*
+Name: WriteMarkdown
+Type: External CLI func
+Purpose: Write the help of every command to w as
+markdown under a heading of title, for a README
+or a docs page generated from the commands
+*/
+func (pc *ParseCtx) WriteMarkdown(w io.Writer, title string) (error) {
+    var b strings.Builder
+    b.WriteString("# " + title + "\n")
+    pc.Walk(func(path string, cmd *Command) {
+        flagged := pc.withInheritedPath(path)
+        b.WriteString("\n## `" + path + "`\n\n")
+        if cmd.Description != "" {
+            b.WriteString(cmd.Description + "\n\n")
+        }
+        b.WriteString("```\n" + strings.Join(synopsis(path, flagged), "\n") + "\n```\n")
+        if len(cmd.Aliases) != 0 {
+            b.WriteString("\nAlso called `" + strings.Join(cmd.Aliases, "`, `") + "`.\n")
+        }
+        if len(cmd.Subcommands) != 0 {
+            b.WriteString("\n")
+            for _, sub := range cmd.Subcommands {
+                b.WriteString("- `" + path + " " + sub.Name + "`: " + sub.Description + "\n")
+            }
+        }
+        if len(flagged.Flags) != 0 {
+            b.WriteString("\n")
+            for _, flag := range flagged.Flags {
+                names := "`-" + flag.Name + "`"
+                if flag.LongName != "" {
+                    names += ", `--" + flag.LongName + "`"
+                }
+                b.WriteString("- " + names + " (" + flagSummary(flag) + ")")
+                if flag.Description != "" {
+                    b.WriteString(": " + flag.Description)
+                }
+                b.WriteString("\n")
+            }
+        }
+        if len(cmd.Examples) != 0 {
+            b.WriteString("\n```\n" + strings.Join(cmd.Examples, "\n") + "\n```\n")
+        }
+    })
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+/*
+Name: WriteMan
+Type: External CLI func
+Purpose: Write the help of every command to w as
+a man page in section 1 for the program name,
+with title as the line under NAME
+*/
+func (pc *ParseCtx) WriteMan(w io.Writer, name string, title string) (error) {
+    var b strings.Builder
+    b.WriteString(".TH " + manEscape(strings.ToUpper(name)) + " 1\n")
+    b.WriteString(".SH NAME\n" + manEscape(name) + " \\- " + manEscape(title) + "\n")
+    b.WriteString(".SH COMMANDS\n")
+    pc.Walk(func(path string, cmd *Command) {
+        flagged := pc.withInheritedPath(path)
+        b.WriteString(".SS " + manEscape(path) + "\n")
+        if cmd.Description != "" {
+            b.WriteString(manLine(cmd.Description))
+        }
+        b.WriteString(".PP\n.nf\n")
+        for _, line := range synopsis(path, flagged) {
+            b.WriteString(manLine(line))
+        }
+        b.WriteString(".fi\n")
+        if len(cmd.Aliases) != 0 {
+            b.WriteString(".PP\n" + manLine("Also called " + strings.Join(cmd.Aliases, ", ") + "."))
+        }
+        for _, flag := range flagged.Flags {
+            b.WriteString(".TP\n.B " + manEscape(flagNames(flag)) + "\n")
+            b.WriteString(manLine(flagSummary(flag) + "."))
+            if flag.Description != "" {
+                b.WriteString(".br\n" + manLine(flag.Description))
+            }
+        }
+        if len(cmd.Examples) != 0 {
+            b.WriteString(".PP\n.nf\n")
+            for _, example := range cmd.Examples {
+                b.WriteString(manLine(example))
+            }
+            b.WriteString(".fi\n")
+        }
+    })
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+/*
+Name: withInheritedPath
+Type: Internal CLI func
+Purpose: The command at path, a path Walk gave,
+with the flags it inherits
+*/
+func (pc *ParseCtx) withInheritedPath(path string) (Command) {
+    return pc.withInherited(pc.descend(strings.Fields(path)))
+}
+
+/*
+Name: manEscape
+Type: Internal CLI func
+Purpose: Keep troff from reading s as anything
+but text
+*/
+func manEscape(s string) (string) {
+    s = strings.ReplaceAll(s, "\\", "\\e")
+    return strings.ReplaceAll(s, "-", "\\-")
+}
+
+/*
+Name: manLine
+Type: Internal CLI func
+Purpose: s as a line of a man page, kept from
+reading as a request when it starts with a dot
+*/
+func manLine(s string) (string) {
+    s = manEscape(s)
+    if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+        s = "\\&" + s
+    }
+    return s + "\n"
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func helpParseCtx() (*ParseCtx) {
+    pc := groupedParseCtx()
+    pc.Commands = append(pc.Commands, Command{
+        Name: "help",
+        Description: "Shows help",
+        Flags: []Flag{
+            Flag{Name: "c", LongName: "command", Positional: true, ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: InfiniteArgs}},
+            Flag{Name: "f", LongName: "format", Default: "text", ValidationCtx: FlagValidationCtx{
+                Type: EnumType, Choices: []string{"text", "markdown"}, MinArgs: 1, MaxArgs: 1,
+            }},
+        },
+        Examples: []string{"help op list"},
+        Handler: func(in Values) (interface{}, error) { return nil, nil },
+    })
+    pc.Commands[0].Description = "Manage operations"
+    pc.Commands[0].Subcommands[0].Description = "List operations"
+    return pc
+}
+
+func TestPositionalFlag(t *testing.T) {
+    pc := helpParseCtx()
+    tests := []struct {
+        line    string
+        want    Values
+    }{
+        {"help op list", Values{"c": {"op", "list"}}},
+        {"help op -f m", Values{"c": {"op"}, "f": {"markdown"}}},
+        {"help -c op", Values{"c": {"op"}}},
+        {"help", Values{}},
+    }
+    for _, test := range tests {
+        _, got, err := pc.Resolve(test.line)
+        if err != nil || !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%q: flags = %v, %v, want %v", test.line, got, err, test.want)
+        }
+    }
+    if _, _, err := pc.Resolve("help op -c list"); !errors.Is(err, ErrRpFlg) {
+        t.Errorf("positional words and the flag: err = %v, want ErrRpFlg", err)
+    }
+    // commands without a positional flag still want a flag first
+    if _, _, err := pc.Resolve("quit now"); !errors.Is(err, ErrNoFlg) {
+        t.Errorf("stray word: err = %v, want ErrNoFlg", err)
+    }
+}
+
+func TestHelp(t *testing.T) {
+    pc := helpParseCtx()
+    got, err := pc.Help("op ls")
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := `NAME
+    op list - List operations
+
+SYNOPSIS
+    op list [-s status...] [-v]
+
+ALIASES
+    ls
+
+FLAGS
+    -s, --status
+        optional, 1 or more values, text
+    -v, --verbose
+        optional, no value
+`
+    if got != want {
+        t.Errorf("Help = %q, want %q", got, want)
+    }
+
+    got, _ = pc.Help("help")
+    for _, line := range []string{
+        "    help [command...] [-f format]\n",
+        "        optional, 1 or more values, text, can be given first without the\n        flag\n",
+        "        optional, 1 value, one of text or markdown, default text\n",
+        "EXAMPLES\n    help op list\n",
+    } {
+        if !strings.Contains(got, line) {
+            t.Errorf("Help(help) = %q, want it to contain %q", got, line)
+        }
+    }
+
+    got, _ = pc.Help("op")
+    if !strings.Contains(got, "    op <list|cancel> ...\n") || !strings.Contains(got, "    list, ls  List operations\n") {
+        t.Errorf("Help(op) = %q, want the subcommands", got)
+    }
+    got, _ = pc.Help("recur")
+    if !strings.Contains(got, "    recur -wd weekdays...\n    recur <rm> ...\n") {
+        t.Errorf("Help(recur) = %q, want both usages", got)
+    }
+    got, _ = pc.Help("")
+    if !strings.HasPrefix(got, "COMMANDS\n    op           Manage operations\n    op list, ls  List operations\n") {
+        t.Errorf("Help() = %q, want every command", got)
+    }
+
+    _, err = pc.Help("op lst")
+    var perr *ParseError
+    if !errors.As(err, &perr) || perr.Command != "op" || !reflect.DeepEqual(perr.Suggestions, []string{"list", "ls"}) {
+        t.Errorf("Help(op lst) err = %v, want a suggestion", err)
+    }
+}
+
+func TestHelpWraps(t *testing.T) {
+    long := Flag{Name: "x", LongName: strings.Repeat("x", 30), ValidationCtx: FlagValidationCtx{MinArgs: 1, MaxArgs: 1}}
+    pc := &ParseCtx{Commands: []Command{
+        Command{
+            Name: "wide",
+            Flags: []Flag{long, long, long},
+        },
+    }}
+    pc.Commands[0].Flags[0].Description = strings.Repeat("word ", 40)
+    got, _ := pc.Help("wide")
+    for _, line := range strings.Split(got, "\n") {
+        if len(line) > helpWidth {
+            t.Errorf("line %q is wider than %d", line, helpWidth)
+        }
+    }
+    if !strings.Contains(got, "\n        [-x " + long.LongName + "]\n") {
+        t.Errorf("Help = %q, want the synopsis wrapped between flags", got)
+    }
+}
+
+func TestArgCount(t *testing.T) {
+    tests := []struct {
+        ctx     FlagValidationCtx
+        want    string
+    }{
+        {FlagValidationCtx{}, "no value"},
+        {FlagValidationCtx{MinArgs: 1, MaxArgs: 1}, "1 value"},
+        {FlagValidationCtx{MinArgs: 2, MaxArgs: 2}, "2 values"},
+        {FlagValidationCtx{MinArgs: 1, MaxArgs: InfiniteArgs}, "1 or more values"},
+        {FlagValidationCtx{MinArgs: 0, MaxArgs: 3}, "0 to 3 values"},
+    }
+    for _, test := range tests {
+        if got := argCount(test.ctx); got != test.want {
+            t.Errorf("argCount(%+v) = %q, want %q", test.ctx, got, test.want)
+        }
+    }
+}
+
+func TestWriteMarkdown(t *testing.T) {
+    pc := helpParseCtx()
+    var b strings.Builder
+    if err := pc.WriteMarkdown(&b, "Commands"); err != nil {
+        t.Fatal(err)
+    }
+    got := b.String()
+    for _, part := range []string{
+        "# Commands\n\n## `op`\n",
+        "\n## `op list`\n\nList operations\n\n```\nop list [-s status...] [-v]\n```\n\nAlso called `ls`.\n",
+        "- `op list`: List operations\n",
+        "- `-f`, `--format` (optional, 1 value, one of text or markdown, default text)\n",
+        "\n```\nhelp op list\n```\n",
+    } {
+        if !strings.Contains(got, part) {
+            t.Errorf("markdown %q doesn't contain %q", got, part)
+        }
+    }
+}
+
+func TestWriteMan(t *testing.T) {
+    pc := helpParseCtx()
+    var b strings.Builder
+    if err := pc.WriteMan(&b, "resolved", "books tables"); err != nil {
+        t.Fatal(err)
+    }
+    got := b.String()
+    for _, part := range []string{
+        ".TH RESOLVED 1\n.SH NAME\nresolved \\- books tables\n",
+        ".SS op list\nList operations\n.PP\n.nf\nop list [\\-s status...] [\\-v]\n.fi\n",
+        ".TP\n.B \\-s, \\-\\-status\noptional, 1 or more values, text.\n",
+    } {
+        if !strings.Contains(got, part) {
+            t.Errorf("man page %q doesn't contain %q", got, part)
+        }
+    }
+    if got := manLine(".dot \\ slash"); got != "\\&.dot \\e slash\n" {
+        t.Errorf("manLine = %q", got)
+    }
+}
+
+func TestCompletePositional(t *testing.T) {
+    pc := helpParseCtx()
+    pc.Commands[len(pc.Commands)-1].Flags[0].Complete = func(prefix string) ([]Completion) {
+        return []Completion{{Value: "op"}}
+    }
+    _, comps := pc.Complete("help ")
+    if len(comps) != 1 || comps[0].Value != "op" {
+        t.Errorf("Complete = %v, want the positional values", comps)
+    }
+    _, comps = pc.Complete("help op -")
+    if len(comps) != 1 || comps[0].Value != "-f" {
+        t.Errorf("Complete = %v, want -f but not -c", comps)
+    }
+}
@@ -0,0 +1,438 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// The formats Render takes
+const (
+    TextOutput = "text"
+    JSONOutput = "json"
+    YAMLOutput = "yaml"
+    TableOutput = "table"
+)
+
+// Every format Render takes, for choices and help
+var Outputs = []string{TextOutput, JSONOutput, YAMLOutput, TableOutput}
+
+var (
+    ErrOutput = errors.New("unknown output format")
+)
+
+/*
+Name: Texter
+Type: External CLI interface
+Purpose: Implemented by results with a form
+written for people, which the text format
+shows instead of their fields
+*/
+type Texter interface {
+    Text() (string)
+}
+
+/*
+Name: Tabler
+Type: External CLI interface
+Purpose: Implemented by results that pick their
+own columns for the table format, instead of
+one per field
+*/
+type Tabler interface {
+    Table() (header []string, rows [][]string)
+}
+
+/*
+Name: ParseOutput
+Type: External CLI func
+Purpose: The format of Outputs name stands for,
+ignoring case and taking a prefix of one
+*/
+func ParseOutput(name string) (string, error) {
+    format, ok := matchChoice(name, Outputs)
+    if !ok {
+        return "", fmt.Errorf("%w %q, want one of %s", ErrOutput, name, strings.Join(Outputs, ", "))
+    }
+    return format, nil
+}
+
+/*
+Name: Render
+Type: External CLI func
+Purpose: Write the result of a Handler in format,
+one of Outputs or empty for text. Text is what
+a Texter or Stringer gives, the string itself or
+else YAML. JSON and YAML come from encoding/json,
+so json tags name the fields, and tables have a
+row per item of a list with a column per field,
+or a row per field of anything else. Nothing
+renders as nothing
+*/
+func Render(result interface{}, format string) (string, error) {
+    if result == nil {
+        return "", nil
+    }
+    if format == "" {
+        format = TextOutput
+    }
+    switch format {
+        case TextOutput:
+            switch r := result.(type) {
+                case Texter:
+                    return r.Text(), nil
+                case string:
+                    return r, nil
+                case fmt.Stringer:
+                    return r.String(), nil
+            }
+            return Render(result, YAMLOutput)
+        case JSONOutput:
+            data, err := json.MarshalIndent(result, "", "  ")
+            if err != nil {
+                return "", err
+            }
+            return string(data), nil
+        case YAMLOutput:
+            n, err := toNode(result)
+            if err != nil {
+                return "", err
+            }
+            return strings.Join(n.yaml(), "\n"), nil
+        case TableOutput:
+            if r, ok := result.(Tabler); ok {
+                header, rows := r.Table()
+                return writeTable(header, rows), nil
+            }
+            if s, ok := result.(string); ok {
+                return s, nil
+            }
+            n, err := toNode(result)
+            if err != nil {
+                return "", err
+            }
+            header, rows := n.table()
+            return writeTable(header, rows), nil
+    }
+    return "", fmt.Errorf("%w %q, want one of %s", ErrOutput, format, strings.Join(Outputs, ", "))
+}
+
+/*
+Name: node
+Type: Internal CLI struct
+Purpose: A decoded JSON value keeping the order
+of object keys, which maps would lose. Scalars
+keep the JSON they were written as
+*/
+type node struct {
+    // one of '{', '[' or 0 for a scalar
+    kind    byte
+    keys    []string
+    items   []node
+    scalar  string
+}
+
+/*
+Name: toNode
+Type: Internal CLI func
+Purpose: Encode v as JSON and read it back as
+a node
+*/
+func toNode(v interface{}) (node, error) {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return node{}, err
+    }
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    return decodeNode(dec)
+}
+
+/*
+Name: decodeNode
+Type: Internal CLI func
+Purpose: Read the next JSON value of dec as a
+node
+*/
+func decodeNode(dec *json.Decoder) (node, error) {
+    tok, err := dec.Token()
+    if err != nil {
+        return node{}, err
+    }
+    delim, ok := tok.(json.Delim)
+    if !ok {
+        return scalarNode(tok), nil
+    }
+    n := node{kind: byte(delim)}
+    for dec.More() {
+        if n.kind == '{' {
+            key, err := dec.Token()
+            if err != nil {
+                return node{}, err
+            }
+            n.keys = append(n.keys, key.(string))
+        }
+        item, err := decodeNode(dec)
+        if err != nil {
+            return node{}, err
+        }
+        n.items = append(n.items, item)
+    }
+    // the closing delimiter
+    if _, err := dec.Token(); err != nil {
+        return node{}, err
+    }
+    return n, nil
+}
+
+/*
+Name: scalarNode
+Type: Internal CLI func
+Purpose: A node for a JSON token that isn't a
+delimiter
+*/
+func scalarNode(tok json.Token) (node) {
+    switch t := tok.(type) {
+        case string:
+            data, _ := json.Marshal(t)
+            return node{scalar: string(data)}
+        case json.Number:
+            return node{scalar: t.String()}
+        case bool:
+            return node{scalar: strconv.FormatBool(t)}
+    }
+    return node{scalar: "null"}
+}
+
+/*
+Name: flat
+Type: Internal CLI func
+Purpose: Report whether n is a scalar or has
+nothing in it, so it fits on one line
+*/
+func (n node) flat() (bool) {
+    return n.kind == 0 || len(n.items) == 0
+}
+
+/*
+Name: yamlFlat
+Type: Internal CLI func
+Purpose: n on one line, for a node flat
+reports
+*/
+func (n node) yamlFlat() (string) {
+    switch n.kind {
+        case '{':
+            return "{}"
+        case '[':
+            return "[]"
+    }
+    if !strings.HasPrefix(n.scalar, "\"") {
+        return n.scalar
+    }
+    var s string
+    json.Unmarshal([]byte(n.scalar), &s)
+    return yamlString(s)
+}
+
+/*
+Name: yaml
+Type: Internal CLI func
+Purpose: The lines of n as a YAML block
+*/
+func (n node) yaml() ([]string) {
+    if n.flat() {
+        return []string{n.yamlFlat()}
+    }
+    lines := make([]string, 0)
+    for i, item := range n.items {
+        if n.kind == '[' {
+            for j, line := range item.yaml() {
+                if j == 0 {
+                    lines = append(lines, "- " + line)
+                } else {
+                    lines = append(lines, "  " + line)
+                }
+            }
+            continue
+        }
+        key := yamlString(n.keys[i])
+        if item.flat() {
+            lines = append(lines, key + ": " + item.yamlFlat())
+            continue
+        }
+        lines = append(lines, key + ":")
+        for _, line := range item.yaml() {
+            lines = append(lines, "  " + line)
+        }
+    }
+    return lines
+}
+
+/*
+Name: yamlString
+Type: Internal CLI func
+Purpose: s as a YAML scalar, quoted when it would
+read as something else or break the layout
+*/
+func yamlString(s string) (string) {
+    quote := s == "" || strings.TrimSpace(s) != s ||
+        strings.ContainsAny(s, "\n\t\"'\\#{}[],&*!|>%@`") ||
+        strings.Contains(s, ": ") || strings.HasSuffix(s, ":") ||
+        strings.HasPrefix(s, "- ") || s == "-" || s == "?" || strings.HasPrefix(s, "? ")
+    switch strings.ToLower(s) {
+        case "true", "false", "yes", "no", "on", "off", "null", "~":
+            quote = true
+    }
+    if _, err := strconv.ParseFloat(s, 64); err == nil {
+        quote = true
+    }
+    if !quote {
+        return s
+    }
+    data, _ := json.Marshal(s)
+    return string(data)
+}
+
+/*
+Name: cell
+Type: Internal CLI func
+Purpose: n in a table cell, strings bare and
+anything else as JSON on one line
+*/
+func (n node) cell() (string) {
+    var s string
+    if n.kind == 0 && json.Unmarshal([]byte(n.scalar), &s) == nil {
+        return s
+    }
+    if n.kind == 0 {
+        return n.scalar
+    }
+    var b strings.Builder
+    n.writeJSON(&b)
+    return b.String()
+}
+
+/*
+Name: writeJSON
+Type: Internal CLI func
+Purpose: Write n as JSON on one line
+*/
+func (n node) writeJSON(b *strings.Builder) {
+    if n.kind == 0 {
+        b.WriteString(n.scalar)
+        return
+    }
+    b.WriteByte(n.kind)
+    for i, item := range n.items {
+        if i != 0 {
+            b.WriteByte(',')
+        }
+        if n.kind == '{' {
+            key, _ := json.Marshal(n.keys[i])
+            b.Write(key)
+            b.WriteByte(':')
+        }
+        item.writeJSON(b)
+    }
+    if n.kind == '{' {
+        b.WriteByte('}')
+    } else {
+        b.WriteByte(']')
+    }
+}
+
+/*
+Name: table
+Type: Internal CLI func
+Purpose: The header and rows of n as a table. A
+list of objects gets a column per key in the
+order they're first seen, other lists a single
+column and an object a row per key
+*/
+func (n node) table() ([]string, [][]string) {
+    switch {
+        case n.kind == '[' && len(n.items) != 0 && n.items[0].kind == '{':
+            header := make([]string, 0)
+            column := make(map[string]int)
+            for _, item := range n.items {
+                for _, key := range item.keys {
+                    if _, ok := column[key]; !ok {
+                        column[key] = len(header)
+                        header = append(header, key)
+                    }
+                }
+            }
+            rows := make([][]string, len(n.items))
+            for i, item := range n.items {
+                rows[i] = make([]string, len(header))
+                for j, key := range item.keys {
+                    rows[i][column[key]] = item.items[j].cell()
+                }
+            }
+            return header, rows
+        case n.kind == '[':
+            rows := make([][]string, len(n.items))
+            for i, item := range n.items {
+                rows[i] = []string{item.cell()}
+            }
+            return []string{"value"}, rows
+        case n.kind == '{':
+            rows := make([][]string, len(n.items))
+            for i, item := range n.items {
+                rows[i] = []string{n.keys[i], item.cell()}
+            }
+            return []string{"key", "value"}, rows
+    }
+    return []string{"value"}, [][]string{{n.cell()}}
+}
+
+/*
+Name: writeTable
+Type: Internal CLI func
+Purpose: Lay out header, in capitals, and rows in
+columns two spaces apart
+*/
+func writeTable(header []string, rows [][]string) (string) {
+    clean := strings.NewReplacer("\n", " ", "\t", " ")
+    all := make([][]string, 0, len(rows) + 1)
+    all = append(all, make([]string, len(header)))
+    for j, name := range header {
+        all[0][j] = strings.ToUpper(name)
+    }
+    for _, row := range rows {
+        cells := make([]string, len(header))
+        for j := 0; j < len(row) && j < len(cells); j++ {
+            cells[j] = clean.Replace(row[j])
+        }
+        all = append(all, cells)
+    }
+    widths := make([]int, len(header))
+    for _, row := range all {
+        for j, cell := range row {
+            if w := utf8.RuneCountInString(cell); w > widths[j] {
+                widths[j] = w
+            }
+        }
+    }
+    lines := make([]string, len(all))
+    for i, row := range all {
+        var b strings.Builder
+        for j, cell := range row {
+            b.WriteString(cell)
+            if j != len(row) - 1 {
+                b.WriteString(strings.Repeat(" ", widths[j] - utf8.RuneCountInString(cell) + 2))
+            }
+        }
+        lines[i] = strings.TrimRight(b.String(), " ")
+    }
+    return strings.Join(lines, "\n")
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+    "errors"
+    "testing"
+)
+
+type venue struct {
+    ID      int64       `json:"id"`
+    Name    string      `json:"name"`
+    Tags    []string    `json:"tags,omitempty"`
+}
+
+type venues []venue
+
+func (v venues) Text() (string) {
+    return "Venues: " + v[0].Name
+}
+
+type tabled struct{}
+
+func (tabled) Table() ([]string, [][]string) {
+    return []string{"name", "size"}, [][]string{{"carbone", "2"}, {"lilia\tnyc", "10"}}
+}
+
+func TestRender(t *testing.T) {
+    list := venues{{ID: 1, Name: "carbone", Tags: []string{"italian", "true"}}, {ID: 20, Name: "lilia: nyc"}}
+    tests := []struct {
+        result  interface{}
+        format  string
+        want    string
+    }{
+        {nil, JSONOutput, ""},
+        {"done", "", "done"},
+        {"done", JSONOutput, `"done"`},
+        {"done", TableOutput, "done"},
+        {list, TextOutput, "Venues: carbone"},
+        // without a Texter text falls back on yaml
+        {[]venue(list), TextOutput, "- id: 1\n  name: carbone\n  tags:\n    - italian\n    - \"true\"\n- id: 20\n  name: \"lilia: nyc\""},
+        {list[1], JSONOutput, "{\n  \"id\": 20,\n  \"name\": \"lilia: nyc\"\n}"},
+        {list, YAMLOutput, "- id: 1\n  name: carbone\n  tags:\n    - italian\n    - \"true\"\n- id: 20\n  name: \"lilia: nyc\""},
+        {map[string]interface{}{"a": []int{}, "b": map[string]int{}, "c": nil, "d": ""}, YAMLOutput, "a: []\nb: {}\nc: null\nd: \"\""},
+        {[][]int{{1, 2}}, YAMLOutput, "- - 1\n  - 2"},
+        {list, TableOutput, "ID  NAME        TAGS\n1   carbone     [\"italian\",\"true\"]\n20  lilia: nyc"},
+        {list[0], TableOutput, "KEY   VALUE\nid    1\nname  carbone\ntags  [\"italian\",\"true\"]"},
+        {[]string{"a", "b"}, TableOutput, "VALUE\na\nb"},
+        {tabled{}, TableOutput, "NAME       SIZE\ncarbone    2\nlilia nyc  10"},
+    }
+    for _, test := range tests {
+        got, err := Render(test.result, test.format)
+        if err != nil || got != test.want {
+            t.Errorf("Render(%v, %q) = %q, %v, want %q", test.result, test.format, got, err, test.want)
+        }
+    }
+    if _, err := Render(list, "xml"); !errors.Is(err, ErrOutput) {
+        t.Errorf("unknown format: err = %v, want ErrOutput", err)
+    }
+}
+
+func TestParseOutput(t *testing.T) {
+    for name, want := range map[string]string{"json": JSONOutput, "YAML": YAMLOutput, "ta": TableOutput} {
+        if got, err := ParseOutput(name); err != nil || got != want {
+            t.Errorf("ParseOutput(%q) = %q, %v, want %q", name, got, err, want)
+        }
+    }
+    if _, err := ParseOutput("t"); !errors.Is(err, ErrOutput) {
+        t.Errorf("ambiguous prefix: err = %v, want ErrOutput", err)
+    }
+}
@@ -23,6 +23,7 @@ var (
     ErrMissReq = errors.New("missing required flag")
     ErrMulArg = errors.New("too many arguments for a flag")
     ErrNoArg = errors.New("too few arguments for a flag")
+    ErrNoSubCmd = errors.New("missing subcommand")
 )
 
 /*
@@ -35,6 +36,12 @@ type FlagValidationCtx struct{
     MaxArgs     int    
     MinArgs     int
     Required    bool
+    // What each argument holds, StringType by default
+    Type        FlagType
+    // Bounds for IntType, unbounded when nil
+    Range       *Range
+    // The values an EnumType takes
+    Choices     []string
 }
 
 /*
@@ -48,6 +55,23 @@ type Flag struct {
     LongName        string
     Description     string
     ValidationCtx   FlagValidationCtx
+    // Offers arguments for tab completion besides the
+    // Choices, like ids that exist right now. It gets
+    // what was typed so far and returns the matches
+    Complete        func(prefix string) ([]Completion)
+    // Whether the subcommands of the command the flag
+    // is on take it too
+    Persistent      bool
+    // Whether words given before any flag are its
+    // arguments, like the command of 'help op list'.
+    // One flag of a command can be
+    Positional      bool
+    // What the handler uses when the flag is left
+    // out, shown in help
+    Default         string
+    // The key of the setting read from pc.Sources
+    // when the flag is left out, like "party-size"
+    Setting         string
 }
 
 /*
@@ -58,9 +82,19 @@ a command that can be used in ParseCtx
 */
 type Command struct {
     Name            string
+    // Other names the command goes by, like exit for quit
+    Aliases         []string
     Description     string
     Flags           []Flag 
-    Handler         func(in map[string][]string)(string, error)
+    // Commands named after this one, like 'account add',
+    // which take the Persistent flags of this command
+    // too. Without a Handler a subcommand must be given
+    Subcommands     []Command
+    // Lines showing the command in use, shown in help
+    Examples        []string
+    // Runs the command, returning what Render shows,
+    // like a string or a struct with json tags
+    Handler         func(in Values)(interface{}, error)
 }
 
 /*
@@ -73,110 +107,41 @@ type ParseCtx struct {
     Commands    []Command
     OpenDelim   string
     CloseDelim  string
-}
-
-/*
-Name: splitOn 
-Type: Internal func
-Purpose: Same as split but leaves delim as its own "token". 
-For example splitOn("a/b/c", "/") == []string{"a" "/" "b" "/" "c"}
-*/
-func splitOn(in string, delim string) []string {
-    inDelim := strings.Split(in, delim)
-    inProc := make([]string, 0, len(inDelim) * 2 - 1)
-    for i, v := range inDelim {
-        inProc = append(inProc, v)    
-        if i != len(inDelim) - 1 {
-            inProc = append(inProc, delim)
-        }
-    }
-    return inProc
-}
-
-/*
-Name: Tokenize 
-Type: External CLI func
-Purpose: Take the input command
-string and split it into tokens
-with grouped tokens defined by
-pc.OpenDelim and pc.CloseDelim
-*/
-func (pc *ParseCtx) Tokenize (in string) ([]string, error) {
-    // creates a tokenized slice using input string on open delim
-    inOpenDelimProc := splitOn(in, pc.OpenDelim)
-    // alloc a slice to process close delim tokens
-    inCloseDelimProc := make([]string, 0,len(inOpenDelimProc))
-    for _,v := range inOpenDelimProc {
-        // check each open delim recognized token for close delims
-        closeSplitted := splitOn(v, pc.CloseDelim)
-        inCloseDelimProc = append(inCloseDelimProc, closeSplitted...)
-    }
-    // isGrp is a flag indicating we are processing a group token
-    isGrp := false
-    // store group token in grpToken
-    grpToken := ""
-    // Arbitrary capacity
-    spaceProc := make([]string, 0, 3 * len(inOpenDelimProc))
-    for _, dSpltToken := range inCloseDelimProc {
-        // if we find an open delim and we arent processing 
-        // a group, then start processing a group
-        if dSpltToken == pc.OpenDelim && !isGrp {
-            isGrp = true
-            grpToken = ""
-            continue
-        }
-        // if we find a close delim and we arent processing 
-        // a group, then append the recorded group token and
-        // stop processing a group
-        if dSpltToken == pc.CloseDelim && isGrp {
-            isGrp = false
-            spaceProc = append(spaceProc, grpToken)
-            continue
-        }
-        // if we arent processing a group, split spaces and add
-        // each token, ignoring emptry strs when found
-        if !isGrp {
-            spaceSplitted := strings.Split(dSpltToken, " ") 
-            for _, spSpltToken := range spaceSplitted {
-                if spSpltToken == "" {
-                    continue
-                }
-                spaceProc = append(spaceProc, spSpltToken)
-            }
-        } else {
-            // if we are in a group, just add the whole token
-            // to the group token
-            grpToken += dSpltToken
-        }
-
-    }
-
-    if isGrp {
-        return nil, ErrNoGrp
-    }
-
-    return spaceProc, nil
+    // Words a line can start with instead of the
+    // command words they stand for, like "ls" for
+    // "op list". Commands win over aliases
+    Aliases     map[string]string
+    // Where flags with a Setting left out of a line
+    // are read from, first one that has it wins
+    Sources     []Source
+    // Flags every command takes, after its own and
+    // the ones it inherits, like an output format
+    Globals     []Flag
 }
 
 /*
 Name: parseFlags 
 Type: Internal CLI func
-Purpose: Parse logic for flags
+Purpose: Parse logic for flags. Errors point at
+the index in tokens of the token at fault
 */
-func (pc *ParseCtx) parseFlags(cmd Command, tokens []string) (string, error) {
-    out := make(map[string][]string)
+func (pc *ParseCtx) parseFlags(cmd Command, tokens []string) (Values, *ParseError) {
+    out := make(Values)
+    // index in tokens of each flag and then of each of its arguments
+    pos := make(map[string][]int)
     currFlg := ""
-    for _, token := range tokens {
+    for i, token := range tokens {
         if len(token) > 2 && string(token[0:2]) == "--" {
             // check if longname exists
             didFnd := false
             for _, flag := range cmd.Flags {
                 if flag.LongName != "" &&  flag.LongName == string(token[2:]) {
                     if out[flag.Name] != nil {
-                        return "", ErrRpFlg
+                        return nil, &ParseError{Err: ErrRpFlg, Flag: flag.Name, token: i}
                     }
                     currFlg = flag.Name
                     out[currFlg] = make([]string, 0)
+                    pos[currFlg] = []int{i}
                     didFnd = true
                     break
                 }
@@ -189,10 +154,11 @@ func (pc *ParseCtx) parseFlags(cmd Command, tokens []string) (string, error) {
             for _, flag := range cmd.Flags {
                 if flag.Name == string(token[1:]) {
                     if out[flag.Name] != nil {
-                        return "", ErrRpFlg
+                        return nil, &ParseError{Err: ErrRpFlg, Flag: flag.Name, token: i}
                     }
                     currFlg = flag.Name
                     out[currFlg] = make([]string, 0)
+                    pos[currFlg] = []int{i}
                     didFnd = true
                     break
                 }
@@ -202,38 +168,65 @@ func (pc *ParseCtx) parseFlags(cmd Command, tokens []string) (string, error) {
             }
         }
         if currFlg == "" {
-            return "", ErrNoFlg
+            flag := positional(cmd)
+            if flag == nil {
+                return nil, &ParseError{Err: ErrNoFlg, token: i}
+            }
+            // the word stands in for the flag too, so errors
+            // about the flag point at it
+            currFlg = flag.Name
+            out[currFlg] = make([]string, 0)
+            pos[currFlg] = []int{i}
         }
         out[currFlg] = append(out[currFlg], token)
+        pos[currFlg] = append(pos[currFlg], i)
     }
 
-    // perform validation
-    err := pc.validation(cmd, out)
+    from := pc.fill(cmd, out)
 
-    if err != nil {
-        return "", err
+    // perform validation
+    perr := pc.validation(cmd, out)
+    if perr != nil {
+        if where, ok := from[perr.Flag]; ok {
+            // nothing on the line to point at
+            perr.Source = where
+            perr.token = -1
+        } else if perr.arg >= 0 {
+            perr.token = pos[perr.Flag][perr.arg]
+        }
+        return nil, perr
     }
 
-    return cmd.Handler(out)
+    return out, nil
 }
 
 
 /*
 Name: validation 
 Type: Internal CLI func
-Purpose: Parse logic for flags
+Purpose: Check argument counts and the type
+of each argument, putting enum arguments in
+their canonical form in place
 */
-func (pc *ParseCtx) validation(cmd Command, in map[string][]string) (error){
+func (pc *ParseCtx) validation(cmd Command, in Values) (*ParseError){
     for _, flag := range cmd.Flags {
         if flag.ValidationCtx.Required && in[flag.Name] == nil {
-            return ErrMissReq
+            return &ParseError{Err: ErrMissReq, Flag: flag.Name, arg: -1, token: -1}
         }
         if in[flag.Name] != nil {
             if flag.ValidationCtx.MaxArgs != InfiniteArgs && len(in[flag.Name]) > flag.ValidationCtx.MaxArgs {
-                return ErrMulArg
+                // point at the first argument too many
+                return &ParseError{Err: ErrMulArg, Flag: flag.Name, arg: flag.ValidationCtx.MaxArgs + 1}
             }
             if len(in[flag.Name]) < flag.ValidationCtx.MinArgs {
-                return ErrNoArg
+                return &ParseError{Err: ErrNoArg, Flag: flag.Name, arg: 0}
+            }
+            for i, raw := range in[flag.Name] {
+                value, err := checkValue(flag, raw)
+                if err != nil {
+                    return &ParseError{Err: err, Flag: flag.Name, arg: i + 1}
+                }
+                in[flag.Name][i] = value
             }
         }
     }
@@ -241,27 +234,154 @@ func (pc *ParseCtx) validation(cmd Command, in map[string][]string) (error){
 }
 
 /*
-Name: Parse 
+Name: Resolve 
 Type: External CLI func
-Purpose: Parse input str
+Purpose: Parse input str into its command 
+and validated flag map without running the
+command handler. Failures are a *ParseError
+pointing at the token at fault
 */
-func (pc *ParseCtx) Parse(in string) (string, error) {
-    tokens, err := pc.Tokenize(in)
+func (pc *ParseCtx) Resolve(in string) (*Command, Values, error) {
+    spans, perr := pc.tokenize(in)
 
-    if err != nil {
-        return "", err
+    if perr != nil {
+        return nil, nil, perr
+    }
+
+    if len(spans) == 0 {
+        return nil, nil, &ParseError{Err: ErrNoCmd, Input: in, Column: len(in)}
+    }
+
+    spans = pc.expandAlias(spans)
+    tokens := make([]string, len(spans))
+    for i, tok := range spans {
+        tokens[i] = tok.text
+    }
+    at := func(perr *ParseError, i int) (*ParseError) {
+        perr.Input = in
+        perr.Token = tokens[i]
+        perr.Column = spans[i].start
+        perr.width = spans[i].width(in)
+        return perr
+    }
+
+    chain := pc.descend(tokens)
+    if len(chain) == 0 {
+        return nil, nil, at(&ParseError{Err: ErrNoCmd, Suggestions: suggest(tokens[0], pc.topNames())}, 0)
+    }
+    cmd := chain[len(chain)-1]
+    path := chainPath(chain)
+    // index of the first token after the command words
+    rest := len(chain)
+    if len(cmd.Subcommands) != 0 && cmd.Handler == nil {
+        if rest < len(tokens) && !strings.HasPrefix(tokens[rest], "-") {
+            perr := at(&ParseError{Err: ErrNoCmd, Command: path}, rest)
+            perr.Suggestions = suggest(perr.Token, commandNames(cmd.Subcommands))
+            return nil, nil, perr
+        }
+        subs := make([]string, len(cmd.Subcommands))
+        for i, sub := range cmd.Subcommands {
+            subs[i] = sub.Name
+        }
+        return nil, nil, &ParseError{Err: ErrNoSubCmd, Input: in, Command: path, Column: len(in), Suggestions: subs}
     }
 
-    if len(tokens) == 0 {
-        return "", ErrNoCmd
+    flagged := pc.withInherited(chain)
+    out, perr := pc.parseFlags(flagged, tokens[rest:])
+    if perr != nil {
+        perr.Command = path
+        if perr.token < 0 {
+            // nothing to point at, so point past the end
+            perr.Input = in
+            perr.Column = len(in)
+            if perr.Source != "" {
+                perr.Column = -1
+            }
+            return nil, nil, perr
+        }
+        at(perr, perr.token + rest)
+        // an unknown flag after a known one reads as its argument
+        mistyped := perr.Err == ErrNoFlg || perr.Err == ErrMulArg
+        switch {
+            case mistyped && strings.HasPrefix(perr.Token, "-"):
+                perr.Suggestions = suggestFlags(flagged, perr.Token)
+            case perr.Err == ErrNoFlg && perr.token == 0 && len(cmd.Subcommands) != 0:
+                // the first word was more likely a subcommand
+                perr.Err = ErrNoCmd
+                perr.Suggestions = suggest(perr.Token, commandNames(cmd.Subcommands))
+        }
+        return nil, nil, perr
+    }
+    return cmd, out, nil
+}
+
+/*
+Name: ResolveFlags
+Type: External CLI func
+Purpose: Look up a command by the words naming
+it, like "account add" or an alias, and validate a flag map
+parsed elsewhere against it, like one sent over
+from another process. Failures are a *ParseError
+without a position
+*/
+func (pc *ParseCtx) ResolveFlags(name string, in Values) (*Command, error) {
+    chain, perr := pc.lookup(name)
+    if perr != nil {
+        return nil, perr
+    }
+    cmd := chain[len(chain)-1]
+    path := chainPath(chain)
+    if len(cmd.Subcommands) != 0 && cmd.Handler == nil {
+        return nil, &ParseError{Err: ErrNoSubCmd, Command: path, Column: -1}
+    }
+    flagged := pc.withInherited(chain)
+    for flagName := range in {
+        if lookupFlag(&flagged, "-" + flagName) == nil {
+            return nil, &ParseError{Err: ErrNoFlg, Command: path, Token: "-" + flagName, Column: -1}
+        }
+    }
+    perr = pc.validation(flagged, in)
+    if perr != nil {
+        perr.Command = path
+        perr.Column = -1
+        return nil, perr
     }
+    return cmd, nil
+}
 
-    for _, cmd := range pc.Commands {
-        if cmd.Name == tokens[0] {
-            return pc.parseFlags(cmd, tokens[1:])
+/*
+Name: positional
+Type: Internal CLI func
+Purpose: The flag of cmd taking the words given
+before any flag, nil when there's none
+*/
+func positional(cmd Command) (*Flag) {
+    for i := range cmd.Flags {
+        if cmd.Flags[i].Positional {
+            return &cmd.Flags[i]
         }
     }
+    return nil
+}
 
-    return "", ErrNoCmd
+/*
+Name: Parse 
+Type: External CLI func
+Purpose: Parse input str and run the
+matching command handler, rendering its
+result as text
+*/
+func (pc *ParseCtx) Parse(in string) (string, error) {
+    cmd, out, err := pc.Resolve(in)
+
+    if err != nil {
+        return "", err
+    }
+
+    result, err := cmd.Handler(out)
+    if err != nil {
+        return "", err
+    }
+    return Render(result, TextOutput)
 }
 
@@ -0,0 +1,66 @@
+package cli
+
+import (
+    "os"
+    "os/exec"
+    "strings"
+)
+
+/*
+Name: IsTerminal 
+Type: External CLI func
+Purpose: Report whether f is attached to
+a terminal rather than a file or pipe
+*/
+func IsTerminal(f *os.File) (bool) {
+    info, err := f.Stat()
+    if err != nil {
+        return false
+    }
+    return info.Mode() & os.ModeCharDevice != 0
+}
+
+/*
+Name: SetEcho 
+Type: External CLI func
+Purpose: Turn echoing of typed characters on
+or off for the terminal attached to f. We shell
+out to stty so we don't need an external pkg
+*/
+func SetEcho(f *os.File, on bool) (error) {
+    mode := "-echo"
+    if on {
+        mode = "echo"
+    }
+    cmd := exec.Command("stty", mode)
+    cmd.Stdin = f
+    return cmd.Run()
+}
+
+/*
+Name: MakeRaw 
+Type: External CLI func
+Purpose: Have the terminal attached to f pass on
+each key as it is typed, without echoing it or
+turning ^C into a signal, and return a func that
+puts it back how it was. Output is still processed
+so newlines written by other threads line up
+*/
+func MakeRaw(f *os.File) (func() (error), error) {
+    get := exec.Command("stty", "-g")
+    get.Stdin = f
+    state, err := get.Output()
+    if err != nil {
+        return nil, err
+    }
+    set := exec.Command("stty", "-icanon", "-echo", "-isig", "-ixon", "-iexten", "min", "1")
+    set.Stdin = f
+    if err := set.Run(); err != nil {
+        return nil, err
+    }
+    return func() (error) {
+        restore := exec.Command("stty", strings.TrimSpace(string(state)))
+        restore.Stdin = f
+        return restore.Run()
+    }, nil
+}
@@ -0,0 +1,219 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "errors"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+var (
+    ErrNoQuote = errors.New("unclosed quote")
+    ErrEscape = errors.New("nothing to escape at end of input")
+)
+
+/*
+Name: token
+Type: Internal CLI struct
+Purpose: One token and the byte span in the
+input it was read from, which is wider than
+the text when it was quoted or escaped
+*/
+type token struct {
+    text    string
+    start   int
+    end     int
+}
+
+/*
+Name: width
+Type: Internal CLI func
+Purpose: How many runes of in tok was read from
+*/
+func (tok token) width(in string) (int) {
+    return utf8.RuneCountInString(in[tok.start:tok.end])
+}
+
+/*
+Name: Tokenize
+Type: External CLI func
+Purpose: Take the input command
+string and split it into tokens
+with grouped tokens defined by
+pc.OpenDelim and pc.CloseDelim
+*/
+func (pc *ParseCtx) Tokenize (in string) ([]string, error) {
+    tokens, perr := pc.tokenize(in)
+    if perr != nil {
+        return nil, perr
+    }
+    out := make([]string, len(tokens))
+    for i, tok := range tokens {
+        out[i] = tok.text
+    }
+    return out, nil
+}
+
+/*
+Name: tokenize
+Type: Internal CLI func
+Purpose: Split in on any run of whitespace, shell
+style. Single quotes keep everything up to the next
+one as is, double quotes do too except that a
+backslash escapes a double quote or a backslash,
+and elsewhere a backslash keeps the next character
+as is. A group keeps everything up to the close
+delim as is, so quotes in a group are plain text,
+and only a backslash before the close delim or
+another backslash escapes it. Quoted, escaped and
+grouped text joins whatever touches it into one
+token
+*/
+func (pc *ParseCtx) tokenize(in string) ([]token, *ParseError) {
+    tokens := make([]token, 0)
+    var text strings.Builder
+    // start of the token being read, -1 between tokens
+    start := -1
+    i := 0
+    for i < len(in) {
+        r, size := utf8.DecodeRuneInString(in[i:])
+        if unicode.IsSpace(r) {
+            if start >= 0 {
+                tokens = append(tokens, token{text.String(), start, i})
+                text.Reset()
+                start = -1
+            }
+            i += size
+            continue
+        }
+        if start < 0 {
+            start = i
+        }
+        var perr *ParseError
+        switch {
+            case pc.grouping() && strings.HasPrefix(in[i:], pc.OpenDelim):
+                i, perr = pc.readGroup(in, i, &text)
+            case r == '\'':
+                i, perr = readSingle(in, i, &text)
+            case r == '"':
+                i, perr = readDouble(in, i, &text)
+            case r == '\\':
+                if i + 1 == len(in) {
+                    perr = &ParseError{Err: ErrEscape, Token: "\\", Column: i}
+                    break
+                }
+                next, nextSize := utf8.DecodeRuneInString(in[i+1:])
+                text.WriteRune(next)
+                i += 1 + nextSize
+            default:
+                text.WriteRune(r)
+                i += size
+        }
+        if perr != nil {
+            perr.Input = in
+            return nil, perr
+        }
+    }
+    if start >= 0 {
+        tokens = append(tokens, token{text.String(), start, len(in)})
+    }
+    return tokens, nil
+}
+
+/*
+Name: grouping
+Type: Internal CLI func
+Purpose: Report whether groups are on, which
+takes both delims
+*/
+func (pc *ParseCtx) grouping() (bool) {
+    return pc.OpenDelim != "" && pc.CloseDelim != ""
+}
+
+/*
+Name: readGroup
+Type: Internal CLI func
+Purpose: Read the group opening at i into text,
+returning where it ends
+*/
+func (pc *ParseCtx) readGroup(in string, i int, text *strings.Builder) (int, *ParseError) {
+    j := i + len(pc.OpenDelim)
+    for j < len(in) {
+        switch {
+            case strings.HasPrefix(in[j:], "\\" + pc.CloseDelim):
+                text.WriteString(pc.CloseDelim)
+                j += 1 + len(pc.CloseDelim)
+            case strings.HasPrefix(in[j:], "\\\\"):
+                text.WriteByte('\\')
+                j += 2
+            case strings.HasPrefix(in[j:], pc.CloseDelim):
+                return j + len(pc.CloseDelim), nil
+            default:
+                text.WriteByte(in[j])
+                j++
+        }
+    }
+    return 0, &ParseError{Err: ErrNoGrp, Token: pc.OpenDelim, Column: i}
+}
+
+/*
+Name: readSingle
+Type: Internal CLI func
+Purpose: Read the single quoted text opening at
+i into text, returning where it ends
+*/
+func readSingle(in string, i int, text *strings.Builder) (int, *ParseError) {
+    end := strings.IndexByte(in[i+1:], '\'')
+    if end < 0 {
+        return 0, &ParseError{Err: ErrNoQuote, Token: "'", Column: i}
+    }
+    text.WriteString(in[i+1:i+1+end])
+    return i + end + 2, nil
+}
+
+/*
+Name: readDouble
+Type: Internal CLI func
+Purpose: Read the double quoted text opening at
+i into text, returning where it ends
+*/
+func readDouble(in string, i int, text *strings.Builder) (int, *ParseError) {
+    j := i + 1
+    for j < len(in) {
+        switch in[j] {
+            case '"':
+                return j + 1, nil
+            case '\\':
+                if j + 1 < len(in) && (in[j+1] == '"' || in[j+1] == '\\') {
+                    text.WriteByte(in[j+1])
+                    j += 2
+                    continue
+                }
+        }
+        text.WriteByte(in[j])
+        j++
+    }
+    return 0, &ParseError{Err: ErrNoQuote, Token: "\"", Column: i}
+}
+
+/*
+Name: Quote
+Type: External CLI func
+Purpose: Write s so that Tokenize reads it back
+as the single token s, leaving it bare when
+nothing in it needs quoting
+*/
+func (pc *ParseCtx) Quote(s string) (string) {
+    special := s == "" || strings.ContainsAny(s, "'\"\\") ||
+        strings.IndexFunc(s, unicode.IsSpace) >= 0 ||
+        (pc.grouping() && strings.Contains(s, pc.OpenDelim))
+    if !special {
+        return s
+    }
+    // single quotes can't hold one, so close them around an escaped one
+    return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
+}
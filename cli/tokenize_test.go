@@ -0,0 +1,160 @@
+package cli
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestTokenize(t *testing.T) {
+    tests := []struct {
+        name    string
+        in      string
+        want    []string
+    }{
+        {"empty", "", []string{}},
+        {"only whitespace", " \t \n", []string{}},
+        {"spaces", "search -n carbone", []string{"search", "-n", "carbone"}},
+        {"runs of whitespace", "  search\t-n \t carbone  ", []string{"search", "-n", "carbone"}},
+        {"unicode whitespace", "a\u00a0b", []string{"a", "b"}},
+        {"group", "search -n [double chicken please]", []string{"search", "-n", "double chicken please"}},
+        {"group keeps whitespace", "[  a\tb ]", []string{"  a\tb "}},
+        {"empty group", "a [] b", []string{"a", "", "b"}},
+        {"group keeps quotes", "[Joe's \"Pizza\"]", []string{"Joe's \"Pizza\""}},
+        {"group keeps open delim", "[a[b]", []string{"a[b"}},
+        {"group escaped close", "[a\\]b]", []string{"a]b"}},
+        {"group escaped backslash", "[a\\\\]", []string{"a\\"}},
+        {"group other backslash", "[C:\\dir]", []string{"C:\\dir"}},
+        {"stray close delim", "a ] b", []string{"a", "]", "b"}},
+        {"single quotes", "'double chicken please'", []string{"double chicken please"}},
+        {"single quotes keep backslash", "'a\\b'", []string{"a\\b"}},
+        {"single quotes keep delims", "'[a]'", []string{"[a]"}},
+        {"double quotes", "\"double chicken please\"", []string{"double chicken please"}},
+        {"double quotes escape quote", "\"say \\\"hi\\\"\"", []string{"say \"hi\""}},
+        {"double quotes escape backslash", "\"a\\\\b\"", []string{"a\\b"}},
+        {"double quotes keep other backslash", "\"a\\nb\"", []string{"a\\nb"}},
+        {"double quotes keep single", "\"Joe's\"", []string{"Joe's"}},
+        {"empty quotes", "a '' \"\" b", []string{"a", "", "", "b"}},
+        {"escaped space", "pass\\ word", []string{"pass word"}},
+        {"escaped quote", "Joe\\'s", []string{"Joe's"}},
+        {"escaped open delim", "\\[a]", []string{"[a]"}},
+        {"escaped backslash", "a\\\\b", []string{"a\\b"}},
+        {"quotes join", "a\"b c\"'d e'f", []string{"ab cd ef"}},
+        {"group joins", "a[b c]d", []string{"ab cd"}},
+        {"unicode", "café 'crème brûlée'", []string{"café", "crème brûlée"}},
+    }
+    pc := &ParseCtx{OpenDelim: "[", CloseDelim: "]"}
+    for _, test := range tests {
+        got, err := pc.Tokenize(test.in)
+        if err != nil {
+            t.Errorf("%s: Tokenize(%q) err = %v", test.name, test.in, err)
+            continue
+        }
+        if !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%s: Tokenize(%q) = %q, want %q", test.name, test.in, got, test.want)
+        }
+    }
+}
+
+func TestTokenizeErrors(t *testing.T) {
+    tests := []struct {
+        in      string
+        err     error
+        column  int
+    }{
+        {"search -n [a b", ErrNoGrp, 10},
+        {"[a] [b", ErrNoGrp, 4},
+        {"-p 'secret", ErrNoQuote, 3},
+        {"-p \"secret\\\"", ErrNoQuote, 3},
+        {"-n 'a' \"b", ErrNoQuote, 7},
+        {"trailing\\", ErrEscape, 8},
+    }
+    pc := &ParseCtx{OpenDelim: "[", CloseDelim: "]"}
+    for _, test := range tests {
+        _, err := pc.Tokenize(test.in)
+        var perr *ParseError
+        if !errors.As(err, &perr) || !errors.Is(err, test.err) {
+            t.Errorf("Tokenize(%q) err = %v, want %v", test.in, err, test.err)
+            continue
+        }
+        if perr.Column != test.column || perr.Input != test.in {
+            t.Errorf("Tokenize(%q) at column %d of %q, want column %d", test.in, perr.Column, perr.Input, test.column)
+        }
+    }
+}
+
+func TestTokenizeDelims(t *testing.T) {
+    tests := []struct {
+        open    string
+        close   string
+        in      string
+        want    []string
+    }{
+        {"{", "}", "a {b c} [d e]", []string{"a", "b c", "[d", "e]"}},
+        {"<<", ">>", "a <<b > c>> d", []string{"a", "b > c", "d"}},
+        {"<<", ">>", "<<a\\>>b>>", []string{"a>>b"}},
+        {"|", "|", "|a b| c", []string{"a b", "c"}},
+        // no groups without both delims
+        {"", "", "[a b]", []string{"[a", "b]"}},
+        {"[", "", "[a b]", []string{"[a", "b]"}},
+    }
+    for _, test := range tests {
+        pc := &ParseCtx{OpenDelim: test.open, CloseDelim: test.close}
+        got, err := pc.Tokenize(test.in)
+        if err != nil || !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%q %q: Tokenize(%q) = %q, %v, want %q", test.open, test.close, test.in, got, err, test.want)
+        }
+    }
+}
+
+func TestQuote(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    string
+    }{
+        {"carbone", "carbone"},
+        {"a]b", "a]b"},
+        {"", "''"},
+        {"double chicken please", "'double chicken please'"},
+        {"Joe's", "'Joe'\\''s'"},
+        {"[a", "'[a'"},
+        {"a\\b", "'a\\b'"},
+        {"tab\there", "'tab\there'"},
+    }
+    pc := &ParseCtx{OpenDelim: "[", CloseDelim: "]"}
+    for _, test := range tests {
+        got := pc.Quote(test.in)
+        if got != test.want {
+            t.Errorf("Quote(%q) = %q, want %q", test.in, got, test.want)
+        }
+        // whatever Quote writes reads back as the one token
+        tokens, err := pc.Tokenize(got)
+        if err != nil || len(tokens) != 1 || tokens[0] != test.in {
+            t.Errorf("Tokenize(Quote(%q)) = %q, %v", test.in, tokens, err)
+        }
+    }
+}
+
+func TestResolveQuotedColumns(t *testing.T) {
+    pc := typedParseCtx()
+    // the caret spans the quoted token as typed
+    _, _, err := pc.Resolve("typed -c 'gar den'")
+    var perr *ParseError
+    if !errors.As(err, &perr) {
+        t.Fatalf("err = %v, want a ParseError", err)
+    }
+    if perr.Token != "gar den" || perr.Column != 9 {
+        t.Errorf("at %q column %d, want \"gar den\" column 9", perr.Token, perr.Column)
+    }
+    if got, want := perr.Caret(), strings.Repeat(" ", 9) + "^" + strings.Repeat("~", 8); got != want {
+        t.Errorf("Caret = %q, want %q", got, want)
+    }
+    _, _, err = pc.Resolve("typed\t-c garden")
+    if !errors.As(err, &perr) {
+        t.Fatalf("err = %v, want a ParseError", err)
+    }
+    if got, want := perr.Caret(), "     \t   ^" + strings.Repeat("~", 5); got != want {
+        t.Errorf("Caret = %q, want %q", got, want)
+    }
+}
@@ -0,0 +1,489 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "net/mail"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// For use in the Range field Max
+const (
+    NoMax = math.MaxInt
+)
+
+var (
+    ErrInvInt = errors.New("want a whole number")
+    ErrInvDuration = errors.New("want hh:mm or a duration like 90s")
+    ErrInvDate = errors.New("want a real day in yyyy:mm:dd format")
+    ErrInvTimeOfDay = errors.New("want a time of day in hh:mm format")
+    ErrInvDateTime = errors.New("want a date and time in yyyy:mm:dd:hh:mm format")
+    ErrInvEmail = errors.New("want an email address")
+    ErrNoChoice = errors.New("not one of the choices")
+    ErrRange = errors.New("out of range")
+)
+
+/*
+Name: FlagType
+Type: External CLI type
+Purpose: The kind of value each argument
+of a flag holds, checked while parsing
+*/
+type FlagType int
+
+const (
+    // Any text, the default
+    StringType FlagType = iota
+    // A whole number, within Range if given
+    IntType
+    // hh:mm, or a go duration like 90s or 1h30m
+    DurationType
+    // yyyy:mm:dd
+    DateType
+    // hh:mm on a 24 hour clock
+    TimeOfDayType
+    // yyyy:mm:dd:hh:mm
+    DateTimeType
+    // One of Choices, or a prefix of only one
+    EnumType
+    // A bare email address
+    EmailType
+)
+
+/*
+Name: Range
+Type: External CLI struct
+Purpose: The inclusive bounds of an IntType
+flag, Max can be NoMax
+*/
+type Range struct {
+    Min     int
+    Max     int
+}
+
+/*
+Name: err
+Type: Internal CLI func
+Purpose: Say what a value outside r should
+have been
+*/
+func (r Range) err() (error) {
+    if r.Max == NoMax {
+        return fmt.Errorf("%w, want at least %d", ErrRange, r.Min)
+    }
+    return fmt.Errorf("%w, want %d to %d", ErrRange, r.Min, r.Max)
+}
+
+/*
+Name: FlagError
+Type: External CLI struct
+Purpose: Say which flag and value failed to
+parse, and why
+*/
+type FlagError struct {
+    Flag    string
+    Value   string
+    Err     error
+}
+
+func (e *FlagError) Error() (string) {
+    return "invalid value \"" + e.Value + "\" for -" + e.Flag + ": " + e.Err.Error()
+}
+
+func (e *FlagError) Unwrap() (error) {
+    return e.Err
+}
+
+/*
+Name: Date
+Type: External CLI struct
+Purpose: A calendar day without a timezone,
+since the zone is usually decided later
+*/
+type Date struct {
+    Year    int
+    Month   time.Month
+    Day     int
+}
+
+/*
+Name: At
+Type: External CLI func
+Purpose: The wall clock time of day t on d in
+loc, which holds across daylight saving changes
+*/
+func (d Date) At(t time.Duration, loc *time.Location) (time.Time) {
+    return time.Date(d.Year, d.Month, d.Day, int(t / time.Hour), int(t % time.Hour / time.Minute), 0, 0, loc)
+}
+
+/*
+Name: DateTime
+Type: External CLI struct
+Purpose: A day and time of day without a
+timezone
+*/
+type DateTime struct {
+    Date        Date
+    TimeOfDay   time.Duration
+}
+
+/*
+Name: In
+Type: External CLI func
+Purpose: The time d names in loc
+*/
+func (d DateTime) In(loc *time.Location) (time.Time) {
+    return d.Date.At(d.TimeOfDay, loc)
+}
+
+/*
+Name: Values
+Type: External CLI type
+Purpose: The validated flag to value map given
+to handlers. Arguments are kept as text so the
+map can be indexed directly and sent to another
+process, the typed getters convert them. A flag
+left out reads as the zero value, an argument
+that doesn't convert, say in a map that never
+went through parsing, is a *FlagError
+*/
+type Values map[string][]string
+
+/*
+Name: Has
+Type: External CLI func
+Purpose: Report whether the flag was given
+*/
+func (v Values) Has(name string) (bool) {
+    return v[name] != nil
+}
+
+/*
+Name: String
+Type: External CLI func
+Purpose: The first argument of the flag
+*/
+func (v Values) String(name string) (string) {
+    if len(v[name]) == 0 {
+        return ""
+    }
+    return v[name][0]
+}
+
+/*
+Name: Int
+Type: External CLI func
+Purpose: The first argument of an IntType flag
+*/
+func (v Values) Int(name string) (int, error) {
+    if len(v[name]) == 0 {
+        return 0, nil
+    }
+    n, err := strconv.Atoi(v[name][0])
+    if err != nil {
+        return 0, &FlagError{name, v[name][0], ErrInvInt}
+    }
+    return n, nil
+}
+
+/*
+Name: Int64s
+Type: External CLI func
+Purpose: Every argument of an IntType flag,
+handy for ids
+*/
+func (v Values) Int64s(name string) ([]int64, error) {
+    if v[name] == nil {
+        return nil, nil
+    }
+    ns := make([]int64, len(v[name]))
+    for i, raw := range v[name] {
+        n, err := strconv.ParseInt(raw, 10, 64)
+        if err != nil {
+            return nil, &FlagError{name, raw, ErrInvInt}
+        }
+        ns[i] = n
+    }
+    return ns, nil
+}
+
+/*
+Name: Int64
+Type: External CLI func
+Purpose: The first argument of an IntType flag,
+handy for ids
+*/
+func (v Values) Int64(name string) (int64, error) {
+    if len(v[name]) == 0 {
+        return 0, nil
+    }
+    n, err := strconv.ParseInt(v[name][0], 10, 64)
+    if err != nil {
+        return 0, &FlagError{name, v[name][0], ErrInvInt}
+    }
+    return n, nil
+}
+
+/*
+Name: Duration
+Type: External CLI func
+Purpose: The first argument of a DurationType flag
+*/
+func (v Values) Duration(name string) (time.Duration, error) {
+    if len(v[name]) == 0 {
+        return 0, nil
+    }
+    d, err := ParseDuration(v[name][0])
+    if err != nil {
+        return 0, &FlagError{name, v[name][0], err}
+    }
+    return d, nil
+}
+
+/*
+Name: Date
+Type: External CLI func
+Purpose: The first argument of a DateType flag
+*/
+func (v Values) Date(name string) (Date, error) {
+    if len(v[name]) == 0 {
+        return Date{}, nil
+    }
+    d, err := ParseDate(v[name][0])
+    if err != nil {
+        return Date{}, &FlagError{name, v[name][0], err}
+    }
+    return d, nil
+}
+
+/*
+Name: TimesOfDay
+Type: External CLI func
+Purpose: Every argument of a TimeOfDayType flag
+*/
+func (v Values) TimesOfDay(name string) ([]time.Duration, error) {
+    if v[name] == nil {
+        return nil, nil
+    }
+    ts := make([]time.Duration, len(v[name]))
+    for i, raw := range v[name] {
+        t, err := ParseTimeOfDay(raw)
+        if err != nil {
+            return nil, &FlagError{name, raw, err}
+        }
+        ts[i] = t
+    }
+    return ts, nil
+}
+
+/*
+Name: TimeOfDay
+Type: External CLI func
+Purpose: The first argument of a TimeOfDayType flag
+*/
+func (v Values) TimeOfDay(name string) (time.Duration, error) {
+    if len(v[name]) == 0 {
+        return 0, nil
+    }
+    t, err := ParseTimeOfDay(v[name][0])
+    if err != nil {
+        return 0, &FlagError{name, v[name][0], err}
+    }
+    return t, nil
+}
+
+/*
+Name: DateTime
+Type: External CLI func
+Purpose: The first argument of a DateTimeType flag
+*/
+func (v Values) DateTime(name string) (DateTime, error) {
+    if len(v[name]) == 0 {
+        return DateTime{}, nil
+    }
+    d, err := ParseDateTime(v[name][0])
+    if err != nil {
+        return DateTime{}, &FlagError{name, v[name][0], err}
+    }
+    return d, nil
+}
+
+/*
+Name: ParseDuration
+Type: External CLI func
+Purpose: Parse hh:mm, with any number of hours,
+or a go duration like 90s
+*/
+func ParseDuration(raw string) (time.Duration, error) {
+    if !strings.Contains(raw, ":") {
+        d, err := time.ParseDuration(raw)
+        if err != nil || d < 0 {
+            return 0, ErrInvDuration
+        }
+        return d, nil
+    }
+    hour, minute, ok := splitClock(raw)
+    if !ok || hour < 0 || minute < 0 || minute > 59 {
+        return 0, ErrInvDuration
+    }
+    return time.Duration(hour) * time.Hour + time.Duration(minute) * time.Minute, nil
+}
+
+/*
+Name: ParseTimeOfDay
+Type: External CLI func
+Purpose: Parse a time of day in hh:mm format
+*/
+func ParseTimeOfDay(raw string) (time.Duration, error) {
+    hour, minute, ok := splitClock(raw)
+    if !ok || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+        return 0, ErrInvTimeOfDay
+    }
+    return time.Duration(hour) * time.Hour + time.Duration(minute) * time.Minute, nil
+}
+
+/*
+Name: ParseDate
+Type: External CLI func
+Purpose: Parse a day in yyyy:mm:dd format,
+rejecting days that don't exist
+*/
+func ParseDate(raw string) (Date, error) {
+    daySplt := strings.Split(raw, ":")
+    if len(daySplt) != 3 {
+        return Date{}, ErrInvDate
+    }
+    parts := make([]int, 3)
+    for i, part := range daySplt {
+        n, err := strconv.Atoi(part)
+        if err != nil {
+            return Date{}, ErrInvDate
+        }
+        parts[i] = n
+    }
+    d := Date{Year: parts[0], Month: time.Month(parts[1]), Day: parts[2]}
+    // time.Date normalizes the 31st of february into march
+    t := d.At(0, time.UTC)
+    if t.Year() != d.Year || t.Month() != d.Month || t.Day() != d.Day {
+        return Date{}, ErrInvDate
+    }
+    return d, nil
+}
+
+/*
+Name: ParseDateTime
+Type: External CLI func
+Purpose: Parse a day and time of day in
+yyyy:mm:dd:hh:mm format
+*/
+func ParseDateTime(raw string) (DateTime, error) {
+    splt := strings.Split(raw, ":")
+    if len(splt) != 5 {
+        return DateTime{}, ErrInvDateTime
+    }
+    d, err := ParseDate(strings.Join(splt[:3], ":"))
+    if err != nil {
+        return DateTime{}, ErrInvDateTime
+    }
+    t, err := ParseTimeOfDay(strings.Join(splt[3:], ":"))
+    if err != nil {
+        return DateTime{}, ErrInvDateTime
+    }
+    return DateTime{Date: d, TimeOfDay: t}, nil
+}
+
+/*
+Name: splitClock
+Type: Internal CLI func
+Purpose: Split hh:mm into its numbers
+*/
+func splitClock(raw string) (int, int, bool) {
+    splt := strings.Split(raw, ":")
+    if len(splt) != 2 {
+        return 0, 0, false
+    }
+    hour, err := strconv.Atoi(splt[0])
+    if err != nil {
+        return 0, 0, false
+    }
+    minute, err := strconv.Atoi(splt[1])
+    if err != nil {
+        return 0, 0, false
+    }
+    return hour, minute, true
+}
+
+/*
+Name: matchChoice
+Type: Internal CLI func
+Purpose: Find the choice raw names, ignoring case.
+An exact match wins, otherwise raw must be the
+prefix of exactly one choice
+*/
+func matchChoice(raw string, choices []string) (string, bool) {
+    raw = strings.ToLower(raw)
+    match := ""
+    count := 0
+    for _, choice := range choices {
+        lower := strings.ToLower(choice)
+        if lower == raw {
+            return choice, true
+        }
+        if raw != "" && strings.HasPrefix(lower, raw) {
+            match = choice
+            count++
+        }
+    }
+    return match, count == 1
+}
+
+/*
+Name: checkValue
+Type: Internal CLI func
+Purpose: Check one argument against the type
+of its flag, returning it in canonical form,
+which only differs for enums
+*/
+func checkValue(flag Flag, raw string) (string, error) {
+    ctx := flag.ValidationCtx
+    var err error
+    switch ctx.Type {
+        case IntType:
+            n, convErr := strconv.Atoi(raw)
+            if convErr != nil {
+                err = ErrInvInt
+            } else if ctx.Range != nil && (n < ctx.Range.Min || n > ctx.Range.Max) {
+                err = ctx.Range.err()
+            }
+        case DurationType:
+            _, err = ParseDuration(raw)
+        case DateType:
+            _, err = ParseDate(raw)
+        case TimeOfDayType:
+            _, err = ParseTimeOfDay(raw)
+        case DateTimeType:
+            _, err = ParseDateTime(raw)
+        case EnumType:
+            choice, ok := matchChoice(raw, ctx.Choices)
+            if !ok {
+                return "", &FlagError{flag.Name, raw, fmt.Errorf("%w, want one of %s", ErrNoChoice, strings.Join(ctx.Choices, ", "))}
+            }
+            return choice, nil
+        case EmailType:
+            addr, parseErr := mail.ParseAddress(raw)
+            if parseErr != nil || addr.Address != raw {
+                err = ErrInvEmail
+            }
+    }
+    if err != nil {
+        return "", &FlagError{flag.Name, raw, err}
+    }
+    return raw, nil
+}
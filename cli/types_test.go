@@ -0,0 +1,141 @@
+package cli
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+func typedParseCtx() (*ParseCtx) {
+    flag := func(name string, ctx FlagValidationCtx) (Flag) {
+        ctx.MinArgs = 1
+        ctx.MaxArgs = InfiniteArgs
+        return Flag{Name: name, ValidationCtx: ctx}
+    }
+    return &ParseCtx{
+        Commands: []Command{
+            Command{
+                Name: "typed",
+                Flags: []Flag{
+                    flag("n", FlagValidationCtx{Type: IntType, Range: &Range{Min: 1, Max: 10}}),
+                    flag("d", FlagValidationCtx{Type: DurationType}),
+                    flag("day", FlagValidationCtx{Type: DateType}),
+                    flag("t", FlagValidationCtx{Type: TimeOfDayType}),
+                    flag("dt", FlagValidationCtx{Type: DateTimeType}),
+                    flag("c", FlagValidationCtx{Type: EnumType, Choices: []string{"dining", "indoor", "inside", "outdoor"}}),
+                    flag("e", FlagValidationCtx{Type: EmailType}),
+                },
+            },
+        },
+        OpenDelim: "[",
+        CloseDelim: "]",
+    }
+}
+
+func TestTypedFlagsAccept(t *testing.T) {
+    pc := typedParseCtx()
+    _, in, err := pc.Resolve("typed -n 10 -d 1:30 90s -day 2024:02:29 -t 23:59 -dt 2023:09:01:09:00 -c INDOOR out -e a@b.com")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if n, err := in.Int("n"); n != 10 || err != nil {
+        t.Errorf("Int = %d, %v, want 10", n, err)
+    }
+    if d, err := in.Duration("d"); d != 90 * time.Minute || err != nil {
+        t.Errorf("Duration = %v, %v, want 1h30m", d, err)
+    }
+    if day, err := in.Date("day"); day != (Date{2024, time.February, 29}) || err != nil {
+        t.Errorf("Date = %v, %v", day, err)
+    }
+    if got, err := in.TimesOfDay("t"); len(got) != 1 || got[0] != 23 * time.Hour + 59 * time.Minute || err != nil {
+        t.Errorf("TimesOfDay = %v, %v", got, err)
+    }
+    want := time.Date(2023, time.September, 1, 9, 0, 0, 0, time.UTC)
+    if got, err := in.DateTime("dt"); !got.In(time.UTC).Equal(want) || err != nil {
+        t.Errorf("DateTime = %v, %v, want %v", got.In(time.UTC), err, want)
+    }
+    // enum arguments come back canonical
+    if in["c"][0] != "indoor" || in["c"][1] != "outdoor" {
+        t.Errorf("enum = %v, want [indoor outdoor]", in["c"])
+    }
+    if n, err := in.Int("x"); in.Has("x") || n != 0 || err != nil {
+        t.Error("missing flag should read as absent and zero")
+    }
+}
+
+func TestTypedFlagsReject(t *testing.T) {
+    tests := []struct {
+        line    string
+        flag    string
+        err     error
+    }{
+        {"typed -n abc", "n", ErrInvInt},
+        {"typed -n 0", "n", ErrRange},
+        {"typed -n 11", "n", ErrRange},
+        {"typed -d 1:60", "d", ErrInvDuration},
+        {"typed -d -5s", "d", ErrInvDuration},
+        {"typed -day 2023:02:29", "day", ErrInvDate},
+        {"typed -day 2023-02-01", "day", ErrInvDate},
+        {"typed -t 24:00", "t", ErrInvTimeOfDay},
+        {"typed -dt 2023:09:01", "dt", ErrInvDateTime},
+        {"typed -c garden", "c", ErrNoChoice},
+        // a prefix of more than one choice is ambiguous
+        {"typed -c in", "c", ErrNoChoice},
+        {"typed -e [Bob <bob@b.com>]", "e", ErrInvEmail},
+        {"typed -e bob", "e", ErrInvEmail},
+    }
+    pc := typedParseCtx()
+    for _, test := range tests {
+        _, _, err := pc.Resolve(test.line)
+        var flagErr *FlagError
+        if !errors.As(err, &flagErr) {
+            t.Errorf("%q: err = %v, want a FlagError", test.line, err)
+            continue
+        }
+        if flagErr.Flag != test.flag || !errors.Is(err, test.err) {
+            t.Errorf("%q: err = %v, want %v on -%s", test.line, err, test.err, test.flag)
+        }
+    }
+}
+
+func TestResolveFlagsValidates(t *testing.T) {
+    pc := typedParseCtx()
+    if _, err := pc.ResolveFlags("typed", Values{"n": {"20"}}); !errors.Is(err, ErrRange) {
+        t.Errorf("err = %v, want ErrRange", err)
+    }
+    if _, err := pc.ResolveFlags("typed", Values{"z": {"1"}}); !errors.Is(err, ErrNoFlg) {
+        t.Errorf("err = %v, want ErrNoFlg", err)
+    }
+    in := Values{"c": {"OUT"}}
+    if _, err := pc.ResolveFlags("typed", in); err != nil || in["c"][0] != "outdoor" {
+        t.Errorf("err = %v, in = %v", err, in)
+    }
+}
+
+// A map that never went through parsing gets errors, not zeros
+func TestValuesUnchecked(t *testing.T) {
+    in := Values{"n": {"ten"}, "ids": {"1", "x"}, "d": {"soon"}, "day": {"2023:02:30"}, "t": {"25:00"}, "dt": {"today"}}
+    check := func(getter string, err error, want error) {
+        t.Helper()
+        var flagErr *FlagError
+        if !errors.As(err, &flagErr) || !errors.Is(err, want) {
+            t.Errorf("%s: err = %v, want a FlagError for %v", getter, err, want)
+        }
+    }
+    _, err := in.Int("n")
+    check("Int", err, ErrInvInt)
+    _, err = in.Int64("n")
+    check("Int64", err, ErrInvInt)
+    _, err = in.Int64s("ids")
+    check("Int64s", err, ErrInvInt)
+    _, err = in.Duration("d")
+    check("Duration", err, ErrInvDuration)
+    _, err = in.Date("day")
+    check("Date", err, ErrInvDate)
+    _, err = in.TimeOfDay("t")
+    check("TimeOfDay", err, ErrInvTimeOfDay)
+    _, err = in.TimesOfDay("t")
+    check("TimesOfDay", err, ErrInvTimeOfDay)
+    _, err = in.DateTime("dt")
+    check("DateTime", err, ErrInvDateTime)
+}
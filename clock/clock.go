@@ -0,0 +1,165 @@
+package clock
+
+import (
+    "sync"
+    "time"
+)
+
+/*
+Name: Clock 
+Type: External Clock Interface
+Purpose: The source of time for anything that 
+schedules, so a fake clock can stand in for the
+real one in tests
+*/
+type Clock interface {
+    Now() (time.Time)
+    After(d time.Duration) (<-chan time.Time)
+}
+
+/*
+Name: Real 
+Type: External Clock Struct
+Purpose: The Clock backed by the time pkg
+*/
+type Real struct {}
+
+/*
+Name: Now 
+Type: External Func
+Purpose: Same as time.Now
+*/
+func (Real) Now() (time.Time) {
+    return time.Now()
+}
+
+/*
+Name: After 
+Type: External Func
+Purpose: Same as time.After
+*/
+func (Real) After(d time.Duration) (<-chan time.Time) {
+    return time.After(d)
+}
+
+/*
+Name: fakeWaiter 
+Type: Internal Clock Struct
+Purpose: A pending After call on a Fake clock
+*/
+type fakeWaiter struct {
+    deadline    time.Time
+    ch          chan time.Time
+}
+
+/*
+Name: Fake 
+Type: External Clock Struct
+Purpose: A Clock that only moves when told to. After
+channels fire once Advance moves the clock past their
+deadline. Use NewFake to create one
+*/
+type Fake struct {
+    mu          sync.Mutex
+    cond        *sync.Cond
+    now         time.Time
+    waiters     []fakeWaiter
+}
+
+/*
+Name: NewFake 
+Type: External Func
+Purpose: Create a Fake clock set to now
+*/
+func NewFake(now time.Time) (*Fake) {
+    f := &Fake{now: now}
+    f.cond = sync.NewCond(&f.mu)
+    return f
+}
+
+/*
+Name: Now 
+Type: External Func
+Purpose: The time the fake clock is set to
+*/
+func (f *Fake) Now() (time.Time) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.now
+}
+
+/*
+Name: After 
+Type: External Func
+Purpose: A channel which is sent the fake time once
+the clock has been advanced by d. A non positive d 
+fires at once
+*/
+func (f *Fake) After(d time.Duration) (<-chan time.Time) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    ch := make(chan time.Time, 1)
+    if d <= 0 {
+        ch <- f.now
+        return ch
+    }
+    f.waiters = append(f.waiters, fakeWaiter{deadline: f.now.Add(d), ch: ch})
+    f.cond.Broadcast()
+    return ch
+}
+
+/*
+Name: Advance 
+Type: External Func
+Purpose: Move the clock forward by d, firing every
+waiter whose deadline has been reached
+*/
+func (f *Fake) Advance(d time.Duration) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.now = f.now.Add(d)
+    pending := f.waiters[:0]
+    for _, w := range f.waiters {
+        if w.deadline.After(f.now) {
+            pending = append(pending, w)
+        } else {
+            w.ch <- f.now
+        }
+    }
+    f.waiters = pending
+}
+
+/*
+Name: Next 
+Type: External Func
+Purpose: The earliest deadline among pending waiters,
+false if there are none
+*/
+func (f *Fake) Next() (time.Time, bool) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if len(f.waiters) == 0 {
+        return time.Time{}, false
+    }
+    next := f.waiters[0].deadline
+    for _, w := range f.waiters[1:] {
+        if w.deadline.Before(next) {
+            next = w.deadline
+        }
+    }
+    return next, true
+}
+
+/*
+Name: BlockUntil 
+Type: External Func
+Purpose: Block until at least n After calls are 
+waiting on the clock
+*/
+func (f *Fake) BlockUntil(n int) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    for len(f.waiters) < n {
+        f.cond.Wait()
+    }
+}
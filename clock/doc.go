@@ -0,0 +1,67 @@
+/*
+**********************************************************************
+
+General Purpose: 
+
+    The clock pkg measures how far the local clock is off from a 
+    remote clock. A drop is won or lost in the first second, so a 
+    host whose clock runs a couple of seconds slow fires its 
+    requests after everyone else. The app layer uses the measured
+    skew to correct every scheduled fire. It also holds the Clock
+    interface scheduling code gets time from, so tests can swap in
+    a fake one.
+
+**********************************************************************
+
+Sampling:
+
+    Two sources are supported:
+
+        1. SampleHTTP(url string, n int) (*Skew, error)
+
+            - Description: Sends n HEAD requests to a web server,
+              usually the reservation service itself, and reads
+              the Date header of each response. The header has 
+              only one second resolution, so one sample only tells
+              us the offset lies in a one second window around the
+              midpoint of the request. Samples are spaced at an 
+              odd interval so their windows fall differently across
+              second boundaries, and intersecting the windows narrows
+              the estimate well below a second.
+
+        2. SampleNTP(server string, n int) (*Skew, error)
+
+            - Description: Sends n SNTP requests to an NTP server,
+              which could be a local one, and computes the offset
+              with the usual four timestamp formula, keeping the
+              sample with the smallest round trip. Replies that
+              aren't from a server, are a kiss-o'-death or carry
+              no transmit time are dropped(ErrBadNTP).
+
+    Both return a Skew, holding the offset(remote minus local, so
+    adding it to a local time yields remote time), the estimated
+    one way latency, the number of usable samples and the source.
+
+**********************************************************************
+
+Clocks:
+
+    Anything that schedules should get time from a Clock instead of
+    calling time.Now and time.After itself:
+
+        1. Real
+
+            - Description: The Clock backed by the time pkg.
+
+        2. Fake
+
+            - Description: A Clock that only moves when Advance is
+              called, firing any After channels whose deadline was
+              reached. Next gives the earliest pending deadline and
+              BlockUntil waits for a number of pending After calls,
+              so a test can step a scheduler from one wait to the 
+              next without really sleeping.
+
+**********************************************************************
+*/
+package clock
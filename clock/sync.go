@@ -0,0 +1,212 @@
+package clock
+
+import (
+    "encoding/binary"
+    "errors"
+    "net"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+)
+
+const (
+    // Spacing between samples, picked so samples don't line 
+    // up with the one second resolution of the Date header
+    sampleSpacing = 113 * time.Millisecond
+    // Seconds between the NTP epoch(1900) and the unix epoch
+    ntpEpochOffset = 2208988800
+)
+
+var (
+    ErrNoSamples = errors.New("no clock samples could be taken")
+    ErrNoDate = errors.New("server response had no Date header")
+    ErrBadNTP = errors.New("ntp server sent an unusable reply")
+)
+
+/*
+Name: Skew 
+Type: External Clock Struct
+Purpose: The measured difference between the local
+clock and a remote clock
+*/
+type Skew struct {
+    // Remote clock minus local clock, so adding Offset 
+    // to a local time yields remote time
+    Offset      time.Duration
+    // Estimated one way trip to the remote
+    Latency     time.Duration
+    // Number of samples the estimate is based on
+    Samples     int
+    // Where the samples were taken from
+    Source      string
+}
+
+/*
+Name: SampleHTTP 
+Type: External Clock Func
+Purpose: Estimate the skew against a web server from the
+Date header of n responses. The header only has one second
+resolution, so each sample only bounds the offset to a one
+second window, and we intersect the windows of all samples
+*/
+func SampleHTTP(url string, n int) (*Skew, error) {
+    if n <= 0 {
+        return nil, ErrNoSamples
+    }
+    client := &http.Client{Timeout: 5 * time.Second}
+    var low, high time.Duration
+    var sum time.Duration
+    minRtt := time.Duration(-1)
+    taken := 0
+    for i := 0; i < n; i++ {
+        if i != 0 {
+            time.Sleep(sampleSpacing)
+        }
+        request, err := http.NewRequest("HEAD", url, nil)
+        if err != nil {
+            return nil, err
+        }
+        sent := time.Now()
+        response, err := client.Do(request)
+        received := time.Now()
+        if err != nil {
+            continue
+        }
+        response.Body.Close()
+        date, err := http.ParseTime(response.Header.Get("Date"))
+        if err != nil {
+            continue
+        }
+        rtt := received.Sub(sent)
+        mid := sent.Add(rtt / 2)
+        // the server read its clock somewhere in [date, date+1s) 
+        sampleLow := date.Sub(mid)
+        sampleHigh := sampleLow + time.Second
+        if taken == 0 || sampleLow > low {
+            low = sampleLow
+        }
+        if taken == 0 || sampleHigh < high {
+            high = sampleHigh
+        }
+        sum += sampleLow + time.Second / 2
+        if minRtt < 0 || rtt < minRtt {
+            minRtt = rtt
+        }
+        taken += 1
+    }
+    if taken == 0 {
+        return nil, ErrNoSamples
+    }
+    offset := (low + high) / 2
+    // network jitter can make the windows miss each other,
+    // in which case the average is the best we have
+    if low > high {
+        offset = sum / time.Duration(taken)
+    }
+    return &Skew{
+        Offset: offset,
+        Latency: minRtt / 2,
+        Samples: taken,
+        Source: url,
+    }, nil
+}
+
+/*
+Name: SampleNTP 
+Type: External Clock Func
+Purpose: Estimate the skew against an NTP server with n
+SNTP(RFC 4330) requests, keeping the sample with the 
+smallest round trip since it is the least disturbed
+*/
+func SampleNTP(server string, n int) (*Skew, error) {
+    if n <= 0 {
+        return nil, ErrNoSamples
+    }
+    if !strings.Contains(server, ":") {
+        server += ":123"
+    }
+    type ntpSample struct {
+        offset  time.Duration
+        delay   time.Duration
+    }
+    samples := make([]ntpSample, 0, n)
+    for i := 0; i < n; i++ {
+        if i != 0 {
+            time.Sleep(sampleSpacing)
+        }
+        offset, delay, err := queryNTP(server)
+        if err != nil {
+            continue
+        }
+        samples = append(samples, ntpSample{offset: offset, delay: delay})
+    }
+    if len(samples) == 0 {
+        return nil, ErrNoSamples
+    }
+    sort.Slice(samples, func(i, j int) bool {
+        return samples[i].delay < samples[j].delay
+    })
+    return &Skew{
+        Offset: samples[0].offset,
+        Latency: samples[0].delay / 2,
+        Samples: len(samples),
+        Source: "ntp://" + server,
+    }, nil
+}
+
+/*
+Name: queryNTP 
+Type: Internal Clock Func
+Purpose: Send one SNTP request and compute the
+clock offset and round trip delay from the reply.
+Replies that aren't from a server(mode 4), are a
+kiss-o'-death(stratum 0) or carry no transmit time
+are rejected, as RFC 4330 asks
+*/
+func queryNTP(server string) (time.Duration, time.Duration, error) {
+    conn, err := net.Dial("udp", server)
+    if err != nil {
+        return 0, 0, err
+    }
+    defer conn.Close()
+    conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+    // LI = 0, VN = 3, Mode = 3(client)
+    request := make([]byte, 48)
+    request[0] = 0x1B
+    originate := time.Now()
+    _, err = conn.Write(request)
+    if err != nil {
+        return 0, 0, err
+    }
+    reply := make([]byte, 48)
+    read, err := conn.Read(reply)
+    destination := time.Now()
+    if err != nil {
+        return 0, 0, err
+    }
+    if read < 48 || reply[0] & 0x07 != 4 || reply[1] == 0 {
+        return 0, 0, ErrBadNTP
+    }
+    if binary.BigEndian.Uint64(reply[40:48]) == 0 {
+        return 0, 0, ErrBadNTP
+    }
+    receive := ntpTime(reply[32:40])
+    transmit := ntpTime(reply[40:48])
+    offset := (receive.Sub(originate) + transmit.Sub(destination)) / 2
+    delay := destination.Sub(originate) - transmit.Sub(receive)
+    return offset, delay, nil
+}
+
+/*
+Name: ntpTime 
+Type: Internal Clock Func
+Purpose: Decode a 64 bit NTP timestamp
+*/
+func ntpTime(b []byte) (time.Time) {
+    seconds := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+    fraction := int64(binary.BigEndian.Uint32(b[4:8]))
+    nanos := (fraction * int64(time.Second)) >> 32
+    return time.Unix(seconds, nanos)
+}
@@ -0,0 +1,210 @@
+package clock
+
+import (
+    "encoding/binary"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+    "time"
+)
+
+/*
+Name: dateServer 
+Type: Test Func
+Purpose: A web server answering its nth request with the
+Date header dates[n], or the last one once they run out
+*/
+func dateServer(t *testing.T, dates ...time.Time) (*httptest.Server) {
+    var mu sync.Mutex
+    served := 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        date := dates[len(dates) - 1]
+        if served < len(dates) {
+            date = dates[served]
+        }
+        served++
+        mu.Unlock()
+        w.Header().Set("Date", date.UTC().Format(http.TimeFormat))
+    }))
+    t.Cleanup(server.Close)
+    return server
+}
+
+func TestSampleHTTPWindows(t *testing.T) {
+    // the server runs an hour ahead, and its date only
+    // has second resolution
+    date := time.Now().Add(time.Hour).Truncate(time.Second)
+    server := dateServer(t, date)
+    start := time.Now()
+    skew, err := SampleHTTP(server.URL, 3)
+    end := time.Now()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if skew.Samples != 3 || skew.Source != server.URL || skew.Latency < 0 {
+        t.Errorf("skew = %+v, want 3 samples from %s", skew, server.URL)
+    }
+    // every window holds date - mid + [0, 1s), and the samples
+    // were taken between start and end, so the intersection's
+    // middle is half a second past date minus some mid
+    low := date.Sub(end) + time.Second / 2
+    high := date.Sub(start) + time.Second / 2
+    if skew.Offset < low || skew.Offset > high {
+        t.Errorf("offset %v, want within [%v, %v]", skew.Offset, low, high)
+    }
+}
+
+func TestSampleHTTPDisjointWindows(t *testing.T) {
+    // the last sample jumps 5s ahead, so its window misses
+    // the others and the offset falls back on the average
+    date := time.Now().Add(time.Hour).Truncate(time.Second)
+    server := dateServer(t, date, date, date.Add(5 * time.Second))
+    start := time.Now()
+    skew, err := SampleHTTP(server.URL, 3)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // the average of the windows' middles is 5/3s + 1/2s past
+    // date minus the average mid, the middle of the (empty)
+    // intersection would be about 3s past it
+    base := date.Sub(start)
+    got := skew.Offset - base
+    if got < 1500 * time.Millisecond || got > 2400 * time.Millisecond {
+        t.Errorf("offset %v past date - start, want about 2.2s", got)
+    }
+}
+
+func TestSampleHTTPErrors(t *testing.T) {
+    if _, err := SampleHTTP("http://127.0.0.1:1", 0); err != ErrNoSamples {
+        t.Errorf("no samples asked: err = %v, want ErrNoSamples", err)
+    }
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header()["Date"] = nil
+    }))
+    defer server.Close()
+    if _, err := SampleHTTP(server.URL, 2); err != ErrNoSamples {
+        t.Errorf("no Date header: err = %v, want ErrNoSamples", err)
+    }
+}
+
+/*
+Name: ntpStamp 
+Type: Test Func
+Purpose: Encode t as a 64 bit NTP timestamp
+*/
+func ntpStamp(b []byte, t time.Time) {
+    binary.BigEndian.PutUint32(b[0:4], uint32(t.Unix() + ntpEpochOffset))
+    binary.BigEndian.PutUint32(b[4:8], uint32((int64(t.Nanosecond()) << 32) / int64(time.Second)))
+}
+
+func TestNTPTime(t *testing.T) {
+    tests := []struct {
+        stamp   []byte
+        want    time.Time
+    }{
+        // the unix epoch, 2208988800s after the ntp one, plus half a second
+        {[]byte{0x83, 0xAA, 0x7E, 0x80, 0x80, 0x00, 0x00, 0x00}, time.Unix(0, 500000000)},
+        // 2023-09-01 00:00:00.25 UTC is 3902515200s after 1900
+        {[]byte{0xE8, 0x9B, 0xA8, 0x00, 0x40, 0x00, 0x00, 0x00}, time.Date(2023, 9, 1, 0, 0, 0, 250000000, time.UTC)},
+    }
+    for _, test := range tests {
+        if got := ntpTime(test.stamp); !got.Equal(test.want) {
+            t.Errorf("ntpTime(% x) = %v, want %v", test.stamp, got, test.want)
+        }
+    }
+    // and back
+    want := time.Date(2023, 9, 1, 12, 34, 56, 789000000, time.UTC)
+    stamp := make([]byte, 8)
+    ntpStamp(stamp, want)
+    if got := ntpTime(stamp); got.Sub(want).Abs() > time.Microsecond {
+        t.Errorf("round trip of %v = %v", want, got)
+    }
+}
+
+/*
+Name: ntpServer 
+Type: Test Func
+Purpose: A local UDP server answering every request
+with the reply build makes of it
+*/
+func ntpServer(t *testing.T, build func(request []byte) ([]byte)) (string) {
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    go func() {
+        buf := make([]byte, 48)
+        for {
+            n, addr, err := conn.ReadFrom(buf)
+            if err != nil {
+                return
+            }
+            conn.WriteTo(build(buf[:n]), addr)
+        }
+    }()
+    return conn.LocalAddr().String()
+}
+
+// A server reply running ahead by offset
+func ntpReply(offset time.Duration) ([]byte) {
+    reply := make([]byte, 48)
+    // LI = 0, VN = 3, Mode = 4(server), stratum 1
+    reply[0] = 0x1C
+    reply[1] = 1
+    now := time.Now().Add(offset)
+    ntpStamp(reply[32:40], now)
+    ntpStamp(reply[40:48], now)
+    return reply
+}
+
+func TestSampleNTP(t *testing.T) {
+    addr := ntpServer(t, func(request []byte) ([]byte) {
+        return ntpReply(time.Hour)
+    })
+    skew, err := SampleNTP(addr, 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if (skew.Offset - time.Hour).Abs() > 50 * time.Millisecond {
+        t.Errorf("offset %v, want about an hour", skew.Offset)
+    }
+    if skew.Samples != 2 || skew.Source != "ntp://" + addr || skew.Latency < 0 {
+        t.Errorf("skew = %+v, want 2 samples from %s", skew, addr)
+    }
+}
+
+func TestQueryNTPRejects(t *testing.T) {
+    tests := map[string]func(reply []byte) ([]byte){
+        "client mode": func(reply []byte) ([]byte) {
+            reply[0] = 0x1B
+            return reply
+        },
+        "kiss-o'-death": func(reply []byte) ([]byte) {
+            reply[1] = 0
+            return reply
+        },
+        "no transmit time": func(reply []byte) ([]byte) {
+            copy(reply[40:48], make([]byte, 8))
+            return reply
+        },
+        "short": func(reply []byte) ([]byte) {
+            return reply[:40]
+        },
+    }
+    for name, spoil := range tests {
+        spoil := spoil
+        addr := ntpServer(t, func(request []byte) ([]byte) {
+            return spoil(ntpReply(0))
+        })
+        if _, _, err := queryNTP(addr); err != ErrBadNTP {
+            t.Errorf("%s: err = %v, want ErrBadNTP", name, err)
+        }
+        if _, err := SampleNTP(addr, 1); err != ErrNoSamples {
+            t.Errorf("%s: SampleNTP err = %v, want ErrNoSamples", name, err)
+        }
+    }
+}
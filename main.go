@@ -5,20 +5,49 @@ Created On: Aug 12, 2023
 package main
 
 import (
+    "github.com/21Bruce/resolved-server/api"
     "github.com/21Bruce/resolved-server/api/resy"
     "github.com/21Bruce/resolved-server/app"
     "github.com/21Bruce/resolved-server/runnable/cli"
+    "github.com/21Bruce/resolved-server/vault"
     "os"
 )
 
 func main() {
     resy_api := resy.GetDefaultAPI()
-    appCtx := app.AppCtx{API: &resy_api}
+    fileStore := &vault.FileStore{Path: vault.DefaultPath()}
     cli := cli.ResolvedCLI{
-        AppCtx: appCtx,
+        AppCtx: app.AppCtx{
+            API: &resy_api,
+            // environment accounts override accounts in the vault file
+            Credentials: vault.Chain{
+                vault.EnvStore{Prefix: "RESOLVED"},
+                fileStore,
+            },
+        },
         In: os.Stdin,
         Out: os.Stdout,
         Err: os.Stderr,
+        Args: os.Args[1:],
+        // what the provider setting can pick
+        Providers: map[string]api.API{
+            "resy": &resy_api,
+        },
+    }
+    fileStore.Passphrase = func() ([]byte, error) {
+        if pass := os.Getenv("RESOLVED_VAULT_PASSPHRASE"); pass != "" {
+            return []byte(pass), nil
+        }
+        return cli.ReadSecret("Vault passphrase: ")
+    }
+    fileStore.Confirm = func() ([]byte, error) {
+        if pass := os.Getenv("RESOLVED_VAULT_PASSPHRASE"); pass != "" {
+            return []byte(pass), nil
+        }
+        return cli.ReadSecret("Confirm vault passphrase: ")
+    }
+    // Run already printed what failed
+    if err := cli.Run(); err != nil {
+        os.Exit(1)
     }
-    cli.Run()
 }
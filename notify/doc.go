@@ -0,0 +1,61 @@
+/*
+**********************************************************************
+
+General Purpose: 
+
+    The notify pkg tells the user how an operation ended without 
+    them having to look. The app layer builds a Notification when an
+    operation succeeds, fails or is cancelled, and hands it to a 
+    Notifier.
+
+**********************************************************************
+
+Notifier:
+
+    The Notifier interface specifies 1 method:
+
+        Notify(n Notification) (error)
+
+    A Notification holds the operation id, its Outcome, when it
+    ended, and the rendered result or error message. Subject and
+    Text render it for sinks that take plain text. A Multi is a
+    slice of notifiers acting as one.
+
+**********************************************************************
+
+Sinks:
+
+    Four sinks are built in. Each talks to something that can be 
+    stood in for locally, so they can be tried without outside 
+    services:
+
+        1. SMTP
+
+            - Description: Sends a plain text email through the 
+              server at Addr, e.g. a local relay, with optional 
+              smtp.Auth.
+
+        2. Webhook
+
+            - Description: POSTs the Notification as JSON to URL,
+              and fails with a WebhookError on a status outside
+              of 2xx.
+
+        3. Command
+
+            - Description: Runs a command with the subject and text
+              appended to its args and the fields of the notification
+              in RESOLVED_OPERATION_ID, RESOLVED_OUTCOME, RESOLVED_TIME,
+              RESOLVED_RESULT and RESOLVED_ERROR, which is enough for
+              notify-send and similar desktop notifiers.
+
+        4. File
+
+            - Description: Appends one tab separated line per 
+              notification to the file at Path.
+
+    A sink missing its destination yields ErrNoSink.
+
+**********************************************************************
+*/
+package notify
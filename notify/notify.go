@@ -0,0 +1,98 @@
+package notify
+
+import (
+    "errors"
+    "strconv"
+    "time"
+)
+
+var (
+    ErrNoSink = errors.New("notification sink is not configured")
+)
+
+/*
+Name: Outcome 
+Type: External Notify Type
+Purpose: How an operation ended
+*/
+type Outcome string
+
+const (
+    Succeeded Outcome = "succeeded"
+    Failed    Outcome = "failed"
+    Cancelled Outcome = "cancelled"
+)
+
+/*
+Name: Notification 
+Type: External Notify Struct
+Purpose: What sinks are told about a finished operation. 
+Result is the rendered result on success, and Error the 
+error message on failure
+*/
+type Notification struct {
+    OperationID int64       `json:"operation_id"`
+    Outcome     Outcome     `json:"outcome"`
+    Time        time.Time   `json:"time"`
+    Result      string      `json:"result,omitempty"`
+    Error       string      `json:"error,omitempty"`
+}
+
+/*
+Name: Subject 
+Type: External Func
+Purpose: A one line summary of the notification
+*/
+func (n Notification) Subject() (string) {
+    return "Resolved operation " + strconv.FormatInt(n.OperationID, 10) + " " + string(n.Outcome)
+}
+
+/*
+Name: Text 
+Type: External Func
+Purpose: The body of the notification for sinks
+that take plain text
+*/
+func (n Notification) Text() (string) {
+    text := n.Subject() + " at " + n.Time.Format("2006-01-02 15:04:05 MST")
+    if n.Result != "" {
+        text += "\nResult: " + n.Result
+    }
+    if n.Error != "" {
+        text += "\nError: " + n.Error
+    }
+    return text
+}
+
+/*
+Name: Notifier 
+Type: Interface 
+Purpose: Anything that can be told about a
+finished operation
+*/
+type Notifier interface {
+    Notify(n Notification) (error)
+}
+
+/*
+Name: Multi
+Type: External Notify Struct
+Purpose: Several notifiers acting as one. Every 
+notifier is tried, and their errors are joined
+*/
+type Multi []Notifier
+
+/*
+Name: Notify 
+Type: External Func
+Purpose: Notify every notifier in order
+*/
+func (m Multi) Notify(n Notification) (error) {
+    errs := make([]error, 0)
+    for _, notifier := range m {
+        if err := notifier.Notify(n); err != nil {
+            errs = append(errs, err)
+        }
+    }
+    return errors.Join(errs...)
+}
@@ -0,0 +1,174 @@
+package notify
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+var testNotification = Notification{
+    OperationID: 7,
+    Outcome: Succeeded,
+    Time: time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC),
+    Result: "2023-09-07 23:00 EDT",
+}
+
+func TestWebhook(t *testing.T) {
+    received := make(chan Notification, 1)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        var n Notification
+        if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        received <- n
+    }))
+    defer server.Close()
+
+    if err := (Webhook{URL: server.URL}).Notify(testNotification); err != nil {
+        t.Fatal(err)
+    }
+    n := <-received
+    if n.OperationID != 7 || n.Outcome != Succeeded || n.Result != testNotification.Result || !n.Time.Equal(testNotification.Time) {
+        t.Errorf("webhook got %+v", n)
+    }
+}
+
+func TestWebhookStatus(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusInternalServerError)
+    }))
+    defer server.Close()
+
+    err := (Webhook{URL: server.URL}).Notify(testNotification)
+    var statusErr *WebhookError
+    if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+        t.Errorf("expected a 500 WebhookError, got %v", err)
+    }
+}
+
+/*
+Name: serveSMTP 
+Type: Test Func
+Purpose: A stand-in SMTP server taking one message, 
+which is sent on the returned channel
+*/
+func serveSMTP(t *testing.T) (string, <-chan string) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { listener.Close() })
+    data := make(chan string, 1)
+    go func() {
+        conn, err := listener.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        reader := bufio.NewReader(conn)
+        conn.Write([]byte("220 localhost ready\r\n"))
+        inData := false
+        msg := ""
+        for {
+            line, err := reader.ReadString('\n')
+            if err != nil {
+                return
+            }
+            if inData {
+                if line == ".\r\n" {
+                    inData = false
+                    data <- msg
+                    conn.Write([]byte("250 queued\r\n"))
+                    continue
+                }
+                msg += line
+                continue
+            }
+            switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+            case "EHLO", "HELO":
+                conn.Write([]byte("250 localhost\r\n"))
+            case "DATA":
+                inData = true
+                conn.Write([]byte("354 go ahead\r\n"))
+            case "QUIT":
+                conn.Write([]byte("221 bye\r\n"))
+                return
+            default:
+                conn.Write([]byte("250 ok\r\n"))
+            }
+        }
+    }()
+    return listener.Addr().String(), data
+}
+
+func TestSMTP(t *testing.T) {
+    addr, data := serveSMTP(t)
+    sink := SMTP{Addr: addr, From: "bot@example.com", To: []string{"me@example.com"}}
+    if err := sink.Notify(testNotification); err != nil {
+        t.Fatal(err)
+    }
+    msg := <-data
+    if !strings.Contains(msg, "Subject: Resolved operation 7 succeeded\r\n") || !strings.Contains(msg, "Result: 2023-09-07 23:00 EDT") {
+        t.Errorf("unexpected message:\n%s", msg)
+    }
+}
+
+func TestCommand(t *testing.T) {
+    if _, err := exec.LookPath("sh"); err != nil {
+        t.Skip("no sh to run")
+    }
+    out := filepath.Join(t.TempDir(), "out")
+    // $1 and $2 are the subject and text
+    sink := Command{Name: "sh", Args: []string{"-c", `printf '%s|%s|%s' "$RESOLVED_OUTCOME" "$RESOLVED_OPERATION_ID" "$1" > ` + out, "sh"}}
+    if err := sink.Notify(testNotification); err != nil {
+        t.Fatal(err)
+    }
+    got, err := os.ReadFile(out)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(got) != "succeeded|7|Resolved operation 7 succeeded" {
+        t.Errorf("command wrote %q", got)
+    }
+}
+
+func TestFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "log")
+    sink := &File{Path: path}
+    failed := Notification{OperationID: 8, Outcome: Failed, Time: testNotification.Time, Error: "no tables"}
+    if err := (Multi{sink, sink}).Notify(testNotification); err != nil {
+        t.Fatal(err)
+    }
+    if err := sink.Notify(failed); err != nil {
+        t.Fatal(err)
+    }
+    got, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    lines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+    if len(lines) != 3 || lines[2] != "2023-09-01T00:00:00Z\t8\tfailed\t\tno tables" {
+        t.Errorf("file holds %q", got)
+    }
+}
+
+func TestMultiJoinsErrors(t *testing.T) {
+    err := (Multi{Webhook{}, &File{}}).Notify(testNotification)
+    if !errors.Is(err, ErrNoSink) {
+        t.Errorf("expected ErrNoSink, got %v", err)
+    }
+}
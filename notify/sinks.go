@@ -0,0 +1,175 @@
+package notify
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/smtp"
+    "os"
+    "os/exec"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+/*
+Name: SMTP
+Type: External Notify Struct
+Purpose: Email notifications through an SMTP server 
+at Addr(host:port). Auth may be nil for servers that
+don't need it, like a local relay
+*/
+type SMTP struct {
+    Addr    string
+    From    string
+    To      []string
+    Auth    smtp.Auth
+}
+
+/*
+Name: Notify 
+Type: External Func
+Purpose: Send the notification as a plain text email
+*/
+func (s SMTP) Notify(n Notification) (error) {
+    if s.Addr == "" || len(s.To) == 0 {
+        return ErrNoSink
+    }
+    msg := "From: " + s.From + "\r\n"
+    msg += "To: " + strings.Join(s.To, ", ") + "\r\n"
+    msg += "Subject: " + n.Subject() + "\r\n"
+    msg += "Content-Type: text/plain; charset=utf-8\r\n"
+    msg += "\r\n"
+    msg += strings.ReplaceAll(n.Text(), "\n", "\r\n") + "\r\n"
+    return smtp.SendMail(s.Addr, s.Auth, s.From, s.To, []byte(msg))
+}
+
+/*
+Name: Webhook
+Type: External Notify Struct
+Purpose: Notifications POSTed as JSON to URL. Client
+defaults to one with a 10 second timeout
+*/
+type Webhook struct {
+    URL     string
+    Client  *http.Client
+}
+
+/*
+Name: WebhookError
+Type: External Notify Struct
+Purpose: The webhook answered with a status
+outside of 2xx
+*/
+type WebhookError struct {
+    StatusCode  int
+}
+
+func (e *WebhookError) Error() (string) {
+    return "webhook responded with status " + strconv.Itoa(e.StatusCode)
+}
+
+/*
+Name: Notify 
+Type: External Func
+Purpose: POST the notification as JSON
+*/
+func (w Webhook) Notify(n Notification) (error) {
+    if w.URL == "" {
+        return ErrNoSink
+    }
+    body, err := json.Marshal(n)
+    if err != nil {
+        return err
+    }
+    client := w.Client
+    if client == nil {
+        client = &http.Client{Timeout: 10 * time.Second}
+    }
+    response, err := client.Post(w.URL, "application/json", bytes.NewBuffer(body))
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+    if response.StatusCode < 200 || response.StatusCode > 299 {
+        return &WebhookError{StatusCode: response.StatusCode}
+    }
+    return nil
+}
+
+/*
+Name: Command
+Type: External Notify Struct
+Purpose: Notifications through an external command, e.g.
+notify-send for desktop notifications. The command runs
+with Args followed by the subject and text, and the fields
+of the notification in RESOLVED_* environment variables
+*/
+type Command struct {
+    Name    string
+    Args    []string
+}
+
+/*
+Name: Notify 
+Type: External Func
+Purpose: Run the command and wait for it
+*/
+func (c Command) Notify(n Notification) (error) {
+    if c.Name == "" {
+        return ErrNoSink
+    }
+    args := append(append([]string{}, c.Args...), n.Subject(), n.Text())
+    cmd := exec.Command(c.Name, args...)
+    cmd.Env = append(os.Environ(),
+        "RESOLVED_OPERATION_ID=" + strconv.FormatInt(n.OperationID, 10),
+        "RESOLVED_OUTCOME=" + string(n.Outcome),
+        "RESOLVED_TIME=" + n.Time.Format(time.RFC3339),
+        "RESOLVED_RESULT=" + n.Result,
+        "RESOLVED_ERROR=" + n.Error,
+    )
+    return cmd.Run()
+}
+
+/*
+Name: File
+Type: External Notify Struct
+Purpose: Notifications appended to the file at Path, 
+one tab separated line each. Use a pointer so writes
+from different operations don't interleave
+*/
+type File struct {
+    Path    string
+    mu      sync.Mutex
+}
+
+/*
+Name: Notify 
+Type: External Func
+Purpose: Append the notification to the file
+*/
+func (f *File) Notify(n Notification) (error) {
+    if f.Path == "" {
+        return ErrNoSink
+    }
+    fields := []string{
+        n.Time.Format(time.RFC3339),
+        strconv.FormatInt(n.OperationID, 10),
+        string(n.Outcome),
+        n.Result,
+        n.Error,
+    }
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+    if err != nil {
+        return err
+    }
+    _, err = file.WriteString(strings.Join(fields, "\t") + "\n")
+    if err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
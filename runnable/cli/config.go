@@ -0,0 +1,308 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "errors"
+    "fmt"
+    "github.com/21Bruce/resolved-server/app"
+    "github.com/21Bruce/resolved-server/cli"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+var (
+    // Error if --config isn't given a path
+    ErrInvConfig = errors.New("--config takes exactly one path")
+    // Error if the provider setting names no provider
+    ErrNoProvider = errors.New("unknown provider")
+    // Error if a key of the release-times table isn't a venue id
+    ErrReleaseVenue = errors.New("release-times keys must be venue ids")
+    // Error if a release time setting isn't one time of day
+    ErrInvReleaseTime = errors.New("release times take one time of day like 10:00")
+)
+
+// Prefix of the environment variables settings are read from
+const envPrefix = "RESOLVED"
+
+// The settings read at startup and by flags left out
+// of a command, in the order 'config show' lists them
+var settingKeys = []string{"account", "provider", "timezone", "party-size", "output", "release-time"}
+
+// Table of the config file holding the release time of
+// each venue, keyed by venue id
+const releaseTable = "release-times."
+
+/*
+Name: DefaultConfig
+Type: External Func
+Purpose: Provide the default location of the config
+file, next to the vault in the user's config directory
+*/
+func DefaultConfig() (string) {
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        dir = "."
+    }
+    return filepath.Join(dir, "resolved", "config")
+}
+
+/*
+Name: configPath
+Type: Internal Func
+Purpose: The config file settings are read from,
+DefaultConfig unless Config is set
+*/
+func (c *ResolvedCLI) configPath() (string) {
+    if c.Config != "" {
+        return c.Config
+    }
+    return DefaultConfig()
+}
+
+/*
+Name: loadSettings
+Type: Internal Func
+Purpose: Read the config file and point the parse
+ctx at the environment and then the file for flags
+left out, then apply the account, provider and
+release time settings to the app and pick the 
+output format
+*/
+func (c *ResolvedCLI) loadSettings() (error) {
+    path := c.configPath()
+    config, err := cli.LoadConfig(path)
+    if err != nil {
+        return err
+    }
+    c.config = config
+    c.parseCtx.Sources = []cli.Source{
+        c.parseCtx.EnvSource(envPrefix),
+        config.Source(path),
+    }
+    if values, where, ok := c.parseCtx.Setting("provider"); ok {
+        provider, ok := c.Providers[values[0]]
+        if !ok {
+            return fmt.Errorf("%w %q (from %s)", ErrNoProvider, values[0], where)
+        }
+        c.AppCtx.API = provider
+    }
+    if values, where, ok := c.parseCtx.Setting("account"); ok {
+        // login and 'account use' still override it
+        if err := c.AppCtx.SetDefaultAccount(values[0]); err != nil {
+            return fmt.Errorf("%w (from %s)", err, where)
+        }
+    }
+    if err := c.loadReleaseTimes(path); err != nil {
+        return err
+    }
+    if c.Output != "" {
+        c.outputFrom = "--output"
+    } else if values, from, ok := c.parseCtx.Setting("output"); ok {
+        c.Output, c.outputFrom = values[0], from
+    } else {
+        return nil
+    }
+    output, err := cli.ParseOutput(c.Output)
+    if err != nil {
+        return fmt.Errorf("%w (from %s)", err, c.outputFrom)
+    }
+    c.Output = output
+    return nil
+}
+
+/*
+Name: loadReleaseTimes
+Type: Internal Func
+Purpose: Give the app the release time of the
+release-time setting, for every venue, and those of
+the release-times table of the config file at path,
+for one venue each. They fill in for providers that 
+don't publish when venues release tables
+*/
+func (c *ResolvedCLI) loadReleaseTimes(path string) (error) {
+    if values, where, ok := c.parseCtx.Setting("release-time"); ok {
+        release, err := parseReleaseTime(values)
+        if err != nil {
+            return fmt.Errorf("%w (from %s)", err, where)
+        }
+        c.AppCtx.SetReleaseTime(app.AnyVenue, release)
+    }
+    for _, key := range c.config.Keys() {
+        if !strings.HasPrefix(key, releaseTable) {
+            continue
+        }
+        venueID, err := strconv.ParseInt(strings.TrimPrefix(key, releaseTable), 10, 64)
+        if err != nil || venueID < 0 {
+            return fmt.Errorf("%w, not %q (from %s)", ErrReleaseVenue, strings.TrimPrefix(key, releaseTable), path)
+        }
+        release, err := parseReleaseTime(c.config[key])
+        if err != nil {
+            return fmt.Errorf("%s: %w (from %s)", key, err, path)
+        }
+        c.AppCtx.SetReleaseTime(venueID, release)
+    }
+    return nil
+}
+
+/*
+Name: parseReleaseTime
+Type: Internal Func
+Purpose: Read the time of day of a release time setting
+*/
+func parseReleaseTime(values []string) (time.Duration, error) {
+    if len(values) != 1 {
+        return 0, ErrInvReleaseTime
+    }
+    release, err := cli.ParseTimeOfDay(values[0])
+    if err != nil {
+        return 0, ErrInvReleaseTime
+    }
+    return release, nil
+}
+
+/*
+Name: configReport
+Type: Internal Struct
+Purpose: What 'config show' found, where settings
+are read from, first one winning, and the value
+of each setting
+*/
+type configReport struct {
+    Sources     []configSource  `json:"sources"`
+    Settings    []configSetting `json:"settings"`
+}
+
+type configSource struct {
+    Name    string  `json:"name"`
+    Where   string  `json:"where,omitempty"`
+    Missing bool    `json:"missing,omitempty"`
+}
+
+// Values are empty when the setting is unset, and Unknown
+// is set for keys of the file that aren't settings
+type configSetting struct {
+    Key     string      `json:"key"`
+    Values  []string    `json:"values,omitempty"`
+    From    string      `json:"from,omitempty"`
+    Unknown bool        `json:"unknown,omitempty"`
+}
+
+/*
+Name: configReport.Text
+Type: Internal Func
+Purpose: The sources and then the settings, one
+per line under a heading each
+*/
+func (r configReport) Text() (string) {
+    showStr := "Sources, first wins:"
+    for _, src := range r.Sources {
+        showStr += "\n\t" + src.Name
+        if src.Where != "" {
+            showStr += ", " + src.Where
+        }
+        if src.Missing {
+            showStr += " (not found)"
+        }
+    }
+    showStr += "\nSettings:"
+    for _, setting := range r.Settings {
+        showStr += "\n\t" + setting.Key + ": "
+        switch {
+            case setting.Unknown:
+                showStr += "unknown, ignored"
+            case setting.Values == nil:
+                showStr += "not set"
+            default:
+                showStr += strings.Join(setting.Values, " ") + " (from " + setting.From + ")"
+        }
+    }
+    return showStr
+}
+
+/*
+Name: configReport.Table
+Type: Internal Func
+Purpose: A row per setting with its value and
+where it came from
+*/
+func (r configReport) Table() ([]string, [][]string) {
+    rows := make([][]string, len(r.Settings))
+    for i, setting := range r.Settings {
+        from := setting.From
+        if setting.Unknown {
+            from = "unknown, ignored"
+        }
+        rows[i] = []string{setting.Key, strings.Join(setting.Values, " "), from}
+    }
+    return []string{"setting", "value", "from"}, rows
+}
+
+/*
+Name: handleConfigShow
+Type: Internal Func
+Purpose: This function is the handler
+for the 'config show' command, its goal is to
+report where settings are read from and the
+value each setting has right now
+*/
+func (c *ResolvedCLI) handleConfigShow(in cli.Values) (interface{}, error) {
+    path := c.configPath()
+    _, err := os.Stat(path)
+    report := configReport{
+        Sources: []configSource{
+            {Name: "command line"},
+            {Name: "environment", Where: envPrefix + "_*"},
+            {Name: "config file", Where: path, Missing: err != nil},
+        },
+    }
+    for _, key := range settingKeys {
+        setting := configSetting{Key: key}
+        setting.Values, setting.From, _ = c.parseCtx.Setting(key)
+        // what Output holds now, which --output and
+        // 'config output' may have changed
+        if key == "output" && c.Output != "" {
+            setting.Values, setting.From = []string{c.Output}, c.outputFrom
+        }
+        report.Settings = append(report.Settings, setting)
+    }
+    for _, key := range c.config.Keys() {
+        if strings.HasPrefix(key, releaseTable) {
+            report.Settings = append(report.Settings, configSetting{Key: key, Values: c.config[key], From: path})
+            continue
+        }
+        known := false
+        for _, setting := range settingKeys {
+            known = known || key == setting
+        }
+        if !known {
+            report.Settings = append(report.Settings, configSetting{Key: key, Unknown: true})
+        }
+    }
+    return report, nil
+}
+
+/*
+Name: handleConfigOutput
+Type: Internal Func
+Purpose: This function is the handler
+for the 'config output' command, its goal is to
+change the format results are printed in until
+the CLI exits, or say which one is used
+*/
+func (c *ResolvedCLI) handleConfigOutput(in cli.Values) (interface{}, error) {
+    if in.Has("f") {
+        c.Output = in.String("f")
+        c.outputFrom = "'config output'"
+    }
+    output := c.Output
+    if output == "" {
+        output = cli.TextOutput
+    }
+    return "Printing results as " + output, nil
+}
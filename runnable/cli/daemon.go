@@ -0,0 +1,319 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "github.com/21Bruce/resolved-server/cli"
+    "github.com/21Bruce/resolved-server/vault"
+    "net"
+    "os"
+    "os/signal"
+    "path/filepath"
+    "syscall"
+)
+
+var (
+    // Error if 'daemon' is run while another daemon owns the socket
+    ErrDaemonRunning = errors.New("a daemon is already listening on the socket")
+    // Error if a command that needs a terminal is sent to the daemon
+    ErrLocalCmd = errors.New("command can't be run through the daemon")
+    // Error if the directory of the socket is open to other users
+    ErrSocketDir = errors.New("socket directory must only be accessible by its owner")
+    // Error if the daemon needs input once it no longer reads its terminal
+    ErrNoPrompt = errors.New("the daemon can't prompt, set RESOLVED_VAULT_PASSPHRASE or give the password")
+)
+
+/*
+Name: DefaultSocket
+Type: External Func
+Purpose: Provide the default location of the daemon
+socket, next to the vault in the user's config directory
+*/
+func DefaultSocket() (string) {
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        dir = "."
+    }
+    return filepath.Join(dir, "resolved", "daemon.sock")
+}
+
+/*
+Name: daemonRequest
+Type: Internal Struct
+Purpose: One command sent by a client, already
+parsed into its flag map on the client side
+*/
+type daemonRequest struct {
+    Command string              `json:"command"`
+    Flags   cli.Values          `json:"flags"`
+    // Format the output is rendered in, text when empty
+    Output  string              `json:"output,omitempty"`
+}
+
+/*
+Name: daemonResponse
+Type: Internal Struct
+Purpose: What the daemon answers a request with,
+the command output or why it failed
+*/
+type daemonResponse struct {
+    Output  string  `json:"output,omitempty"`
+    Error   string  `json:"error,omitempty"`
+}
+
+/*
+Name: socketPath
+Type: Internal Func
+Purpose: The socket the daemon listens on and
+clients dial, DefaultSocket unless Socket is set
+*/
+func (c *ResolvedCLI) socketPath() (string) {
+    if c.Socket != "" {
+        return c.Socket
+    }
+    return DefaultSocket()
+}
+
+/*
+Name: runDaemon
+Type: Internal Func
+Purpose: Listen on the socket and run the commands
+clients send until interrupted or terminated. The vault
+is unlocked first, since nothing will answer a prompt 
+once the daemon is detached, and the socket is only open
+to the user, since logins go through it
+*/
+func (c *ResolvedCLI) runDaemon() (error) {
+    path := c.socketPath()
+    if conn, err := net.Dial("unix", path); err == nil {
+        conn.Close()
+        return ErrDaemonRunning
+    }
+    if unlocker, ok := c.AppCtx.Credentials.(vault.Unlocker); ok {
+        err := unlocker.Unlock()
+        if err == ErrNoSecret {
+            return ErrNoPrompt
+        }
+        if err != nil {
+            return err
+        }
+    }
+    c.detached = true
+    c.detach()
+    listener, err := listenSocket(path)
+    if err != nil {
+        return err
+    }
+
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+    defer signal.Stop(signals)
+    go func() {
+        <-signals
+        // closing the listener removes the socket and ends the loop below
+        listener.Close()
+    }()
+
+    fmt.Fprintln(c.Out, "Daemon listening on " + path)
+    for {
+        conn, err := listener.Accept()
+        if err != nil {
+            if errors.Is(err, net.ErrClosed) {
+                return nil
+            }
+            return err
+        }
+        go c.serveClient(conn)
+    }
+}
+
+/*
+Name: detach
+Type: Internal Func
+Purpose: Keep the daemon running once the terminal it
+was started from closes. SIGHUP is ignored, and when In
+is a terminal the daemon moves to a session of its own
+so the terminal can't signal it at all. A shell's
+foreground job leads its process group and can't start
+a session, there ignoring SIGHUP keeps it alive and
+Ctrl-C still stops it
+*/
+func (c *ResolvedCLI) detach() {
+    signal.Ignore(syscall.SIGHUP)
+    if f, ok := c.In.(*os.File); ok && cli.IsTerminal(f) {
+        // fails for a process group leader, which is fine
+        syscall.Setsid()
+    }
+}
+
+/*
+Name: listenSocket
+Type: Internal Func
+Purpose: Listen on the socket at path, replacing one
+left by a dead daemon. The directory holding it must
+be private so no one can connect before the socket's
+own mode is set, ours is made so and any other is 
+refused
+*/
+func listenSocket(path string) (net.Listener, error) {
+    dir := filepath.Dir(path)
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, err
+    }
+    info, err := os.Stat(dir)
+    if err != nil {
+        return nil, err
+    }
+    if info.Mode().Perm() & 0077 != 0 {
+        if dir != filepath.Dir(DefaultSocket()) {
+            return nil, ErrSocketDir
+        }
+        if err := os.Chmod(dir, 0700); err != nil {
+            return nil, err
+        }
+    }
+    // nothing answered, so whatever is there was left by a dead daemon
+    os.Remove(path)
+    listener, err := net.Listen("unix", path)
+    if err != nil {
+        return nil, err
+    }
+    if err := os.Chmod(path, 0600); err != nil {
+        listener.Close()
+        return nil, err
+    }
+    return listener, nil
+}
+
+/*
+Name: serveClient
+Type: Internal Func
+Purpose: Run each request of one client in turn
+until it hangs up. Commands from every client
+share c, so they run one at a time
+*/
+func (c *ResolvedCLI) serveClient(conn net.Conn) {
+    defer conn.Close()
+    dec := json.NewDecoder(conn)
+    enc := json.NewEncoder(conn)
+    for {
+        var req daemonRequest
+        if err := dec.Decode(&req); err != nil {
+            return
+        }
+        var resp daemonResponse
+        output, err := c.runRemote(req)
+        if err != nil {
+            resp.Error = err.Error()
+        } else {
+            resp.Output = output
+        }
+        if err := enc.Encode(resp); err != nil {
+            return
+        }
+    }
+}
+
+/*
+Name: runRemote
+Type: Internal Func
+Purpose: Run one command sent by a client, refusing
+commands that only make sense on a terminal
+*/
+func (c *ResolvedCLI) runRemote(req daemonRequest) (string, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    // the client validated the flags but we don't trust it to
+    cmd, err := c.parseCtx.ResolveFlags(req.Command, req.Flags)
+    if err != nil {
+        return "", err
+    }
+    switch cmd.Name {
+        case "tail", "quit":
+            return "", ErrLocalCmd
+    }
+    if req.Flags == nil {
+        req.Flags = make(cli.Values)
+    }
+    return c.runHandler(cmd, req.Flags, req.Output)
+}
+
+/*
+Name: sendCommand
+Type: Internal Func
+Purpose: Run one command through the daemon. The
+line was parsed here so mistakes are caught before
+sending, a password left out is prompted for on
+this terminal, and help, exit, quit, config show
+and config output run on the client
+*/
+func (c *ResolvedCLI) sendCommand(cmd *cli.Command, flags cli.Values) (string, error) {
+    path := c.parseCtx.Path(cmd)
+    switch path {
+        case "help", "quit", "config show", "config output":
+            return c.runHandler(cmd, flags, c.Output)
+    }
+    if flags["e"] != nil && flags["p"] == nil && hasFlag(*cmd, "p") {
+        password, err := c.ReadSecret("Password: ")
+        if err != nil {
+            return "", err
+        }
+        flags["p"] = []string{string(password)}
+    }
+    return c.sendDaemon(daemonRequest{Command: path, Flags: flags, Output: c.Output})
+}
+
+/*
+Name: hasFlag
+Type: Internal Func
+Purpose: Report whether cmd takes the named flag
+*/
+func hasFlag(cmd cli.Command, name string) (bool) {
+    for _, flag := range cmd.Flags {
+        if flag.Name == name {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+Name: dialDaemon
+Type: Internal Func
+Purpose: Connect to a running daemon, leaving the
+CLI to run commands itself when there is none
+*/
+func (c *ResolvedCLI) dialDaemon() {
+    conn, err := net.Dial("unix", c.socketPath())
+    if err != nil {
+        return
+    }
+    c.conn = conn
+    c.enc = json.NewEncoder(conn)
+    c.dec = json.NewDecoder(conn)
+}
+
+/*
+Name: sendDaemon
+Type: Internal Func
+Purpose: Send one request to the daemon and
+return its output
+*/
+func (c *ResolvedCLI) sendDaemon(req daemonRequest) (string, error) {
+    if err := c.enc.Encode(req); err != nil {
+        return "", err
+    }
+    var resp daemonResponse
+    if err := c.dec.Decode(&resp); err != nil {
+        return "", err
+    }
+    if resp.Error != "" {
+        return "", errors.New(resp.Error)
+    }
+    return resp.Output, nil
+}
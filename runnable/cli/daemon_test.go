@@ -0,0 +1,281 @@
+package cli
+
+import (
+    "bytes"
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/app"
+    "github.com/21Bruce/resolved-server/cli"
+    "github.com/21Bruce/resolved-server/clock"
+    "github.com/21Bruce/resolved-server/vault"
+    "os"
+    "path/filepath"
+    "strings"
+    "syscall"
+    "testing"
+    "time"
+)
+
+var testStart = time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+
+/*
+Name: idleAPI
+Type: Test Struct
+Purpose: An api.API that logs in and books anything,
+operations never get that far on a clock that stands still
+*/
+type idleAPI struct{}
+
+func (idleAPI) Login(params api.LoginParam) (*api.LoginResponse, error) {
+    return &api.LoginResponse{Email: params.Email, AuthToken: "token"}, nil
+}
+
+func (idleAPI) Search(params api.SearchParam) (*api.SearchResponse, error) {
+    return &api.SearchResponse{}, nil
+}
+
+func (idleAPI) Reserve(params api.ReserveParam) (*api.ReserveResponse, error) {
+    return &api.ReserveResponse{ReservationTime: params.ReservationTimes[0]}, nil
+}
+
+func (idleAPI) AuthMinExpire() (time.Duration) {
+    return time.Hour
+}
+
+func (idleAPI) BookingPolicy(params api.BookingPolicyParam) (*api.BookingPolicyResponse, error) {
+    return nil, api.ErrNoPolicy
+}
+
+func (idleAPI) Timezone(params api.TimezoneParam) (*api.TimezoneResponse, error) {
+    return nil, api.ErrNoTimezone
+}
+
+func (idleAPI) Availability(params api.AvailabilityParam) (*api.AvailabilityResponse, error) {
+    return &api.AvailabilityResponse{}, nil
+}
+
+/*
+Name: newTestCLI
+Type: Test Func
+Purpose: A CLI over an app on a fake clock, reading
+accounts from TEST_* environment variables and
+writing everything to out
+*/
+func newTestCLI(out *bytes.Buffer) (*ResolvedCLI) {
+    c := &ResolvedCLI{
+        AppCtx: app.AppCtx{
+            API: idleAPI{},
+            Clock: clock.NewFake(testStart),
+            Credentials: vault.EnvStore{Prefix: "TEST"},
+        },
+        In: strings.NewReader(""),
+        Out: out,
+        Err: out,
+    }
+    c.initParseCtx()
+    return c
+}
+
+/*
+Name: socketDir
+Type: Test Func
+Purpose: A private directory for a socket, kept
+short since socket paths are limited in length
+*/
+func socketDir(t *testing.T) (string) {
+    dir, err := os.MkdirTemp("", "rd")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.RemoveAll(dir) })
+    return dir
+}
+
+/*
+Name: send
+Type: Test Func
+Purpose: Parse line on the client and send it
+to the daemon
+*/
+func send(t *testing.T, client *ResolvedCLI, line string) (string, error) {
+    t.Helper()
+    cmd, flags, err := client.parseCtx.Resolve(line)
+    if err != nil {
+        t.Fatalf("%s: %v", line, err)
+    }
+    return client.sendCommand(cmd, flags)
+}
+
+func TestDaemonRoundTrip(t *testing.T) {
+    t.Setenv("TEST_WORK_EMAIL", "me@example.com")
+    t.Setenv("TEST_WORK_PASSWORD", "secret")
+    path := filepath.Join(socketDir(t), "daemon.sock")
+    var daemonOut bytes.Buffer
+    daemon := newTestCLI(&daemonOut)
+    listener, err := listenSocket(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer listener.Close()
+    if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+        t.Errorf("socket mode = %v, %v, want 0600", info.Mode().Perm(), err)
+    }
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go daemon.serveClient(conn)
+        }
+    }()
+
+    var clientOut bytes.Buffer
+    client := newTestCLI(&clientOut)
+    client.Socket = path
+    client.dialDaemon()
+    if client.conn == nil {
+        t.Fatal("client didn't connect to the daemon")
+    }
+    defer client.conn.Close()
+
+    out, err := send(t, client, "account list")
+    if err != nil || out != "Accounts:\n\twork" {
+        t.Errorf("account list = %q, %v", out, err)
+    }
+    // the client's format is the one rendered
+    client.Output = "json"
+    out, err = send(t, client, "account list")
+    if err != nil || out != "[\n  \"work\"\n]" {
+        t.Errorf("account list in json = %q, %v", out, err)
+    }
+
+    // commands needing the daemon's terminal are refused
+    if _, err := send(t, client, "tail"); err == nil || err.Error() != ErrLocalCmd.Error() {
+        t.Errorf("tail: err = %v, want %v", err, ErrLocalCmd)
+    }
+    // the daemon checks the flags itself
+    _, err = client.sendDaemon(daemonRequest{Command: "account list", Flags: cli.Values{"z": {"1"}}})
+    if err == nil {
+        t.Errorf("unknown flag: no error")
+    }
+    _, err = client.sendDaemon(daemonRequest{Command: "nope"})
+    if err == nil {
+        t.Errorf("unknown command: no error")
+    }
+    // and the connection outlives the errors
+    if out, err := send(t, client, "account list"); err != nil || !strings.Contains(out, "work") {
+        t.Errorf("account list after errors = %q, %v", out, err)
+    }
+    if daemonOut.Len() != 0 {
+        t.Errorf("daemon printed %q, output belongs to the client", daemonOut.String())
+    }
+
+    // a second daemon won't take over the socket
+    var otherOut bytes.Buffer
+    other := newTestCLI(&otherOut)
+    other.Socket = path
+    if err := other.runDaemon(); err != ErrDaemonRunning {
+        t.Errorf("second daemon: err = %v, want ErrDaemonRunning", err)
+    }
+}
+
+func TestListenSocketDir(t *testing.T) {
+    dir := socketDir(t)
+    if err := os.Chmod(dir, 0755); err != nil {
+        t.Fatal(err)
+    }
+    path := filepath.Join(dir, "daemon.sock")
+    if _, err := listenSocket(path); err != ErrSocketDir {
+        t.Errorf("open directory: err = %v, want ErrSocketDir", err)
+    }
+    if _, err := os.Stat(path); err == nil {
+        t.Errorf("socket created in an open directory")
+    }
+
+    // a missing directory is made private
+    nested := filepath.Join(dir, "sub", "daemon.sock")
+    listener, err := listenSocket(nested)
+    if err != nil {
+        t.Fatal(err)
+    }
+    listener.Close()
+    if info, err := os.Stat(filepath.Dir(nested)); err != nil || info.Mode().Perm() != 0700 {
+        t.Errorf("directory mode = %v, %v, want 0700", info.Mode().Perm(), err)
+    }
+}
+
+func TestDaemonNeedsPassphrase(t *testing.T) {
+    dir := socketDir(t)
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    c.Socket = filepath.Join(dir, "daemon.sock")
+    c.AppCtx.Credentials = &vault.FileStore{
+        Path: filepath.Join(dir, "vault"),
+        Passphrase: func() ([]byte, error) {
+            return c.ReadSecret("Vault passphrase: ")
+        },
+    }
+    // nothing to read the passphrase from, so it fails before listening
+    if err := c.runDaemon(); err != ErrNoPrompt {
+        t.Errorf("runDaemon: err = %v, want ErrNoPrompt", err)
+    }
+    if _, err := os.Stat(c.Socket); err == nil {
+        t.Errorf("socket created without an unlocked vault")
+    }
+
+    // once detached, nothing is read from In
+    c.In = strings.NewReader("passphrase\n")
+    c.detached = true
+    if _, err := c.ReadSecret("Password: "); err != ErrNoPrompt {
+        t.Errorf("detached ReadSecret: err = %v, want ErrNoPrompt", err)
+    }
+}
+
+func TestDaemonSurvivesHangup(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    c.Socket = filepath.Join(socketDir(t), "daemon.sock")
+    done := make(chan error, 1)
+    go func() { done <- c.runDaemon() }()
+
+    var client *ResolvedCLI
+    deadline := time.Now().Add(5 * time.Second)
+    for client == nil || client.conn == nil {
+        if time.Now().After(deadline) {
+            t.Fatal("daemon didn't start listening")
+        }
+        time.Sleep(time.Millisecond)
+        var clientOut bytes.Buffer
+        client = newTestCLI(&clientOut)
+        client.Socket = c.Socket
+        client.dialDaemon()
+    }
+    defer client.conn.Close()
+
+    // the terminal closing sends SIGHUP, which would kill the test if not ignored
+    if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+        t.Fatal(err)
+    }
+    time.Sleep(50 * time.Millisecond)
+    if out, err := send(t, client, "op list -o json"); err != nil || strings.TrimSpace(out) != "[]" {
+        t.Errorf("op list after SIGHUP = %q, %v", out, err)
+    }
+    select {
+        case err := <-done:
+            t.Fatalf("daemon stopped on SIGHUP: %v", err)
+        default:
+    }
+
+    // SIGTERM still stops it
+    if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+        t.Fatal(err)
+    }
+    select {
+        case err := <-done:
+            if err != nil {
+                t.Errorf("runDaemon: err = %v", err)
+            }
+        case <-time.After(5 * time.Second):
+            t.Fatal("daemon didn't stop on SIGTERM")
+    }
+}
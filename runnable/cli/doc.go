@@ -9,6 +9,10 @@ General Purpose:
     The runnable/cli pkg is a complete program representing the combo
     of the core app with a command line interface. This cli is a REPL,
     and acts as a running environment which is not supposed to crash.
+    It can also run a single command or a script of commands without
+    prompting, for use from cron and shell scripts, and run as a 
+    daemon serving other instances over a Unix socket so operations
+    outlive the terminal.
 
     This pkg has two very big dependencies: The app pkg(back-end) and
     the cli pkg(front-end), so understanding internals can be learned
@@ -24,6 +28,58 @@ How To Use(In Code):
     fields. It takes in an io.Reader object to read input from
     in the In field, and io.Writer objects for the Out and Err
     fields, where it will report valid output or errors respectively.
+    The optional Args field takes the process arguments: a command
+    to run once, or --script followed by a file of commands. With
+    no Args and an In that isn't a terminal, the commands are read
+    from In without a prompt. Outside the REPL, Run waits for
+    scheduled operations to finish and send their notifications,
+    and returns ErrFailed if any command or operation failed, so
+    the caller can exit non-zero. There 'tail' is refused with
+    ErrBatchTail, and 'watch -k', 'snipe' and 'recur', which only
+    end when cancelled, with ErrBatchForever unless a daemon runs
+    them. Run returns at EOF or on 'exit'.
+    The REPL edits lines like a shell, keeps its history in the 
+    History field, DefaultHistory when empty, leaving out lines 
+    with passwords, and completes commands, flags, choices, 
+    operation and template ids, venue ids, by id or by the name 
+    'search' found, and account names on tab. Account names come
+    from the account commands already run, never from the vault,
+    so completing can't stop for its passphrase.
+    A line that fails to parse is reported on Err with a caret under
+    the token at fault, below the prompt in the REPL and under an 
+    echo of the line otherwise, along with the closest commands or
+    flags when one was mistyped.
+    Commands on operations are subcommands of 'op' and those on
+    saved accounts of 'account', like 'op list' and 'account add',
+    and the names they had before, like 'list' and 'account-add',
+    are kept as aliases.
+
+    When Args is 'daemon', Run listens on a Unix socket, the Socket
+    field or DefaultSocket when empty, and runs the commands clients
+    send until interrupted or sent SIGTERM. The vault is unlocked
+    before listening, so the passphrase comes from the terminal the
+    daemon starts on or the environment, and the daemon never
+    prompts afterwards. It then ignores SIGHUP and, when it can,
+    starts a session of its own, so closing that terminal doesn't
+    stop it.
+    The socket's directory must be private to the user. Otherwise, when a daemon answers on the
+    socket, the commands read are parsed and sent to it instead of
+    run locally, except 'help', 'exit', 'quit' and 'config show'.
+    The party size and timezone flags of rats, rais, watch, snipe
+    and recur, when left out, are read from RESOLVED_PARTY_SIZE and
+    RESOLVED_TIMEZONE, then from the config file in the Config 
+    field, DefaultConfig when empty or --config in Args. The file
+    can also set the default account and the provider, picked by
+    name from the Providers field. See 'config show'.
+    Results are printed in the format in the Output field, one of 
+    text, json, yaml or table, set by --output in Args or the 
+    output setting, and changed in the REPL by 'config output'.
+    Every command also takes --output, or -o, anywhere on its 
+    line, for its own result only. Text is what commands always 
+    printed, and
+    search, op list, op show, account list and config show give
+    their results fields for the other formats, so they can be 
+    piped into jq. Errors stay text on Err.
     Finally, the Resolved CLI takes in an AppCtx, with the intent
     being that this CLI pkg can be easily repurposed between external
     APIs. Although the opentable go API is not complete yet, its 
@@ -37,12 +93,18 @@ How to Use(On Computer):
     app features for the Resy(Opentable soon) implementation
     via a simple CLI. We explain each command here:
 
-        1. login [-e email] [-p password] 
+        1. login [-e email] [-p password] [-s]
 
             This command checks if the given 
             login information link to a valid Resy
             account and if they do will save them
-            for use in future commands
+            in memory for use in future commands, 
+            which look them up by the email instead of
+            copying the password. If
+            -p is left out, the password is prompted
+            for without echo. With -s the login is 
+            also saved in the credential store under
+            the email, like 'account add' does
  
         2. logout 
 
@@ -64,7 +126,7 @@ How to Use(On Computer):
             specify restaurants and a piece of data
             that must be sent in a reservation command
 
-        4. rats [-v venue-id] [-ps party-size] [-resD reservation-day] [-resT reservation-times] [-reqD request-date]
+        4. rats [-v venue-id] [-ps party-size] [-resD reservation-day] [-resT reservation-times] [-reqD request-date] [-relT release-time] [--tz timezone]
             
             This command sends a reservation request
             at a specified date down to the minute.
@@ -79,9 +141,30 @@ How to Use(On Computer):
             restaurant locale), and the date to send
             the request to resy in the -reqD field
             (in YYYY:MM:DD:HH:MM miliatry time format
-            relative to the local locale).
+            also relative to the restaurant locale).
+            The restaurant locale is looked up from resy,
+            and can be overridden with a tz database name
+            in the --tz field, e.g. --tz America/Chicago.
+            If the lookup fails the command does too, 
+            asking for --tz instead of guessing. If -reqD is
+            left out, the request date is computed from the
+            venue booking policy as the reservation day
+            minus the days in advance the venue releases
+            tables, at the time of day it releases them. 
+            Since resy doesn't publish that time of day, it
+            is read from the release-time setting, the 
+            release-times table of the config file for the
+            venue or else release-time for every venue, or 
+            given in the -relT field(in HH:MM military time
+            format relative to the restaurant locale), which
+            is needed when neither is set. Giving several
+            saved accounts to the -a field tries the same
+            reservation with every account at once, and
+            stops the others as soon as one books. Each 
+            account logs in with what was saved for it, so
+            -e and -p can't be given along with them.
 
-        5. rais [-v venue-id] [-ps party-size] [-resD reservation-day] [-resT reservation-times] [-i interval] 
+        5. rais [-v venue-id] [-ps party-size] [-resD reservation-day] [-resT reservation-times] [-i interval] [--tz timezone]
             
             This command sends a reservation request
             on a repeated interval until a time is
@@ -98,30 +181,202 @@ How to Use(On Computer):
             the request to resy in the -i field
             (in HH:MM format).
 
-        6. list
+        6. race [-m members]
+
+            This command races several targets against each 
+            other. Each arg in the -m field is a full rats or
+            rais command wrapped in brackets, e.g.
+            race -m [rats -v 1 ...] [rais -v 2 ...]. Every
+            target is its own operation belonging to a group 
+            operation, and as soon as one target books the
+            rest are cancelled. Cancelling or cleaning the 
+            group cancels or cleans every target in it
+
+        7. watch [-v venue-id] [-ps party-size] [-resD reservation-day] [-resT reservation-times] [-i interval] [-k]
+
+            This command takes the same flags as rais, but 
+            instead of booking it only checks which of the 
+            times are open on every interval, and prints them
+            as soon as they appear. Without -k it stops after
+            the first open slots, with -k it keeps watching 
+            and prints each slot that opens up again
+
+        8. snipe [-v venue-id] [-ps party-size] [-resD reservation-day] [-resT reservation-times] [-min seconds] [-max seconds]
+
+            This command takes the same flags as rais but -i,
+            and books cancellations. It keeps track of every 
+            slot the venue offers that day, and as soon as one
+            of the times opens up again it is booked. Polling 
+            runs every -min seconds(30 by default) 24 to 48 
+            hours before the reservation, when most people 
+            cancel, and slows down to every -max seconds(600 by
+            default) further from it
+
+        9. recur [-v venue-id] [-ps party-size] [-wd weekdays] [-resT reservation-times] [-ahead days] [-relT release-time] [--tz timezone]
+
+            This command makes a recurring template for a standing
+            reservation. For every reservation day falling on one
+            of the weekdays in the -wd field(e.g. thu), a rats
+            operation is scheduled for the times in the -resT 
+            field, requested the number of days in the -ahead 
+            field before at the time in the -relT field. Without
+            -ahead and -relT the venue booking policy is used,
+            with the release time settings rats reads. One
+            operation is scheduled at a time, the next once the 
+            request of the last has gone out. recur list lists
+            templates with the operations they scheduled, and 
+            recur pause, recur resume and recur rm [-i id] pause,
+            resume and delete them. Pausing or deleting cancels 
+            the operation waiting on the next occurrence
+
+        10. op list [-s status...] [-v venue] [-a account]
             
             This command lists a history of operations, their IDs,
-            and statuses
+            types, venues, accounts and statuses. Request and 
+            reservation times are shown in the restaurant locale,
+            followed by the local time when the two differ. The -s
+            field keeps only operations with the given statuses:
+            in-progress, succeeded, failed or cancelled. The -v
+            field keeps operations on a venue id, and the -a field
+            operations run by a saved account name or login email
+
+        11. op show [-i id]
+
+            This command shows one operation in full: what the
+            list command shows, when it was created, made its 
+            first call and finished, and a log of every login,
+            reserve and availability call it made, with how long
+            each took and what came back, to the millisecond
+
+        12. op edit [-i id] [-resD reservation-day] [-resT reservation-times] [-t table] [-ps party-size] [-iv interval] [-reqD request-date] [--tz timezone]
 
-        7. cancel [-i id]
+            This command changes an in progress operation without
+            cancelling it, keeping its ID and history. Only the
+            fields given are changed: the reservation day and 
+            times(given together), table types, party size, the 
+            interval of a rais or watch operation in hh:mm format
+            or as a duration like 30s
+            and the request date of a rats operation. Dates and
+            times are read in the timezone of the operation. A
+            rats operation waiting on its request wakes up and 
+            waits on the new one, the request date can't be 
+            changed once the request went out
+
+        13. op cancel [-i id]
             
             This command will attempt to cancel the operations with
             ids specified in the -i field. Operations can only be
             cancelled if they are in progress 
 
-        8. clean [-i id]
+        14. op clean [-i id]
             
             This command will attempt to remove the operation
             from the history displayed by the list command. This
             will only work on operations that are not in progress.
             
-        9. help 
+        15. account add [-a account] [-e email] [-p password]
+
+            This command checks the login information like
+            login does and saves it in the encrypted vault
+            under the name in the -a field. The -a flag of 
+            rats and rais can then be used to pick the account.
+            account use [-a account] makes a saved account the
+            login default, account list lists saved accounts
+            and account rm [-a account] removes them. 
+            
+            The vault lives in the user config directory and
+            its passphrase is prompted for on first use, twice 
+            when the vault is created so a typo can't lock it, or
+            read from the RESOLVED_VAULT_PASSPHRASE environment 
+            variable. Accounts can also be provided through the
+            RESOLVED_<NAME>_EMAIL and RESOLVED_<NAME>_PASSWORD 
+            environment variables
+
+        16. clock [-u url] [-n ntp-server] [-l lead]
+
+            This command measures how far the local clock
+            is from the provider's, by sampling the Date
+            header of the URL in the -u field(the resy api
+            by default) or by asking the NTP server in the
+            -n field. Every scheduled fire then uses the 
+            provider's clock. The -l field sets how many
+            milliseconds early requests are fired; given
+            alone it only sets the lead time. The measured
+            offset, latency and lead are printed
+
+        17. notify [-w url] [-c command] [-f file] [-m addresses] [--smtp host:port] [-i id] [-x]
+
+            This command sets where operation outcomes are sent
+            when operations succeed, fail or are cancelled. The
+            -w field POSTs them as JSON to a webhook, the -c field
+            runs a command, e.g. notify-send for a desktop 
+            notification, the -f field appends them to a file, 
+            and the -m field emails the addresses through the 
+            smtp server in the --smtp field, logged into with the
+            RESOLVED_SMTP_USERNAME and RESOLVED_SMTP_PASSWORD 
+            environment variables if set. Several sinks can be
+            given at once. With -i only the operations with the
+            given ids are affected, and -x turns notifications off
+
+        18. tail [-i id] [-t type]
+
+            This command prints operation events as they 
+            happen, one per line, until enter is hit. The -i 
+            field only prints events of the operations with the
+            given ids, and the -t field only the given types:
+            scheduled, login-started, waiting, attempt, 
+            edited, slot-found, booked, watched, failed, 
+            cancelled and cleaned
+
+        19. serve [-a address]
+
+            This command starts the HTTP server next to the CLI,
+            listening on the host:port in the -a field
+            (127.0.0.1:8080 by default). Events are streamed at 
+            /events as Server-Sent Events, see runnable/server
+
+        20. config show
+
+            Lists where settings are read from, first one 
+            winning: the command line, RESOLVED_* environment 
+            variables and the config file, then the account,
+            provider, timezone, party-size, output and 
+            release-time settings and the release-times table
+            with where each came from. The config file is TOML,
+            e.g.
+                account = "work"
+                provider = "resy"
+                timezone = "America/New_York"
+                party-size = 2
+                release-time = "10:00"
+
+                [release-times]
+                1505 = "09:00"
+            or the same as a JSON object. Settings are read 
+            when the CLI starts, so the daemon uses its own.
+
+        21. config output [format]
+
+            Changes the format results are printed in, one 
+            of text, json, yaml or table, until the CLI exits,
+            or without a format says which one is in use. 
+            Runs on the client when a daemon is running
+
+        22. help [-c command] [-f format] [-w file]
 
-            Display helpful info about commands    
+            Display helpful info about commands. Without -c
+            every command is listed with its description, 
+            with -c, or the command given first like 
+            help op list, its synopsis, flags, what they take
+            and examples are shown. -f markdown or -f man 
+            exports the help of every command instead, and -w
+            writes the help to a file. COMMANDS.md is made 
+            with help -f markdown -w COMMANDS.md    
 
-        10. exit/quit 
+        23. quit/exit 
             
-            Leave the CLI environment 
+            Leave the CLI environment. In a script,
+            stop reading commands 
  
 **********************************************************************
 */
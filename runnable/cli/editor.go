@@ -0,0 +1,242 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "fmt"
+    "github.com/21Bruce/resolved-server/app"
+    "github.com/21Bruce/resolved-server/cli"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+/*
+Name: DefaultHistory
+Type: External Func
+Purpose: Provide the default location of the REPL
+history, next to the vault in the user's config
+directory
+*/
+func DefaultHistory() (string) {
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        dir = "."
+    }
+    return filepath.Join(dir, "resolved", "history")
+}
+
+/*
+Name: newEditor
+Type: Internal Func
+Purpose: Make the line editor the REPL reads with,
+its history loaded and completing from the parse ctx
+*/
+func (c *ResolvedCLI) newEditor() (*cli.LineEditor) {
+    history := c.History
+    if history == "" {
+        history = DefaultHistory()
+    }
+    editor := &cli.LineEditor{
+        In: c.In,
+        Out: c.Out,
+        Prompt: replPrompt,
+        HistoryFile: history,
+        Private: c.privateLine,
+        Complete: c.parseCtx.Complete,
+    }
+    if err := editor.LoadHistory(); err != nil {
+        fmt.Fprintln(c.Err, "ERROR: " + err.Error())
+    }
+    return editor
+}
+
+/*
+Name: privateLine
+Type: Internal Func
+Purpose: Report whether line holds a password,
+keeping it out of the history file
+*/
+func (c *ResolvedCLI) privateLine(line string) (bool) {
+    tokens, err := c.parseCtx.Tokenize(line)
+    if err != nil {
+        // can't tell, so better safe
+        return true
+    }
+    for _, token := range tokens {
+        if token == "-p" || token == "--password" {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+Name: setCompletions
+Type: Internal Func
+Purpose: Have the flags naming operations, templates,
+venues, accounts and commands complete to the ones
+that exist
+*/
+func (c *ResolvedCLI) setCompletions() {
+    finished := []app.OperationStatus{app.SuccessStatusType, app.FailStatusType, app.CancelStatusType}
+    c.parseCtx.Walk(func(path string, cmd *cli.Command) {
+        for j := range cmd.Flags {
+            flag := &cmd.Flags[j]
+            switch {
+                case flag.LongName == "venue-id" || flag.LongName == "venue":
+                    flag.Complete = c.completeVenues
+                case flag.LongName == "account" && path != "account add":
+                    flag.Complete = c.completeAccounts
+                case flag.LongName == "command" && path == "help":
+                    flag.Complete = c.completeCommands
+                case flag.LongName == "id":
+                    switch {
+                        case strings.HasPrefix(path, "recur "):
+                            flag.Complete = c.completeTemplates
+                        case path == "op cancel" || path == "op edit":
+                            flag.Complete = c.completeOperations(app.InProgressStatusType)
+                        case path == "op clean":
+                            flag.Complete = c.completeOperations(finished...)
+                        default:
+                            flag.Complete = c.completeOperations()
+                    }
+            }
+        }
+    })
+}
+
+/*
+Name: completeCommands
+Type: Internal Func
+Purpose: Complete to the names of commands and
+subcommands, for 'help'
+*/
+func (c *ResolvedCLI) completeCommands(prefix string) ([]cli.Completion) {
+    comps := make([]cli.Completion, 0)
+    seen := make(map[string]bool)
+    c.parseCtx.Walk(func(path string, cmd *cli.Command) {
+        if !seen[cmd.Name] && strings.HasPrefix(cmd.Name, prefix) {
+            seen[cmd.Name] = true
+            comps = append(comps, cli.Completion{Value: cmd.Name})
+        }
+    })
+    return comps
+}
+
+/*
+Name: completeOperations
+Type: Internal Func
+Purpose: Complete to the ids of operations with
+one of statuses, or of any status when none
+*/
+func (c *ResolvedCLI) completeOperations(statuses ...app.OperationStatus) (func(string) ([]cli.Completion)) {
+    return func(prefix string) ([]cli.Completion) {
+        comps := make([]cli.Completion, 0)
+        for _, op := range c.AppCtx.ListOperations(app.OperationFilter{Statuses: statuses}) {
+            id := strconv.FormatInt(op.ID, 10)
+            if !strings.HasPrefix(id, prefix) {
+                continue
+            }
+            note := string(op.Type) + ", " + op.Status.String()
+            if name := c.venues[op.VenueID]; name != "" {
+                note += ", " + name
+            }
+            comps = append(comps, cli.Completion{Value: id, Note: note})
+        }
+        return comps
+    }
+}
+
+/*
+Name: completeTemplates
+Type: Internal Func
+Purpose: Complete to the ids of recurring templates
+*/
+func (c *ResolvedCLI) completeTemplates(prefix string) ([]cli.Completion) {
+    comps := make([]cli.Completion, 0)
+    for _, template := range c.AppCtx.Templates() {
+        id := strconv.FormatInt(template.ID, 10)
+        if !strings.HasPrefix(id, prefix) {
+            continue
+        }
+        note := "venue " + strconv.FormatInt(template.Params.VenueID, 10)
+        if template.Paused {
+            note += ", paused"
+        }
+        comps = append(comps, cli.Completion{Value: id, Note: note})
+    }
+    return comps
+}
+
+/*
+Name: completeVenues
+Type: Internal Func
+Purpose: Complete to the ids of venues found by
+'search' or booked at by an operation, matching
+either the id or the start of the venue name
+*/
+func (c *ResolvedCLI) completeVenues(prefix string) ([]cli.Completion) {
+    names := make(map[int64]string)
+    for _, op := range c.AppCtx.ListOperations(app.OperationFilter{}) {
+        if op.VenueID != 0 {
+            names[op.VenueID] = ""
+        }
+    }
+    for id, name := range c.venues {
+        names[id] = name
+    }
+    comps := make([]cli.Completion, 0)
+    for id, name := range names {
+        value := strconv.FormatInt(id, 10)
+        if strings.HasPrefix(value, prefix) || (prefix != "" && strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix))) {
+            comps = append(comps, cli.Completion{Value: value, Note: name})
+        }
+    }
+    sort.Slice(comps, func(i, j int) (bool) {
+        return comps[i].Value < comps[j].Value
+    })
+    return comps
+}
+
+/*
+Name: completeAccounts
+Type: Internal Func
+Purpose: Complete to the names of saved accounts
+the account commands saw. The store isn't asked,
+since listing the vault may prompt for its
+passphrase in the middle of a line
+*/
+func (c *ResolvedCLI) completeAccounts(prefix string) ([]cli.Completion) {
+    comps := make([]cli.Completion, 0)
+    for name := range c.accounts {
+        if strings.HasPrefix(name, prefix) {
+            comps = append(comps, cli.Completion{Value: name})
+        }
+    }
+    sort.Slice(comps, func(i, j int) (bool) {
+        return comps[i].Value < comps[j].Value
+    })
+    return comps
+}
+
+/*
+Name: noteAccount
+Type: Internal Func
+Purpose: Remember whether the account name exists,
+for completion
+*/
+func (c *ResolvedCLI) noteAccount(name string, exists bool) {
+    if !exists {
+        delete(c.accounts, name)
+        return
+    }
+    if c.accounts == nil {
+        c.accounts = make(map[string]bool)
+    }
+    c.accounts[name] = true
+}
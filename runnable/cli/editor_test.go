@@ -0,0 +1,140 @@
+package cli
+
+import (
+    "bytes"
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/app"
+    "github.com/21Bruce/resolved-server/vault"
+    "path/filepath"
+    "reflect"
+    "strconv"
+    "testing"
+    "time"
+)
+
+/*
+Name: completions
+Type: Test Func
+Purpose: The values the REPL offers at the end of line
+*/
+func completions(c *ResolvedCLI, line string) ([]string) {
+    _, comps := c.parseCtx.Complete(line)
+    values := make([]string, len(comps))
+    for i, comp := range comps {
+        values[i] = comp.Value
+    }
+    return values
+}
+
+func TestCompleteAccounts(t *testing.T) {
+    t.Setenv("TEST_WORK_EMAIL", "me@example.com")
+    t.Setenv("TEST_WORK_PASSWORD", "secret")
+    path := filepath.Join(t.TempDir(), "vault")
+    seed := &vault.FileStore{Path: path, Passphrase: func() ([]byte, error) { return []byte("right"), nil }}
+    if err := seed.Put(vault.Credential{Name: "home", Login: api.LoginParam{Email: "me@home.com", Password: "x"}}); err != nil {
+        t.Fatal(err)
+    }
+    asked := 0
+    file := &vault.FileStore{
+        Path: path,
+        Passphrase: func() ([]byte, error) {
+            asked++
+            return []byte("right"), nil
+        },
+    }
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    c.AppCtx.Credentials = vault.Chain{vault.EnvStore{Prefix: "TEST"}, file}
+
+    // nothing seen yet, and the vault is left alone
+    if got := completions(c, "rats -a "); len(got) != 0 || asked != 0 {
+        t.Errorf("before any account command: %v, asked %d times", got, asked)
+    }
+    cmd, flags, err := c.parseCtx.Resolve("account list")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := c.runHandler(cmd, flags, ""); err != nil {
+        t.Fatal(err)
+    }
+    if got := completions(c, "rats -a "); !reflect.DeepEqual(got, []string{"home", "work"}) {
+        t.Errorf("after account list: %v, want [home work]", got)
+    }
+    if got := completions(c, "op list --account h"); !reflect.DeepEqual(got, []string{"home"}) {
+        t.Errorf("prefix: %v, want [home]", got)
+    }
+    // 'account add' names a new account, so it isn't completed
+    if got := completions(c, "account add -a "); len(got) != 0 {
+        t.Errorf("account add: %v, want nothing", got)
+    }
+
+    cmd, flags, err = c.parseCtx.Resolve("account rm -a home")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := c.runHandler(cmd, flags, ""); err != nil {
+        t.Fatal(err)
+    }
+    // a locked vault would prompt if completion listed it
+    file.Lock()
+    before := asked
+    if got := completions(c, "rats -a "); !reflect.DeepEqual(got, []string{"work"}) || asked != before {
+        t.Errorf("after account rm: %v, asked %d more times, want [work] without asking", got, asked - before)
+    }
+}
+
+func TestCompleteOperations(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    id, err := c.AppCtx.ScheduleReserveAtTimeOperation(app.ReserveAtTimeParam{
+        Login: app.LoginParam{Email: "me@example.com", Password: "secret"},
+        VenueID: 1505,
+        ReservationTimes: []time.Time{testStart.Add(7 * 24 * time.Hour)},
+        RequestTime: testStart.Add(3 * time.Hour),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    c.venues = map[int64]string{1505: "Carbone", 2000: "Double Chicken Please"}
+    idstr := strconv.FormatInt(id, 10)
+
+    _, comps := c.parseCtx.Complete("op cancel -i ")
+    if len(comps) != 1 || comps[0].Value != idstr || comps[0].Note != "reserve-at-time, in-progress, Carbone" {
+        t.Errorf("op cancel: %v, want operation %s noted with its venue", comps, idstr)
+    }
+    // only finished operations can be cleaned
+    if got := completions(c, "op clean -i "); len(got) != 0 {
+        t.Errorf("op clean: %v, want nothing", got)
+    }
+    if got := completions(c, "rats -v 1"); !reflect.DeepEqual(got, []string{"1505"}) {
+        t.Errorf("venue id: %v, want [1505]", got)
+    }
+    if got := completions(c, "rats -v doub"); !reflect.DeepEqual(got, []string{"2000"}) {
+        t.Errorf("venue name: %v, want [2000]", got)
+    }
+    if got := completions(c, "recur pause -i "); len(got) != 0 {
+        t.Errorf("recur pause: %v, want no templates", got)
+    }
+}
+
+func TestPrivateLine(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    tests := []struct {
+        line    string
+        private bool
+    }{
+        {"login -e me@example.com -p hunter2", true},
+        {"account add -a work -e me@example.com --password hunter2", true},
+        {"rats -v 1 -resD 2023:09:08 -resT 19:00 -e me@example.com -p [two words]", true},
+        {"login -e me@example.com", false},
+        {"search -n pizza", false},
+        // can't tell where the flags are, so keep it out
+        {"login -p [hunter2", true},
+    }
+    for _, test := range tests {
+        if got := c.privateLine(test.line); got != test.private {
+            t.Errorf("privateLine(%q) = %v, want %v", test.line, got, test.private)
+        }
+    }
+}
@@ -0,0 +1,161 @@
+/*
+Author: Bruce Jagid
+Created On: Aug 12, 2023
+*/
+package cli
+
+import (
+    "errors"
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/app"
+    "github.com/21Bruce/resolved-server/cli"
+    "strconv"
+    "strings"
+)
+
+var (
+    // Error if --output isn't given a format
+    ErrInvOutput = errors.New("--output takes exactly one format")
+)
+
+/*
+Name: runHandler
+Type: Internal Func
+Purpose: Run the handler of cmd and render its
+result in the format its --output gives, or
+else in format
+*/
+func (c *ResolvedCLI) runHandler(cmd *cli.Command, flags cli.Values, format string) (string, error) {
+    if flags.Has("o") {
+        format = flags.String("o")
+    }
+    c.format = format
+    result, err := cmd.Handler(flags)
+    if err != nil {
+        return "", err
+    }
+    return cli.Render(result, format)
+}
+
+/*
+Name: venueList
+Type: Internal Type
+Purpose: The venues 'search' found
+*/
+type venueList []api.SearchResult
+
+/*
+Name: venueList.Text
+Type: Internal Func
+Purpose: The venues the way search always
+printed them
+*/
+func (v venueList) Text() (string) {
+    resp := api.SearchResponse{Results: v}
+    return resp.ToString()
+}
+
+/*
+Name: operationList
+Type: Internal Type
+Purpose: The operations 'op list' found, a row
+each in a table
+*/
+type operationList []app.OperationSnapshot
+
+/*
+Name: operationList.Text
+Type: Internal Func
+Purpose: The operations the way the app
+stringifies them
+*/
+func (l operationList) Text() (string) {
+    return app.SnapshotsToString(l)
+}
+
+/*
+Name: operationList.Table
+Type: Internal Func
+Purpose: A row per operation with what tells
+them apart, leaving the params and log to
+json and yaml
+*/
+func (l operationList) Table() ([]string, [][]string) {
+    header := []string{"id", "type", "status", "venue", "accounts", "result"}
+    rows := make([][]string, len(l))
+    for i, snap := range l {
+        result := snap.Error
+        if snap.Result != nil {
+            t := snap.Result.Time()
+            if snap.Location != nil {
+                t = t.In(snap.Location)
+            }
+            result = t.Format("2006-01-02 15:04 MST")
+        }
+        venue := ""
+        if snap.VenueID != 0 {
+            venue = strconv.FormatInt(snap.VenueID, 10)
+        }
+        rows[i] = []string{
+            strconv.FormatInt(snap.ID, 10),
+            string(snap.Type),
+            snap.Status.String(),
+            venue,
+            strings.Join(snap.Accounts, ","),
+            result,
+        }
+    }
+    return header, rows
+}
+
+/*
+Name: operationHistory
+Type: Internal Type
+Purpose: One operation and its attempt log, as
+'op show' prints it
+*/
+type operationHistory app.OperationSnapshot
+
+/*
+Name: operationHistory.Text
+Type: Internal Func
+Purpose: The operation the way the app
+stringifies its history
+*/
+func (h operationHistory) Text() (string) {
+    return app.HistoryToString(app.OperationSnapshot(h))
+}
+
+/*
+Name: accountList
+Type: Internal Type
+Purpose: The names of saved accounts
+*/
+type accountList []string
+
+/*
+Name: accountList.Text
+Type: Internal Func
+Purpose: The accounts one per line under
+a heading
+*/
+func (l accountList) Text() (string) {
+    listStr := "Accounts:"
+    for _, name := range l {
+        listStr += "\n\t" + name
+    }
+    return listStr
+}
+
+/*
+Name: accountList.Table
+Type: Internal Func
+Purpose: A row per account under one column
+*/
+func (l accountList) Table() ([]string, [][]string) {
+    rows := make([][]string, len(l))
+    for i, name := range l {
+        rows[i] = []string{name}
+    }
+    return []string{"account"}, rows
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+    "bytes"
+    "encoding/json"
+    "github.com/21Bruce/resolved-server/app"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+/*
+Name: run
+Type: Test Func
+Purpose: Parse line and run it through runHandler
+in format, as the REPL would
+*/
+func run(t *testing.T, c *ResolvedCLI, line string, format string) (string, error) {
+    t.Helper()
+    cmd, flags, err := c.parseCtx.Resolve(line)
+    if err != nil {
+        t.Fatalf("%s: %v", line, err)
+    }
+    return c.runHandler(cmd, flags, format)
+}
+
+func TestRenderEmptyList(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    if _, err := run(t, c, "op list", ""); err != app.ErrNoOp {
+        t.Errorf("text: err = %v, want ErrNoOp", err)
+    }
+    tests := map[string]string{
+        "json": "[]",
+        "yaml": "[]",
+        "table": "ID  TYPE  STATUS  VENUE  ACCOUNTS  RESULT",
+    }
+    for format, want := range tests {
+        got, err := run(t, c, "op list", format)
+        if err != nil || strings.TrimSpace(got) != want {
+            t.Errorf("%s: %q, %v, want %q", format, got, err, want)
+        }
+    }
+}
+
+func TestRenderOperations(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    id, err := c.AppCtx.ScheduleReserveAtTimeOperation(app.ReserveAtTimeParam{
+        Login: app.LoginParam{Email: "me@example.com", Password: "secret"},
+        VenueID: 1505,
+        ReservationTimes: []time.Time{testStart.Add(7 * 24 * time.Hour)},
+        RequestTime: testStart.Add(3 * time.Hour),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    text, err := run(t, c, "op list", "")
+    if err != nil || !strings.HasPrefix(text, "Operations:") {
+        t.Errorf("text = %q, %v", text, err)
+    }
+    // --output anywhere on the line wins over the format given
+    raw, err := run(t, c, "op list --output json -v 1505", "yaml")
+    if err != nil {
+        t.Fatal(err)
+    }
+    var list []map[string]interface{}
+    if err := json.Unmarshal([]byte(raw), &list); err != nil {
+        t.Fatalf("%v in %s", err, raw)
+    }
+    if len(list) != 1 || list[0]["id"] != float64(id) || list[0]["venue_id"] != float64(1505) {
+        t.Errorf("json = %v, want the operation", list)
+    }
+    yaml, err := run(t, c, "op show -o yaml -i 0", "")
+    if err != nil || !strings.Contains(yaml, "venue_id: 1505") {
+        t.Errorf("yaml = %q, %v", yaml, err)
+    }
+    table, err := run(t, c, "op list", "table")
+    lines := strings.Split(strings.TrimSpace(table), "\n")
+    if err != nil || len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "in-progress") {
+        t.Errorf("table = %q, %v", table, err)
+    }
+    // messages stay strings in every format
+    msg, err := run(t, c, "op cancel -i 0 -o json", "")
+    if err != nil || msg != `"Cancelled Operations Successfully"` {
+        t.Errorf("json message = %q, %v", msg, err)
+    }
+}
+
+func TestConfigOutput(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    c.Config = filepath.Join(t.TempDir(), "config")
+    if err := c.loadSettings(); err != nil {
+        t.Fatal(err)
+    }
+    if !c.runLine("config output", true) || strings.TrimSpace(out.String()) != "Printing results as text" {
+        t.Errorf("current format: %q", out.String())
+    }
+    out.Reset()
+    if !c.runLine("config output js", true) || c.Output != "json" {
+        t.Errorf("after config output js: Output = %q, printed %q", c.Output, out.String())
+    }
+    out.Reset()
+    if !c.runLine("op list", true) || strings.TrimSpace(out.String()) != "[]" {
+        t.Errorf("op list after config output: %q, want []", out.String())
+    }
+    // a line's own --output is for that line only
+    out.Reset()
+    c.runLine("op list --output text", true)
+    if !strings.Contains(out.String(), app.ErrNoOp.Error()) || c.Output != "json" {
+        t.Errorf("op list --output text: %q, Output = %q", out.String(), c.Output)
+    }
+    out.Reset()
+    if !c.runLine("config show", true) {
+        t.Fatalf("config show: %q", out.String())
+    }
+    var report configReport
+    if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+        t.Fatalf("%v in %s", err, out.String())
+    }
+    for _, setting := range report.Settings {
+        if setting.Key == "output" && (len(setting.Values) != 1 || setting.Values[0] != "json" || setting.From != "'config output'") {
+            t.Errorf("output setting = %+v, want json from 'config output'", setting)
+        }
+    }
+}
+
+// The command line the output flag was missed on
+func TestRunOutputAfterCommand(t *testing.T) {
+    var out bytes.Buffer
+    c := &ResolvedCLI{
+        In: strings.NewReader(""),
+        Out: &out,
+        Err: &out,
+        Args: []string{"op", "list", "--output", "json"},
+        Config: filepath.Join(t.TempDir(), "config"),
+        Socket: filepath.Join(t.TempDir(), "daemon.sock"),
+    }
+    if err := c.Run(); err != nil || strings.TrimSpace(out.String()) != "[]" {
+        t.Errorf("Run = %v, printed %q, want []", err, out.String())
+    }
+}
@@ -6,6 +6,7 @@ package cli
 
 import (
     "bufio"
+    "encoding/json"
     "io"
     "fmt"
     "strconv"
@@ -13,18 +14,67 @@ import (
     "github.com/21Bruce/resolved-server/app"
     "github.com/21Bruce/resolved-server/api"
     "github.com/21Bruce/resolved-server/cli"
+    "github.com/21Bruce/resolved-server/notify"
+    "github.com/21Bruce/resolved-server/runnable/server"
+    "net"
+    "net/smtp"
+    "sync"
+    "sync/atomic"
     "os"
     "errors"
     "time"
 )
 
 var (
-    // Error if we can't parse date properly
-    ErrInvDate = errors.New("invalid date format")
-    // Error if we can't parse table type properly
-    ErrInvTableType = errors.New("invalid table type")
+    // Error if input ends while reading a secret
+    ErrNoSecret = errors.New("no input while reading secret")
+    // Error if a race member is not a rats or rais command
+    ErrInvMember = errors.New("race members must be rats or rais commands")
+    // Error if notify is given no sink and isn't turning notifications off
+    ErrNoSink = errors.New("no notification sink given")
+    // Error if mail recipients are given without an smtp server
+    ErrNoSMTP = errors.New("mail recipients need an smtp server")
+    // Error if 'serve' is run while already serving
+    ErrServing = errors.New("server is already running")
+    // Error if the venue timezone can't be looked up and --tz wasn't given
+    ErrVenueTZ = errors.New("can't look up the venue timezone, give it with --tz or the timezone setting")
+    // Error if a login is given to rats along with several accounts
+    ErrInvFanOut = errors.New("-e and -p can't be given with more than one -a")
+    // Error if only one of -ahead and -relT is given
+    ErrInvRelease = errors.New("-ahead and -relT must be given together")
+    // Error if rats can't infer the request date for a venue
+    // that doesn't publish its release time
+    ErrNeedRelease = errors.New("venue doesn't publish when it releases tables, give -relT or -reqD or set release-time")
+    // Error if only one of -resD and -resT is given to 'op edit'
+    ErrInvEdit = errors.New("-resD and -resT must be given together")
+    // Error if 'op edit' is given nothing to change
+    ErrNoEdit = errors.New("nothing to edit")
+    // Error if --script isn't given exactly one file
+    ErrInvScript = errors.New("--script takes exactly one file")
+    // Error if --socket isn't given a path
+    ErrInvSocket = errors.New("--socket takes exactly one path")
+    // Error if 'help' is given a command to export
+    ErrInvHelp = errors.New("markdown and man help covers every command, leave out -c")
+    // Error if 'daemon' is given a command or script
+    ErrInvDaemon = errors.New("daemon takes no command or script")
+    // Error Run returns when a command or operation failed outside the REPL
+    ErrFailed = errors.New("a command or operation failed")
+    // Error if 'tail' is run from a script, piped commands or arguments
+    ErrBatchTail = errors.New("tail needs a terminal, use it from the REPL or use serve and /events")
+    // Error if an operation that never finishes on its own is
+    // started where Run waits for operations before exiting
+    ErrBatchForever = errors.New("watch -k, snipe and recur run until cancelled, start them from the REPL or a daemon")
 )
 
+// What the REPL prints before reading each command
+const replPrompt = "resolved(0.1.0)>> "
+
+// Where 'serve' listens when no address is given
+const defaultServeAddr = "127.0.0.1:8080"
+
+// Where the clock is synced against when no source is given
+const defaultClockURL = "https://api.resy.com/"
+
 /*
 Name: ResolvedCLI
 Type: External CLI Struct
@@ -36,7 +86,111 @@ type ResolvedCLI struct {
     In          io.Reader
     Out         io.Writer
     Err         io.Writer
+    // Process arguments, a command to run once, --script file or daemon
+    Args        []string
+    // Socket the daemon listens on, DefaultSocket when empty
+    Socket      string
+    // File the REPL keeps history in, DefaultHistory when empty
+    History     string
+    // Format results are printed in, one of cli.Outputs, text when empty
+    Output      string
+    // Config file settings are read from, DefaultConfig when empty
+    Config      string
+    // APIs the provider setting picks from by name
+    Providers   map[string]api.API
     parseCtx    cli.ParseCtx
+    // Settings read from the config file
+    config      cli.Config
+    // Where Output was read, --output, 'config output'
+    // or a setting source
+    outputFrom  string
+    // Format the result of the running command is
+    // rendered in, for handlers that answer differently
+    format      string
+    scanner     *bufio.Scanner
+    // Set by 'exit' and 'quit' to stop reading commands
+    quit        bool
+    // Set when a command fails outside the REPL
+    failed      bool
+    // Set when commands come from arguments, a script or a
+    // pipe, where Run waits for operations before exiting
+    batch       bool
+    // Set while the 'tail' command prints events
+    tailing     atomic.Bool
+    // Listener of the server started by 'serve', if any
+    listener    net.Listener
+    // Set once the daemon stops reading its terminal
+    detached    bool
+    // Runs commands of daemon clients one at a time
+    mu          sync.Mutex
+    // Connection to the daemon when one is running
+    conn        net.Conn
+    enc         *json.Encoder
+    dec         *json.Decoder
+    // Names of the venues 'search' found, for completion
+    venues      map[int64]string
+    // Accounts the account commands saw, for completion
+    accounts    map[string]bool
+}
+
+/*
+Name: ReadSecret
+Type: External Func
+Purpose: Prompt for and read one line of input 
+without echoing it back when the input is a 
+terminal, used for passwords and passphrases.
+A daemon never prompts once it is listening
+*/
+func (c *ResolvedCLI) ReadSecret(prompt string) ([]byte, error) {
+    if c.detached {
+        return nil, ErrNoPrompt
+    }
+    if c.scanner == nil {
+        c.scanner = bufio.NewScanner(c.In)
+    }
+    fmt.Fprint(c.Out, prompt)
+    if f, ok := c.In.(*os.File); ok && cli.IsTerminal(f) {
+        // if echo can't be turned off we still read the secret
+        if cli.SetEcho(f, false) == nil {
+            defer cli.SetEcho(f, true)
+            defer fmt.Fprintln(c.Out)
+        }
+    }
+    if !c.scanner.Scan() {
+        if err := c.scanner.Err(); err != nil {
+            return nil, err
+        }
+        return nil, ErrNoSecret
+    }
+    return []byte(c.scanner.Text()), nil
+}
+
+/*
+Name: parseLogin
+Type: Internal Func
+Purpose: Pull the optional login flags shared by
+the scheduling commands out of the flag map, prompting
+for the password if only an email was given
+*/
+func (c *ResolvedCLI) parseLogin(in cli.Values) (app.LoginParam, string, error) {
+    login := app.LoginParam{}
+    account := ""
+    if in["a"] != nil {
+        account = in["a"][0]
+    }
+    if in["e"] != nil {
+        login.Email = in["e"][0]
+    }
+    if in["p"] != nil {
+        login.Password = in["p"][0]
+    } else if login.Email != "" {
+        password, err := c.ReadSecret("Password: ")
+        if err != nil {
+            return login, "", err
+        }
+        login.Password = string(password)
+    }
+    return login, account, nil
 }
 
 /*
@@ -47,19 +201,9 @@ parsing on top of the cli parser
 and return the field for use in the main
 handler
 */
-func parseSearch(in map[string][]string) (string, int, error){
-
-    name := in["n"][0]
-    limit := 0
-    if in["l"] != nil {
-        limitRes, err := strconv.Atoi(in["l"][0])
-        if err != nil {
-            return "", 0 , err
-        }
-        limit = limitRes
-    }
-    return name, limit, nil
-
+func parseSearch(in cli.Values) (string, int, error) {
+    limit, err := in.Int("l")
+    return in.String("n"), limit, err
 }
 
 /*
@@ -71,7 +215,7 @@ It is responsible for taking in the validated
 flag args and returning a string
 of the search results
 */
-func (c *ResolvedCLI) handleSearch(in map[string][]string) (string, error) {
+func (c *ResolvedCLI) handleSearch(in cli.Values) (interface{}, error) {
     name, limit, err := parseSearch(in)
     if err != nil {
         return "", err
@@ -81,8 +225,13 @@ func (c *ResolvedCLI) handleSearch(in map[string][]string) (string, error) {
     if err != nil {
         return "", err
     }
-    retVal := api.SearchResponse(*resp)
-    return retVal.ToString(), nil
+    if c.venues == nil {
+        c.venues = make(map[int64]string)
+    }
+    for _, result := range resp.Results {
+        c.venues[result.VenueID] = result.Name
+    }
+    return venueList(resp.Results), nil
 }
 
 /*
@@ -90,64 +239,150 @@ Name: handleQuit
 Type: Internal Func
 Purpose: This function
 is the handler for the 'exit' and 'quit' commands.
-It is responsible for exiting the CLI
+It is responsible for stopping Run from reading
+more commands
 */
-func (c *ResolvedCLI) handleQuit(in map[string][]string) (string, error) {
-    os.Exit(0)
-    return "", nil
+func (c *ResolvedCLI) handleQuit(in cli.Values) (interface{}, error) {
+    c.quit = true
+    return nil, nil
 }
 
 /*
-Name: flagToShortStr 
+Name: handleHelp 
 Type: Internal Func
-Purpose: This function assists in
-the 'help' command, this all should be moved
-to the cli top level
+Purpose: This function is the handler
+for the 'help' command, It is responsible
+for printing the help the cli pkg generates
+for one command or all of them, or exporting
+it as markdown or a man page
 */
-func flagToShortStr(flag cli.Flag) (string) {
-    flagStr := " [-" + flag.Name 
-    if flag.LongName != "" {
-        flagStr += "|--" + flag.LongName
+func (c *ResolvedCLI) handleHelp(in cli.Values) (interface{}, error) {
+    var out strings.Builder
+    switch in.String("f") {
+        case "markdown", "man":
+            if in.Has("c") {
+                return "", ErrInvHelp
+            }
+            var err error
+            if in.String("f") == "markdown" {
+                err = c.parseCtx.WriteMarkdown(&out, "Commands")
+            } else {
+                err = c.parseCtx.WriteMan(&out, "resolved-server", "a bot for booking resy reservations")
+            }
+            if err != nil {
+                return "", err
+            }
+        default:
+            help, err := c.parseCtx.Help(strings.Join(in["c"], " "))
+            if err != nil {
+                return "", err
+            }
+            out.WriteString(help)
+            if !in.Has("c") {
+                out.WriteString("\nRun 'help <command>' for its flags and examples\n")
+            }
+    }
+    if in.Has("w") {
+        if err := os.WriteFile(in.String("w"), []byte(out.String()), 0644); err != nil {
+            return "", err
+        }
+        return "Help written to " + in.String("w"), nil
     }
-    flagStr += "]"
-    return flagStr
+    return strings.TrimRight(out.String(), "\n"), nil
 }
 
 /*
-Name: handleHelp 
+Name: handleList 
 Type: Internal Func
 Purpose: This function is the handler
-for the 'help' command, It is responsible
-for printing out helpful info for each
-command, it really should be moved to the
-cli top level
+for the 'op list' command, It is responsible
+for printing out a history of operations
+from the AppCtx, narrowed by any filters.
+Finding none is an error only in text
 */
-func (c *ResolvedCLI) handleHelp(in map[string][]string) (string, error) {
-    helpStr := "Commands: \n"
-    for _, cmd := range c.parseCtx.Commands {
-        helpStr += "\t" + cmd.Name 
-        for _, flag := range cmd.Flags {
-            helpStr += flagToShortStr(flag)
-        }
-        helpStr += ": "+ cmd.Description + "\n"
-        for _, flag := range cmd.Flags {
-            helpStr += "\t\t" + flagToShortStr(flag) + ": "  + flag.Description + "\n"
+func (c *ResolvedCLI) handleList(in cli.Values) (interface{}, error) {
+    filter := app.OperationFilter{}
+    for _, statusStr := range in["s"] {
+        status, err := app.ParseOperationStatus(statusStr)
+        if err != nil {
+            return "", err
         }
+        filter.Statuses = append(filter.Statuses, status)
     }
-
-    return helpStr, nil 
+    venueID, err := in.Int64("v")
+    if err != nil {
+        return "", err
+    }
+    filter.VenueID = venueID
+    filter.Account = in.String("a")
+    snaps := c.AppCtx.ListOperations(filter)
+    // people are told there's nothing, scripts get an empty list
+    if len(snaps) == 0 && (c.format == "" || c.format == cli.TextOutput) {
+        return "", app.ErrNoOp
+    }
+    return operationList(snaps), nil
 }
 
 /*
-Name: handleList 
+Name: handleShow 
 Type: Internal Func
 Purpose: This function is the handler
-for the 'list' command, It is responsible
-for printing out a history of operations
-from the AppCtx
+for the 'op show' command, It is responsible
+for printing out one operation along with
+its timestamps and attempt log
+*/
+func (c *ResolvedCLI) handleShow(in cli.Values) (interface{}, error) {
+    id, err := in.Int64("i")
+    if err != nil {
+        return "", err
+    }
+    snap, err := c.AppCtx.GetOperation(id)
+    if err != nil {
+        return "", err
+    }
+    return operationHistory(*snap), nil
+}
+
+/*
+Name: parseTableTypes 
+Type: Internal Func
+Purpose: This function parses the -t field of
+the scheduling commands, keeping its order of
+preference
+*/
+func parseTableTypes(rawTypes []string) ([]api.TableType) {
+    if rawTypes == nil {
+        return nil
+    }
+    tableTypes := make([]api.TableType, len(rawTypes), len(rawTypes)) 
+    for i, rawType := range rawTypes {
+        // the parser already matched each to a table type
+        tableTypes[i] = api.TableType(rawType)
+    }
+    return tableTypes
+}
+
+/*
+Name: parseReservationTimes 
+Type: Internal Func
+Purpose: This function puts the -resT times
+of day of the scheduling commands on the
+-resD day in loc
 */
-func (c *ResolvedCLI) handleList(in map[string][]string) (string, error) {
-    return c.AppCtx.OperationsToString()
+func parseReservationTimes(in cli.Values, loc *time.Location) ([]time.Time, error) {
+    day, err := in.Date("resD")
+    if err != nil {
+        return nil, err
+    }
+    timesOfDay, err := in.TimesOfDay("resT")
+    if err != nil {
+        return nil, err
+    }
+    times := make([]time.Time, len(timesOfDay), len(timesOfDay))
+    for i, t := range timesOfDay {
+        times[i] = day.At(t, loc)
+    }
+    return times, nil
 }
 
 /*
@@ -156,115 +391,108 @@ Type: Internal Func
 Purpose: This function helps with parsing
 for the main 'rats' handler function
 */
-func (c *ResolvedCLI) parseRats(in map[string][]string) (*app.ReserveAtTimeParam, error) {
+func (c *ResolvedCLI) parseRats(in cli.Values) (*app.ReserveAtTimeParam, error) {
     req := app.ReserveAtTimeParam{}
-    // if we have login info, overwrite the default
-    if in["e"] != nil {
-        req.Login.Email = in["e"][0]
-    }
-    if in["p"] != nil {
-        req.Login.Password = in["p"][0]
-    }
-    if in["t"] != nil {
-	    req.TableTypes = make([]api.TableType, len(in["t"]), len(in["t"])) 
-	    for i := 0; i < len(in["t"]); i++ {
-            currType := strings.ToLower(in["t"][i])
-	        if strings.Contains(currType, string(api.DiningRoom)) {
-                req.TableTypes[i] = api.DiningRoom
-	        } else if strings.Contains(currType, string(api.Indoor)) {
-                req.TableTypes[i] = api.Indoor
-	        } else if strings.Contains(currType, string(api.Outdoor)) {
-                req.TableTypes[i] = api.Outdoor
-	        } else if strings.Contains(currType, string(api.Patio)) {
-                req.TableTypes[i] = api.Patio
-	        } else if strings.Contains(currType, string(api.Bar)) {
-                req.TableTypes[i] = api.Bar
-	        } else if strings.Contains(currType, string(api.Lounge)) {
-                req.TableTypes[i] = api.Lounge
-	        } else if strings.Contains(currType, string(api.Booth)) {
-                req.TableTypes[i] = api.Booth
-	        } else {
-                return nil, ErrInvTableType
-            }
-	    }	
+    // a fan out logs in with each account, so a login would
+    // go unused, refuse it before prompting for its password
+    if len(in["a"]) > 1 && (in.Has("e") || in.Has("p")) {
+        return nil, ErrInvFanOut
     }
-    id, err := strconv.ParseInt(in["v"][0], 10, 64)
+    // if we have login info, overwrite the default
+    login, account, err := c.parseLogin(in)
     if err != nil {
         return nil, err
     }
-    req.VenueID = id
-    rawResDay := in["resD"][0]
-    resDaySplt := strings.Split(rawResDay, ":")
-    if len(resDaySplt) != 3 {
-        return nil, ErrInvDate
+    req.Login = login
+    req.Account = account
+    req.TableTypes = parseTableTypes(in["t"])
+    req.VenueID, err = in.Int64("v")
+    if err != nil {
+        return nil, err
     }
-
-    reqYear, err := strconv.Atoi(resDaySplt[0])
+    loc, err := c.parseLocation(in, req.VenueID)
     if err != nil {
         return nil, err
     }
-    reqMonth, err := strconv.Atoi(resDaySplt[1])
+    req.ReservationTimes, err = parseReservationTimes(in, loc)
     if err != nil {
         return nil, err
     }
-    reqDay, err := strconv.Atoi(resDaySplt[2])
+    req.PartySize, err = in.Int("ps")
     if err != nil {
         return nil, err
     }
-
-    req.ReservationTimes = make([]time.Time, len(in["resT"]), len(in["resT"]))
-    for i, timeStr := range in["resT"] {
-        timeSplt := strings.Split(timeStr, ":")        
-        if len(timeSplt) != 2 {
-            return nil, ErrInvDate
-        }
-        reqHour, err := strconv.Atoi(timeSplt[0])
-        if err != nil {
-            return nil, err
-        }
-        reqMin, err := strconv.Atoi(timeSplt[1])
-        if err != nil {
-            return nil, err
-        }
-        req.ReservationTimes[i] = time.Date(reqYear, time.Month(reqMonth), reqDay, reqHour, reqMin, 0, 0, time.Local)
-
+    // without a request date, go by the venue booking policy
+    if !in.Has("reqD") {
+        return c.parseRatsRelease(in, &req)
     }
-    ps, err := strconv.ParseInt(in["ps"][0], 10, 64)
+    reqD, err := in.DateTime("reqD")
     if err != nil {
         return nil, err
     }
-    req.PartySize = int(ps)
-    rawReqDate := in["reqD"][0]
-    reqDateSplt := strings.Split(rawReqDate, ":")
+    req.RequestTime = reqD.In(loc).UTC()
+    return &req, nil
+}
 
-    if len(reqDateSplt) != 5 {
-        return nil, ErrInvDate
+/*
+Name: parseLocation
+Type: Internal Func
+Purpose: This function decides the timezone
+the dates and times of a scheduling command are
+read in. An explicit --tz wins, then the venue 
+timezone from the api, then the local timezone
+if the api doesn't publish one. When the lookup
+fails we don't guess, since a wrong timezone
+fires at the wrong instant, but say to pass --tz
+*/
+func (c *ResolvedCLI) parseLocation(in cli.Values, venueID int64) (*time.Location, error) {
+    if in["tz"] != nil {
+        return time.LoadLocation(in["tz"][0])
     }
-
-    year, err := strconv.ParseInt(reqDateSplt[0], 10, 64) 
-    if err != nil {
-        return nil, err
+    loc, err := c.AppCtx.VenueLocation(venueID)
+    if err == api.ErrNoTimezone {
+        return time.Local, nil
     }
-    month, err := strconv.ParseInt(reqDateSplt[1], 10, 64) 
     if err != nil {
-        return nil, err
+        return nil, fmt.Errorf("%w (%v)", ErrVenueTZ, err)
     }
-    day, err := strconv.ParseInt(reqDateSplt[2], 10, 64) 
+    return loc, nil
+}
+
+/*
+Name: parseRatsRelease
+Type: Internal Func
+Purpose: This function computes the request
+time of a 'rats' command given no -reqD from
+the venue booking policy. The release time
+in -relT stands in for the policy's, and is 
+required when the venue doesn't publish one
+and no release time setting covers it
+*/
+func (c *ResolvedCLI) parseRatsRelease(in cli.Values, req *app.ReserveAtTimeParam) (*app.ReserveAtTimeParam, error) {
+    policy, err := c.AppCtx.BookingPolicy(req.VenueID)
     if err != nil {
         return nil, err
     }
-    hour, err := strconv.ParseInt(reqDateSplt[3], 10, 64) 
-    if err != nil {
-        return nil, err
+    if in.Has("relT") {
+        release, err := in.TimeOfDay("relT")
+        if err != nil {
+            return nil, err
+        }
+        policy.ReleaseHour = int(release / time.Hour)
+        policy.ReleaseMinute = int(release % time.Hour / time.Minute)
+        policy.ReleaseKnown = true
+        // the release time is given in the same timezone as the
+        // reservation times, which honors an explicit --tz
+        policy.Location = req.ReservationTimes[0].Location()
+    } else if !policy.ReleaseKnown {
+        return nil, ErrNeedRelease
     }
-    minute, err := strconv.ParseInt(reqDateSplt[4], 10, 64) 
+    req.RequestTime, err = app.RequestTimeFromPolicy(*policy, req.ReservationTimes[0])
     if err != nil {
         return nil, err
     }
-    timeLoc := time.Date(int(year), time.Month(int(month)), int(day), int(hour), int(minute), 0, 0, time.Local)
-    timeUTC := timeLoc.UTC()
-    req.RequestTime = timeUTC
-    return &req, nil
+    return req, nil
 }
 
 /*
@@ -276,11 +504,15 @@ take the values defined in each flag field
 and schedule a reserve at time operation
 in the AppCtx
 */
-func (c *ResolvedCLI) handleRats(in map[string][]string) (string, error) {
+func (c *ResolvedCLI) handleRats(in cli.Values) (interface{}, error) {
     req, err := c.parseRats(in)
     if err != nil {
         return "", err
     }
+    // several accounts means racing the same target on each
+    if len(in["a"]) > 1 {
+        return c.handleRatsFanOut(in["a"], req)
+    }
     id, err := c.AppCtx.ScheduleReserveAtTimeOperation(*req)
     if err != nil {
         return "", err
@@ -291,6 +523,30 @@ func (c *ResolvedCLI) handleRats(in map[string][]string) (string, error) {
     return retstr, nil 
 }
 
+/*
+Name: handleRatsFanOut 
+Type: Internal Func
+Purpose: This function finishes the 'rats'
+command when more than one account is given,
+scheduling a fan out operation in the AppCtx
+*/
+func (c *ResolvedCLI) handleRatsFanOut(accounts []string, req *app.ReserveAtTimeParam) (string, error) {
+    id, err := c.AppCtx.ScheduleReserveFanOutOperation(app.ReserveFanOutParam{
+        Accounts: accounts,
+        VenueID: req.VenueID,
+        ReservationTimes: req.ReservationTimes,
+        PartySize: req.PartySize,
+        RequestTime: req.RequestTime,
+        TableTypes: req.TableTypes,
+    })
+    if err != nil {
+        return "", err
+    }
+    idstr := strconv.FormatInt(id, 10)
+    retstr := "Successfully started rats operation across " + strconv.Itoa(len(accounts)) + " accounts with ID " + idstr 
+    return retstr, nil 
+}
+
 /*
 Name: parseRais 
 Type: Internal Func
@@ -299,97 +555,36 @@ for the main 'rats' handler function.
 This function is very similiar to parseRats
 and can probably be merged a little
 */
-func (c *ResolvedCLI) parseRais(in map[string][]string) (*app.ReserveAtIntervalParam, error) {
+func (c *ResolvedCLI) parseRais(in cli.Values) (*app.ReserveAtIntervalParam, error) {
     req := app.ReserveAtIntervalParam{}
-    if in["e"] != nil {
-        req.Login.Email = in["e"][0]
-    }
-    if in["p"] != nil {
-        req.Login.Password = in["p"][0]
-    } 
-    if in["t"] != nil {
-	    req.TableTypes = make([]api.TableType, len(in["t"]), len(in["t"])) 
-	    for i := 0; i < len(in["t"]); i++ {
-            currType := in["t"][i]
-	        if strings.Contains(currType, string(api.DiningRoom)) {
-                req.TableTypes[i] = api.DiningRoom
-	        } else if strings.Contains(currType, string(api.Indoor)) {
-                req.TableTypes[i] = api.Indoor
-	        } else if strings.Contains(currType, string(api.Outdoor)) {
-                req.TableTypes[i] = api.Outdoor
-	        } else if strings.Contains(currType, string(api.Patio)) {
-                req.TableTypes[i] = api.Patio
-	        } else if strings.Contains(currType, string(api.Bar)) {
-                req.TableTypes[i] = api.Bar
-	        } else if strings.Contains(currType, string(api.Lounge)) {
-                req.TableTypes[i] = api.Lounge
-	        } else if strings.Contains(currType, string(api.Booth)) {
-                req.TableTypes[i] = api.Booth
-	        } else {
-                return nil, ErrInvTableType
-            }
-	    }	
-    }    
-    id, err := strconv.ParseInt(in["v"][0], 10, 64)
-    if err != nil {
-        return nil, err
-    }
-    req.VenueID = id
-    rawResDay := in["resD"][0]
-    resDaySplt := strings.Split(rawResDay, ":")
-    if len(resDaySplt) != 3 {
-        return nil, ErrInvDate
-    }
-    reqYear, err := strconv.Atoi(resDaySplt[0])
+    login, account, err := c.parseLogin(in)
     if err != nil {
         return nil, err
     }
-    reqMonth, err := strconv.Atoi(resDaySplt[1])
+    req.Login = login
+    req.Account = account
+    req.TableTypes = parseTableTypes(in["t"])
+    req.VenueID, err = in.Int64("v")
     if err != nil {
         return nil, err
     }
-    reqDay, err := strconv.Atoi(resDaySplt[2])
+    loc, err := c.parseLocation(in, req.VenueID)
     if err != nil {
         return nil, err
     }
-    req.ReservationTimes = make([]time.Time, len(in["resT"]), len(in["resT"]))
-    for i, timeStr := range in["resT"] {
-        timeSplt := strings.Split(timeStr, ":")        
-        if len(timeSplt) != 2 {
-            return nil, ErrInvDate
-        }
-        reqHour, err := strconv.Atoi(timeSplt[0])
-        if err != nil {
-            return nil, err
-        }
-        reqMin, err := strconv.Atoi(timeSplt[1])
-        if err != nil {
-            return nil, err
-        }
-        req.ReservationTimes[i] = time.Date(reqYear, time.Month(reqMonth), reqDay, reqHour, reqMin, 0, 0, time.Local)
-    }
-    ps, err := strconv.ParseInt(in["ps"][0], 10, 64)
+    req.ReservationTimes, err = parseReservationTimes(in, loc)
     if err != nil {
         return nil, err
     }
-    req.PartySize = int(ps)
-    rawRepInt := in["i"][0]
-    repIntSplt := strings.Split(rawRepInt, ":")
-
-    if len(repIntSplt) != 2 {
-        return nil, ErrInvDate
-    }
-
-    repHour, err := strconv.Atoi(repIntSplt[0])
+    req.PartySize, err = in.Int("ps")
     if err != nil {
         return nil, err
     }
-    repMin, err := strconv.Atoi(repIntSplt[1])
+    // snipe shares this parsing but has no interval
+    req.RepeatInterval, err = in.Duration("i")
     if err != nil {
         return nil, err
     }
-    req.RepeatInterval = time.Hour * time.Duration(repHour) + time.Minute * time.Duration(repMin)
-
     return &req, nil
 }
 
@@ -402,7 +597,7 @@ take the values defined in each flag field
 and schedule a reserve at interval operation
 in the AppCtx
 */
-func (c *ResolvedCLI) handleRais(in map[string][]string) (string, error) {
+func (c *ResolvedCLI) handleRais(in cli.Values) (interface{}, error) {
     req, err := c.parseRais(in)
     if err != nil {
         return "", err
@@ -418,194 +613,1407 @@ func (c *ResolvedCLI) handleRais(in map[string][]string) (string, error) {
 }
 
 /*
-Name: handleLogin 
+Name: handleWatch 
 Type: Internal Func
 Purpose: This function is the handler
-for the 'login' command, its goal is to
-save the login info on the appctx if its
-valid
+for the 'watch' command. It takes the same
+flags as the 'rais' command, plus -k, and
+schedules a watch operation in the AppCtx
 */
-func (c *ResolvedCLI) handleLogin(in map[string][]string) (string, error) {
-    req := app.LoginParam{
-        Email: in["e"][0],
-        Password: in["p"][0],
+func (c *ResolvedCLI) handleWatch(in cli.Values) (interface{}, error) {
+    req, err := c.parseRais(in)
+    if err != nil {
+        return "", err
     }
-    err := c.AppCtx.Login(req)
+    _, keep := in["k"]
+    id, err := c.AppCtx.ScheduleWatchOperation(app.WatchParam{
+        Login: req.Login,
+        Account: req.Account,
+        VenueID: req.VenueID,
+        ReservationTimes: req.ReservationTimes,
+        PartySize: req.PartySize,
+        RepeatInterval: req.RepeatInterval,
+        TableTypes: req.TableTypes,
+        Keep: keep,
+    })
     if err != nil {
         return "", err
     }
-    // if successful, tell user
-    return "Successfully Logged In", nil
+    idstr := strconv.FormatInt(id, 10)
+    // if successful, tell user and print ID of new operation
+    retstr := "Successfully started watch operation with ID " + idstr 
+    return retstr, nil 
 }
 
 /*
-Name: handleLogout
+Name: handleSnipe 
 Type: Internal Func
 Purpose: This function is the handler
-for the 'logout' command, its goal is to
-erase login info from the appctx
+for the 'snipe' command. It takes the same
+flags as the 'rais' command but -i, plus the
+polling bounds, and schedules a cancellation
+snipe operation in the AppCtx
 */
-func (c *ResolvedCLI) handleLogout(in map[string][]string) (string, error) {
-    err := c.AppCtx.Logout()
+func (c *ResolvedCLI) handleSnipe(in cli.Values) (interface{}, error) {
+    req, err := c.parseRais(in)
     if err != nil {
         return "", err
     }
-    // if successful, tell user
-    return "Successfully Logged Out", nil
+    params := app.SnipeParam{
+        Login: req.Login,
+        Account: req.Account,
+        VenueID: req.VenueID,
+        ReservationTimes: req.ReservationTimes,
+        PartySize: req.PartySize,
+        TableTypes: req.TableTypes,
+    }
+    minInterval, err := in.Int("min")
+    if err != nil {
+        return "", err
+    }
+    maxInterval, err := in.Int("max")
+    if err != nil {
+        return "", err
+    }
+    params.MinInterval = time.Duration(minInterval) * time.Second
+    params.MaxInterval = time.Duration(maxInterval) * time.Second
+    id, err := c.AppCtx.ScheduleSnipeOperation(params)
+    if err != nil {
+        return "", err
+    }
+    idstr := strconv.FormatInt(id, 10)
+    // if successful, tell user and print ID of new operation
+    retstr := "Successfully started snipe operation with ID " + idstr 
+    return retstr, nil 
 }
 
 /*
-Name: handleCancel
+Name: printSlotsFound 
 Type: Internal Func
-Purpose: This function is the handler
-for the 'cancel' command, its goal is to
-cancel all operations given the id list
-in the -i field. We only cancel all or no 
-operations, so we check before if they are
-valid to be cancelled
+Purpose: This function is intended to run on a 
+separate thread, printing slots found by watch 
+and snipe operations as they come in, unless 
+the 'tail' command is printing them already
 */
-func (c *ResolvedCLI) handleCancel(in map[string][]string) (string, error) {
-    for _, idStr := range in["i"] {
-        id, err := strconv.ParseInt(idStr, 10, 64)
-        if err != nil {
-            return "", err
+func (c *ResolvedCLI) printSlotsFound(events <-chan app.Event) {
+    for event := range events {
+        if event.Type != app.SlotFoundEvent || c.tailing.Load() {
+            continue
         }
-        stat, err := c.AppCtx.OperationStatus(id)
-        if err != nil {
-            return "", err
+        foundStr := "\nOperation " + strconv.FormatInt(event.OperationID, 10) + " found open slots:"
+        for _, slot := range event.Slots {
+            foundStr += "\n\t" + slot.Time.Format("2006-01-02 15:04 MST")
+            if slot.TableType != "" {
+                foundStr += " (" + slot.TableType + ")"
+            }
         }
-        if stat != app.InProgressStatusType {
-            return "", app.ErrFinOp
+        fmt.Fprintln(c.Out, foundStr)
+        fmt.Fprint(c.Out, replPrompt) 
+    }
+}
+
+/*
+Name: eventToString 
+Type: Internal Func
+Purpose: Render an event on one line for
+the 'tail' command
+*/
+func eventToString(event app.Event) (string) {
+    eventStr := event.Time.Format("15:04:05") + " [" + strconv.FormatInt(event.OperationID, 10) + "] " + string(event.Type)
+    if event.Account != "" {
+        eventStr += " account " + event.Account
+    }
+    if event.Until != nil {
+        eventStr += " until " + event.Until.Format("2006-01-02 15:04:05 MST")
+    }
+    if event.Attempt != 0 {
+        eventStr += " #" + strconv.Itoa(event.Attempt)
+    }
+    for i, slot := range event.Slots {
+        if i == 0 {
+            eventStr += " "
+        } else {
+            eventStr += ", "
+        }
+        eventStr += slot.Time.Format("2006-01-02 15:04 MST")
+        if slot.TableType != "" {
+            eventStr += " (" + slot.TableType + ")"
         }
     }
-    for _, idStr := range in["i"] {
-        // errs checked above
-        id, _ := strconv.ParseInt(idStr, 10, 64)
-        c.AppCtx.CancelOperation(id)
+    if event.ReservationTime != nil {
+        eventStr += " " + event.ReservationTime.Format("2006-01-02 15:04 MST")
     }
-    return "Cancelled Operations Successfully", nil 
+    if event.Error != "" {
+        eventStr += ": " + event.Error
+    }
+    return eventStr
 }
 
 /*
-Name: handleClean
+Name: parseWeekday 
+Type: Internal Func
+Purpose: This function turns a weekday name
+the parser matched back into its weekday
+*/
+func parseWeekday(name string) (time.Weekday) {
+    for day := time.Sunday; day <= time.Saturday; day++ {
+        if strings.ToLower(day.String()) == name {
+            return day
+        }
+    }
+    // the parser only lets weekday names through
+    return time.Sunday
+}
+
+/*
+Name: handleEdit 
 Type: Internal Func
 Purpose: This function is the handler
-for the 'clean' command, its goal is to
-cancel all operations given the id list
-in the -i field. We only clean all or no 
-operations, so we check before if they are
-valid to be cleaned
+for the 'op edit' command, its goal is to
+change the params of an in progress 
+operation without cancelling it
 */
-func (c *ResolvedCLI) handleClean(in map[string][]string) (string, error) {
-    for _, idStr := range in["i"] {
-        id, err := strconv.ParseInt(idStr, 10, 64)
+func (c *ResolvedCLI) handleEdit(in cli.Values) (interface{}, error) {
+    id, err := in.Int64("i")
+    if err != nil {
+        return "", err
+    }
+    snap, err := c.AppCtx.GetOperation(id)
+    if err != nil {
+        return "", err
+    }
+    update := app.OperationUpdate{}
+    changed := false
+    // dates and times are read like the command that
+    // scheduled the operation reads them
+    loc := snap.Location
+    if in.Has("tz") || loc == nil {
+        loc, err = c.parseLocation(in, snap.VenueID)
         if err != nil {
             return "", err
         }
-        stat, err := c.AppCtx.OperationStatus(id)
+    }
+    if in.Has("resD") != in.Has("resT") {
+        return "", ErrInvEdit
+    }
+    if in.Has("resD") {
+        update.ReservationTimes, err = parseReservationTimes(in, loc)
         if err != nil {
             return "", err
         }
-        if stat == app.InProgressStatusType {
-            return "", app.ErrCurrOp
+        changed = true
+    }
+    if in.Has("t") {
+        update.TableTypes = parseTableTypes(in["t"])
+        changed = true
+    }
+    if in.Has("ps") {
+        update.PartySize, err = in.Int("ps")
+        if err != nil {
+            return "", err
         }
+        changed = true
     }
-    for _, idStr := range in["i"] {
-        // errs checked above
-        id, _ := strconv.ParseInt(idStr, 10, 64)
-        c.AppCtx.CleanOperation(id)
+    if in.Has("iv") {
+        update.RepeatInterval, err = in.Duration("iv")
+        if err != nil {
+            return "", err
+        }
+        changed = true
     }
-    return "Cleaned Operations Successfully", nil 
+    if in.Has("reqD") {
+        reqD, err := in.DateTime("reqD")
+        if err != nil {
+            return "", err
+        }
+        update.RequestTime = reqD.In(loc).UTC()
+        changed = true
+    }
+    if !changed {
+        return "", ErrNoEdit
+    }
+    err = c.AppCtx.UpdateOperation(id, update)
+    if err != nil {
+        return "", err
+    }
+    return "Successfully Edited Operation " + in.String("i"), nil
 }
 
 /*
-Name: initParseCtx 
+Name: parseRecur 
 Type: Internal Func
-Purpose: This function initializes
-the parse ctx with the above handlers
+Purpose: This function helps with parsing
+for the main 'recur' handler function
+*/
+func (c *ResolvedCLI) parseRecur(in cli.Values) (*app.RecurringParam, error) {
+    req := app.RecurringParam{}
+    login, account, err := c.parseLogin(in)
+    if err != nil {
+        return nil, err
+    }
+    req.Login = login
+    req.Account = account
+    req.TableTypes = parseTableTypes(in["t"])
+    req.VenueID, err = in.Int64("v")
+    if err != nil {
+        return nil, err
+    }
+    req.Location, err = c.parseLocation(in, req.VenueID)
+    if err != nil {
+        return nil, err
+    }
+    for _, name := range in["wd"] {
+        req.Weekdays = append(req.Weekdays, parseWeekday(name))
+    }
+    req.ReservationTimes, err = in.TimesOfDay("resT")
+    if err != nil {
+        return nil, err
+    }
+    req.PartySize, err = in.Int("ps")
+    if err != nil {
+        return nil, err
+    }
+    // without both, go by the venue booking policy
+    if in.Has("ahead") != in.Has("relT") {
+        return nil, ErrInvRelease
+    }
+    req.DaysAhead, err = in.Int("ahead")
+    if err != nil {
+        return nil, err
+    }
+    req.ReleaseTime, err = in.TimeOfDay("relT")
+    if err != nil {
+        return nil, err
+    }
+    return &req, nil
+}
+
+/*
+Name: handleRecur 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'recur' command, its goal is to
+make a recurring template in the AppCtx
+*/
+func (c *ResolvedCLI) handleRecur(in cli.Values) (interface{}, error) {
+    req, err := c.parseRecur(in)
+    if err != nil {
+        return "", err
+    }
+    id, err := c.AppCtx.ScheduleTemplate(*req)
+    if err != nil {
+        return "", err
+    }
+    idstr := strconv.FormatInt(id, 10)
+    retstr := "Successfully started recurring template with ID " + idstr 
+    return retstr, nil 
+}
+
+/*
+Name: handleRecurList 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'recur list' command, its goal is to
+print the recurring templates
+*/
+func (c *ResolvedCLI) handleRecurList(in cli.Values) (interface{}, error) {
+    return c.AppCtx.TemplatesToString()
+}
+
+/*
+Name: handleRecurPause 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'recur pause' command, its goal is to
+pause the templates with the given ids
+*/
+func (c *ResolvedCLI) handleRecurPause(in cli.Values) (interface{}, error) {
+    return c.forTemplates(in, c.AppCtx.PauseTemplate, "Paused")
+}
+
+/*
+Name: handleRecurResume 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'recur resume' command, its goal is to
+resume the templates with the given ids
+*/
+func (c *ResolvedCLI) handleRecurResume(in cli.Values) (interface{}, error) {
+    return c.forTemplates(in, c.AppCtx.ResumeTemplate, "Resumed")
+}
+
+/*
+Name: handleRecurRm 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'recur rm' command, its goal is to
+delete the templates with the given ids
+*/
+func (c *ResolvedCLI) handleRecurRm(in cli.Values) (interface{}, error) {
+    return c.forTemplates(in, c.AppCtx.DeleteTemplate, "Deleted")
+}
+
+/*
+Name: forTemplates 
+Type: Internal Func
+Purpose: This function runs f on each template
+id given, stopping at the first error
+*/
+func (c *ResolvedCLI) forTemplates(in cli.Values, f func(int64) (error), done string) (string, error) {
+    ids, err := in.Int64s("i")
+    if err != nil {
+        return "", err
+    }
+    for _, id := range ids {
+        err := f(id)
+        if err != nil {
+            return "", err
+        }
+    }
+    return done + " Templates " + strings.Join(in["i"], ", "), nil
+}
+
+/*
+Name: handleTail 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'tail' command, its goal is to
+print operation events as they happen until
+the user hits enter
+*/
+func (c *ResolvedCLI) handleTail(in cli.Values) (interface{}, error) {
+    idList, err := in.Int64s("i")
+    if err != nil {
+        return "", err
+    }
+    ids := make(map[int64]bool)
+    for _, id := range idList {
+        ids[id] = true
+    }
+    types := make(map[app.EventType]bool)
+    for _, eventType := range in["t"] {
+        types[app.EventType(eventType)] = true
+    }
+
+    events, unsubscribe := c.AppCtx.Subscribe(0)
+    done := make(chan bool)
+    go func() {
+        for event := range events {
+            if len(ids) != 0 && !ids[event.OperationID] {
+                continue
+            }
+            if len(types) != 0 && !types[event.Type] {
+                continue
+            }
+            fmt.Fprintln(c.Out, eventToString(event))
+        }
+        close(done)
+    }()
+    c.tailing.Store(true)
+    fmt.Fprintln(c.Out, "Tailing events, press enter to stop")
+
+    if c.scanner == nil {
+        c.scanner = bufio.NewScanner(c.In)
+    }
+    c.scanner.Scan()
+    unsubscribe()
+    <-done
+    c.tailing.Store(false)
+    return "Stopped Tailing Events", nil
+}
+
+/*
+Name: handleServe 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'serve' command, its goal is to
+start the HTTP server next to the CLI
+*/
+func (c *ResolvedCLI) handleServe(in cli.Values) (interface{}, error) {
+    if c.listener != nil {
+        return "", ErrServing
+    }
+    addr := defaultServeAddr
+    if in["a"] != nil {
+        addr = in["a"][0]
+    }
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        return "", err
+    }
+    c.listener = listener
+    srv := server.Server{AppCtx: &c.AppCtx, Addr: addr}
+    go srv.Serve(listener)
+    return "Serving events on http://" + listener.Addr().String() + "/events", nil
+}
+
+/*
+Name: handleRace 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'race' command. Each -m arg is a
+full rats or rais command, which we parse
+with the same rules as the commands themselves
+and schedule together as a group operation
+in the AppCtx
+*/
+func (c *ResolvedCLI) handleRace(in cli.Values) (interface{}, error) {
+    params := app.ReserveGroupParam{
+        Members: make([]app.ReserveGroupMember, len(in["m"])),
+    }
+    for i, memberStr := range in["m"] {
+        cmd, flags, err := c.parseCtx.Resolve(memberStr)
+        if err != nil {
+            return "", err
+        }
+        switch cmd.Name {
+        case "rats":
+            req, err := c.parseRats(flags)
+            if err != nil {
+                return "", err
+            }
+            params.Members[i].AtTime = req
+        case "rais":
+            req, err := c.parseRais(flags)
+            if err != nil {
+                return "", err
+            }
+            params.Members[i].AtInterval = req
+        default:
+            return "", ErrInvMember
+        }
+    }
+    id, err := c.AppCtx.ScheduleReserveGroupOperation(params)
+    if err != nil {
+        return "", err
+    }
+    idstr := strconv.FormatInt(id, 10)
+    retstr := "Successfully started race operation with ID " + idstr 
+    return retstr, nil 
+}
+
+/*
+Name: handleLogin 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'login' command, its goal is to
+save the login info on the appctx if its
+valid, and in the vault with -s
+*/
+func (c *ResolvedCLI) handleLogin(in cli.Values) (interface{}, error) {
+    req, _, err := c.parseLogin(in)
+    if err != nil {
+        return "", err
+    }
+    if in.Has("s") {
+        err = c.AppCtx.SaveLogin(req)
+        if err == nil {
+            c.noteAccount(req.Email, true)
+        }
+    } else {
+        err = c.AppCtx.Login(req)
+    }
+    if err != nil {
+        return "", err
+    }
+    // if successful, tell user
+    return "Successfully Logged In", nil
+}
+
+/*
+Name: handleAccountAdd 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'account add' command, its goal is to
+save login info in the credential store under
+an account name if its valid
+*/
+func (c *ResolvedCLI) handleAccountAdd(in cli.Values) (interface{}, error) {
+    req, name, err := c.parseLogin(in)
+    if err != nil {
+        return "", err
+    }
+    err = c.AppCtx.SaveAccount(name, req)
+    if err != nil {
+        return "", err
+    }
+    c.noteAccount(name, true)
+    return "Successfully Saved Account " + name, nil
+}
+
+/*
+Name: handleAccountUse 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'account use' command, its goal is to
+make a saved account the login default
+*/
+func (c *ResolvedCLI) handleAccountUse(in cli.Values) (interface{}, error) {
+    err := c.AppCtx.UseAccount(in["a"][0])
+    if err != nil {
+        return "", err
+    }
+    c.noteAccount(in["a"][0], true)
+    return "Successfully Logged In", nil
+}
+
+/*
+Name: handleAccountList 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'account list' command, its goal is to
+print the names of saved accounts
+*/
+func (c *ResolvedCLI) handleAccountList(in cli.Values) (interface{}, error) {
+    names, err := c.AppCtx.Accounts()
+    if err != nil {
+        return "", err
+    }
+    // the whole list, so forget the ones we saw before
+    c.accounts = nil
+    for _, name := range names {
+        c.noteAccount(name, true)
+    }
+    return accountList(names), nil
+}
+
+/*
+Name: handleAccountRm 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'account rm' command, its goal is to
+remove saved accounts from the credential store
+*/
+func (c *ResolvedCLI) handleAccountRm(in cli.Values) (interface{}, error) {
+    for _, name := range in["a"] {
+        err := c.AppCtx.DeleteAccount(name)
+        if err != nil {
+            return "", err
+        }
+        c.noteAccount(name, false)
+    }
+    return "Successfully Removed Accounts", nil
+}
+
+/*
+Name: handleClock 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'clock' command, its goal is to
+measure the clock skew against the provider,
+set the lead time, and report both
+*/
+func (c *ResolvedCLI) handleClock(in cli.Values) (interface{}, error) {
+    if in.Has("l") {
+        lead, err := in.Int("l")
+        if err != nil {
+            return "", err
+        }
+        c.AppCtx.SetLeadTime(time.Duration(lead) * time.Millisecond)
+    }
+
+    // only sync if asked to, or if nothing else was asked for
+    _, urlOk := in["u"]
+    _, ntpOk := in["n"]
+    _, leadOk := in["l"]
+    if urlOk || ntpOk || !leadOk {
+        params := app.ClockSyncParam{URL: defaultClockURL}
+        if urlOk {
+            params.URL = in["u"][0]
+        }
+        if ntpOk {
+            params.NTPServer = in["n"][0]
+        }
+        _, err := c.AppCtx.SyncClock(params)
+        if err != nil {
+            return "", err
+        }
+    }
+
+    skew, lead := c.AppCtx.ClockStatus()
+    if skew == nil {
+        return "Clock:\n\tOffset: not synced\n\tLead: " + lead.String(), nil
+    }
+    clockStr := "Clock:\n"
+    clockStr += "\tSource: " + skew.Source + "\n"
+    clockStr += "\tOffset: " + skew.Offset.String() + "\n"
+    clockStr += "\tLatency: " + skew.Latency.String() + "\n"
+    clockStr += "\tSamples: " + strconv.Itoa(skew.Samples) + "\n"
+    clockStr += "\tLead: " + lead.String()
+    return clockStr, nil
+}
+
+/*
+Name: parseNotifier 
+Type: Internal Func
+Purpose: This function helps with parsing
+for the 'notify' handler function, building
+one notifier out of every sink flag given
+*/
+func (c *ResolvedCLI) parseNotifier(in cli.Values) (notify.Notifier, error) {
+    sinks := notify.Multi{}
+    if in["w"] != nil {
+        sinks = append(sinks, notify.Webhook{URL: in["w"][0]})
+    }
+    if in["c"] != nil {
+        sinks = append(sinks, notify.Command{Name: in["c"][0], Args: in["c"][1:]})
+    }
+    if in["f"] != nil {
+        sinks = append(sinks, &notify.File{Path: in["f"][0]})
+    }
+    if in["m"] != nil {
+        if in["smtp"] == nil {
+            return nil, ErrNoSMTP
+        }
+        addr := in["smtp"][0]
+        mail := notify.SMTP{Addr: addr, To: in["m"], From: in["m"][0]}
+        if in["from"] != nil {
+            mail.From = in["from"][0]
+        }
+        // smtp credentials stay out of the command history
+        if user := os.Getenv("RESOLVED_SMTP_USERNAME"); user != "" {
+            host, _, err := net.SplitHostPort(addr)
+            if err != nil {
+                return nil, err
+            }
+            mail.Auth = smtp.PlainAuth("", user, os.Getenv("RESOLVED_SMTP_PASSWORD"), host)
+        }
+        sinks = append(sinks, mail)
+    }
+    if len(sinks) == 0 {
+        return nil, ErrNoSink
+    }
+    if len(sinks) == 1 {
+        return sinks[0], nil
+    }
+    return sinks, nil
+}
+
+/*
+Name: handleNotify 
+Type: Internal Func
+Purpose: This function is the handler
+for the 'notify' command, its goal is to
+set where operation outcomes are sent, for
+every operation or for the ones given
+*/
+func (c *ResolvedCLI) handleNotify(in cli.Values) (interface{}, error) {
+    var notifier notify.Notifier
+    _, off := in["x"]
+    if !off {
+        var err error
+        notifier, err = c.parseNotifier(in)
+        if err != nil {
+            return "", err
+        }
+    }
+    if in["i"] == nil {
+        c.AppCtx.SetNotifier(notifier)
+        if off {
+            return "Successfully Turned Off Notifications", nil
+        }
+        return "Successfully Set Notifications", nil
+    }
+    ids, err := in.Int64s("i")
+    if err != nil {
+        return "", err
+    }
+    for _, id := range ids {
+        err := c.AppCtx.SetOperationNotifier(id, notifier)
+        if err != nil {
+            return "", err
+        }
+    }
+    if off {
+        return "Successfully Reset Operation Notifications", nil
+    }
+    return "Successfully Set Operation Notifications", nil
+}
+
+/*
+Name: handleLogout
+Type: Internal Func
+Purpose: This function is the handler
+for the 'logout' command, its goal is to
+erase login info from the appctx
+*/
+func (c *ResolvedCLI) handleLogout(in cli.Values) (interface{}, error) {
+    err := c.AppCtx.Logout()
+    if err != nil {
+        return "", err
+    }
+    // if successful, tell user
+    return "Successfully Logged Out", nil
+}
+
+/*
+Name: handleCancel
+Type: Internal Func
+Purpose: This function is the handler
+for the 'op cancel' command, its goal is to
+cancel all operations given the id list
+in the -i field. We only cancel all or no 
+operations, so we check before if they are
+valid to be cancelled
+*/
+func (c *ResolvedCLI) handleCancel(in cli.Values) (interface{}, error) {
+    ids, err := in.Int64s("i")
+    if err != nil {
+        return "", err
+    }
+    for _, id := range ids {
+        stat, err := c.AppCtx.OperationStatus(id)
+        if err != nil {
+            return "", err
+        }
+        if stat != app.InProgressStatusType {
+            return "", app.ErrFinOp
+        }
+    }
+    for _, id := range ids {
+        // errs checked above
+        c.AppCtx.CancelOperation(id)
+    }
+    return "Cancelled Operations Successfully", nil 
+}
+
+/*
+Name: handleClean
+Type: Internal Func
+Purpose: This function is the handler
+for the 'op clean' command, its goal is to
+cancel all operations given the id list
+in the -i field. We only clean all or no 
+operations, so we check before if they are
+valid to be cleaned
+*/
+func (c *ResolvedCLI) handleClean(in cli.Values) (interface{}, error) {
+    ids, err := in.Int64s("i")
+    if err != nil {
+        return "", err
+    }
+    for _, id := range ids {
+        stat, err := c.AppCtx.OperationStatus(id)
+        if err != nil {
+            return "", err
+        }
+        if stat == app.InProgressStatusType {
+            return "", app.ErrCurrOp
+        }
+    }
+    for _, id := range ids {
+        // errs checked above
+        c.AppCtx.CleanOperation(id)
+    }
+    return "Cleaned Operations Successfully", nil 
+}
+
+/*
+Name: initParseCtx 
+Type: Internal Func
+Purpose: This function initializes
+the parse ctx with the above handlers
 and command info
 */
 func (c *ResolvedCLI) initParseCtx() {
+    // the values the enum flags take
+    tableChoices := []string{
+        string(api.DiningRoom), string(api.Indoor), string(api.Outdoor), string(api.Patio),
+        string(api.Bar), string(api.Lounge), string(api.Booth),
+    }
+    weekdayChoices := []string{}
+    for day := time.Sunday; day <= time.Saturday; day++ {
+        weekdayChoices = append(weekdayChoices, strings.ToLower(day.String()))
+    }
+    statusChoices := []string{}
+    for _, status := range []app.OperationStatus{app.InProgressStatusType, app.SuccessStatusType, app.FailStatusType, app.CancelStatusType} {
+        statusChoices = append(statusChoices, status.String())
+    }
+    eventChoices := []string{}
+    for _, eventType := range []app.EventType{
+        app.ScheduledEvent, app.LoginStartedEvent, app.WaitingEvent, app.AttemptEvent, app.EditedEvent,
+        app.SlotFoundEvent, app.BookedEvent, app.WatchedEvent, app.FailedEvent, app.CancelledEvent,
+        app.CleanedEvent,
+    } {
+        eventChoices = append(eventChoices, string(eventType))
+    }
+
     // 'search' command
     searchCommand := cli.Command{
         Name: "search",
         Description: "Finds restaurant info",
         Flags: []cli.Flag{
             cli.Flag{
-                Name: "n",
-                LongName: "name",
-                Description: "This flag is required. It takes one text input, the name of the restaurant",
+                Name: "n",
+                LongName: "name",
+                Description: "The name of the restaurant",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "l",
+                LongName: "limit",
+                Description: "The max amount of results to return",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 1, Max: cli.NoMax},
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Examples: []string{
+            "search -n carbone -l 5",
+            "search -n [double chicken please]",
+        },
+        Handler: c.handleSearch,
+    }
+
+    // 'rats' command
+    ratsCommand := cli.Command{
+        Name: "rats",
+        Description: "Reserve At Time Scheduler",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "e",
+                LongName: "email",
+                Description: "Specifies login email, needed unless logged in using the login command",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EmailType,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "p",
+                LongName: "password",
+                Description: "Specifies login password, needed unless logged in using the login command and prompted for if -e is given without it",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "a",
+                LongName: "account",
+                Description: "Specifies saved accounts to login with instead of the login default. Giving several accounts tries the reservation with all of them at once, stopping the rest when one books, and can't be combined with -e or -p",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+            cli.Flag{
+                Name: "v",
+                LongName: "venue-id",
+                Description: "Specifies the venueu id(use search to find by name)",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+	        cli.Flag{
+		        Name: "t",
+		        LongName: "table",
+		        Description: "Used to set the type of table in order of preference. The available types are dining, patio, bar, lounge, indoor, outdoor and booth, or any prefix of only one",
+		        ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: tableChoices,
+		            Required: false,
+		            MinArgs: 1,
+		            MaxArgs: cli.InfiniteArgs, 
+		        },
+	        },
+            cli.Flag{
+                Name: "resD",
+                LongName: "reservation-day",
+                Description: "Specifies the day for the reservation in yyyy:mm:dd format",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.DateType,
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "resT",
+                LongName: "reservation-times",
+                Description: "Specifies the priority time list for the reservation in hh:mm format",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.TimeOfDayType,
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+            cli.Flag{
+                Name: "reqD",
+                LongName: "request-date",
+                Description: "Specifies the date to send request in yyyy:mm:dd:hh:mm format. If left out, the date is computed from the venue booking policy",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.DateTimeType,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "relT",
+                LongName: "release-time",
+                Description: "Specifies the time of day in hh:mm format, venue time, the venue releases tables at, for venues that don't publish it, which resy never does. Required without -reqD unless the venue publishes it or the release-time setting covers it",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.TimeOfDayType,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "ps",
+                LongName: "party-size",
+                Description: "Specifies the size of party",
+                Setting: "party-size",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 1, Max: cli.NoMax},
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "tz",
+                LongName: "tz",
+                Description: "Specifies the timezone, e.g. America/New_York, the dates and times of this command are given in. Defaults to the venue timezone",
+                Setting: "timezone",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+ 
+        },
+        Examples: []string{
+            "rats -v 1505 -resD 2023:09:07 -resT 23:00 -ps 2 -reqD 2023:09:01:00:00",
+            "rats -v 1505 -resD 2023:09:07 -resT 23:00 22:30 -ps 2 -t outdoor dining",
+        },
+        Handler: c.handleRats,
+    }
+
+    // 'rais' command
+    raisCommand := cli.Command{
+        Name: "rais",
+        Description: "Reserve At Interval Scheduler",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "e",
+                LongName: "email",
+                Description: "Specifies login email, needed unless logged in using the login command",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EmailType,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "p",
+                LongName: "password",
+                Description: "Specifies login password, needed unless logged in using the login command and prompted for if -e is given without it",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "a",
+                LongName: "account",
+                Description: "Specifies a saved account to login with instead of the login default",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "v",
+                LongName: "venue-id",
+                Description: "Specifies the venueu id(use search to find by name)",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+	        cli.Flag{
+		        Name: "t",
+		        LongName: "table",
+		        Description: "Used to set the type of table in order of preference. The available types are dining, patio, bar, lounge, indoor, outdoor and booth, or any prefix of only one",
+		        ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: tableChoices,
+		            Required: false,
+		            MinArgs: 1,
+		            MaxArgs: cli.InfiniteArgs, 
+		        },
+	        },
+            cli.Flag{
+                Name: "resD",
+                LongName: "reservation-day",
+                Description: "Specifies the day for the reservation in yyyy:mm:dd format",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.DateType,
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "resT",
+                LongName: "reservation-times",
+                Description: "Specifies the priority time list for the reservation in hh:mm format",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.TimeOfDayType,
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+            cli.Flag{
+                Name: "i",
+                LongName: "interval",
+                Description: "Specifies the interval to send request on in hh:mm format, or as a duration like 30s",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.DurationType,
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "ps",
+                LongName: "party-size",
+                Description: "Specifies the size of party",
+                Setting: "party-size",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 1, Max: cli.NoMax},
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "tz",
+                LongName: "tz",
+                Setting: "timezone",
+                Description: "Specifies the timezone, e.g. America/New_York, the dates and times of this command are given in. Defaults to the venue timezone",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+ 
+        },
+        Examples: []string{
+            "rais -v 1505 -resD 2023:09:07 -resT 23:30 -ps 2 -i 00:01",
+        },
+        Handler: c.handleRais,
+    }
+
+
+    // 'watch' command, takes the same flags as 'rais'
+    watchCommand := cli.Command{
+        Name: "watch",
+        Description: "Watch for open slots without booking them",
+        Flags: append(append([]cli.Flag{}, raisCommand.Flags...), 
+            cli.Flag{
+                Name: "k",
+                LongName: "keep",
+                Description: "Keep watching after slots are found, reporting each newly opened slot",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 0,
+                    MaxArgs: 0,
+                },
+            },
+        ),
+        Examples: []string{
+            "watch -v 1505 -resD 2023:09:07 -resT 19:00 20:00 -ps 2 -i 30s -k",
+        },
+        Handler: c.handleWatch,
+    }
+
+    // 'recur' command, takes the same flags as 'rais' but -i 
+    // and -resD, plus the weekdays and when to request
+    recurFlags := []cli.Flag{}
+    for _, flag := range raisCommand.Flags {
+        if flag.Name != "i" && flag.Name != "resD" {
+            recurFlags = append(recurFlags, flag)
+        }
+    }
+    recurCommand := cli.Command{
+        Name: "recur",
+        Description: "Schedule a rats operation for every occurrence of a weekly reservation",
+        Flags: append(recurFlags, 
+            cli.Flag{
+                Name: "wd",
+                LongName: "weekdays",
+                Description: "Specifies the weekdays of the reservation, e.g. th or thursday",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: weekdayChoices,
                     Required: true,
                     MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+            cli.Flag{
+                Name: "ahead",
+                LongName: "days-ahead",
+                Description: "Specifies how many days before each reservation to send the request. Given with -relT, if both are left out the venue booking policy is used, its release time filled in by the release-time setting",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: false,
+                    MinArgs: 1,
                     MaxArgs: 1,
                 },
             },
             cli.Flag{
-                Name: "l",
-                LongName: "limit",
-                Description: "This flag is optional. It takes one number input, the max amount of results to return",
+                Name: "relT",
+                LongName: "release-time",
+                Description: "Specifies the time of day in hh:mm format to send the request at. Given with -ahead",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.TimeOfDayType,
                     Required: false,
                     MinArgs: 1,
                     MaxArgs: 1,
                 },
             },
+        ),
+        Examples: []string{
+            "recur -v 1505 -ps 2 -wd thu -resT 19:00 -ahead 14 -relT 09:00",
         },
-        Handler: c.handleSearch,
+        Handler: c.handleRecur,
     }
 
-    // 'rats' command
-    ratsCommand := cli.Command{
-        Name: "rats",
-        Description: "Reserve At Time Scheduler",
-        Flags: []cli.Flag{
+    // 'recur list', 'recur pause', 'recur resume' and 'recur rm' commands
+    recurIDFlag := cli.Flag{
+        Name: "i",
+        LongName: "id",
+        Description: "Ids of templates",
+        ValidationCtx: cli.FlagValidationCtx{
+            Type: cli.IntType,
+            Range: &cli.Range{Min: 0, Max: cli.NoMax},
+            Required: true,
+            MinArgs: 1,
+            MaxArgs: cli.InfiniteArgs,
+        },
+    }
+    recurListCommand := cli.Command{
+        Name: "list",
+        Description: "List recurring templates",
+        Flags: []cli.Flag{},
+        Handler: c.handleRecurList,
+    }
+    recurPauseCommand := cli.Command{
+        Name: "pause",
+        Description: "Stop recurring templates from scheduling, cancelling their next operation",
+        Flags: []cli.Flag{recurIDFlag},
+        Examples: []string{
+            "recur pause -i 0",
+        },
+        Handler: c.handleRecurPause,
+    }
+    recurResumeCommand := cli.Command{
+        Name: "resume",
+        Description: "Let paused recurring templates schedule again",
+        Flags: []cli.Flag{recurIDFlag},
+        Handler: c.handleRecurResume,
+    }
+    recurRmCommand := cli.Command{
+        Name: "rm",
+        Description: "Delete recurring templates, cancelling their next operation",
+        Flags: []cli.Flag{recurIDFlag},
+        Handler: c.handleRecurRm,
+    }
+
+    // 'snipe' command, takes the same flags as 'rais' but -i
+    snipeFlags := []cli.Flag{}
+    for _, flag := range raisCommand.Flags {
+        if flag.Name != "i" {
+            snipeFlags = append(snipeFlags, flag)
+        }
+    }
+    snipeCommand := cli.Command{
+        Name: "snipe",
+        Description: "Book cancellations as they open up",
+        Flags: append(snipeFlags, 
             cli.Flag{
-                Name: "e",
-                LongName: "email",
-                Description: "This flag is optional if already logged in using Login command. Specifies login email",
+                Name: "min",
+                LongName: "min-interval",
+                Description: "Specifies the fastest polling interval in seconds, used near the 24 to 48 hour cancellation window",
+                Default: "30",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 1, Max: cli.NoMax},
                     Required: false,
                     MinArgs: 1,
                     MaxArgs: 1,
                 },
             },
             cli.Flag{
-                Name: "p",
-                LongName: "password",
-                Description: "This flag is optional if already logged in using Login command. Specifies login password",
+                Name: "max",
+                LongName: "max-interval",
+                Description: "Specifies the slowest polling interval in seconds, used far from the cancellation window",
+                Default: "600",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 1, Max: cli.NoMax},
                     Required: false,
                     MinArgs: 1,
                     MaxArgs: 1,
                 },
             },
+        ),
+        Examples: []string{
+            "snipe -v 1505 -resD 2023:09:07 -resT 19:00 -ps 2",
+        },
+        Handler: c.handleSnipe,
+    }
+
+    // 'race' command
+    raceCommand := cli.Command{
+        Name: "race",
+        Description: "Race several rats and rais targets, stopping the rest when one books",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "m",
+                LongName: "members",
+                Description: "Each a full rats or rais command wrapped in brackets",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+        },
+        Examples: []string{
+            "race -m [rats -v 1505 -resD 2023:09:07 -resT 19:00 -ps 2] [rais -v 2000 -resD 2023:09:07 -resT 19:00 -ps 2 -i 00:01]",
+        },
+        Handler: c.handleRace,
+    }
+
+    // 'op list' command
+    listCommand := cli.Command{
+        Name: "list",
+        Description: "List operations, all of them unless filtered",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "s",
+                LongName: "status",
+                Description: "Statuses to list: in-progress, succeeded, failed and cancelled",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: statusChoices,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
             cli.Flag{
                 Name: "v",
-                LongName: "venue-id",
-                Description: "This flag is required. Specifies the venueu id(use search to find by name)",
+                LongName: "venue",
+                Description: "The venue id to list operations for",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "a",
+                LongName: "account",
+                Description: "The saved account name or login email to list operations for",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Examples: []string{
+            "op list -s in-progress failed",
+            "op list -v 1505 -a work",
+        },
+        Handler: c.handleList,
+    }
+
+    // 'op show' command
+    showCommand := cli.Command{
+        Name: "show",
+        Description: "Show an operation with its timestamps and a log of every call it made",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "i",
+                LongName: "id",
+                Description: "The id of the operation to show",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Examples: []string{
+            "op show -i 0",
+        },
+        Handler: c.handleShow,
+    }
+
+    // 'op edit' command
+    editCommand := cli.Command{
+        Name: "edit",
+        Description: "Change the params of an in progress operation, keeping its id and history",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "i",
+                LongName: "id",
+                Description: "The id of the operation to edit",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
                     Required: true,
                     MinArgs: 1,
                     MaxArgs: 1,
                 },
             },
-	        cli.Flag{
-		        Name: "t",
-		        LongName: "table",
-		        Description: "This flag is optional. Used to set the type of table in order of preference. The available types are dining, patio, bar, lounge, indoor, and outdoor",
-		        ValidationCtx: cli.FlagValidationCtx{
-		            Required: false,
-		            MinArgs: 1,
-		            MaxArgs: cli.InfiniteArgs, 
-		        },
-	        },
             cli.Flag{
                 Name: "resD",
                 LongName: "reservation-day",
-                Description: "This flag is required. Specifies the day for the reservation in yyyy:mm:dd format",
+                Description: "Specifies the new day for the reservation in yyyy:mm:dd format, given with -resT",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
+                    Type: cli.DateType,
+                    Required: false,
                     MinArgs: 1,
                     MaxArgs: 1,
                 },
@@ -613,47 +2021,262 @@ func (c *ResolvedCLI) initParseCtx() {
             cli.Flag{
                 Name: "resT",
                 LongName: "reservation-times",
-                Description: "This flag is required. Specifies the priority time list for the reservation in hh:mm format",
+                Description: "Specifies the new priority time list for the reservation in hh:mm format, given with -resD",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
+                    Type: cli.TimeOfDayType,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+            cli.Flag{
+                Name: "t",
+                LongName: "table",
+                Description: "Specifies the new table types in order of preference",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: tableChoices,
+                    Required: false,
                     MinArgs: 1,
                     MaxArgs: cli.InfiniteArgs,
                 },
             },
+            cli.Flag{
+                Name: "ps",
+                LongName: "party-size",
+                Description: "Specifies the new size of party",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 1, Max: cli.NoMax},
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "iv",
+                LongName: "interval",
+                Description: "Specifies the new repeat interval of a rais or watch operation in hh:mm format, or as a duration like 30s",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.DurationType,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
             cli.Flag{
                 Name: "reqD",
                 LongName: "request-date",
-                Description: "This flag is required. Specifies the date to send request in yyyy:mm:dd:hh:mm format",
+                Description: "Specifies the new date to send the request of a rats operation in yyyy:mm:dd:hh:mm format",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.DateTimeType,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "tz",
+                LongName: "tz",
+                Description: "Specifies the timezone the dates and times of this command are given in. Defaults to the timezone of the operation",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Examples: []string{
+            "op edit -i 0 -ps 4",
+            "op edit -i 0 -resD 2023:09:07 -resT 23:30 22:00",
+        },
+        Handler: c.handleEdit,
+    }
+
+    // 'login' command
+    loginCommand := cli.Command{
+        Name: "login",
+        Description: "Set login defaults",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "e",
+                LongName: "email",
+                Description: "Provides login email",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EmailType,
                     Required: true,
+                    MaxArgs: 1,
                     MinArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "p",
+                LongName: "password",
+                Description: "Provides login password, prompted for without echo if missing",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
                     MaxArgs: 1,
+                    MinArgs: 1,
                 },
             },
             cli.Flag{
-                Name: "ps",
-                LongName: "party-size",
-                Description: "This flag is required. Specifies the size of party",
+                Name: "s",
+                LongName: "save",
+                Description: "Also save the login in the encrypted vault under the email, like account add",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MaxArgs: 0,
+                    MinArgs: 0,
+                },
+            },
+        },
+        Handler: c.handleLogin,
+    }
+
+    // 'account add' command
+    accountAddCommand := cli.Command{
+        Name: "add",
+        Description: "Save login credentials under an account name",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "a",
+                LongName: "account",
+                Description: "Provides the name to save the account under",
                 ValidationCtx: cli.FlagValidationCtx{
                     Required: true,
+                    MaxArgs: 1,
                     MinArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "e",
+                LongName: "email",
+                Description: "Provides login email",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EmailType,
+                    Required: true,
                     MaxArgs: 1,
+                    MinArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "p",
+                LongName: "password",
+                Description: "Provides login password, prompted for without echo if missing",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MaxArgs: 1,
+                    MinArgs: 1,
                 },
             },
- 
         },
-        Handler: c.handleRats,
+        Examples: []string{
+            "account add -a work -e me@example.com",
+        },
+        Handler: c.handleAccountAdd,
     }
 
-    // 'rais' command
-    raisCommand := cli.Command{
-        Name: "rais",
-        Description: "Reserve At Interval Scheduler",
+    // 'account use' command
+    accountUseCommand := cli.Command{
+        Name: "use",
+        Description: "Set a saved account as the login default",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "a",
+                LongName: "account",
+                Description: "Provides the name of the saved account",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: true,
+                    MaxArgs: 1,
+                    MinArgs: 1,
+                },
+            },
+        },
+        Handler: c.handleAccountUse,
+    }
+
+    // 'account list' command
+    accountListCommand := cli.Command{
+        Name: "list",
+        Description: "List saved accounts",
+        Flags: []cli.Flag{},
+        Handler: c.handleAccountList,
+    }
+
+    // 'account rm' command
+    accountRmCommand := cli.Command{
+        Name: "rm",
+        Description: "Remove saved accounts",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "a",
+                LongName: "account",
+                Description: "The names of saved accounts",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: true,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+        },
+        Handler: c.handleAccountRm,
+    }
+
+    // 'clock' command
+    clockCommand := cli.Command{
+        Name: "clock",
+        Description: "Measure clock skew against the provider and set the lead time",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "u",
+                LongName: "url",
+                Description: "The URL whose Date headers are sampled",
+                Default: defaultClockURL,
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "n",
+                LongName: "ntp",
+                Description: "An NTP server to sync against instead of the URL",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "l",
+                LongName: "lead",
+                Description: "How many milliseconds early to fire requests. Without -u or -n only the lead time is set",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Examples: []string{
+            "clock",
+            "clock -l 150",
+        },
+        Handler: c.handleClock,
+    }
+
+    // 'notify' command
+    notifyCommand := cli.Command{
+        Name: "notify",
+        Description: "Send operation outcomes to email, a webhook, a command or a file",
         Flags: []cli.Flag{
             cli.Flag{
-                Name: "e",
-                LongName: "email",
-                Description: "This flag is optional if already logged in using Login command. Specifies login email",
+                Name: "w",
+                LongName: "webhook",
+                Description: "A URL outcomes are POSTed to as JSON",
                 ValidationCtx: cli.FlagValidationCtx{
                     Required: false,
                     MinArgs: 1,
@@ -661,116 +2284,141 @@ func (c *ResolvedCLI) initParseCtx() {
                 },
             },
             cli.Flag{
-                Name: "p",
-                LongName: "password",
-                Description: "This flag is optional if already logged in using Login command. Specifies login password",
+                Name: "c",
+                LongName: "command",
+                Description: "A command and its args, run with the subject and text of each outcome appended, e.g. notify-send",
                 ValidationCtx: cli.FlagValidationCtx{
                     Required: false,
                     MinArgs: 1,
-                    MaxArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
                 },
             },
             cli.Flag{
-                Name: "v",
-                LongName: "venue-id",
-                Description: "This flag is required. Specifies the venueu id(use search to find by name)",
+                Name: "f",
+                LongName: "file",
+                Description: "A file outcomes are appended to",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
+                    Required: false,
                     MinArgs: 1,
                     MaxArgs: 1,
                 },
             },
-	        cli.Flag{
-		        Name: "t",
-		        LongName: "table",
-		        Description: "This flag is optional. Used to set the type of table in order of preference. The available types are dining, patio, bar, lounge, indoor, and outdoor",
-		        ValidationCtx: cli.FlagValidationCtx{
-		            Required: false,
-		            MinArgs: 1,
-		            MaxArgs: cli.InfiniteArgs, 
-		        },
-	        },
             cli.Flag{
-                Name: "resD",
-                LongName: "reservation-day",
-                Description: "This flag is required. Specifies the day for the reservation in yyyy:mm:dd format",
+                Name: "m",
+                LongName: "mail",
+                Description: "Email addresses outcomes are sent to. Needs --smtp",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
+                    Type: cli.EmailType,
+                    Required: false,
                     MinArgs: 1,
-                    MaxArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
                 },
             },
             cli.Flag{
-                Name: "resT",
-                LongName: "reservation-times",
-                Description: "This flag is required. Specifies the priority time list for the reservation in hh:mm format",
+                Name: "smtp",
+                LongName: "smtp",
+                Description: "The host:port of the smtp server mail is sent through, logged into with RESOLVED_SMTP_USERNAME and RESOLVED_SMTP_PASSWORD if set",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
+                    Required: false,
                     MinArgs: 1,
-                    MaxArgs: cli.InfiniteArgs,
+                    MaxArgs: 1,
                 },
             },
             cli.Flag{
-                Name: "i",
-                LongName: "interval",
-                Description: "This flag is required. Specifies the interval to send request on in hh:mm format",
+                Name: "from",
+                LongName: "from",
+                Description: "The address mail is sent from, defaults to the first -m address",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
+                    Type: cli.EmailType,
+                    Required: false,
                     MinArgs: 1,
                     MaxArgs: 1,
                 },
             },
             cli.Flag{
-                Name: "ps",
-                LongName: "party-size",
-                Description: "This flag is required. Specifies the size of party",
+                Name: "i",
+                LongName: "id",
+                Description: "Ids of operations to notify for instead of every operation",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: false,
                     MinArgs: 1,
-                    MaxArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+            cli.Flag{
+                Name: "x",
+                LongName: "off",
+                Description: "Turns notifications off, or with -i makes the operations use the global setting again",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 0,
+                    MaxArgs: 0,
                 },
             },
- 
         },
-        Handler: c.handleRais,
-    }
-
-
-    // 'list' command
-    listCommand := cli.Command{
-        Name: "list",
-        Description: "List all operations",
-        Flags: []cli.Flag{},
-        Handler: c.handleList,
+        Examples: []string{
+            "notify -c notify-send",
+            "notify -w https://example.com/hook -i 3",
+        },
+        Handler: c.handleNotify,
     }
 
-    // 'login' command
-    loginCommand := cli.Command{
-        Name: "login",
-        Description: "Set login defaults",
+    // 'tail' command
+    tailCommand := cli.Command{
+        Name: "tail",
+        Description: "Print operation events as they happen, until enter is hit",
         Flags: []cli.Flag{
             cli.Flag{
-                Name: "e",
-                LongName: "email",
-                Description: "This flag is required. Provides login email",
+                Name: "i",
+                LongName: "id",
+                Description: "Ids of operations to print events of",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
-                    MaxArgs: 1,
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
+                    Required: false,
                     MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
                 },
             },
             cli.Flag{
-                Name: "p",
-                LongName: "password",
-                Description: "This flag is required. Provides login password",
+                Name: "t",
+                LongName: "type",
+                Description: "Event types to print: scheduled, login-started, waiting, attempt, edited, slot-found, booked, watched, failed, cancelled and cleaned",
                 ValidationCtx: cli.FlagValidationCtx{
-                    Required: true,
-                    MaxArgs: 1,
+                    Type: cli.EnumType,
+                    Choices: eventChoices,
+                    Required: false,
                     MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
                 },
             },
         },
-        Handler: c.handleLogin,
+        Examples: []string{
+            "tail -t booked failed",
+        },
+        Handler: c.handleTail,
+    }
+
+    // 'serve' command
+    serveCommand := cli.Command{
+        Name: "serve",
+        Description: "Start the HTTP server, streaming events at /events",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "a",
+                LongName: "addr",
+                Description: "The host:port to listen on",
+                Default: defaultServeAddr,
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Handler: c.handleServe,
     }
 
     // 'logout' command
@@ -781,7 +2429,7 @@ func (c *ResolvedCLI) initParseCtx() {
         Handler: c.handleLogout,
     }
 
-    // 'cancel' command
+    // 'op cancel' command
     cancelCommand := cli.Command{
         Name: "cancel",
         Description: "Cancel operations given ids",
@@ -789,18 +2437,23 @@ func (c *ResolvedCLI) initParseCtx() {
             cli.Flag{
                 Name: "i",
                 LongName: "id",
-                Description: "This flag is required. It takes one to unmeasured number inputs, the ids of operations",
+                Description: "The ids of operations",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
                     Required: true,
                     MinArgs: 1,
                     MaxArgs: cli.InfiniteArgs,
                 },
             },
         },
+        Examples: []string{
+            "op cancel -i 0 1",
+        },
         Handler: c.handleCancel,
     }
 
-    // 'clean' command
+    // 'op clean' command
     cleanCommand := cli.Command{
         Name: "clean",
         Description: "Clean operations given ids",
@@ -808,8 +2461,10 @@ func (c *ResolvedCLI) initParseCtx() {
             cli.Flag{
                 Name: "i",
                 LongName: "id",
-                Description: "This flag is required. It takes one to unmeasured number inputs, the ids of operations",
+                Description: "The ids of operations",
                 ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.IntType,
+                    Range: &cli.Range{Min: 0, Max: cli.NoMax},
                     Required: true,
                     MinArgs: 1,
                     MaxArgs: cli.InfiniteArgs,
@@ -819,27 +2474,142 @@ func (c *ResolvedCLI) initParseCtx() {
         Handler: c.handleClean,
     }
 
-    // 'quit' command
+    // 'quit' command, also called 'exit'
     quitCommand := cli.Command{
         Name: "quit",
+        Aliases: []string{"exit"},
         Description: "Exits the CLI",
         Flags: []cli.Flag{},
         Handler: c.handleQuit,
     }
 
-    // 'exit' command, same handler as 'quit' command
-    exitCommand := cli.Command{
-        Name: "exit",
-        Description: "Exits the CLI",
+    // 'op' command, grouping the commands on operations
+    opCommand := cli.Command{
+        Name: "op",
+        Description: "Manage scheduled operations",
         Flags: []cli.Flag{},
-        Handler: c.handleQuit,
+        Subcommands: []cli.Command{
+            listCommand,
+            showCommand,
+            editCommand,
+            cancelCommand,
+            cleanCommand,
+        },
+    }
+
+    // 'account' command, grouping the commands on saved accounts
+    accountCommand := cli.Command{
+        Name: "account",
+        Description: "Manage saved accounts",
+        Flags: []cli.Flag{},
+        Subcommands: []cli.Command{
+            accountAddCommand,
+            accountUseCommand,
+            accountListCommand,
+            accountRmCommand,
+        },
+    }
+
+    recurCommand.Subcommands = []cli.Command{
+        recurListCommand,
+        recurPauseCommand,
+        recurResumeCommand,
+        recurRmCommand,
+    }
+
+    // 'config show' command
+    configShowCommand := cli.Command{
+        Name: "show",
+        Description: "Show where settings are read from and their values",
+        Flags: []cli.Flag{},
+        Examples: []string{
+            "config show",
+        },
+        Handler: c.handleConfigShow,
+    }
+
+    // 'config output' command
+    configOutputCommand := cli.Command{
+        Name: "output",
+        Description: "Set the format results are printed in for the rest of the session",
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "f",
+                LongName: "format",
+                Description: "The format to print results in. Without it the current one is shown",
+                Positional: true,
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: cli.Outputs,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Examples: []string{
+            "config output json",
+            "config output",
+        },
+        Handler: c.handleConfigOutput,
+    }
+
+    // 'config' command, grouping the commands on settings
+    configCommand := cli.Command{
+        Name: "config",
+        Description: "Inspect settings read from the environment and config file",
+        Flags: []cli.Flag{},
+        Subcommands: []cli.Command{
+            configShowCommand,
+            configOutputCommand,
+        },
     }
 
     // 'help' command
     helpCommand := cli.Command{
         Name: "help",
         Description: "Displays helpful info about commands",
-        Flags: []cli.Flag{},
+        Flags: []cli.Flag{
+            cli.Flag{
+                Name: "c",
+                LongName: "command",
+                Description: "The command to show, e.g. op list. Without it every command is listed",
+                Positional: true,
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: cli.InfiniteArgs,
+                },
+            },
+            cli.Flag{
+                Name: "f",
+                LongName: "format",
+                Description: "Exports the help of every command as a markdown document or a man page instead",
+                Default: "text",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: []string{"text", "markdown", "man"},
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+            cli.Flag{
+                Name: "w",
+                LongName: "write",
+                Description: "A file to write the help to instead of printing it",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
+        Examples: []string{
+            "help op list",
+            "help -f markdown -w COMMANDS.md",
+            "help -f man -w resolved-server.1",
+        },
         Handler: c.handleHelp,
     }
 
@@ -847,52 +2617,366 @@ func (c *ResolvedCLI) initParseCtx() {
     c.parseCtx = cli.ParseCtx{
         OpenDelim: "[",
         CloseDelim: "]",
+        // taken by every command, anywhere on the line
+        Globals: []cli.Flag{
+            cli.Flag{
+                Name: "o",
+                LongName: "output",
+                Description: "The format to print the result in, for this command only",
+                ValidationCtx: cli.FlagValidationCtx{
+                    Type: cli.EnumType,
+                    Choices: cli.Outputs,
+                    Required: false,
+                    MinArgs: 1,
+                    MaxArgs: 1,
+                },
+            },
+        },
         Commands: []cli.Command{
             searchCommand,
-            cancelCommand,
-            cleanCommand,
-            listCommand,
+            opCommand,
             loginCommand,
             logoutCommand,
+            accountCommand,
             ratsCommand,
             raisCommand,
+            raceCommand,
+            watchCommand,
+            snipeCommand,
+            recurCommand,
+            configCommand,
+            clockCommand,
+            notifyCommand,
+            tailCommand,
+            serveCommand,
             quitCommand,
-            exitCommand,
             helpCommand,
         },
+        // the names commands had before they were grouped
+        Aliases: map[string]string{
+            "list": "op list",
+            "show": "op show",
+            "edit": "op edit",
+            "cancel": "op cancel",
+            "clean": "op clean",
+            "account-add": "account add",
+            "account-use": "account use",
+            "account-list": "account list",
+            "account-rm": "account rm",
+            "recur-list": "recur list",
+            "recur-pause": "recur pause",
+            "recur-resume": "recur resume",
+            "recur-rm": "recur rm",
+        },
+    }
+    c.setCompletions()
+}
+
+/*
+Name: argsToLine
+Type: Internal Func
+Purpose: Join process arguments back into a command
+line, quoting arguments that need it so the shell
+quoting survives
+*/
+func (c *ResolvedCLI) argsToLine(args []string) (string) {
+    line := make([]string, len(args))
+    for i, arg := range args {
+        line[i] = c.parseCtx.Quote(arg)
+    }
+    return strings.Join(line, " ")
+}
+
+/*
+Name: runLine
+Type: Internal Func
+Purpose: Parse and run one command line, printing
+its result or error, and return whether it succeeded.
+A parse error is pointed at under the line, which is
+echoed first unless it was just typed after a prompt
+*/
+func (c *ResolvedCLI) runLine(line string, prompted bool) (bool) {
+    cmd, flags, err := c.parseCtx.Resolve(line)
+    if err != nil {
+        c.printParseError(err, prompted)
+        return false
+    }
+    if c.batch {
+        err = c.checkBatch(cmd, flags)
+    }
+    var result string
+    if err == nil && c.conn != nil {
+        result, err = c.sendCommand(cmd, flags)
+    } else if err == nil {
+        result, err = c.runHandler(cmd, flags, c.Output)
+    }
+    if err != nil {
+        fmt.Fprint(c.Err, "ERROR: ")
+        fmt.Fprintln(c.Err, err)
+        return false
+    }
+    if !c.quit {
+        fmt.Fprintln(c.Out, result) 
+    }
+    return true
+}
+
+/*
+Name: checkBatch
+Type: Internal Func
+Purpose: Refuse commands that can't run from arguments,
+a script or a pipe. tail would read its stop line from
+the commands, and operations that only end when cancelled
+would keep Run waiting forever unless a daemon runs them
+*/
+func (c *ResolvedCLI) checkBatch(cmd *cli.Command, flags cli.Values) (error) {
+    switch c.parseCtx.Path(cmd) {
+        case "tail":
+            return ErrBatchTail
+        case "snipe", "recur":
+            if c.conn == nil {
+                return ErrBatchForever
+            }
+        case "watch":
+            if c.conn == nil && flags.Has("k") {
+                return ErrBatchForever
+            }
+    }
+    return nil
+}
+
+/*
+Name: printParseError
+Type: Internal Func
+Purpose: Print why a line failed to parse with a
+caret under the token at fault
+*/
+func (c *ResolvedCLI) printParseError(err error, prompted bool) {
+    var perr *cli.ParseError
+    if errors.As(err, &perr) && perr.Caret() != "" {
+        if prompted {
+            fmt.Fprintln(c.Err, strings.Repeat(" ", len(replPrompt)) + perr.Caret())
+        } else {
+            fmt.Fprintln(c.Err, "    " + perr.Input)
+            fmt.Fprintln(c.Err, "    " + perr.Caret())
+        }
+    }
+    fmt.Fprint(c.Err, "ERROR: ")
+    fmt.Fprintln(c.Err, err)
+}
+
+/*
+Name: runBatch
+Type: Internal Func
+Purpose: Run each line of a script in order until
+EOF or 'exit', skipping blank lines and lines
+starting with '#'. No prompt is printed
+*/
+func (c *ResolvedCLI) runBatch(scanner *bufio.Scanner) {
+    for !c.quit && scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        if !c.runLine(line, false) {
+            c.failed = true
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        fmt.Fprintln(c.Err, err)
+        c.failed = true
+    }
+}
+
+/*
+Name: waitOperations
+Type: Internal Func
+Purpose: Block until no operation is in progress or
+sending its notification, so the process doesn't exit
+under a scheduled reservation or cut a notification
+off, and mark the run failed if any operation failed.
+checkBatch keeps operations that never finish out
+*/
+func (c *ResolvedCLI) waitOperations() {
+    // subscribe before looking so no finish is missed, the
+    // outcome event comes once the notification was sent
+    events, unsubscribe := c.AppCtx.Subscribe(0)
+    defer unsubscribe()
+    inProgress := app.OperationFilter{Statuses: []app.OperationStatus{app.InProgressStatusType}}
+    for len(c.AppCtx.ListOperations(inProgress)) != 0 || c.AppCtx.Notifying() {
+        <-events
+    }
+    failed := app.OperationFilter{Statuses: []app.OperationStatus{app.FailStatusType}}
+    if len(c.AppCtx.ListOperations(failed)) != 0 {
+        c.failed = true
+    }
+}
+
+/*
+Name: runREPL
+Type: Internal Func
+Purpose: Prompt for and run commands until EOF
+or 'exit', with line editing, history and
+tab completion
+*/
+func (c *ResolvedCLI) runREPL() {
+    // print welcome msg 
+    fmt.Fprintln(c.Out, "Welcome to the Resolved CLI! For Help type 'help'") 
+    if c.conn != nil {
+        fmt.Fprintln(c.Out, "Sending commands to the daemon at " + c.socketPath())
+    }
+    editor := c.newEditor()
+    for !c.quit {
+        line, err := editor.ReadLine()
+        if errors.Is(err, cli.ErrInterrupt) {
+            continue
+        }
+        if err != nil {
+            if err != io.EOF {
+                fmt.Fprintln(c.Err, err)
+            }
+            // leave the prompt line on EOF
+            fmt.Fprintln(c.Out)
+            return
+        }
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+        // history is a convenience, a full disk shouldn't stop the REPL
+        editor.AddHistory(line)
+        c.runLine(line, true)
+    }
+}
+
+/*
+Name: parseArgs
+Type: Internal Func
+Purpose: Pull the --script, --socket, --config and
+--output options off the front of Args, returning
+the script and the arguments left
+*/
+func (c *ResolvedCLI) parseArgs(args []string) (string, []string, error) {
+    script := ""
+    // what each option sets and the error when its value is missing
+    options := map[string]struct{
+        value   *string
+        err     error
+    }{
+        "--script": {&script, ErrInvScript},
+        "--socket": {&c.Socket, ErrInvSocket},
+        "--config": {&c.Config, ErrInvConfig},
+        "--output": {&c.Output, ErrInvOutput},
+    }
+    for len(args) != 0 {
+        option, ok := options[args[0]]
+        if !ok {
+            break
+        }
+        if len(args) < 2 {
+            return "", nil, option.err
+        }
+        *option.value = args[1]
+        args = args[2:]
     }
+    if script != "" && len(args) != 0 {
+        return "", nil, ErrInvScript
+    }
+    return script, args, nil
 }
 
 /*
 Name: Run 
 Type: External Func
 Purpose: This function inits the 
-parse ctx and enters into an infinite
-loop, starting the REPL
+parse ctx, reads the settings from the
+environment and config file and runs
+the command in Args once, the script given by --script, the
+commands piped to In, or when In is a 
+terminal the REPL. Commands go to the 
+daemon when one is running, and 'daemon'
+starts one. Outside the REPL it waits for
+local operations to finish and returns
+ErrFailed if a command or operation failed
 */
 func (c *ResolvedCLI) Run() (error) {
     // init the parse ctx w/the above handler
     c.initParseCtx()
-    scanner := bufio.NewScanner(c.In)
-    // print welcome msg 
-    fmt.Fprintln(c.Out, "Welcome to the Resolved CLI! For Help type 'help'") 
-    for {
-        // print prompt 
-        fmt.Fprint(c.Out, "resolved(0.1.0)>> ") 
-        scanner.Scan()
-        if err := scanner.Err(); err != nil {
-            fmt.Fprintln(c.Err, err);
-        }
-        // parse input 
-        result, err := c.parseCtx.Parse(scanner.Text()) 
+    script, args, err := c.parseArgs(c.Args)
+    if err == nil && len(args) != 0 && args[0] == "daemon" {
+        // allow the options after the daemon keyword too
+        script, args, err = c.parseArgs(args[1:])
+        if err == nil && (script != "" || len(args) != 0) {
+            err = ErrInvDaemon
+        }
+        if err == nil {
+            err = c.loadSettings()
+        }
+        if err == nil {
+            slotEvents, _ := c.AppCtx.Subscribe(0)
+            go c.printSlotsFound(slotEvents)
+            err = c.runDaemon()
+        }
         if err != nil {
-            fmt.Fprint(c.Err, "ERROR: ")
-            fmt.Fprintln(c.Err, err)
-        } else  {
-            fmt.Fprintln(c.Out, result) 
+            fmt.Fprintln(c.Err, "ERROR: " + err.Error())
         }
+        return err
+    }
+    if err == nil {
+        err = c.loadSettings()
+    }
+    if err != nil {
+        fmt.Fprintln(c.Err, "ERROR: " + err.Error())
+        return err
+    }
+
+    c.dialDaemon()
+    if c.conn != nil {
+        defer c.conn.Close()
+    } else {
+        slotEvents, _ := c.AppCtx.Subscribe(0)
+        go c.printSlotsFound(slotEvents)
+    }
+    if c.scanner == nil {
+        c.scanner = bufio.NewScanner(c.In)
+    }
+    c.batch = script != "" || len(args) != 0 || !c.interactive()
+    switch {
+        case script != "":
+            // the script gets its own reader so prompts still read In
+            f, err := os.Open(script)
+            if err != nil {
+                fmt.Fprintln(c.Err, "ERROR: " + err.Error())
+                return err
+            }
+            defer f.Close()
+            c.runBatch(bufio.NewScanner(f))
+        case len(args) != 0:
+            if !c.runLine(c.argsToLine(args), false) {
+                c.failed = true
+            }
+        case !c.interactive():
+            c.runBatch(c.scanner)
+        default:
+            c.runREPL()
+            return nil
+    }
+    // operations run by the daemon outlive us
+    if c.conn == nil {
+        c.waitOperations()
+    }
+    if c.failed {
+        return ErrFailed
     }
     return nil
 }
 
-
+/*
+Name: interactive
+Type: Internal Func
+Purpose: Report whether In is a terminal, 
+where the REPL prompts for commands
+*/
+func (c *ResolvedCLI) interactive() (bool) {
+    f, ok := c.In.(*os.File)
+    return ok && cli.IsTerminal(f)
+}
@@ -0,0 +1,422 @@
+package cli
+
+import (
+    "bytes"
+    "errors"
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/app"
+    "github.com/21Bruce/resolved-server/clock"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+/*
+Name: policyAPI
+Type: Test Struct
+Purpose: An idleAPI publishing a booking window
+but not its release time, like resy
+*/
+type policyAPI struct {
+    idleAPI
+}
+
+func (policyAPI) BookingPolicy(params api.BookingPolicyParam) (*api.BookingPolicyResponse, error) {
+    return &api.BookingPolicyResponse{DaysInAdvance: 7}, nil
+}
+
+/*
+Name: withConfig
+Type: Test Func
+Purpose: Load the settings of c from a config
+file holding config
+*/
+func withConfig(t *testing.T, c *ResolvedCLI, config string) (error) {
+    c.Config = filepath.Join(t.TempDir(), "config")
+    if err := os.WriteFile(c.Config, []byte(config), 0600); err != nil {
+        t.Fatal(err)
+    }
+    return c.loadSettings()
+}
+
+func TestRatsFanOutLogin(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    // a password would be read from here if asked for
+    c.In = strings.NewReader("hunter2\n")
+    _, err := run(t, c, "rats -a work home -e me@example.com -v 1505 -resD 2023:09:08 -resT 19:00 -ps 2 -reqD 2023:09:02:09:00", "")
+    if err != ErrInvFanOut {
+        t.Errorf("rats with -e and several -a: err = %v, want ErrInvFanOut", err)
+    }
+    if strings.Contains(out.String(), "Password") {
+        t.Errorf("prompted for a password that can't be used: %q", out.String())
+    }
+    // without a login the accounts fan out
+    msg, err := run(t, c, "rats -a work home -v 1505 -resD 2023:09:08 -resT 19:00 -ps 2 -reqD 2023:09:02:09:00", "")
+    if err != nil || !strings.Contains(msg, "across 2 accounts") {
+        t.Errorf("rats with several -a = %q, %v", msg, err)
+    }
+}
+
+func TestRatsReleaseSetting(t *testing.T) {
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    c.AppCtx.API = policyAPI{}
+    t.Setenv("TEST_WORK_EMAIL", "me@example.com")
+    t.Setenv("TEST_WORK_PASSWORD", "secret")
+    line := "rats -a work -v 1505 -resD 2023:09:15 -resT 19:00 -ps 2 --tz UTC"
+    if _, err := run(t, c, line, ""); err != ErrNeedRelease {
+        t.Errorf("no release time: err = %v, want ErrNeedRelease", err)
+    }
+
+    if err := withConfig(t, c, "release-time = \"10:00\"\n[release-times]\n1505 = \"09:00\"\n"); err != nil {
+        t.Fatal(err)
+    }
+    tests := map[int64]time.Time{
+        // the venue's own
+        1505: time.Date(2023, 9, 8, 9, 0, 0, 0, time.UTC),
+        // every other venue's
+        42: time.Date(2023, 9, 8, 10, 0, 0, 0, time.UTC),
+    }
+    for venueID, want := range tests {
+        line := strings.Replace(line, "1505", strconv.FormatInt(venueID, 10), 1)
+        if _, err := run(t, c, line, ""); err != nil {
+            t.Fatalf("%s: %v", line, err)
+        }
+        snaps := c.AppCtx.ListOperations(app.OperationFilter{VenueID: venueID})
+        if len(snaps) != 1 || snaps[0].RequestTime == nil || !snaps[0].RequestTime.Equal(want) {
+            t.Errorf("venue %d: operations %+v, want one requested at %v", venueID, snaps, want)
+        }
+    }
+    // -relT still wins
+    if _, err := run(t, c, strings.Replace(line, "1505", "7", 1) + " -relT 11:30", ""); err != nil {
+        t.Fatal(err)
+    }
+    snaps := c.AppCtx.ListOperations(app.OperationFilter{VenueID: 7})
+    if len(snaps) != 1 || !snaps[0].RequestTime.Equal(time.Date(2023, 9, 8, 11, 30, 0, 0, time.UTC)) {
+        t.Errorf("-relT: operations %+v, want one requested at 11:30", snaps)
+    }
+
+    report, err := run(t, c, "config show", "")
+    if err != nil || !strings.Contains(report, "release-time: 10:00") || !strings.Contains(report, "release-times.1505: 09:00") {
+        t.Errorf("config show = %q, %v, want the release times", report, err)
+    }
+}
+
+func TestReleaseSettingErrors(t *testing.T) {
+    tests := map[string]error{
+        "[release-times]\ncarbone = \"09:00\"\n": ErrReleaseVenue,
+        "[release-times]\n1505 = \"9am\"\n": ErrInvReleaseTime,
+        "release-time = [\"09:00\", \"10:00\"]\n": ErrInvReleaseTime,
+    }
+    for config, want := range tests {
+        var out bytes.Buffer
+        if err := withConfig(t, newTestCLI(&out), config); !errors.Is(err, want) {
+            t.Errorf("%q: err = %v, want %v", config, err, want)
+        }
+    }
+}
+
+/*
+Name: tzAPI
+Type: Test Struct
+Purpose: An idleAPI placing every venue in loc, or
+failing the lookup with err
+*/
+type tzAPI struct {
+    idleAPI
+    loc     *time.Location
+    err     error
+}
+
+func (a tzAPI) Timezone(params api.TimezoneParam) (*api.TimezoneResponse, error) {
+    if a.err != nil {
+        return nil, a.err
+    }
+    return &api.TimezoneResponse{Location: a.loc}, nil
+}
+
+func TestVenueTimezone(t *testing.T) {
+    ny, err := time.LoadLocation("America/New_York")
+    if err != nil {
+        t.Skipf("no tz database: %v", err)
+    }
+    chicago, err := time.LoadLocation("America/Chicago")
+    if err != nil {
+        t.Skipf("no tz database: %v", err)
+    }
+    t.Setenv("TEST_WORK_EMAIL", "me@example.com")
+    t.Setenv("TEST_WORK_PASSWORD", "secret")
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    c.AppCtx.API = tzAPI{loc: ny}
+
+    // every day and time is read in the venue timezone
+    if _, err := run(t, c, "rats -a work -v 1 -resD 2023:09:08 -resT 19:00 -ps 2 -reqD 2023:09:02:09:00", ""); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := run(t, c, "rais -a work -v 2 -resD 2023:09:08 -resT 19:00 -ps 2 -i 00:01", ""); err != nil {
+        t.Fatal(err)
+    }
+    // unless --tz says otherwise
+    if _, err := run(t, c, "rats -a work -v 3 -resD 2023:09:08 -resT 19:00 -ps 2 -reqD 2023:09:02:09:00 --tz America/Chicago", ""); err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        venueID     int64
+        reservation time.Time
+        request     time.Time
+    }{
+        {1, time.Date(2023, 9, 8, 19, 0, 0, 0, ny), time.Date(2023, 9, 2, 9, 0, 0, 0, ny)},
+        {2, time.Date(2023, 9, 8, 19, 0, 0, 0, ny), time.Time{}},
+        {3, time.Date(2023, 9, 8, 19, 0, 0, 0, chicago), time.Date(2023, 9, 2, 9, 0, 0, 0, chicago)},
+    }
+    for _, test := range tests {
+        snaps := c.AppCtx.ListOperations(app.OperationFilter{VenueID: test.venueID})
+        if len(snaps) != 1 {
+            t.Fatalf("venue %d: %d operations, want 1", test.venueID, len(snaps))
+        }
+        var reservation time.Time
+        switch params := snaps[0].Params.(type) {
+            case app.ReserveAtTimeParam:
+                reservation = params.ReservationTimes[0]
+            case app.ReserveAtIntervalParam:
+                reservation = params.ReservationTimes[0]
+        }
+        if !reservation.Equal(test.reservation) {
+            t.Errorf("venue %d: reservation at %v, want %v", test.venueID, reservation, test.reservation)
+        }
+        if !test.request.IsZero() && (snaps[0].RequestTime == nil || !snaps[0].RequestTime.Equal(test.request)) {
+            t.Errorf("venue %d: request at %v, want %v", test.venueID, snaps[0].RequestTime, test.request)
+        }
+    }
+
+    // op list shows the venue time and, when it differs, the local one
+    list, err := run(t, c, "op list -v 1", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    request := time.Date(2023, 9, 2, 9, 0, 0, 0, ny)
+    layout := "2006-01-02 15:04 MST"
+    want := "Request Time: " + request.Format(layout)
+    if local := request.In(time.Local).Format(layout); local != request.Format(layout) {
+        want += " (" + local + " local)"
+    }
+    if !strings.Contains(list, want) {
+        t.Errorf("op list = %q, want %q", list, want)
+    }
+}
+
+func TestVenueTimezoneLookupFails(t *testing.T) {
+    t.Setenv("TEST_WORK_EMAIL", "me@example.com")
+    t.Setenv("TEST_WORK_PASSWORD", "secret")
+    var out bytes.Buffer
+    c := newTestCLI(&out)
+    c.AppCtx.API = tzAPI{err: api.ErrNetwork}
+    line := "rats -a work -v 1 -resD 2023:09:08 -resT 19:00 -ps 2 -reqD 2023:09:02:09:00"
+    _, err := run(t, c, line, "")
+    if !errors.Is(err, ErrVenueTZ) || !strings.Contains(err.Error(), api.ErrNetwork.Error()) {
+        t.Errorf("failed lookup: err = %v, want ErrVenueTZ with the cause", err)
+    }
+    // --tz doesn't need the lookup
+    if _, err := run(t, c, line + " --tz America/New_York", ""); err != nil {
+        t.Errorf("with --tz: err = %v", err)
+    }
+    // a venue without a published timezone is read locally
+    c.AppCtx.API = tzAPI{err: api.ErrNoTimezone}
+    if _, err := run(t, c, strings.Replace(line, "-v 1", "-v 2", 1), ""); err != nil {
+        t.Errorf("unpublished timezone: err = %v", err)
+    }
+}
+
+/*
+Name: failAPI
+Type: Test Struct
+Purpose: An idleAPI that never finds a table
+*/
+type failAPI struct {
+    idleAPI
+}
+
+func (failAPI) Reserve(params api.ReserveParam) (*api.ReserveResponse, error) {
+    return nil, api.ErrNoTable
+}
+
+/*
+Name: openAPI
+Type: Test Struct
+Purpose: An idleAPI with a table open at the
+time runRats asks for, read locally
+*/
+type openAPI struct {
+    idleAPI
+}
+
+func (openAPI) Availability(params api.AvailabilityParam) (*api.AvailabilityResponse, error) {
+    slot := api.Slot{Time: time.Date(2023, 9, 8, 19, 0, 0, 0, time.Local)}
+    return &api.AvailabilityResponse{Slots: []api.Slot{slot}}, nil
+}
+
+/*
+Name: newRunCLI
+Type: Test Func
+Purpose: A test CLI for Run, with no daemon to dial
+and no config file, given args and reading in as
+piped commands
+*/
+func newRunCLI(t *testing.T, out *bytes.Buffer, in string, args ...string) (*ResolvedCLI) {
+    t.Setenv("TEST_WORK_EMAIL", "me@example.com")
+    t.Setenv("TEST_WORK_PASSWORD", "secret")
+    c := newTestCLI(out)
+    c.In = strings.NewReader(in)
+    c.Args = args
+    dir := socketDir(t)
+    c.Socket = filepath.Join(dir, "daemon.sock")
+    c.Config = filepath.Join(dir, "config")
+    return c
+}
+
+/*
+Name: runDriven
+Type: Test Func
+Purpose: Run c, advancing its fake clock to each
+timer set until Run returns
+*/
+func runDriven(t *testing.T, c *ResolvedCLI) (error) {
+    t.Helper()
+    done := make(chan error, 1)
+    go func() { done <- c.Run() }()
+    fc := c.AppCtx.Clock.(*clock.Fake)
+    deadline := time.Now().Add(5 * time.Second)
+    for time.Now().Before(deadline) {
+        select {
+            case err := <-done:
+                return err
+            default:
+        }
+        if next, ok := fc.Next(); ok {
+            fc.Advance(next.Sub(fc.Now()))
+        }
+        time.Sleep(time.Millisecond)
+    }
+    t.Fatal("Run didn't return")
+    return nil
+}
+
+// A rats line booking through the TEST_WORK account just after testStart
+const runRats = "rats -a work -v 1 -resD 2023:09:08 -resT 19:00 -ps 2 -reqD 2023:09:01:00:05"
+
+func TestRunArgs(t *testing.T) {
+    var out bytes.Buffer
+    c := newRunCLI(t, &out, "", "op", "list", "-o", "json")
+    if err := c.Run(); err != nil {
+        t.Fatalf("Run = %v, output %q", err, out.String())
+    }
+    if strings.TrimSpace(out.String()) != "[]" {
+        t.Errorf("one shot printed %q, want only []", out.String())
+    }
+}
+
+func TestRunScriptWaitsForNotify(t *testing.T) {
+    var out bytes.Buffer
+    dir := t.TempDir()
+    notified := filepath.Join(dir, "notified")
+    script := filepath.Join(dir, "script")
+    lines := "# book and say so\n\nnotify -f " + notified + "\n" + runRats + "\n"
+    if err := os.WriteFile(script, []byte(lines), 0600); err != nil {
+        t.Fatal(err)
+    }
+    c := newRunCLI(t, &out, "", "--script", script)
+    if err := runDriven(t, c); err != nil {
+        t.Fatalf("Run = %v, output %q", err, out.String())
+    }
+    succeeded := app.OperationFilter{Statuses: []app.OperationStatus{app.SuccessStatusType}}
+    if n := len(c.AppCtx.ListOperations(succeeded)); n != 1 {
+        t.Errorf("Run returned with %d operations booked, want 1", n)
+    }
+    // the notification was sent before Run returned
+    if data, err := os.ReadFile(notified); err != nil || len(data) == 0 {
+        t.Errorf("notification file = %q, %v, want the booking", data, err)
+    }
+}
+
+func TestRunPiped(t *testing.T) {
+    var out bytes.Buffer
+    // EOF ends the run
+    c := newRunCLI(t, &out, "op list -o json\nop list -o json\n")
+    if err := c.Run(); err != nil {
+        t.Fatalf("Run = %v, output %q", err, out.String())
+    }
+    if n := strings.Count(out.String(), "[]"); n != 2 || strings.Contains(out.String(), replPrompt) {
+        t.Errorf("piped commands printed %q, want two results and no prompt", out.String())
+    }
+    // and so does exit, leaving the rest unread
+    out.Reset()
+    c = newRunCLI(t, &out, "op list -o json\nexit\nop list -o json\n")
+    if err := c.Run(); err != nil {
+        t.Fatalf("Run = %v, output %q", err, out.String())
+    }
+    if n := strings.Count(out.String(), "[]"); n != 1 {
+        t.Errorf("commands after exit ran: %q", out.String())
+    }
+}
+
+func TestRunFailed(t *testing.T) {
+    var out bytes.Buffer
+    c := newRunCLI(t, &out, "", "nosuch")
+    if err := c.Run(); err != ErrFailed {
+        t.Errorf("unknown command: Run = %v, want ErrFailed", err)
+    }
+    // a failed line doesn't stop the ones after it
+    out.Reset()
+    c = newRunCLI(t, &out, "op show -i 9\nop list -o json\n")
+    if err := c.Run(); err != ErrFailed {
+        t.Errorf("failed line: Run = %v, want ErrFailed", err)
+    }
+    if !strings.Contains(out.String(), "[]") {
+        t.Errorf("line after the failure didn't run: %q", out.String())
+    }
+    // an operation failing after it was scheduled fails the run too
+    out.Reset()
+    c = newRunCLI(t, &out, "", strings.Fields(runRats)...)
+    c.AppCtx.API = failAPI{}
+    if err := runDriven(t, c); err != ErrFailed {
+        t.Errorf("failed operation: Run = %v, want ErrFailed, output %q", err, out.String())
+    }
+}
+
+func TestRunRefusesInBatch(t *testing.T) {
+    var out bytes.Buffer
+    // tail would read the next line as its stop
+    c := newRunCLI(t, &out, "tail\nop list -o json\n")
+    if err := c.Run(); err != ErrFailed {
+        t.Errorf("tail: Run = %v, want ErrFailed", err)
+    }
+    if !strings.Contains(out.String(), ErrBatchTail.Error()) || !strings.Contains(out.String(), "[]") {
+        t.Errorf("tail in batch printed %q, want its error and the next result", out.String())
+    }
+    for _, line := range []string{
+        "watch -a work -v 1 -resD 2023:09:08 -resT 19:00 -ps 2 -i 30s -k",
+        "snipe -a work -v 1 -resD 2023:09:08 -resT 19:00 -ps 2",
+        "recur -a work -v 1 -ps 2 -wd thu -resT 19:00 -ahead 14 -relT 09:00",
+    } {
+        out.Reset()
+        c := newRunCLI(t, &out, "", strings.Fields(line)...)
+        if err := c.Run(); err != ErrFailed || !strings.Contains(out.String(), ErrBatchForever.Error()) {
+            t.Errorf("%s: Run = %v, output %q, want ErrBatchForever", line, err, out.String())
+        }
+        if n := len(c.AppCtx.ListOperations(app.OperationFilter{})); n != 0 {
+            t.Errorf("%s: scheduled %d operations", line, n)
+        }
+    }
+    // a watch that stops at the first slots is fine
+    out.Reset()
+    c = newRunCLI(t, &out, "", "watch", "-a", "work", "-v", "1", "-resD", "2023:09:08", "-resT", "19:00", "-ps", "2", "-i", "30s")
+    c.AppCtx.API = openAPI{}
+    if err := runDriven(t, c); err != nil {
+        t.Errorf("watch: Run = %v, output %q", err, out.String())
+    }
+}
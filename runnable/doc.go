@@ -7,10 +7,10 @@ Created On: Aug 12, 2023
 General Purpose: 
 
     The runnable pkg provides a common namespace and interface
-    to systems that wrap around the core app. Currently, the only
-    working program made with the core app is in the runnable/cli
-    directory, although the intent is to expand this to include
-    some servers. 
+    to systems that wrap around the core app. The main program made
+    with the core app is in the runnable/cli directory, and the
+    runnable/server directory holds an HTTP server, which the CLI
+    can start next to itself. 
 
     We hope this abstraction and separation of concerns will achieve
     a few important things. First, since we strive to write all of 
@@ -0,0 +1,48 @@
+/*
+**********************************************************************
+
+General Purpose: 
+
+    The server pkg exposes the core app over HTTP, using nothing
+    but net/http. It shares its AppCtx with whatever else runs on 
+    it, so the CLI can start a server next to its own prompt.
+
+**********************************************************************
+
+Routes:
+
+    1. GET /events
+
+        - Description: Streams the operation lifecycle events of
+          the AppCtx as Server-Sent Events, from the moment of 
+          connecting. Each event is sent as
+
+              id: <seq>
+              event: <type>
+              data: <event as JSON>
+
+          so browsers can listen per type with EventSource. The
+          'operation' and 'type' query fields take comma separated
+          ids and event types to filter on, e.g.
+          /events?operation=3&type=booked,failed. A comment line is
+          sent every 15 seconds to keep idle connections open.
+          Events are dropped for a client that falls too far 
+          behind, which an unfiltered client can tell by a gap 
+          in the ids.
+
+    2. GET /operations
+
+        - Description: Lists operation snapshots as a JSON array.
+          The 'status' query field takes comma separated statuses
+          (in-progress, succeeded, failed, cancelled), 'venue' a 
+          venue id and 'account' an account name or login email,
+          e.g. /operations?status=in-progress&venue=1505.
+
+    3. GET /operations/{id}
+
+        - Description: Shows one operation snapshot as JSON, 404
+          if there is no operation with the id.
+
+**********************************************************************
+*/
+package server
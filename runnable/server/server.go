@@ -0,0 +1,242 @@
+package server
+
+import (
+    "github.com/21Bruce/resolved-server/app"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+var (
+    // Error if the response writer can't stream
+    ErrNoStream = errors.New("response does not support streaming")
+)
+
+// Comment line sent to keep idle event streams open through proxies
+const heartbeatInterval = 15 * time.Second
+
+/*
+Name: Server
+Type: External Server Struct
+Purpose: Expose the AppCtx over HTTP. The AppCtx 
+is shared, so a CLI can run next to the server
+*/
+type Server struct {
+    AppCtx      *app.AppCtx
+    Addr        string
+}
+
+/*
+Name: Handler 
+Type: External Func
+Purpose: The routes of the server, for mounting
+on a server of one's own
+*/
+func (s *Server) Handler() (http.Handler) {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/events", s.handleEvents)
+    mux.HandleFunc("/operations", s.handleOperations)
+    mux.HandleFunc("/operations/", s.handleOperation)
+    return mux
+}
+
+/*
+Name: Run 
+Type: External Func
+Purpose: Listen on Addr and serve until the
+listener fails
+*/
+func (s *Server) Run() (error) {
+    listener, err := net.Listen("tcp", s.Addr)
+    if err != nil {
+        return err
+    }
+    return s.Serve(listener)
+}
+
+/*
+Name: Serve 
+Type: External Func
+Purpose: Serve on a listener that's already open
+*/
+func (s *Server) Serve(listener net.Listener) (error) {
+    return http.Serve(listener, s.Handler())
+}
+
+/*
+Name: handleEvents 
+Type: Internal Func
+Purpose: Stream operation events as Server-Sent Events.
+The operation and type query fields take comma separated 
+ids and event types to filter on
+*/
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        http.Error(w, ErrNoStream.Error(), http.StatusInternalServerError)
+        return
+    }
+    ids, err := parseIDs(r.URL.Query().Get("operation"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    types := make(map[app.EventType]bool)
+    for _, eventType := range splitList(r.URL.Query().Get("type")) {
+        types[app.EventType(eventType)] = true
+    }
+
+    events, unsubscribe := s.AppCtx.Subscribe(0)
+    defer unsubscribe()
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.WriteHeader(http.StatusOK)
+    fmt.Fprint(w, ": connected\n\n")
+    flusher.Flush()
+
+    heartbeat := time.NewTicker(heartbeatInterval)
+    defer heartbeat.Stop()
+    for {
+        select {
+        case event := <-events:
+            if len(ids) != 0 && !ids[event.OperationID] {
+                continue
+            }
+            if len(types) != 0 && !types[event.Type] {
+                continue
+            }
+            data, err := json.Marshal(event)
+            if err != nil {
+                return
+            }
+            _, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+            if err != nil {
+                return
+            }
+            flusher.Flush()
+        case <-heartbeat.C:
+            if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+                return
+            }
+            flusher.Flush()
+        case <-r.Context().Done():
+            return
+        }
+    }
+}
+
+/*
+Name: handleOperations 
+Type: Internal Func
+Purpose: List operations as JSON. The status query field
+takes comma separated statuses, venue and account take
+one venue id and one account to filter on
+*/
+func (s *Server) handleOperations(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    query := r.URL.Query()
+    filter := app.OperationFilter{Account: query.Get("account")}
+    for _, statusStr := range splitList(query.Get("status")) {
+        status, err := app.ParseOperationStatus(statusStr)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        filter.Statuses = append(filter.Statuses, status)
+    }
+    if venueStr := query.Get("venue"); venueStr != "" {
+        venueID, err := strconv.ParseInt(venueStr, 10, 64)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        filter.VenueID = venueID
+    }
+    writeJSON(w, s.AppCtx.ListOperations(filter))
+}
+
+/*
+Name: handleOperation 
+Type: Internal Func
+Purpose: Show the operation whose id follows
+/operations/ as JSON
+*/
+func (s *Server) handleOperation(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/operations/"), 10, 64)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    snap, err := s.AppCtx.GetOperation(id)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    writeJSON(w, snap)
+}
+
+/*
+Name: writeJSON 
+Type: Internal Func
+Purpose: Write v as the JSON body of a response
+*/
+func writeJSON(w http.ResponseWriter, v interface{}) {
+    data, err := json.Marshal(v)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    w.Write(data)
+}
+
+/*
+Name: splitList 
+Type: Internal Func
+Purpose: Split a comma separated query field,
+dropping empty entries
+*/
+func splitList(field string) ([]string) {
+    list := make([]string, 0)
+    for _, entry := range strings.Split(field, ",") {
+        if entry = strings.TrimSpace(entry); entry != "" {
+            list = append(list, entry)
+        }
+    }
+    return list
+}
+
+/*
+Name: parseIDs 
+Type: Internal Func
+Purpose: Parse a comma separated list of operation ids
+*/
+func parseIDs(field string) (map[int64]bool, error) {
+    ids := make(map[int64]bool)
+    for _, entry := range splitList(field) {
+        id, err := strconv.ParseInt(entry, 10, 64)
+        if err != nil {
+            return nil, err
+        }
+        ids[id] = true
+    }
+    return ids, nil
+}
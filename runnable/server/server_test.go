@@ -0,0 +1,295 @@
+package server
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "github.com/21Bruce/resolved-server/api"
+    "github.com/21Bruce/resolved-server/app"
+    "github.com/21Bruce/resolved-server/clock"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+const testTimeout = 5 * time.Second
+
+var testStart = time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+
+/*
+Name: idleAPI
+Type: Test Struct
+Purpose: An api.API that logs in and books anything,
+operations never get that far on a clock that stands still
+*/
+type idleAPI struct{}
+
+func (idleAPI) Login(params api.LoginParam) (*api.LoginResponse, error) {
+    return &api.LoginResponse{Email: params.Email, AuthToken: "token"}, nil
+}
+
+func (idleAPI) Search(params api.SearchParam) (*api.SearchResponse, error) {
+    return &api.SearchResponse{}, nil
+}
+
+func (idleAPI) Reserve(params api.ReserveParam) (*api.ReserveResponse, error) {
+    return &api.ReserveResponse{ReservationTime: params.ReservationTimes[0]}, nil
+}
+
+func (idleAPI) AuthMinExpire() (time.Duration) {
+    return time.Hour
+}
+
+func (idleAPI) BookingPolicy(params api.BookingPolicyParam) (*api.BookingPolicyResponse, error) {
+    return nil, api.ErrNoPolicy
+}
+
+func (idleAPI) Timezone(params api.TimezoneParam) (*api.TimezoneResponse, error) {
+    return nil, api.ErrNoTimezone
+}
+
+func (idleAPI) Availability(params api.AvailabilityParam) (*api.AvailabilityResponse, error) {
+    return &api.AvailabilityResponse{}, nil
+}
+
+/*
+Name: newTestServer
+Type: Test Func
+Purpose: A server over an app on a fake clock with one
+operation waiting to fire and one that failed, returning
+their ids
+*/
+func newTestServer(t *testing.T) (*Server, int64, int64) {
+    s := &Server{AppCtx: &app.AppCtx{API: idleAPI{}, Clock: clock.NewFake(testStart)}}
+    params := app.ReserveAtTimeParam{
+        Login: app.LoginParam{Email: "me@example.com", Password: "secret"},
+        VenueID: 1505,
+        ReservationTimes: []time.Time{testStart.Add(7 * 24 * time.Hour)},
+        PartySize: 2,
+        RequestTime: testStart.Add(3 * time.Hour),
+    }
+    waiting, err := s.AppCtx.ScheduleReserveAtTimeOperation(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    params.VenueID = 42
+    params.RequestTime = testStart.Add(-time.Minute)
+    failed, err := s.AppCtx.ScheduleReserveAtTimeOperation(params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    deadline := time.Now().Add(testTimeout)
+    for {
+        if status, _ := s.AppCtx.OperationStatus(failed); status == app.FailStatusType {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("timed out waiting for the past operation to fail")
+        }
+        time.Sleep(time.Millisecond)
+    }
+    return s, waiting, failed
+}
+
+/*
+Name: get
+Type: Test Func
+Purpose: Serve a GET of path, returning the status
+code and the body decoded as JSON when it is JSON
+*/
+func get(t *testing.T, s *Server, path string, v interface{}) (int, string) {
+    t.Helper()
+    recorder := httptest.NewRecorder()
+    s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+    body := recorder.Body.String()
+    if recorder.Code == http.StatusOK {
+        if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+            t.Errorf("%s: Content-Type %q, want application/json", path, ct)
+        }
+        if err := json.Unmarshal([]byte(body), v); err != nil {
+            t.Fatalf("%s: %v in %s", path, err, body)
+        }
+    }
+    return recorder.Code, body
+}
+
+/*
+Name: keys
+Type: Test Func
+Purpose: The sorted keys of a JSON object
+*/
+func keys(object map[string]interface{}) (string) {
+    names := make([]string, 0, len(object))
+    for name := range object {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return strings.Join(names, ",")
+}
+
+func TestOperations(t *testing.T) {
+    s, waiting, failed := newTestServer(t)
+    var list []map[string]interface{}
+    if code, body := get(t, s, "/operations", &list); code != http.StatusOK {
+        t.Fatalf("status %d: %s", code, body)
+    }
+    if len(list) != 2 {
+        t.Fatalf("got %d operations, want 2", len(list))
+    }
+    first := list[0]
+    if want := "accounts,attempts,created,group,id,params,request_time,status,type,venue_id"; keys(first) != want {
+        t.Errorf("operation keys %s, want %s", keys(first), want)
+    }
+    if first["id"] != float64(waiting) || first["group"] != float64(app.NoGroup) || first["status"] != "in-progress" ||
+        first["type"] != "reserve-at-time" || first["venue_id"] != float64(1505) || first["request_time"] != "2023-09-01T03:00:00Z" {
+        t.Errorf("waiting operation = %v", first)
+    }
+    if accounts := first["accounts"].([]interface{}); len(accounts) != 1 || accounts[0] != "me@example.com" {
+        t.Errorf("accounts = %v, want the login email", accounts)
+    }
+    params := first["params"].(map[string]interface{})
+    login := params["Login"].(map[string]interface{})
+    if login["Email"] != "me@example.com" || login["Password"] != "" {
+        t.Errorf("params login = %v, want the email without the password", login)
+    }
+    second := list[1]
+    if second["id"] != float64(failed) || second["status"] != "failed" || second["error"] != app.ErrTimeFut.Error() {
+        t.Errorf("failed operation = %v", second)
+    }
+
+    // filters
+    var filtered []map[string]interface{}
+    get(t, s, "/operations?status=failed,cancelled", &filtered)
+    if len(filtered) != 1 || filtered[0]["id"] != float64(failed) {
+        t.Errorf("status filter = %v, want the failed operation", filtered)
+    }
+    get(t, s, "/operations?venue=1505", &filtered)
+    if len(filtered) != 1 || filtered[0]["id"] != float64(waiting) {
+        t.Errorf("venue filter = %v, want the waiting operation", filtered)
+    }
+    // no match is an empty list, not null
+    if _, body := get(t, s, "/operations?venue=7", &filtered); strings.TrimSpace(body) != "[]" {
+        t.Errorf("empty list = %s, want []", body)
+    }
+    if code, _ := get(t, s, "/operations?status=asleep", nil); code != http.StatusBadRequest {
+        t.Errorf("bad status: code %d, want 400", code)
+    }
+    if code, _ := get(t, s, "/operations?venue=x", nil); code != http.StatusBadRequest {
+        t.Errorf("bad venue: code %d, want 400", code)
+    }
+}
+
+func TestOperation(t *testing.T) {
+    s, waiting, _ := newTestServer(t)
+    var one map[string]interface{}
+    if code, body := get(t, s, "/operations/" + strconv.FormatInt(waiting, 10), &one); code != http.StatusOK {
+        t.Fatalf("status %d: %s", code, body)
+    }
+    var list []map[string]interface{}
+    get(t, s, "/operations", &list)
+    if !reflect.DeepEqual(one, list[0]) {
+        t.Errorf("operation %v, want the one listed %v", one, list[0])
+    }
+
+    code, body := get(t, s, "/operations/99", nil)
+    if code != http.StatusNotFound || strings.TrimSpace(body) != app.ErrIdOp.Error() {
+        t.Errorf("unknown id: %d %q, want 404 %q", code, body, app.ErrIdOp.Error())
+    }
+    if code, _ := get(t, s, "/operations/abc", nil); code != http.StatusBadRequest {
+        t.Errorf("bad id: code %d, want 400", code)
+    }
+    recorder := httptest.NewRecorder()
+    s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/operations/0", nil))
+    if recorder.Code != http.StatusMethodNotAllowed {
+        t.Errorf("POST: code %d, want 405", recorder.Code)
+    }
+}
+
+func TestEvents(t *testing.T) {
+    s, _, _ := newTestServer(t)
+    // know when the handler returns
+    done := make(chan bool)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        s.Handler().ServeHTTP(w, r)
+        if r.URL.Path == "/events" {
+            close(done)
+        }
+    }))
+    defer server.Close()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL + "/events?type=scheduled", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    response, err := http.DefaultClient.Do(request)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer response.Body.Close()
+    if ct := response.Header.Get("Content-Type"); ct != "text/event-stream" {
+        t.Errorf("Content-Type %q, want text/event-stream", ct)
+    }
+    lines := make(chan string)
+    go func() {
+        scanner := bufio.NewScanner(response.Body)
+        for scanner.Scan() {
+            lines <- scanner.Text()
+        }
+        close(lines)
+    }()
+    next := func() (string) {
+        select {
+        case line := <-lines:
+            return line
+        case <-time.After(testTimeout):
+            t.Fatal("timed out waiting for the event stream")
+        }
+        return ""
+    }
+    // subscribed once the greeting is out
+    if line := next(); line != ": connected" {
+        t.Fatalf("first line %q, want the greeting", line)
+    }
+    next()
+
+    id, err := s.AppCtx.ScheduleReserveAtTimeOperation(app.ReserveAtTimeParam{
+        Login: app.LoginParam{Email: "me@example.com", Password: "secret"},
+        VenueID: 1,
+        ReservationTimes: []time.Time{testStart.Add(7 * 24 * time.Hour)},
+        RequestTime: testStart.Add(3 * time.Hour),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if line := next(); !strings.HasPrefix(line, "id: ") {
+        t.Errorf("id line %q", line)
+    }
+    if line := next(); line != "event: scheduled" {
+        t.Errorf("event line %q, want event: scheduled", line)
+    }
+    var event app.Event
+    if err := json.Unmarshal([]byte(strings.TrimPrefix(next(), "data: ")), &event); err != nil {
+        t.Fatal(err)
+    }
+    if event.Type != app.ScheduledEvent || event.OperationID != id {
+        t.Errorf("event = %+v, want operation %d scheduled", event, id)
+    }
+    if line := next(); line != "" {
+        t.Errorf("event ends with %q, want a blank line", line)
+    }
+
+    // hanging up ends the handler
+    cancel()
+    select {
+    case <-done:
+    case <-time.After(testTimeout):
+        t.Fatal("handler still running after the client left")
+    }
+}
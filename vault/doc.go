@@ -0,0 +1,86 @@
+/*
+**********************************************************************
+
+General Purpose: 
+
+    The vault pkg keeps login credentials for external reservation
+    services out of the app layer. Instead of holding a plaintext
+    password for the lifetime of the program and copying it into
+    every operation, the app layer holds an account name and asks
+    a Store for the credentials right before it needs to log in.
+
+**********************************************************************
+
+Store:
+
+    The Store interface specifies 4 methods:
+
+        Get(name string) (*Credential, error)
+        Put(cred Credential) (error)
+        Delete(name string) (error)
+        List() ([]string, error)
+
+    A Credential is just an account name attached to an 
+    api.LoginParam, so any field an external service needs to log
+    in can be saved.
+
+**********************************************************************
+
+FileStore:
+
+    The FileStore keeps every credential in one file, by default 
+    in the user's config directory(see DefaultPath). The file is a
+    small JSON envelope holding a random salt, a nonce and the 
+    credentials encrypted with AES-256-GCM. The key is derived from
+    a passphrase with PBKDF2-HMAC-SHA256, which we implement 
+    ourselves to stay free of external pkgs.
+
+    The passphrase is obtained through the Passphrase callback the
+    first time the vault is touched, so front-ends can decide how
+    to ask for it(a no-echo prompt, an environment variable, etc).
+    Only the derived key is kept in memory, and Lock forgets it.
+    Unlock asks for the passphrase up front instead, for programs
+    like a daemon that won't have anyone to ask later, and a Chain
+    unlocks every store in it that is an Unlocker.
+    A new vault asks for its passphrase twice, the second time 
+    through the Confirm callback when set, and isn't written if
+    the two differ(ErrPassMismatch), so a typo can't lock the 
+    saved credentials away for good.
+    A wrong passphrase yields ErrBadPass and the callback is asked
+    again on the next access. A file that isn't a vault at all, 
+    or is truncated, yields ErrCorrupt, and one written by another
+    version of the format ErrVersion, so neither is mistaken for a 
+    wrong passphrase. A file that is a vault but won't decrypt 
+    can't be told apart from a wrong passphrase, so that stays 
+    ErrBadPass.
+
+**********************************************************************
+
+EnvStore:
+
+    The EnvStore is a read only store for machines where no one is
+    around to type a passphrase. An account named "work" with the
+    prefix "RESOLVED" is read from the RESOLVED_WORK_EMAIL and 
+    RESOLVED_WORK_PASSWORD environment variables.
+
+**********************************************************************
+
+MemStore:
+
+    The MemStore keeps credentials in memory for the life of the
+    process. The app layer holds the logins given to it without 
+    saving them here, so operations still only carry an account 
+    name and the password sits in one place.
+
+**********************************************************************
+
+Chain:
+
+    A Chain is a slice of stores acting as one. Reads go to the
+    first store that knows the account and writes go to the first
+    store that is not read only, so an EnvStore in front of a 
+    FileStore lets the environment override the vault.
+
+**********************************************************************
+*/
+package vault
@@ -0,0 +1,421 @@
+package vault
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "sort"
+    "sync"
+)
+
+const (
+    // PBKDF2 work factor used when deriving a key from the passphrase
+    keyIterations = 200000
+    keyLength = 32
+    saltLength = 16
+    fileVersion = 1
+)
+
+/*
+Name: FileStore
+Type: External Vault Struct
+Purpose: Store implementation that keeps credentials in a
+file encrypted with AES-GCM under a key derived from a
+passphrase. Only the derived key is held in memory, the
+credentials are decrypted on every access.
+*/
+type FileStore struct {
+
+    // Location of the encrypted vault file
+    Path        string
+
+    // Called the first time the vault is unlocked, and 
+    // again if the passphrase turns out to be wrong
+    Passphrase  func() ([]byte, error)
+
+    // Called for the passphrase a second time when a new
+    // vault is created, so a typo can't lock it for good.
+    // Passphrase is asked twice when nil
+    Confirm     func() ([]byte, error)
+
+    mu          sync.Mutex
+    key         []byte
+    salt        []byte
+}
+
+/*
+Name: vaultFile 
+Type: Internal Struct
+Purpose: On disk layout of the vault file
+*/
+type vaultFile struct {
+    Version     int     `json:"version"`
+    Salt        []byte  `json:"salt"`
+    Nonce       []byte  `json:"nonce"`
+    Data        []byte  `json:"data"`
+}
+
+/*
+Name: DefaultPath 
+Type: External Func
+Purpose: Provide the default location of the vault file,
+inside the user's config directory
+*/
+func DefaultPath() (string) {
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        dir = "."
+    }
+    return filepath.Join(dir, "resolved", "vault")
+}
+
+/*
+Name: pbkdf2 
+Type: Internal Func
+Purpose: PBKDF2 with HMAC-SHA256 as defined in RFC 8018,
+implemented here to avoid pulling in an external pkg
+*/
+func pbkdf2(password []byte, salt []byte, iter int, keyLen int) ([]byte) {
+    prf := hmac.New(sha256.New, password)
+    hashLen := prf.Size()
+    numBlocks := (keyLen + hashLen - 1) / hashLen
+    out := make([]byte, 0, numBlocks * hashLen)
+    buf := make([]byte, 4)
+    for block := 1; block <= numBlocks; block++ {
+        prf.Reset()
+        prf.Write(salt)
+        binary.BigEndian.PutUint32(buf, uint32(block))
+        prf.Write(buf)
+        u := prf.Sum(nil)
+        t := make([]byte, len(u))
+        copy(t, u)
+        for n := 2; n <= iter; n++ {
+            prf.Reset()
+            prf.Write(u)
+            u = prf.Sum(u[:0])
+            for i := range t {
+                t[i] ^= u[i]
+            }
+        }
+        out = append(out, t...)
+    }
+    return out[:keyLen]
+}
+
+/*
+Name: unlock 
+Type: Internal Func
+Purpose: Make sure a key for the given salt is derived,
+asking for the passphrase if needed, twice when it is for
+a new vault. Must hold f.mu
+*/
+func (f *FileStore) unlock(salt []byte, create bool) (error) {
+    if f.key != nil && string(f.salt) == string(salt) {
+        return nil
+    }
+    if f.Passphrase == nil {
+        return ErrNoPass
+    }
+    pass, err := f.Passphrase()
+    if err != nil {
+        return err
+    }
+    if len(pass) == 0 {
+        return ErrNoPass
+    }
+    if create {
+        err = f.confirm(pass)
+        if err != nil {
+            return err
+        }
+    }
+    f.key = pbkdf2(pass, salt, keyIterations, keyLength)
+    f.salt = salt
+    for i := range pass {
+        pass[i] = 0
+    }
+    return nil
+}
+
+/*
+Name: confirm 
+Type: Internal Func
+Purpose: Ask for the passphrase of a new vault again
+and check it matches pass
+*/
+func (f *FileStore) confirm(pass []byte) (error) {
+    ask := f.Confirm
+    if ask == nil {
+        ask = f.Passphrase
+    }
+    again, err := ask()
+    if err != nil {
+        return err
+    }
+    match := hmac.Equal(pass, again)
+    for i := range again {
+        again[i] = 0
+    }
+    if !match {
+        return ErrPassMismatch
+    }
+    return nil
+}
+
+/*
+Name: load 
+Type: Internal Func
+Purpose: Read and decrypt the vault file. A missing file 
+is an empty vault. A file that can't be read as a vault is
+ErrCorrupt, one that can but won't decrypt is ErrBadPass
+since a wrong passphrase looks the same. Must hold f.mu
+*/
+func (f *FileStore) load() (map[string]Credential, error) {
+    raw, err := os.ReadFile(f.Path)
+    if errors.Is(err, os.ErrNotExist) {
+        return make(map[string]Credential), nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    var file vaultFile
+    err = json.Unmarshal(raw, &file)
+    if err != nil {
+        return nil, ErrCorrupt
+    }
+    if file.Version != fileVersion {
+        return nil, ErrVersion
+    }
+    if len(file.Salt) == 0 {
+        return nil, ErrCorrupt
+    }
+    err = f.unlock(file.Salt, false)
+    if err != nil {
+        return nil, err
+    }
+    gcm, err := newGCM(f.key)
+    if err != nil {
+        return nil, err
+    }
+    // Open panics on a nonce of the wrong size
+    if len(file.Nonce) != gcm.NonceSize() {
+        return nil, ErrCorrupt
+    }
+    plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+    if err != nil {
+        // forget the key so the next access asks again
+        f.key = nil
+        return nil, ErrBadPass
+    }
+    creds := make(map[string]Credential)
+    err = json.Unmarshal(plain, &creds)
+    for i := range plain {
+        plain[i] = 0
+    }
+    // it decrypted, so the passphrase was right
+    if err != nil {
+        return nil, ErrCorrupt
+    }
+    return creds, nil
+}
+
+/*
+Name: save 
+Type: Internal Func
+Purpose: Encrypt and atomically write the vault file,
+creating it under a fresh salt if there is none. Must
+hold f.mu
+*/
+func (f *FileStore) save(creds map[string]Credential) (error) {
+    if f.salt == nil {
+        salt := make([]byte, saltLength)
+        if _, err := rand.Read(salt); err != nil {
+            return err
+        }
+        err := f.unlock(salt, true)
+        if err != nil {
+            return err
+        }
+    }
+    gcm, err := newGCM(f.key)
+    if err != nil {
+        return err
+    }
+    nonce := make([]byte, gcm.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return err
+    }
+    plain, err := json.Marshal(creds)
+    if err != nil {
+        return err
+    }
+    file := vaultFile{
+        Version: fileVersion,
+        Salt: f.salt,
+        Nonce: nonce,
+        Data: gcm.Seal(nil, nonce, plain, nil),
+    }
+    for i := range plain {
+        plain[i] = 0
+    }
+    raw, err := json.Marshal(file)
+    if err != nil {
+        return err
+    }
+    err = os.MkdirAll(filepath.Dir(f.Path), 0700)
+    if err != nil {
+        return err
+    }
+    tmp := f.Path + ".tmp"
+    err = os.WriteFile(tmp, raw, 0600)
+    if err != nil {
+        return err
+    }
+    return os.Rename(tmp, f.Path)
+}
+
+/*
+Name: newGCM 
+Type: Internal Func
+Purpose: Build the AES-GCM cipher for a key
+*/
+func newGCM(key []byte) (cipher.AEAD, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
+
+/*
+Name: Get 
+Type: Store Func
+Purpose: FileStore implementation of Store.Get
+*/
+func (f *FileStore) Get(name string) (*Credential, error) {
+    if name == "" {
+        return nil, ErrNoName
+    }
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    creds, err := f.load()
+    if err != nil {
+        return nil, err
+    }
+    cred, ok := creds[name]
+    if !ok {
+        return nil, ErrNoCred
+    }
+    return &cred, nil
+}
+
+/*
+Name: Put 
+Type: Store Func
+Purpose: FileStore implementation of Store.Put, 
+overwrites any account with the same name
+*/
+func (f *FileStore) Put(cred Credential) (error) {
+    if cred.Name == "" {
+        return ErrNoName
+    }
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    creds, err := f.load()
+    if err != nil {
+        return err
+    }
+    creds[cred.Name] = cred
+    return f.save(creds)
+}
+
+/*
+Name: Delete 
+Type: Store Func
+Purpose: FileStore implementation of Store.Delete
+*/
+func (f *FileStore) Delete(name string) (error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    creds, err := f.load()
+    if err != nil {
+        return err
+    }
+    if _, ok := creds[name]; !ok {
+        return ErrNoCred
+    }
+    delete(creds, name)
+    return f.save(creds)
+}
+
+/*
+Name: List 
+Type: Store Func
+Purpose: FileStore implementation of Store.List
+*/
+func (f *FileStore) List() ([]string, error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    creds, err := f.load()
+    if err != nil {
+        return nil, err
+    }
+    names := make([]string, 0, len(creds))
+    for name := range creds {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+/*
+Name: Unlock 
+Type: Unlocker Func
+Purpose: FileStore implementation of Unlocker.Unlock. 
+Asks for the passphrase now and checks it against the
+vault file, or when there is none yet asks for it twice
+and keeps the key a new vault will be written under
+*/
+func (f *FileStore) Unlock() (error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    _, err := os.Stat(f.Path)
+    if errors.Is(err, os.ErrNotExist) {
+        if f.key != nil {
+            return nil
+        }
+        salt := make([]byte, saltLength)
+        if _, err := rand.Read(salt); err != nil {
+            return err
+        }
+        return f.unlock(salt, true)
+    }
+    if err != nil {
+        return err
+    }
+    _, err = f.load()
+    return err
+}
+
+/*
+Name: Lock 
+Type: External Func
+Purpose: Forget the derived key, so the passphrase
+must be provided again on next access
+*/
+func (f *FileStore) Lock() {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    for i := range f.key {
+        f.key[i] = 0
+    }
+    f.key = nil
+    f.salt = nil
+}
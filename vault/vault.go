@@ -0,0 +1,347 @@
+package vault
+
+import (
+    "github.com/21Bruce/resolved-server/api"
+    "errors"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+)
+
+var (
+    ErrNoCred = errors.New("no credentials saved under given account name")
+    ErrNoName = errors.New("account name is empty")
+    ErrReadOnly = errors.New("credential store is read only")
+    ErrBadPass = errors.New("vault passphrase is wrong or vault is corrupt")
+    ErrNoPass = errors.New("no vault passphrase provided")
+    ErrPassMismatch = errors.New("vault passphrases don't match")
+    ErrCorrupt = errors.New("vault file is corrupt")
+    ErrVersion = errors.New("vault file was written by an unsupported version")
+)
+
+/*
+Name: Credential
+Type: External Vault Struct
+Purpose: A named set of login credentials 
+for an external reservation service
+*/
+type Credential struct {
+    Name    string
+    Login   api.LoginParam
+}
+
+/*
+Name: Store 
+Type: Interface 
+Purpose: Provide a minimal abstraction over places 
+login credentials can be kept, so the app layer only 
+ever needs to hold on to an account name
+*/
+type Store interface {
+    Get(name string) (*Credential, error)
+    Put(cred Credential) (error)
+    Delete(name string) (error)
+    List() ([]string, error)
+}
+
+/*
+Name: Unlocker
+Type: Interface
+Purpose: A Store that needs a passphrase, which can be
+asked for up front instead of on first access, for
+front-ends that won't be able to prompt later
+*/
+type Unlocker interface {
+    Unlock() (error)
+}
+
+/*
+Name: EnvStore 
+Type: External Vault Struct
+Purpose: Read only Store backed by environment variables.
+An account named "work" with Prefix "RESOLVED" is read from
+RESOLVED_WORK_EMAIL and RESOLVED_WORK_PASSWORD
+*/
+type EnvStore struct {
+    Prefix  string
+}
+
+/*
+Name: envName 
+Type: Internal Func
+Purpose: Convert an account name into the 
+environment variable name for a field
+*/
+func (e EnvStore) envName(name string, field string) (string) {
+    key := strings.Map(func(r rune) rune {
+        if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+            return r
+        }
+        return '_'
+    }, name)
+    return strings.ToUpper(e.Prefix + "_" + key + "_" + field)
+}
+
+/*
+Name: Get 
+Type: Store Func
+Purpose: EnvStore implementation of Store.Get
+*/
+func (e EnvStore) Get(name string) (*Credential, error) {
+    if name == "" {
+        return nil, ErrNoName
+    }
+    email := os.Getenv(e.envName(name, "EMAIL"))
+    password := os.Getenv(e.envName(name, "PASSWORD"))
+    if email == "" || password == "" {
+        return nil, ErrNoCred
+    }
+    return &Credential{
+        Name: name,
+        Login: api.LoginParam{
+            Email: email,
+            Password: password,
+        },
+    }, nil
+}
+
+/*
+Name: Put 
+Type: Store Func
+Purpose: EnvStore implementation of Store.Put, 
+environment credentials can not be written
+*/
+func (e EnvStore) Put(cred Credential) (error) {
+    return ErrReadOnly
+}
+
+/*
+Name: Delete 
+Type: Store Func
+Purpose: EnvStore implementation of Store.Delete, 
+environment credentials can not be removed
+*/
+func (e EnvStore) Delete(name string) (error) {
+    return ErrReadOnly
+}
+
+/*
+Name: List 
+Type: Store Func
+Purpose: EnvStore implementation of Store.List. Account
+names are reported lowercased since the environment 
+loses their original case
+*/
+func (e EnvStore) List() ([]string, error) {
+    prefix := strings.ToUpper(e.Prefix) + "_"
+    suffix := "_PASSWORD"
+    names := make([]string, 0)
+    for _, kv := range os.Environ() {
+        key := strings.SplitN(kv, "=", 2)[0]
+        if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+            continue
+        }
+        name := key[len(prefix):len(key) - len(suffix)]
+        if name == "" {
+            continue
+        }
+        name = strings.ToLower(name)
+        if _, err := e.Get(name); err == nil {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+/*
+Name: MemStore 
+Type: External Vault Struct
+Purpose: Store kept in memory for the life of the
+process, for logins that shouldn't be written to disk
+but shouldn't be copied around either. The zero value
+is an empty store
+*/
+type MemStore struct {
+    mu      sync.Mutex
+    creds   map[string]Credential
+}
+
+/*
+Name: Get 
+Type: Store Func
+Purpose: MemStore implementation of Store.Get
+*/
+func (m *MemStore) Get(name string) (*Credential, error) {
+    if name == "" {
+        return nil, ErrNoName
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    cred, ok := m.creds[name]
+    if !ok {
+        return nil, ErrNoCred
+    }
+    return &cred, nil
+}
+
+/*
+Name: Put 
+Type: Store Func
+Purpose: MemStore implementation of Store.Put
+*/
+func (m *MemStore) Put(cred Credential) (error) {
+    if cred.Name == "" {
+        return ErrNoName
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.creds == nil {
+        m.creds = make(map[string]Credential)
+    }
+    m.creds[cred.Name] = cred
+    return nil
+}
+
+/*
+Name: Delete 
+Type: Store Func
+Purpose: MemStore implementation of Store.Delete
+*/
+func (m *MemStore) Delete(name string) (error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if _, ok := m.creds[name]; !ok {
+        return ErrNoCred
+    }
+    delete(m.creds, name)
+    return nil
+}
+
+/*
+Name: List 
+Type: Store Func
+Purpose: MemStore implementation of Store.List
+*/
+func (m *MemStore) List() ([]string, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    names := make([]string, 0, len(m.creds))
+    for name := range m.creds {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+/*
+Name: Chain 
+Type: External Vault Type
+Purpose: Combine several stores, reading from the first
+store that has an account and writing to the first store
+that is not read only
+*/
+type Chain []Store
+
+/*
+Name: Get 
+Type: Store Func
+Purpose: Chain implementation of Store.Get
+*/
+func (c Chain) Get(name string) (*Credential, error) {
+    for _, store := range c {
+        cred, err := store.Get(name)
+        if err == ErrNoCred {
+            continue
+        }
+        return cred, err
+    }
+    return nil, ErrNoCred
+}
+
+/*
+Name: Put 
+Type: Store Func
+Purpose: Chain implementation of Store.Put
+*/
+func (c Chain) Put(cred Credential) (error) {
+    for _, store := range c {
+        err := store.Put(cred)
+        if err == ErrReadOnly {
+            continue
+        }
+        return err
+    }
+    return ErrReadOnly
+}
+
+/*
+Name: Delete 
+Type: Store Func
+Purpose: Chain implementation of Store.Delete, removes
+the account from every writable store holding it
+*/
+func (c Chain) Delete(name string) (error) {
+    found := false
+    for _, store := range c {
+        err := store.Delete(name)
+        if err == ErrReadOnly || err == ErrNoCred {
+            continue
+        }
+        if err != nil {
+            return err
+        }
+        found = true
+    }
+    if !found {
+        return ErrNoCred
+    }
+    return nil
+}
+
+/*
+Name: List 
+Type: Store Func
+Purpose: Chain implementation of Store.List, names
+found in several stores are only reported once
+*/
+func (c Chain) List() ([]string, error) {
+    seen := make(map[string]bool)
+    names := make([]string, 0)
+    for _, store := range c {
+        storeNames, err := store.List()
+        if err != nil {
+            return nil, err
+        }
+        for _, name := range storeNames {
+            if seen[name] {
+                continue
+            }
+            seen[name] = true
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+/*
+Name: Unlock 
+Type: Unlocker Func
+Purpose: Chain implementation of Unlocker.Unlock,
+unlocks every store of the chain that needs it
+*/
+func (c Chain) Unlock() (error) {
+    for _, store := range c {
+        unlocker, ok := store.(Unlocker)
+        if !ok {
+            continue
+        }
+        err := unlocker.Unlock()
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,331 @@
+package vault
+
+import (
+    "bytes"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "github.com/21Bruce/resolved-server/api"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+// The RFC 6070 inputs, with the outputs PBKDF2 gives for
+// them over HMAC-SHA256 instead of HMAC-SHA1
+func TestPBKDF2(t *testing.T) {
+    tests := []struct {
+        password    string
+        salt        string
+        iter        int
+        want        string
+    }{
+        {"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+        {"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+        {"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+        {"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1c635518c7dac47e9"},
+        {"pass\x00word", "sa\x00lt", 4096, "89b69d0516f829893c696226650a8687"},
+    }
+    for _, test := range tests {
+        got := hex.EncodeToString(pbkdf2([]byte(test.password), []byte(test.salt), test.iter, len(test.want) / 2))
+        if got != test.want {
+            t.Errorf("pbkdf2(%q, %q, %d) = %s, want %s", test.password, test.salt, test.iter, got, test.want)
+        }
+    }
+}
+
+// A FileStore asking for pass, counting how often it asks
+func testStore(path string, pass string, asked *int) (*FileStore) {
+    return &FileStore{
+        Path: path,
+        Passphrase: func() ([]byte, error) {
+            *asked++
+            return []byte(pass), nil
+        },
+    }
+}
+
+var testCred = Credential{
+    Name: "work",
+    Login: api.LoginParam{Email: "me@example.com", Password: "hunter2"},
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "resolved", "vault")
+    asked := 0
+    store := testStore(path, "correct horse", &asked)
+    if names, err := store.List(); err != nil || len(names) != 0 {
+        t.Fatalf("missing vault: List = %v, %v, want it empty", names, err)
+    }
+    if asked != 0 {
+        t.Errorf("a missing vault asked for the passphrase")
+    }
+    if err := store.Put(testCred); err != nil {
+        t.Fatal(err)
+    }
+    if err := store.Put(Credential{Name: "home", Login: api.LoginParam{Email: "me@home.com", Password: "x"}}); err != nil {
+        t.Fatal(err)
+    }
+    // once more to confirm it, since the vault was new
+    if asked != 2 {
+        t.Errorf("asked for the passphrase %d times, want twice", asked)
+    }
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(raw) == 0 || bytes.Contains(raw, []byte("hunter2")) {
+        t.Errorf("vault file holds the password in the clear")
+    }
+    if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+        t.Errorf("vault file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+    }
+
+    // a fresh store reads back what was written
+    reopened := testStore(path, "correct horse", &asked)
+    cred, err := reopened.Get("work")
+    if err != nil || !reflect.DeepEqual(*cred, testCred) {
+        t.Errorf("Get = %v, %v, want %v", cred, err, testCred)
+    }
+    if names, err := reopened.List(); err != nil || !reflect.DeepEqual(names, []string{"home", "work"}) {
+        t.Errorf("List = %v, %v", names, err)
+    }
+    if err := reopened.Delete("home"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := reopened.Get("home"); err != ErrNoCred {
+        t.Errorf("Get of a deleted account: err = %v, want ErrNoCred", err)
+    }
+    if err := reopened.Delete("home"); err != ErrNoCred {
+        t.Errorf("Delete twice: err = %v, want ErrNoCred", err)
+    }
+    if _, err := reopened.Get(""); err != ErrNoName {
+        t.Errorf("Get without a name: err = %v, want ErrNoName", err)
+    }
+
+    // locking forgets the key
+    before := asked
+    reopened.Lock()
+    if _, err := reopened.Get("work"); err != nil || asked != before + 1 {
+        t.Errorf("after Lock: err = %v, asked %d more times, want once", err, asked - before)
+    }
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "vault")
+    asked := 0
+    if err := testStore(path, "right", &asked).Put(testCred); err != nil {
+        t.Fatal(err)
+    }
+    wrong := testStore(path, "wrong", &asked)
+    if _, err := wrong.Get("work"); err != ErrBadPass {
+        t.Errorf("wrong passphrase: err = %v, want ErrBadPass", err)
+    }
+    // the bad key is forgotten, so the next access asks again
+    before := asked
+    if _, err := wrong.Get("work"); err != ErrBadPass || asked != before + 1 {
+        t.Errorf("second try: err = %v, asked %d more times, want ErrBadPass after asking once", err, asked - before)
+    }
+    // a wrong passphrase must never overwrite the vault
+    if err := wrong.Put(Credential{Name: "other"}); err != ErrBadPass {
+        t.Errorf("Put with a wrong passphrase: err = %v, want ErrBadPass", err)
+    }
+    if cred, err := testStore(path, "right", &asked).Get("work"); err != nil || cred.Login != testCred.Login {
+        t.Errorf("right passphrase afterwards = %v, %v", cred, err)
+    }
+
+    empty := testStore(path, "", &asked)
+    if _, err := empty.Get("work"); err != ErrNoPass {
+        t.Errorf("empty passphrase: err = %v, want ErrNoPass", err)
+    }
+    failing := &FileStore{Path: path, Passphrase: func() ([]byte, error) { return nil, os.ErrClosed }}
+    if _, err := failing.Get("work"); !errors.Is(err, os.ErrClosed) {
+        t.Errorf("failing prompt: err = %v, want its error", err)
+    }
+    if _, err := (&FileStore{Path: path}).Get("work"); err != ErrNoPass {
+        t.Errorf("no prompt: err = %v, want ErrNoPass", err)
+    }
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+    dir := t.TempDir()
+    asked := 0
+    good := filepath.Join(dir, "good")
+    if err := testStore(good, "right", &asked).Put(testCred); err != nil {
+        t.Fatal(err)
+    }
+    raw, err := os.ReadFile(good)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // flip a byte of the ciphertext itself, editing the
+    // base64 text could land on bits that decode the same
+    var file vaultFile
+    if err := json.Unmarshal(raw, &file); err != nil {
+        t.Fatal(err)
+    }
+    file.Data[0] ^= 0xff
+    tampered, err := json.Marshal(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    file.Data[0] ^= 0xff
+    file.Nonce = file.Nonce[:4]
+    shortNonce, err := json.Marshal(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // only a file that fails to decrypt could be a wrong passphrase
+    tests := map[string]struct{
+        data    []byte
+        want    error
+    }{
+        "garbage": {[]byte("not a vault"), ErrCorrupt},
+        "truncated": {raw[:len(raw) / 2], ErrCorrupt},
+        "no salt": {[]byte(`{"version": 1}`), ErrCorrupt},
+        "short nonce": {shortNonce, ErrCorrupt},
+        "version": {[]byte(`{"version": 2}`), ErrVersion},
+        "tampered": {tampered, ErrBadPass},
+    }
+    for name, test := range tests {
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, test.data, 0600); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := testStore(path, "right", &asked).Get("work"); err != test.want {
+            t.Errorf("%s: err = %v, want %v", name, err, test.want)
+        }
+    }
+}
+
+func TestChain(t *testing.T) {
+    asked := 0
+    file := testStore(filepath.Join(t.TempDir(), "vault"), "right", &asked)
+    chain := Chain{EnvStore{Prefix: "TEST"}, file}
+    t.Setenv("TEST_WORK_EMAIL", "env@example.com")
+    t.Setenv("TEST_WORK_PASSWORD", "fromenv")
+    t.Setenv("TEST_HALF_PASSWORD", "no email")
+
+    // writes skip the read only environment
+    if err := chain.Put(testCred); err != nil {
+        t.Fatal(err)
+    }
+    if err := chain.Put(Credential{Name: "home", Login: api.LoginParam{Email: "me@home.com", Password: "x"}}); err != nil {
+        t.Fatal(err)
+    }
+    // the environment comes first and shadows the file
+    cred, err := chain.Get("work")
+    if err != nil || cred.Login.Email != "env@example.com" || cred.Login.Password != "fromenv" {
+        t.Errorf("Get(work) = %v, %v, want the environment's", cred, err)
+    }
+    if cred, err := chain.Get("home"); err != nil || cred.Login.Email != "me@home.com" {
+        t.Errorf("Get(home) = %v, %v, want the file's", cred, err)
+    }
+    if _, err := chain.Get("half"); err != ErrNoCred {
+        t.Errorf("Get of a half set account: err = %v, want ErrNoCred", err)
+    }
+    if names, err := chain.List(); err != nil || !reflect.DeepEqual(names, []string{"home", "work"}) {
+        t.Errorf("List = %v, %v, want each name once", names, err)
+    }
+
+    // deleting removes the file's copy, the environment's stays
+    if err := chain.Delete("work"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := file.Get("work"); err != ErrNoCred {
+        t.Errorf("file still holds work: err = %v", err)
+    }
+    if cred, err := chain.Get("work"); err != nil || cred.Login.Email != "env@example.com" {
+        t.Errorf("Get(work) after Delete = %v, %v, want the environment's", cred, err)
+    }
+    if err := chain.Delete("work"); err != ErrNoCred {
+        t.Errorf("Delete of an environment account: err = %v, want ErrNoCred", err)
+    }
+    if err := (Chain{EnvStore{Prefix: "TEST"}}).Put(testCred); err != ErrReadOnly {
+        t.Errorf("Put without a writable store: err = %v, want ErrReadOnly", err)
+    }
+}
+
+func TestFileStoreUnlock(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "vault")
+    asked := 0
+    store := testStore(path, "right", &asked)
+    // asked for up front even with no vault yet, and confirmed
+    if err := (Chain{EnvStore{Prefix: "TEST"}, store}).Unlock(); err != nil || asked != 2 {
+        t.Fatalf("Unlock = %v, asked %d times, want twice", err, asked)
+    }
+    if err := store.Unlock(); err != nil || asked != 2 {
+        t.Errorf("second Unlock = %v, asked %d times, want no more", err, asked)
+    }
+    if err := store.Put(testCred); err != nil || asked != 2 {
+        t.Errorf("Put after Unlock = %v, asked %d times, want no more", err, asked)
+    }
+    if cred, err := testStore(path, "right", &asked).Get("work"); err != nil || cred.Login != testCred.Login {
+        t.Errorf("vault written after Unlock reads back as %v, %v", cred, err)
+    }
+
+    // an existing vault checks the passphrase
+    if err := testStore(path, "wrong", &asked).Unlock(); err != ErrBadPass {
+        t.Errorf("wrong passphrase: err = %v, want ErrBadPass", err)
+    }
+    if err := testStore(path, "", &asked).Unlock(); err != ErrNoPass {
+        t.Errorf("empty passphrase: err = %v, want ErrNoPass", err)
+    }
+}
+
+func TestFileStoreConfirm(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "vault")
+    asked := 0
+    store := testStore(path, "right", &asked)
+    store.Confirm = func() ([]byte, error) { return []byte("rihgt"), nil }
+    if err := store.Put(testCred); err != ErrPassMismatch {
+        t.Errorf("mistyped confirmation: err = %v, want ErrPassMismatch", err)
+    }
+    if _, err := os.Stat(path); err == nil {
+        t.Errorf("vault created under a passphrase that wasn't confirmed")
+    }
+    if err := store.Unlock(); err != ErrPassMismatch {
+        t.Errorf("Unlock with a mistyped confirmation: err = %v, want ErrPassMismatch", err)
+    }
+
+    store.Confirm = func() ([]byte, error) { return []byte("right"), nil }
+    if err := store.Put(testCred); err != nil {
+        t.Fatal(err)
+    }
+    // an existing vault isn't confirmed
+    confirmed := false
+    reopened := testStore(path, "right", &asked)
+    reopened.Confirm = func() ([]byte, error) {
+        confirmed = true
+        return []byte("right"), nil
+    }
+    if err := reopened.Put(Credential{Name: "home", Login: api.LoginParam{Email: "me@home.com", Password: "x"}}); err != nil || confirmed {
+        t.Errorf("Put to an existing vault = %v, confirmed %v, want no confirmation", err, confirmed)
+    }
+}
+
+func TestMemStore(t *testing.T) {
+    var store MemStore
+    if names, err := store.List(); err != nil || len(names) != 0 {
+        t.Errorf("zero value: List = %v, %v, want it empty", names, err)
+    }
+    if err := store.Put(testCred); err != nil {
+        t.Fatal(err)
+    }
+    if cred, err := store.Get("work"); err != nil || !reflect.DeepEqual(*cred, testCred) {
+        t.Errorf("Get = %v, %v, want %v", cred, err, testCred)
+    }
+    if err := store.Put(Credential{}); err != ErrNoName {
+        t.Errorf("Put without a name: err = %v, want ErrNoName", err)
+    }
+    if err := store.Delete("work"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := store.Get("work"); err != ErrNoCred {
+        t.Errorf("Get of a deleted account: err = %v, want ErrNoCred", err)
+    }
+    if err := store.Delete("work"); err != ErrNoCred {
+        t.Errorf("Delete twice: err = %v, want ErrNoCred", err)
+    }
+}